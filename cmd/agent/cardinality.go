@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// overflowLabel replaces every label value of a series over the cap
+const overflowLabel = "other"
+
+// metricCardinalityCapped counts observations collapsed into the "other"
+// series because a metric hit -metric-label-cap
+var metricCardinalityCapped = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "upf_metric_cardinality_capped_total",
+		Help: "Total number of observations recorded under the 'other' series because the metric reached its label cap",
+	},
+	[]string{"metric"},
+)
+
+// cappedCounterVec is a CounterVec that creates at most *metricLabelCap
+// series. Further label combinations all go to a single series whose labels
+// are "other", so enriching labels (TEIDs, IPs, ...) cannot blow up
+// Prometheus.
+type cappedCounterVec struct {
+	*prometheus.CounterVec
+	name string
+
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	warned bool
+}
+
+func newCappedCounterVec(opts prometheus.CounterOpts, labelNames []string) *cappedCounterVec {
+	return &cappedCounterVec{
+		CounterVec: prometheus.NewCounterVec(opts, labelNames),
+		name:       opts.Name,
+		seen:       make(map[string]struct{}),
+	}
+}
+
+// WithLabelValues returns the counter for lvs, or the "other" counter once
+// the cap is reached and lvs is a new combination
+func (c *cappedCounterVec) WithLabelValues(lvs ...string) prometheus.Counter {
+	limit := *metricLabelCap
+	if limit <= 0 {
+		return c.CounterVec.WithLabelValues(lvs...)
+	}
+
+	key := strings.Join(lvs, "\xff")
+	c.mu.Lock()
+	_, known := c.seen[key]
+	if !known && len(c.seen) < limit {
+		c.seen[key] = struct{}{}
+		known = true
+	}
+	capped := !known && !c.warned
+	if capped {
+		c.warned = true
+	}
+	c.mu.Unlock()
+
+	if known {
+		return c.CounterVec.WithLabelValues(lvs...)
+	}
+	if capped {
+		log.Printf("[WARN] %s reached %d label combinations, recording new ones as %q", c.name, limit, overflowLabel)
+	}
+	metricCardinalityCapped.WithLabelValues(c.name).Inc()
+
+	other := make([]string, len(lvs))
+	for i := range other {
+		other[i] = overflowLabel
+	}
+	return c.CounterVec.WithLabelValues(other...)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"maps"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/adminauth"
+)
+
+// maxDumpSessions bounds the sessions of a debug dump without ?full=true,
+// a dump of a large correlation would otherwise run into hundreds of MB
+const maxDumpSessions = 1000
+
+// adminToken is the bearer token of the admin endpoints, loaded from
+// -admin-token-file (empty disables them)
+var adminToken string
+
+// buildInfo identifies the agent binary
+type buildInfo struct {
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"vcs_revision,omitempty"`
+	Time      string `json:"vcs_time,omitempty"`
+	Modified  bool   `json:"vcs_modified,omitempty"`
+	Tags      string `json:"tags,omitempty"` // e.g. redis,kafka
+}
+
+// handleDebugDumpAPI returns the agent's internal state in one JSON
+// document to attach to bug reports: sessions with their maps, PFCP peers,
+// counters, flags and build. SUPIs and credentials are redacted, and the
+// sessions are limited to maxDumpSessions unless ?full=true.
+func handleDebugDumpAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	writeError := func(status int, msg string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+	if adminToken == "" {
+		writeError(http.StatusNotFound, "debug dump disabled, start the agent with -admin-token-file")
+		return
+	}
+	if !adminauth.Authorized(r.Header.Get("Authorization"), adminToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="upf-agent"`)
+		writeError(http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := maxDumpSessions
+	if v := r.URL.Query().Get("full"); v != "" {
+		full, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(http.StatusBadRequest, "full must be true or false")
+			return
+		}
+		if full {
+			limit = 0
+		}
+	}
+
+	state := pfcpCorrelation.Dump(limit)
+	for i := range state.Sessions {
+		state.Sessions[i].SUPI = redactSUPI(state.Sessions[i].SUPI)
+	}
+
+	counters := map[string]interface{}{
+		"pfcp_messages":                 pfcpSniffer.MessageCounts(),
+		"parse_errors":                  pfcpSniffer.ParseErrors(),
+		"truncated_messages":            pfcpSniffer.TruncatedMessages(),
+		"capture_drops":                 pfcpSniffer.CaptureDrops(),
+		"incomplete_establishments":     pfcpSniffer.IncompleteEstablishments(),
+		"gtpu_error_indications":        pfcpSniffer.ErrorIndications(),
+		"bearer_errors":                 pfcpCorrelation.BearerErrors(),
+		"teid_collisions":               pfcpCorrelation.TEIDCollisions(),
+		"teid_evictions":                pfcpCorrelation.TEIDEvictions(),
+		"session_overflows":             pfcpCorrelation.SessionOverflows(),
+		"establishment_retransmissions": pfcpCorrelation.EstablishmentRetransmissions(),
+		"reestablishments":              pfcpCorrelation.Reestablishments(),
+		"establishments":                pfcpSniffer.EstablishmentStats(),
+	}
+	dropEventsMu.RLock()
+	counters["drops"] = totalDrops
+	counters["drops_by_reason"] = maps.Clone(dropsByReason)
+	dropEventsMu.RUnlock()
+	if ebpfLoader != nil {
+		counters["drop_events_lost"] = ebpfLoader.LostDueToBackpressure()
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generated_at": time.Now().Format(time.RFC3339),
+		"node":         *nodeName,
+		"started_at":   startedAt.Format(time.RFC3339),
+		"build":        readBuildInfo(),
+		"config":       dumpConfig(),
+		"ebpf_ready":   ebpfReady.Load(),
+		"correlation":  state,
+		"pfcp_peers":   pfcpSniffer.NodeMessageStats(),
+		"counters":     counters,
+	})
+}
+
+// readBuildInfo returns what the Go toolchain recorded about the binary
+func readBuildInfo() buildInfo {
+	info := buildInfo{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Module = bi.Main.Path
+	info.Version = bi.Main.Version
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.Time = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		case "-tags":
+			info.Tags = setting.Value
+		}
+	}
+	return info
+}
+
+// dumpConfig returns the value of every flag. Secrets are only ever read
+// from files, so the values are paths, except for credentials embedded in
+// URLs, which are redacted.
+func dumpConfig() map[string]string {
+	config := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			value = u.Redacted()
+		}
+		config[f.Name] = value
+	})
+	return config
+}
+
+// redactSUPI keeps the type and the last 4 digits of a SUPI, enough to
+// tell sessions apart in a report without identifying the subscriber:
+// imsi-208930000000003 becomes imsi-***********0003
+func redactSUPI(supi string) string {
+	if supi == "" {
+		return ""
+	}
+	prefix, id, ok := strings.Cut(supi, "-")
+	if !ok {
+		prefix, id = "", supi
+	} else {
+		prefix += "-"
+	}
+	if len(id) <= 4 {
+		return prefix + strings.Repeat("*", len(id))
+	}
+	return prefix + strings.Repeat("*", len(id)-4) + id[len(id)-4:]
+}
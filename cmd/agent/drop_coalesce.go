@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// dropCoalesceMaxKeys bounds the drops held per window. Drops for further
+// (TEID, reason, direction) combinations are emitted right away.
+const dropCoalesceMaxKeys = 10000
+
+// dropCoalesceKey identifies drops that are collapsed into one event
+type dropCoalesceKey struct {
+	teid, reason, direction string
+}
+
+// dropCoalescer collapses identical drops within a window into a single
+// event carrying their count, so a drop storm does not flood the API, the
+// WebSocket and the sinks. Only the event stream is coalesced, the
+// Prometheus drop counters are updated for every drop.
+type dropCoalescer struct {
+	emit func(model.DropEvent)
+
+	mu      sync.Mutex
+	pending map[dropCoalesceKey]*model.DropEvent
+	order   []dropCoalesceKey // first-seen order, so events keep their sequence
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newDropCoalescer(emit func(model.DropEvent)) *dropCoalescer {
+	return &dropCoalescer{
+		emit:    emit,
+		pending: make(map[dropCoalesceKey]*model.DropEvent),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// add holds event until the end of the window, or counts it on the event
+// already held for its TEID, reason and direction. The held event keeps
+// the details of the first drop.
+func (d *dropCoalescer) add(event model.DropEvent) {
+	key := dropCoalesceKey{event.TEID, event.Reason, event.Direction}
+
+	d.mu.Lock()
+	if held, ok := d.pending[key]; ok {
+		held.Count++
+		d.mu.Unlock()
+		return
+	}
+	if len(d.pending) >= dropCoalesceMaxKeys {
+		d.mu.Unlock()
+		event.Count = 1
+		d.emit(event)
+		return
+	}
+	event.Count = 1
+	d.pending[key] = &event
+	d.order = append(d.order, key)
+	d.mu.Unlock()
+}
+
+// run emits the held events every window until close
+func (d *dropCoalescer) run(window time.Duration) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			d.flush()
+			return
+		case <-ticker.C:
+			d.flush()
+		}
+	}
+}
+
+// flush emits the held events in first-seen order
+func (d *dropCoalescer) flush() {
+	d.mu.Lock()
+	pending := d.pending
+	order := d.order
+	d.pending = make(map[dropCoalesceKey]*model.DropEvent, len(pending))
+	d.order = nil
+	d.mu.Unlock()
+
+	for _, key := range order {
+		d.emit(*pending[key])
+	}
+}
+
+// close emits the events still held and stops run. Safe on nil.
+func (d *dropCoalescer) close() {
+	if d == nil {
+		return
+	}
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// Bounds of the interval at which -drop-retention is applied, a tenth of
+// the retention in between
+const (
+	minDropCompactInterval = time.Second
+	maxDropCompactInterval = time.Minute
+)
+
+// recentDrop is a drop event kept for /api/drops with the time it was
+// stored. recentDrops holds them oldest first, so the stored times are
+// sorted and expired events are a prefix.
+type recentDrop struct {
+	at    time.Time
+	event model.DropEvent
+}
+
+// recentDropEventsLocked returns the recent drop events newest first.
+// dropEventsMu must be held.
+func recentDropEventsLocked() []model.DropEvent {
+	events := make([]model.DropEvent, len(recentDrops))
+	for i, drop := range recentDrops {
+		events[len(recentDrops)-1-i] = drop.event
+	}
+	return events
+}
+
+// runDropRetention discards recent drop events older than retention until
+// stop is closed. It runs apart from storeDropEvent, which only appends
+// and trims to -drop-recent-max.
+func runDropRetention(retention time.Duration, stop <-chan struct{}) {
+	interval := retention / 10
+	if interval < minDropCompactInterval {
+		interval = minDropCompactInterval
+	}
+	if interval > maxDropCompactInterval {
+		interval = maxDropCompactInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			compactRecentDrops(now.Add(-retention))
+		}
+	}
+}
+
+// compactRecentDrops discards the recent drop events stored before cutoff.
+// Finding them is a binary search, so the lock is held briefly; the backing
+// array is only copied once most of it is unused.
+func compactRecentDrops(cutoff time.Time) {
+	dropEventsMu.Lock()
+	defer dropEventsMu.Unlock()
+
+	expired := sort.Search(len(recentDrops), func(i int) bool {
+		return !recentDrops[i].at.Before(cutoff)
+	})
+	switch {
+	case expired == len(recentDrops):
+		recentDrops = nil
+	case expired > 0 && expired >= cap(recentDrops)/2:
+		recentDrops = append([]recentDrop(nil), recentDrops[expired:]...)
+	default:
+		recentDrops = recentDrops[expired:]
+	}
+}
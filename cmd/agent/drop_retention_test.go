@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+func TestCompactRecentDrops(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		cutoff time.Duration // after the first of 8 drops, 1s apart
+		kept   string        // TEIDs of the drops kept
+		copied bool          // whether the kept ones were moved to a new array
+	}{
+		{"none expired", -time.Second, "[0 1 2 3 4 5 6 7]", false},
+		{"cutoff at the first", 0, "[0 1 2 3 4 5 6 7]", false},
+		{"partial", 3 * time.Second, "[3 4 5 6 7]", false},
+		{"partial, past half", 4500 * time.Millisecond, "[5 6 7]", true},
+		{"all expired", 8 * time.Second, "[]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := recentDrops
+			t.Cleanup(func() { recentDrops = saved })
+
+			recentDrops = make([]recentDrop, 0, 8)
+			for i := 0; i < 8; i++ {
+				recentDrops = append(recentDrops, recentDrop{
+					at:    start.Add(time.Duration(i) * time.Second),
+					event: model.DropEvent{TEID: fmt.Sprint(i)},
+				})
+			}
+			last := &recentDrops[7]
+
+			compactRecentDrops(start.Add(tt.cutoff))
+
+			teids := make([]string, 0, len(recentDrops))
+			for _, drop := range recentDrops {
+				teids = append(teids, drop.event.TEID)
+			}
+			if got := fmt.Sprint(teids); got != tt.kept {
+				t.Errorf("kept %s, want %s", got, tt.kept)
+			}
+			if len(recentDrops) == 0 {
+				if recentDrops != nil {
+					t.Error("array kept with no drops left")
+				}
+				return
+			}
+			if copied := &recentDrops[len(recentDrops)-1] != last; copied != tt.copied {
+				t.Errorf("copied %v, want %v", copied, tt.copied)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dropSummary aggregates drop events between periodic summary log lines,
+// replacing the per-drop log line in headless deployments
+type dropSummary struct {
+	mu       sync.Mutex
+	total    uint64
+	byReason map[string]uint64
+	byTEID   map[uint32]uint64
+	topN     int
+}
+
+func newDropSummary(topN int) *dropSummary {
+	return &dropSummary{
+		byReason: make(map[string]uint64),
+		byTEID:   make(map[uint32]uint64),
+		topN:     topN,
+	}
+}
+
+// add counts one drop for the current interval
+func (d *dropSummary) add(reason string, teid uint32) {
+	d.mu.Lock()
+	d.total++
+	d.byReason[reason]++
+	if teid != 0 {
+		d.byTEID[teid]++
+	}
+	d.mu.Unlock()
+}
+
+// run logs a summary every interval until stop is closed. Quiet intervals
+// are not logged.
+func (d *dropSummary) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		d.mu.Lock()
+		total := d.total
+		byReason := d.byReason
+		byTEID := d.byTEID
+		d.total = 0
+		d.byReason = make(map[string]uint64)
+		d.byTEID = make(map[uint32]uint64)
+		d.mu.Unlock()
+
+		if total == 0 {
+			continue
+		}
+
+		reasons := make([]string, 0, len(byReason))
+		for reason, count := range byReason {
+			reasons = append(reasons, fmt.Sprintf("%s:%d", reason, count))
+		}
+		sort.Strings(reasons)
+
+		log.Printf("[DROP-SUMMARY] interval=%s total=%d by_reason=%s top_teids=%s",
+			interval, total, strings.Join(reasons, ","), formatTopTEIDs(byTEID, d.topN))
+	}
+}
+
+// formatTopTEIDs renders the n TEIDs with the most drops as "0x1:5,0x2:3"
+func formatTopTEIDs(byTEID map[uint32]uint64, n int) string {
+	teids := make([]uint32, 0, len(byTEID))
+	for teid := range byTEID {
+		teids = append(teids, teid)
+	}
+	sort.Slice(teids, func(i, j int) bool {
+		if byTEID[teids[i]] != byTEID[teids[j]] {
+			return byTEID[teids[i]] > byTEID[teids[j]]
+		}
+		return teids[i] < teids[j]
+	})
+	if n >= 0 && len(teids) > n {
+		teids = teids[:n]
+	}
+	if len(teids) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, 0, len(teids))
+	for _, teid := range teids {
+		parts = append(parts, fmt.Sprintf("0x%x:%d", teid, byTEID[teid]))
+	}
+	return strings.Join(parts, ",")
+}
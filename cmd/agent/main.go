@@ -1,28 +1,108 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"net"
 	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof/ on the default mux, gated by -pprof
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/solar224/5G-DPOP/internal/adminauth"
+	"github.com/solar224/5G-DPOP/internal/configfile"
 	"github.com/solar224/5G-DPOP/internal/ebpf"
+	"github.com/solar224/5G-DPOP/internal/events"
+	"github.com/solar224/5G-DPOP/internal/httpcache"
+	"github.com/solar224/5G-DPOP/internal/jsonbody"
+	"github.com/solar224/5G-DPOP/internal/loglevel"
+	"github.com/solar224/5G-DPOP/internal/model"
 	"github.com/solar224/5G-DPOP/internal/pfcp"
+	"github.com/solar224/5G-DPOP/internal/sink"
+	"github.com/solar224/5G-DPOP/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	// Command line flags
-	pfcpIface = flag.String("pfcp-iface", "lo", "Interface to capture PFCP packets")
+	pfcpIface        = flag.String("pfcp-iface", "lo", "Interface to capture PFCP packets")
+	pfcpAddress      = flag.String("pfcp-address", "", "Capture PFCP on the interface owning this N4 IP or subnet (e.g. 10.100.200.3 or 10.100.200.0/24), instead of -pfcp-iface")
+	pfcpBootstrap    = flag.String("pfcp-bootstrap", "", "Replay this PFCP pcap into the session store before starting live capture")
+	replayMaxSize    = flag.Int64("pfcp-replay-max-size", 32<<20, "Largest pcap accepted by POST /api/pfcp/replay, in bytes")
+	replayTimeout    = flag.Duration("pfcp-replay-timeout", 30*time.Second, "Time limit of a POST /api/pfcp/replay, which returns what it parsed so far")
+	pfcpWorkers      = flag.Int("pfcp-workers", 1, "Number of PFCP packet processing workers (1 processes on the capture goroutine). Messages between the same two N4 peers share a worker, so a single SMF/UPF pair gets no speedup")
+	pfcpTapPath      = flag.String("pfcp-tap", "", "Copy every captured PFCP packet into this pcap file (disabled if empty)")
+	pfcpTapMaxSize   = flag.Int64("pfcp-tap-max-size", 100*1024*1024, "Rotate the PFCP tap file after this many bytes (0 disables rotation)")
+	maxSessions      = flag.Int("max-sessions", 100000, "Maximum number of tracked PFCP sessions (0 = unlimited)")
+	maxSessionsMode  = flag.String("max-sessions-policy", "reject", "What to do with new sessions at -max-sessions: reject or evict-oldest")
+	maxTEIDs         = flag.Int("max-teids", 400000, "Maximum number of TEID to session mappings, least recently used ones are evicted (0 = unlimited)")
+	sessionStore     = flag.String("session-store", "memory", "Where PFCP sessions are kept: memory, or redis to also save them in -redis-addr so they survive a restart (requires -tags redis build)")
+	redisAddr        = flag.String("redis-addr", "localhost:6379", "Redis server for -session-store redis")
+	redisPass        = flag.String("redis-password-file", "", "File holding the password for -redis-addr")
+	redisDB          = flag.Int("redis-db", 0, "Redis database for -session-store redis")
+	redisKey         = flag.String("redis-key", "", "Redis hash holding the sessions (default 5g-dpop:sessions:<node-name>)")
+	pfcpIfaceWait    = flag.Duration("pfcp-iface-wait", 30*time.Second, "How long to wait for -pfcp-iface to appear before giving up on PFCP capture (0 = no wait)")
+	gtpuErrorInd     = flag.Bool("gtpu-error-indications", false, "Also capture GTP-U Error Indications on -pfcp-iface to detect broken bearers (the interface must carry N3/N9)")
+	pfcpMaxIEDepth   = flag.Int("pfcp-max-ie-depth", pfcp.DefaultMaxIEDepth, "Deepest nesting of grouped IEs accepted, PFCP messages nesting deeper are dropped as malformed")
+	pfcpIEStats      = flag.Bool("pfcp-ie-stats", false, "Count every PFCP IE type seen, served on /api/pfcp/ie-coverage")
+	pfcpPeerHealth   = flag.Bool("pfcp-peer-health", false, "Track the inter-arrival time and jitter of PFCP messages per peer, served on /api/pfcp/peers")
+	pfcpHBInterval   = flag.Duration("pfcp-heartbeat-interval", 0, "Heartbeat interval configured on the PFCP peers, to count missed heartbeats with -pfcp-peer-health (0 = not counted)")
+	pfcpLogTypes     = flag.String("pfcp-log-types", "", "Comma-separated PFCP message types to log verbosely, by name (e.g. \"Session Establishment Request,Session Deletion Request\"), number or all (default none, all with -debug)")
+	debugMode        = flag.Bool("debug", false, "Debug mode: log every PFCP message verbosely unless -pfcp-log-types is set")
+	logLevel         = flag.String("log-level", "info", "Minimum level of the log lines printed: debug, info, warn or error (changed at runtime with PUT /api/log-level)")
+	pfcpTstampType   = flag.String("pfcp-tstamp-type", "", "libpcap timestamp source for PFCP capture, e.g. host, host_hiprec, adapter (default: libpcap default)")
+	dropLogPath      = flag.String("drop-log", "", "Append every drop event as NDJSON to this file (disabled if empty)")
+	dropLogMaxSize   = flag.Int64("drop-log-max-size", 100*1024*1024, "Rotate the drop log after this many bytes (0 disables rotation)")
+	dropSummaryEvery = flag.Duration("drop-summary-interval", 0, "Log a drop summary at this interval instead of one line per drop (0 logs every drop)")
+	dropSummaryTop   = flag.Int("drop-summary-top", 5, "Number of top TEIDs listed in each drop summary")
+	dropReasonMap    = flag.String("drop-reason-map", "", "JSON or YAML file mapping drop reason codes to names, overriding the built-in gtp5g names (for forked eBPF programs)")
+	dropRecentMax    = flag.Int("drop-recent-max", 100, "Number of most recent drop events kept for /api/drops")
+	dropRetention    = flag.Duration("drop-retention", 0, "Also discard recent drop events older than this, e.g. 10m with a -drop-recent-max large enough for the window (0 keeps them until newer ones replace them)")
+	dropCoalesce     = flag.Duration("drop-coalesce-window", 0, "Collapse identical drop events (same TEID, reason and direction) within this window into one event with a count (0 disables)")
+	kafkaBrokers     = flag.String("kafka-brokers", "", "Comma-separated Kafka brokers for drop/session events (disabled if empty, requires -tags kafka build)")
+	metricLabelCap   = flag.Int("metric-label-cap", 1000, "Maximum label combinations per capped metric, further ones are recorded as 'other' (0 = unlimited)")
+	collectInterval  = flag.Duration("collect-interval", time.Second, "Interval between eBPF traffic counter reads, which sets the resolution of the pps/bps rates (at least 100ms)")
+	statsCacheTTL    = flag.Duration("stats-cache-ttl", ebpf.DefaultStatsCacheTTL, "Reuse eBPF stats map reads for this long across callers (0 disables)")
+	dropQueueSize    = flag.Int("drop-queue-size", ebpf.DefaultDropQueueSize, "Drop events buffered between the ring buffer reader and their consumers; further ones are lost and counted")
+	tcEgressIface    = flag.String("tc-egress-iface", "", "Count downlink GTP-U at TC egress of this N3 interface instead of the gtp5g_dev_xmit kprobe (adds a clsact qdisc; disabled if empty)")
+	bpfObject        = flag.String("bpf-object", "", "Load this compiled eBPF object instead of the embedded one (must provide the same maps and programs)")
+	httpAddr         = flag.String("http-addr", ":9100", "TCP address of the metrics and API server (empty disables it, then -metrics-socket is required)")
+	metricsSocket    = flag.String("metrics-socket", "", "Also serve the metrics and API endpoints on this Unix domain socket, e.g. /run/upf-agent.sock (disabled if empty)")
+	metricsSockMode  = flag.String("metrics-socket-mode", "0660", "File permissions of -metrics-socket")
+	pprofEnabled     = flag.Bool("pprof", false, "Serve net/http/pprof profiles under /debug/pprof/ on the HTTP server")
+	adminTokenFile   = flag.String("admin-token-file", "", "File holding the bearer token required by /api/debug/dump (the endpoint is disabled if empty)")
+	selfTest         = flag.Bool("selftest", false, "Load the eBPF programs, check their maps, attach and detach them, print a pass/fail report and exit (non-zero on failure)")
+	selfTestIface    = flag.String("selftest-iface", "lo", "Interface -selftest attaches the TC egress counter to")
+	requireEBPF      = flag.Bool("require-ebpf", true, "Exit if the eBPF programs cannot be loaded (e.g. not root); false keeps running with PFCP capture and the APIs only")
+	kafkaTopic       = flag.String("kafka-topic", "upf-events", "Kafka topic for drop/session events")
+	remoteWriteURL   = flag.String("remote-write-url", "", "Push metrics to this Prometheus remote-write endpoint (disabled if empty)")
+	remoteWriteEvery = flag.Duration("remote-write-interval", sink.DefaultRemoteWriteInterval, "Interval between remote-write pushes")
+	remoteWriteBatch = flag.Int("remote-write-batch-size", sink.DefaultRemoteWriteBatchSize, "Maximum number of series per remote-write request")
+	remoteWriteUser  = flag.String("remote-write-username", "", "Basic auth username for -remote-write-url")
+	remoteWritePass  = flag.String("remote-write-password-file", "", "File holding the basic auth password for -remote-write-url")
+	nodeName         = flag.String("node-name", defaultNodeName(), "Node name added as the node label to all metrics and to /api/stats/summary")
+	configPath       = flag.String("config", "", "JSON or YAML file of flag values, e.g. {\"log-level\": \"debug\"}; command line flags win, and SIGHUP reloads -log-level, -drop-reason-map and -drop-recent-max from it")
+	drainTimeout     = flag.Duration("drain-timeout", 10*time.Second, "On SIGTERM, how long to wait for the drop log, Kafka and remote-write sinks to flush before detaching eBPF")
+
+	// Registers the metrics with the node label, set by registerMetrics
+	metricsRegisterer prometheus.Registerer
 
 	// Prometheus metrics
 	packetsTotal = prometheus.NewCounterVec(
@@ -41,7 +121,7 @@ var (
 		[]string{"direction"},
 	)
 
-	packetDropsTotal = prometheus.NewCounterVec(
+	packetDropsTotal = newCappedCounterVec(
 		prometheus.CounterOpts{
 			Name: "upf_packet_drops_total",
 			Help: "Total number of dropped packets",
@@ -56,246 +136,1297 @@ var (
 		},
 	)
 
+	packetsPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "upf_packets_per_second",
+			Help: "Current packet rate by direction, over the last collection interval",
+		},
+		[]string{"direction"},
+	)
+
+	bitsPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "upf_bits_per_second",
+			Help: "Current throughput in bits per second by direction, over the last collection interval",
+		},
+		[]string{"direction"},
+	)
+
+	kafkaPublishFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "upf_kafka_publish_failures_total",
+			Help: "Total number of events that could not be published to Kafka",
+		},
+	)
+
+	sessionStoreFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "upf_session_store_write_failures_total",
+			Help: "Total number of session writes to the Redis session store that failed (retried on the next flush)",
+		},
+	)
+
+	uncorrelatedDrops = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "upf_uncorrelated_drops_total",
+			Help: "Total number of GTP-U drops whose TEID is unknown to PFCP correlation (missed PFCP messages or control/data plane out of sync)",
+		},
+	)
+
+	remoteWriteFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "upf_remote_write_failed_samples_total",
+			Help: "Total number of samples that could not be pushed to -remote-write-url",
+		},
+	)
+
 	// Drop events storage
 	dropEventsMu  sync.RWMutex
-	recentDrops   []DropEventJSON
+	recentDrops   []recentDrop // oldest first
+	recentDropMax int          // -drop-recent-max, changed on reload
 	totalDrops    uint64
 	dropsByReason = make(map[string]uint64)
 
 	// PFCP correlation
 	pfcpCorrelation *pfcp.Correlation
 
+	// PFCP sniffer for API access
+	pfcpSniffer *pfcp.Sniffer
+
+	// Event bus shared by the eBPF loader, PFCP correlation and sinks
+	eventBus = events.NewBus()
+
 	// Global eBPF loader for API access
 	ebpfLoader *ebpf.Loader
 
-	// Previous counter values for calculating deltas
+	// Readiness state: set once eBPF programs are loaded and attached
+	ebpfReady atomic.Bool
+
+	// Set when running without eBPF under -require-ebpf=false
+	ebpfDisabled atomic.Bool
+
+	// Set on SIGTERM while the agent flushes its sinks before exiting
+	draining atomic.Bool
+
+	// Process start time, and when the counters last started from zero.
+	// Counters are only reset by a restart today, so both are the same.
+	startedAt       = time.Now()
+	countersResetAt = startedAt
+
+	// Previous counter values for calculating deltas, owned by collectStats
 	prevUplinkPackets   uint64
 	prevDownlinkPackets uint64
 	prevUplinkBytes     uint64
 	prevDownlinkBytes   uint64
+
+	// Current rates, computed from the deltas in collectStats, and the
+	// byte counters they were computed from
+	ratesMu              sync.RWMutex
+	currentRates         trafficRates
+	currentUplinkBytes   uint64
+	currentDownlinkBytes uint64
+)
+
+// trafficRates holds the packet and bit rates over the last collection
+// interval
+type trafficRates struct {
+	PPSUL float64 `json:"pps_ul"`
+	PPSDL float64 `json:"pps_dl"`
+	BPSUL float64 `json:"bps_ul"`
+	BPSDL float64 `json:"bps_dl"`
+}
+
+// pfcpMessagesDesc describes the per-message-type PFCP counters
+var pfcpMessagesDesc = prometheus.NewDesc(
+	"upf_pfcp_messages_total",
+	"Total number of PFCP messages seen by type",
+	[]string{"msg_type"}, nil,
+)
+
+// pfcpParseErrorsDesc describes the malformed PFCP message counter
+var pfcpParseErrorsDesc = prometheus.NewDesc(
+	"upf_pfcp_parse_errors_total",
+	"Total number of PFCP messages dropped as malformed",
+	nil, nil,
+)
+
+// pfcpTruncatedDesc describes the counter of PFCP messages cut short
+var pfcpTruncatedDesc = prometheus.NewDesc(
+	"upf_pfcp_truncated_messages_total",
+	"Total number of PFCP messages skipped because their length exceeds the captured payload (snaplen or a bogus length)",
+	nil, nil,
+)
+
+// pfcpCaptureDropsDesc describes the counter of packets libpcap dropped
+var pfcpCaptureDropsDesc = prometheus.NewDesc(
+	"upf_pfcp_capture_drops_total",
+	"Total number of packets dropped by libpcap on the PFCP capture (buffer full or dropped by the interface)",
+	nil, nil,
+)
+
+// pfcpIncompleteDesc describes the counter of sessions first seen mid-stream
+var pfcpIncompleteDesc = prometheus.NewDesc(
+	"upf_pfcp_incomplete_establishments_total",
+	"Total number of sessions first seen in a Modification Request without a prior Establishment",
+	nil, nil,
+)
+
+// pfcpTEIDCollisionsDesc describes the counter of TEIDs reassigned between sessions
+var pfcpTEIDCollisionsDesc = prometheus.NewDesc(
+	"upf_pfcp_teid_collisions_total",
+	"Total number of TEIDs that were already mapped to another session when added",
+	nil, nil,
 )
 
-// DropEventJSON is the JSON representation of a drop event
-type DropEventJSON struct {
-	Timestamp string `json:"timestamp"`
-	TEID      string `json:"teid"`
-	SrcIP     string `json:"src_ip"`
-	DstIP     string `json:"dst_ip"`
-	SrcPort   uint16 `json:"src_port"`
-	DstPort   uint16 `json:"dst_port"`
-	PktLen    uint32 `json:"pkt_len"`
-	Reason    string `json:"reason"`
-	Direction string `json:"direction"`
-}
+// pfcpTEIDMapSizeDesc describes the gauge of TEIDs mapped to sessions
+var pfcpTEIDMapSizeDesc = prometheus.NewDesc(
+	"upf_pfcp_teid_map_size",
+	"Number of TEIDs currently mapped to sessions",
+	nil, nil,
+)
+
+// activeTEIDsDesc describes the per-direction TEID gauge
+var activeTEIDsDesc = prometheus.NewDesc(
+	"upf_active_teids",
+	"Number of TEIDs of the active sessions by the direction of the traffic they receive, from the Source Interface of their PDR",
+	[]string{"direction"}, nil,
+)
+
+// pfcpTEIDEvictionsDesc describes the counter of TEID mappings evicted by -max-teids
+var pfcpTEIDEvictionsDesc = prometheus.NewDesc(
+	"upf_pfcp_teid_evictions_total",
+	"Total number of TEID mappings evicted because the TEID limit was reached",
+	nil, nil,
+)
+
+// pfcpUsageReportsDesc describes the counter of PFCP Usage Reports with a
+// Volume Measurement
+var pfcpUsageReportsDesc = prometheus.NewDesc(
+	"upf_pfcp_usage_reports_total",
+	"Total number of PFCP Usage Reports carrying a Volume Measurement",
+	nil, nil,
+)
+
+// pfcpReportedBytesDesc describes the counter of bytes the UPF reported in
+// Usage Reports, by volume (total, uplink, downlink), to compare with
+// upf_bytes_total measured by eBPF
+var pfcpReportedBytesDesc = prometheus.NewDesc(
+	"upf_pfcp_reported_bytes_total",
+	"Total bytes reported by the UPF in PFCP Usage Report Volume Measurements",
+	[]string{"direction"}, nil,
+)
+
+// pfcpSessionOverflowsDesc describes the counter of sessions that hit -max-sessions
+var pfcpSessionOverflowsDesc = prometheus.NewDesc(
+	"upf_pfcp_session_overflows_total",
+	"Total number of new sessions that exceeded the session limit (rejected or evicted the oldest)",
+	nil, nil,
+)
+
+// pfcpReestablishmentsDesc describes the counter of Establishments for a
+// CP SEID that already had a session, by whether they were retransmissions
+// (ignored) or reused the SEID (old session replaced)
+var pfcpReestablishmentsDesc = prometheus.NewDesc(
+	"upf_pfcp_reestablishments_total",
+	"Total number of Session Establishment Requests for a CP SEID that already had a session",
+	[]string{"kind"}, nil,
+)
+
+// pfcpEstablishmentRequestsDesc describes the counter of Session
+// Establishment Requests, pfcpEstablishmentResponsesDesc the counter of
+// their Responses by outcome and Cause, and pfcpEstablishmentRatioDesc the
+// share of successful ones over pfcp.EstablishmentWindow (not exported
+// while there were no Responses in the window)
+var (
+	pfcpEstablishmentRequestsDesc = prometheus.NewDesc(
+		"upf_pfcp_session_establishment_requests_total",
+		"Total number of PFCP Session Establishment Requests",
+		nil, nil,
+	)
+	pfcpEstablishmentResponsesDesc = prometheus.NewDesc(
+		"upf_pfcp_session_establishment_responses_total",
+		"Total number of PFCP Session Establishment Responses, by result (success or failure) and Cause",
+		[]string{"result", "cause"}, nil,
+	)
+	pfcpEstablishmentRatioDesc = prometheus.NewDesc(
+		"upf_pfcp_session_establishment_success_ratio",
+		"Share of Session Establishment Responses accepting the request over the last "+pfcp.EstablishmentWindow.String(),
+		nil, nil,
+	)
+)
+
+// gtpuErrorIndicationsDesc describes the counter of GTP-U Error Indications,
+// by whether their TEID belonged to a known session
+var gtpuErrorIndicationsDesc = prometheus.NewDesc(
+	"upf_gtpu_error_indications_total",
+	"Total number of GTP-U Error Indications (peer received G-PDUs for an unknown TEID)",
+	[]string{"correlated"}, nil,
+)
+
+// bufferingSessionsDesc describes the gauge of sessions buffering downlink
+// packets (a FAR with Apply Action BUFF, UE idle)
+var bufferingSessionsDesc = prometheus.NewDesc(
+	"upf_sessions_buffering",
+	"Number of sessions whose downlink is buffered by the UPF (UE idle)",
+	nil, nil,
+)
+
+// pfcpMessageCollector exports the sniffer's message counters at scrape time
+type pfcpMessageCollector struct{}
+
+func (pfcpMessageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pfcpMessagesDesc
+	ch <- pfcpParseErrorsDesc
+	ch <- pfcpTruncatedDesc
+	ch <- pfcpCaptureDropsDesc
+	ch <- pfcpIncompleteDesc
+	ch <- pfcpTEIDCollisionsDesc
+	ch <- pfcpTEIDMapSizeDesc
+	ch <- activeTEIDsDesc
+	ch <- pfcpTEIDEvictionsDesc
+	ch <- pfcpUsageReportsDesc
+	ch <- pfcpReportedBytesDesc
+	ch <- pfcpSessionOverflowsDesc
+	ch <- pfcpReestablishmentsDesc
+	ch <- pfcpEstablishmentRequestsDesc
+	ch <- pfcpEstablishmentResponsesDesc
+	ch <- pfcpEstablishmentRatioDesc
+	ch <- gtpuErrorIndicationsDesc
+	ch <- bufferingSessionsDesc
+}
+
+func (pfcpMessageCollector) Collect(ch chan<- prometheus.Metric) {
+	if pfcpSniffer == nil {
+		return
+	}
+	for name, count := range pfcpSniffer.MessageCounts() {
+		ch <- prometheus.MustNewConstMetric(pfcpMessagesDesc, prometheus.CounterValue, float64(count), name)
+	}
+	ch <- prometheus.MustNewConstMetric(pfcpParseErrorsDesc, prometheus.CounterValue, float64(pfcpSniffer.ParseErrors()))
+	ch <- prometheus.MustNewConstMetric(pfcpTruncatedDesc, prometheus.CounterValue, float64(pfcpSniffer.TruncatedMessages()))
+	ch <- prometheus.MustNewConstMetric(pfcpCaptureDropsDesc, prometheus.CounterValue, float64(pfcpSniffer.CaptureDrops()))
+	ch <- prometheus.MustNewConstMetric(pfcpIncompleteDesc, prometheus.CounterValue, float64(pfcpSniffer.IncompleteEstablishments()))
+	ch <- prometheus.MustNewConstMetric(pfcpTEIDCollisionsDesc, prometheus.CounterValue, float64(pfcpCorrelation.TEIDCollisions()))
+	ch <- prometheus.MustNewConstMetric(pfcpTEIDMapSizeDesc, prometheus.GaugeValue, float64(pfcpCorrelation.TEIDMapSize()))
+	uplinkTEIDs, downlinkTEIDs := pfcpCorrelation.ActiveTEIDs()
+	ch <- prometheus.MustNewConstMetric(activeTEIDsDesc, prometheus.GaugeValue, float64(uplinkTEIDs), "uplink")
+	ch <- prometheus.MustNewConstMetric(activeTEIDsDesc, prometheus.GaugeValue, float64(downlinkTEIDs), "downlink")
+	ch <- prometheus.MustNewConstMetric(pfcpTEIDEvictionsDesc, prometheus.CounterValue, float64(pfcpCorrelation.TEIDEvictions()))
+	reports, totalBytes, uplinkBytes, downlinkBytes := pfcpSniffer.UsageReports()
+	ch <- prometheus.MustNewConstMetric(pfcpUsageReportsDesc, prometheus.CounterValue, float64(reports))
+	ch <- prometheus.MustNewConstMetric(pfcpReportedBytesDesc, prometheus.CounterValue, float64(totalBytes), "total")
+	ch <- prometheus.MustNewConstMetric(pfcpReportedBytesDesc, prometheus.CounterValue, float64(uplinkBytes), "uplink")
+	ch <- prometheus.MustNewConstMetric(pfcpReportedBytesDesc, prometheus.CounterValue, float64(downlinkBytes), "downlink")
+	ch <- prometheus.MustNewConstMetric(pfcpSessionOverflowsDesc, prometheus.CounterValue, float64(pfcpCorrelation.SessionOverflows()))
+	ch <- prometheus.MustNewConstMetric(pfcpReestablishmentsDesc, prometheus.CounterValue, float64(pfcpCorrelation.EstablishmentRetransmissions()), "retransmission")
+	ch <- prometheus.MustNewConstMetric(pfcpReestablishmentsDesc, prometheus.CounterValue, float64(pfcpCorrelation.Reestablishments()), "reuse")
+	ch <- prometheus.MustNewConstMetric(bufferingSessionsDesc, prometheus.GaugeValue, float64(pfcpCorrelation.BufferingSessions()))
+
+	establishments := pfcpSniffer.EstablishmentStats()
+	ch <- prometheus.MustNewConstMetric(pfcpEstablishmentRequestsDesc, prometheus.CounterValue, float64(establishments.Requested))
+	ch <- prometheus.MustNewConstMetric(pfcpEstablishmentResponsesDesc, prometheus.CounterValue, float64(establishments.Succeeded), "success", "Request accepted")
+	for cause, count := range establishments.FailedByCause {
+		ch <- prometheus.MustNewConstMetric(pfcpEstablishmentResponsesDesc, prometheus.CounterValue, float64(count), "failure", cause)
+	}
+	if establishments.SuccessRatio != nil {
+		ch <- prometheus.MustNewConstMetric(pfcpEstablishmentRatioDesc, prometheus.GaugeValue, *establishments.SuccessRatio)
+	}
+
+	// Read the correlated count first, so it never exceeds the total
+	correlated := pfcpCorrelation.BearerErrors()
+	total := pfcpSniffer.ErrorIndications()
+	if correlated > total {
+		correlated = total
+	}
+	ch <- prometheus.MustNewConstMetric(gtpuErrorIndicationsDesc, prometheus.CounterValue, float64(correlated), "true")
+	ch <- prometheus.MustNewConstMetric(gtpuErrorIndicationsDesc, prometheus.CounterValue, float64(total-correlated), "false")
+}
+
+// defaultNodeName is the default -node-name, the hostname
+func defaultNodeName() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return name
+}
+
+// registerMetrics registers the agent's metrics with a constant node label,
+// so series from several agents stay apart when aggregated without relying
+// on scrape-time relabeling
+func registerMetrics(node string) {
+	metricsRegisterer = prometheus.WrapRegistererWith(prometheus.Labels{"node": node}, prometheus.DefaultRegisterer)
+	metricsRegisterer.MustRegister(packetsTotal)
+	metricsRegisterer.MustRegister(bytesTotal)
+	metricsRegisterer.MustRegister(packetDropsTotal)
+	metricsRegisterer.MustRegister(uncorrelatedDrops)
+	metricsRegisterer.MustRegister(metricCardinalityCapped)
+	metricsRegisterer.MustRegister(activeSessions)
+	metricsRegisterer.MustRegister(packetsPerSecond)
+	metricsRegisterer.MustRegister(bitsPerSecond)
+	metricsRegisterer.MustRegister(kafkaPublishFailures)
+	metricsRegisterer.MustRegister(remoteWriteFailures)
+	metricsRegisterer.MustRegister(sessionStoreFailures)
+	metricsRegisterer.MustRegister(pfcpMessageCollector{})
+	metricsRegisterer.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "upf_events_dropped_total",
+			Help: "Total number of drop/session events discarded because a consumer (drop log, Kafka) fell behind",
+		},
+		func() float64 { return float64(eventBus.Dropped()) },
+	))
+	metricsRegisterer.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "upf_drop_events_lost_due_to_backpressure_total",
+			Help: "Total number of eBPF drop events discarded because their consumers fell behind the ring buffer reader",
+		},
+		func() float64 {
+			if ebpfLoader == nil {
+				return 0
+			}
+			return float64(ebpfLoader.LostDueToBackpressure())
+		},
+	))
+
+	// Pre-create the "unknown" direction series so drops without a resolvable
+	// direction show up explicitly instead of collapsing into uplink
+	packetDropsTotal.WithLabelValues(ebpf.FormatDropReason(ebpf.DropReasonUnknown), ebpf.FormatDirection(ebpf.DirectionUnknown))
+}
+
+func main() {
+	flag.Parse()
+
+	var config *configfile.File
+	if *configPath != "" {
+		var err error
+		if config, err = configfile.Load(flag.CommandLine, "config"); err != nil {
+			log.Fatalf("Failed to load -config: %v", err)
+		}
+	}
+
+	level, err := loglevel.Parse(*logLevel)
+	if err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	loglevel.Set(level)
+	loglevel.Install(os.Stderr)
+
+	if *selfTest {
+		os.Exit(runSelfTest(*bpfObject, *selfTestIface))
+	}
+
+	log.Println("============================================================")
+	log.Println("    5G-DPOP: UPF Data Plane Observability Agent")
+	log.Println("============================================================")
+
+	if *nodeName == "" {
+		log.Fatal("-node-name must not be empty")
+	}
+	if *httpAddr == "" && *metricsSocket == "" {
+		log.Fatal("-http-addr may only be empty with -metrics-socket")
+	}
+	if *collectInterval < minCollectInterval {
+		log.Fatalf("-collect-interval must be at least %s", minCollectInterval)
+	}
+	if *dropRecentMax < 1 {
+		log.Fatal("-drop-recent-max must be at least 1")
+	}
+	recentDropMax = *dropRecentMax
+	if *pfcpMaxIEDepth < 1 {
+		log.Fatal("-pfcp-max-ie-depth must be at least 1")
+	}
+	if *dropReasonMap != "" {
+		reasons, err := ebpf.LoadReasonMap(*dropReasonMap)
+		if err != nil {
+			log.Fatalf("Failed to load -drop-reason-map: %v", err)
+		}
+		ebpf.SetReasonClassifier(reasons)
+		log.Printf("[OK] Drop reason names loaded from %s (%d codes)", *dropReasonMap, len(reasons.All()))
+	}
+	if *adminTokenFile != "" {
+		token, err := adminauth.Load(*adminTokenFile)
+		if err != nil {
+			log.Fatalf("Failed to load -admin-token-file: %v", err)
+		}
+		adminToken = token
+	}
+	registerMetrics(*nodeName)
+	log.Printf("[INFO] Node name: %s", *nodeName)
+
+	logTypesList := *pfcpLogTypes
+	if logTypesList == "" && *debugMode {
+		logTypesList = "all"
+	}
+	logTypes, err := pfcp.ParseMessageTypes(logTypesList)
+	if err != nil {
+		log.Fatalf("Invalid -pfcp-log-types: %v", err)
+	}
+
+	// eBPF needs root or CAP_BPF+CAP_PERFMON; PFCP capture only CAP_NET_RAW
+	privs := currentPrivileges()
+	if !privs.ebpf {
+		if *requireEBPF {
+			log.Fatal("This program must be run as root (for eBPF), or with -require-ebpf=false to run without traffic and drop monitoring")
+		}
+		log.Println("[WARN] Not privileged for eBPF (needs root, or CAP_BPF and CAP_PERFMON), traffic and drop monitoring disabled")
+	}
+	if !privs.capture {
+		log.Println("[WARN] No CAP_NET_RAW, PFCP capture will fail; sessions can still be injected through /api/demo")
+	}
+	if *tcEgressIface != "" && !privs.tc {
+		log.Println("[WARN] No CAP_NET_ADMIN, -tc-egress-iface cannot add its qdisc and filter")
+	}
+
+	// Export traces over OTLP if OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Init(context.Background(), "upf-agent")
+	if err != nil {
+		log.Printf("[WARN] Tracing disabled: %v", err)
+	} else if tracing.Enabled() {
+		log.Println("[OK] OpenTelemetry tracing enabled")
+	}
+	defer shutdownTracing(context.Background())
+
+	// Initialize PFCP correlation
+	store, closeStore, err := newSessionStore()
+	if err != nil {
+		log.Fatalf("Failed to open -session-store %s: %v", *sessionStore, err)
+	}
+	pfcpCorrelation = pfcp.NewCorrelationWithStore(store)
+	if *sessionStore != "memory" {
+		log.Printf("[OK] Sessions saved in %s %s (%d restored)", *sessionStore, *redisAddr, pfcpCorrelation.SessionCount())
+	}
+	pfcpCorrelation.SetEventBus(eventBus)
+	overflowPolicy, err := pfcp.ParseOverflowPolicy(*maxSessionsMode)
+	if err != nil {
+		log.Fatalf("Invalid -max-sessions-policy: %v", err)
+	}
+	pfcpCorrelation.SetSessionLimit(*maxSessions, overflowPolicy)
+	pfcpCorrelation.SetTEIDLimit(*maxTEIDs)
+
+	// Summarize drops periodically instead of logging each one
+	var dropSummaryLog *dropSummary
+	if *dropSummaryEvery > 0 {
+		dropSummaryLog = newDropSummary(*dropSummaryTop)
+		log.Printf("[OK] Drop summary logged every %s", *dropSummaryEvery)
+	}
+
+	// Expire recent drop events by age if requested
+	if *dropRetention > 0 {
+		log.Printf("[OK] Recent drop events kept for %s (at most %d)", *dropRetention, *dropRecentMax)
+	}
+
+	// Coalesce identical drop events for the API and sinks if requested
+	var dropCoalescing *dropCoalescer
+	if *dropCoalesce > 0 {
+		dropCoalescing = newDropCoalescer(storeDropEvent)
+		go dropCoalescing.run(*dropCoalesce)
+		log.Printf("[OK] Identical drop events coalesced every %s", *dropCoalesce)
+	}
+
+	// Create eBPF loader
+	loader := ebpf.NewLoaderWithObject(*bpfObject)
+	// A cached read must not outlive the collect interval, or consecutive
+	// samples would see the same counters and the rates would alternate
+	// between zero and double
+	cacheTTL := *statsCacheTTL
+	if cacheTTL >= *collectInterval {
+		cacheTTL = *collectInterval / 2
+		log.Printf("[INFO] -stats-cache-ttl lowered to %s to stay below -collect-interval %s", cacheTTL, *collectInterval)
+	}
+	loader.SetStatsCacheTTL(cacheTTL)
+	loader.SetDropQueueSize(*dropQueueSize)
+	loader.SetTCEgress(*tcEgressIface)
+	metricsRegisterer.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "upf_ebpf_map_read_syscalls_total",
+		Help: "Total number of BPF syscalls issued to read the stats maps",
+	}, func() float64 { return float64(loader.MapSyscalls()) }))
+	if *bpfObject != "" {
+		log.Printf("[INFO] Using eBPF object %s", *bpfObject)
+	}
+
+	// Set up event handler for drops
+	loader.OnDropEvent = func(event ebpf.DropEvent) {
+		// The kernel can't always tell the direction, resolve it from the
+		// packet and the known UE IPs (see ebpf.InferDirection)
+		event.Direction = ebpf.InferDirection(event, isKnownUEIP)
+
+		reason := ebpf.FormatDropReason(event.Reason)
+		direction := ebpf.FormatDirection(event.Direction)
+
+		if dropSummaryLog != nil {
+			dropSummaryLog.add(reason, event.TEID)
+		} else {
+			// DEBUG: Show raw reason code to debug
+			log.Printf("[DROP] reason=%s(code=%d) direction=%s teid=0x%x src=%s dst=%s len=%d",
+				reason, event.Reason, direction,
+				event.TEID,
+				event.SrcAddr.String(),
+				event.DstAddr.String(),
+				event.PktLen)
+		}
+
+		// Update Prometheus metrics
+		packetDropsTotal.WithLabelValues(reason, direction).Inc()
+
+		// A GTP-U drop with a TEID PFCP never announced measures how much
+		// of the signaling the capture misses
+		var correlated *bool
+		var ueIP net.IP
+		if event.TEID != 0 {
+			var known bool
+			ueIP, known = pfcpCorrelation.UEIPByTEID(event.TEID)
+			correlated = &known
+			if !known {
+				uncorrelatedDrops.Inc()
+			}
+		}
+
+		// Attach the drop to the owning session's trace
+		traceDrop(event, reason, direction)
+
+		// Store drop event for API
+		dropEvent := event.Model(time.Now())
+		dropEvent.Correlated = correlated
+		if ueIP != nil {
+			dropEvent.UEIP = ueIP.String()
+		}
+
+		if dropCoalescing != nil {
+			dropCoalescing.add(dropEvent)
+			return
+		}
+		storeDropEvent(dropEvent)
+	}
+
+	// Store loader globally for API access
+	ebpfLoader = loader
+
+	// Resolve the capture interface from the N4 address if given
+	if *pfcpAddress != "" {
+		find := pfcp.FindInterfaceByIP
+		if strings.Contains(*pfcpAddress, "/") {
+			find = pfcp.FindInterfaceBySubnet
+		}
+		iface, err := find(*pfcpAddress)
+		if err != nil {
+			log.Fatalf("Invalid -pfcp-address: %v", err)
+		}
+		log.Printf("[INFO] PFCP address %s is on interface %s", *pfcpAddress, iface)
+		*pfcpIface = iface
+	}
+
+	// Create the PFCP sniffer (started below) so its stats are available
+	// to the API as soon as the HTTP server is up
+	pfcpSniffer = pfcp.NewSniffer(*pfcpIface, 8805, pfcpCorrelation)
+	pfcpSniffer.SetWorkers(*pfcpWorkers)
+	pfcpSniffer.SetLogTypes(logTypes)
+	pfcpSniffer.SetTimestampSource(*pfcpTstampType)
+	pfcpSniffer.SetIEStats(*pfcpIEStats)
+	pfcpSniffer.SetPeerHealth(*pfcpPeerHealth, *pfcpHBInterval)
+	pfcpSniffer.SetMaxIEDepth(*pfcpMaxIEDepth)
+	pfcpSniffer.SetGTPUErrorIndications(*gtpuErrorInd)
+	pfcpSniffer.SetInterfaceWait(*pfcpIfaceWait)
+	if *pfcpTapPath != "" {
+		pfcpSniffer.SetTap(*pfcpTapPath, *pfcpTapMaxSize)
+	}
+
+	// Start HTTP server before loading eBPF so liveness probes succeed
+	// while the (slow) program load is still in progress
+	var socket net.Listener
+	if *metricsSocket != "" {
+		mode, err := parseFileMode(*metricsSockMode)
+		if err != nil {
+			log.Fatalf("Invalid -metrics-socket-mode: %v", err)
+		}
+		socket, err = listenUnix(*metricsSocket, mode)
+		if err != nil {
+			log.Fatalf("Failed to listen on -metrics-socket: %v", err)
+		}
+	}
+	go startHTTPServer(socket)
+
+	// Load eBPF programs, unless degraded mode was chosen above
+	ebpfLoaded := false
+	if privs.ebpf {
+		log.Println("Loading eBPF programs...")
+		if err := loader.Load(); err != nil {
+			if *requireEBPF {
+				log.Fatalf("Failed to load eBPF programs: %v", err)
+			}
+			log.Printf("[WARN] Failed to load eBPF programs, continuing without them: %v", err)
+		} else {
+			ebpfLoaded = true
+		}
+	}
+	if ebpfLoaded {
+		setupEBPF(loader)
+	} else {
+		ebpfDisabled.Store(true)
+	}
+
+	// Prime the correlation from a recent capture before going live
+	if *pfcpBootstrap != "" {
+		bootstrapSessions(*pfcpBootstrap, logTypes)
+	}
+
+	// Start PFCP sniffer
+	snifferStarted := false
+	if err := pfcpSniffer.Start(); err != nil {
+		log.Printf("[WARN] Failed to start PFCP sniffer: %v", err)
+		log.Printf("       PDU session tracking will be limited")
+	} else {
+		snifferStarted = true
+		log.Printf("[OK] PFCP sniffer started on interface %s", *pfcpIface)
+	}
+
+	// Outbound sinks, closed (and so flushed) by shutdown
+	var sinks []func()
+	if closeStore != nil {
+		sinks = append(sinks, closeStore)
+	}
+
+	// Persist drop events to an NDJSON file if requested
+	var dropLog *sink.NDJSONFile
+	if *dropLogPath != "" {
+		var err error
+		dropLog, err = sink.NewNDJSONFile(*dropLogPath, *dropLogMaxSize)
+		if err != nil {
+			log.Fatalf("Failed to open drop log: %v", err)
+		}
+		dropLog.Start(eventBus.Subscribe(events.TopicDrops, 4096))
+		sinks = append(sinks, dropLog.Close)
+		log.Printf("[OK] Drop events are logged to %s", *dropLogPath)
+	}
+
+	// Stream drop and session events to Kafka if requested
+	if *kafkaBrokers != "" {
+		kafkaSink, err := sink.NewKafka(sink.KafkaConfig{
+			Brokers: strings.Split(*kafkaBrokers, ","),
+			Topic:   *kafkaTopic,
+			OnError: func(n int, err error) {
+				kafkaPublishFailures.Add(float64(n))
+			},
+		})
+		if err != nil {
+			log.Printf("[WARN] Kafka sink disabled: %v", err)
+		} else {
+			kafkaSink.Start(
+				eventBus.Subscribe(events.TopicDrops, 4096),
+				eventBus.Subscribe(events.TopicSessions, 1024),
+			)
+			sinks = append(sinks, kafkaSink.Close)
+			log.Printf("[OK] Publishing drop/session events to Kafka topic %s (%s)", *kafkaTopic, *kafkaBrokers)
+		}
+	}
+
+	// Push metrics to a remote-write endpoint if requested
+	if *remoteWriteURL != "" {
+		if rw, err := newRemoteWrite(); err != nil {
+			log.Printf("[WARN] Remote-write disabled: %v", err)
+		} else {
+			rw.Start()
+			sinks = append(sinks, rw.Close)
+			log.Printf("[OK] Pushing metrics every %s to %s", *remoteWriteEvery, *remoteWriteURL)
+		}
+	}
+
+	// Start event processing loop
+	if ebpfLoaded {
+		loader.StartEventLoop()
+		log.Println("[OK] Event loop started")
+	}
+
+	// Start periodic stats collection, the session count updater and the
+	// drop summary and retention
+	stopCollectors := make(chan struct{})
+	collectors := startCollectors(loader, ebpfLoaded, dropSummaryLog, stopCollectors)
+
+	// Wait for interrupt signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// SIGHUP reopens log files (for logrotate) and reloads -config
+	if config != nil {
+		registerReloadable(config)
+	}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			if dropLog != nil {
+				dropLog.Reopen()
+			}
+			if config != nil {
+				if err := config.Reload(); err != nil {
+					log.Printf("[WARN] Config reload failed, keeping the current settings: %v", err)
+				}
+			}
+		}
+	}()
+
+	log.Println("[INFO] Agent is running. Press Ctrl+C to stop.")
+	if *httpAddr != "" {
+		log.Printf("   Metrics available at %s/metrics", localURL(*httpAddr))
+		log.Printf("   Sessions API: %s/api/sessions", localURL(*httpAddr))
+		log.Printf("   Drops API: %s/api/drops", localURL(*httpAddr))
+	}
+	if socket != nil {
+		log.Printf("   Metrics and APIs also on unix socket %s", *metricsSocket)
+	}
+	log.Println("")
+
+	<-sigChan
+	log.Println("\n[INFO] Shutting down, draining...")
+	go func() {
+		<-sigChan
+		log.Fatalf("Interrupted while draining, exiting without flushing")
+	}()
+
+	shutdown(loader, ebpfLoaded, snifferStarted, dropCoalescing, stopCollectors, collectors, sinks)
+
+	// Closing the listener removes the socket file
+	if socket != nil {
+		socket.Close()
+	}
+}
+
+// startCollectors starts the periodic goroutines of the agent: stats
+// collection (with eBPF loaded), the session count updater, and the drop
+// summary and retention when enabled. They run until stop is closed, which
+// shutdown does before loader.Close so they never read closed maps.
+func startCollectors(loader *ebpf.Loader, ebpfLoaded bool, summary *dropSummary, stop chan struct{}) *sync.WaitGroup {
+	var collectors sync.WaitGroup
+	if ebpfLoaded {
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			collectStats(loader, stop)
+		}()
+	}
+	collectors.Add(1)
+	go func() {
+		defer collectors.Done()
+		updateSessionCount(stop)
+	}()
+	if summary != nil {
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			summary.run(*dropSummaryEvery, stop)
+		}()
+	}
+	if *dropRetention > 0 {
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			runDropRetention(*dropRetention, stop)
+		}()
+	}
+	return &collectors
+}
+
+// shutdown quiesces the agent before exit, in order: report draining on
+// /readyz and /health so load balancers stop routing to it, stop producing
+// events (ring buffers, PFCP capture, collectors), flush the outbound sinks
+// for at most -drain-timeout, then detach the eBPF programs
+func shutdown(loader *ebpf.Loader, ebpfLoaded, snifferStarted bool, coalescer *dropCoalescer, stopCollectors chan struct{}, collectors *sync.WaitGroup, sinks []func()) {
+	draining.Store(true)
+
+	if ebpfLoaded {
+		loader.StopEventLoop()
+	}
+	coalescer.close()
+	if snifferStarted {
+		pfcpSniffer.Stop()
+	}
+	close(stopCollectors)
+	collectors.Wait()
+	log.Println("   └─ Event sources stopped")
+
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for _, closeSink := range sinks {
+			closeSink()
+		}
+	}()
+	select {
+	case <-flushed:
+		if len(sinks) > 0 {
+			log.Printf("   └─ Flushed %d sink(s)", len(sinks))
+		}
+	case <-time.After(*drainTimeout):
+		log.Printf("[WARN] Sinks not flushed after %s, pending events are lost", *drainTimeout)
+	}
+
+	if ebpfLoaded {
+		loader.Close()
+		log.Println("   └─ eBPF programs detached")
+	}
+}
+
+// setupEBPF finishes the setup of loaded eBPF programs: readiness, detailed
+// tracing for topology discovery and the packet event handler
+func setupEBPF(loader *ebpf.Loader) {
+	if loader.AttachedCount() > 0 {
+		ebpfReady.Store(true)
+	} else {
+		log.Println("[WARN] No eBPF hooks attached, agent will report not ready")
+	}
+
+	// Enable detailed tracing for topology discovery
+	if err := loader.EnableDetailedTracing(true); err != nil {
+		log.Printf("[WARN] Failed to enable detailed tracing: %v", err)
+	} else {
+		log.Println("[INFO] Detailed tracing enabled for topology discovery")
+	}
+
+	// Set up packet event handler
+	loader.OnPacketEvent = func(event ebpf.PacketEvent) {
+		// Only interested in Uplink packets to discover Uplink Peer (gNB or prev UPF)
+		if event.Direction == ebpf.DirectionUplink && event.TEID > 0 {
+			srcIP := net.IP(event.SrcAddr.AsSlice())
+
+			// Update session with Uplink Peer IP
+			pfcpCorrelation.UpdateUplinkPeer(event.TEID, srcIP)
+		}
+	}
+
+	log.Println("[OK] eBPF programs loaded successfully")
+
+	// NOTE: kfree_skb tracing is DISABLED by default because it captures ALL kernel drops
+	// which creates too much noise. Only gtp5g-specific drops are captured via kprobes.
+	// To enable kernel-wide drop tracing, use: POST /api/config/drop-tracing {"enabled": true}
+	log.Println("[INFO] Kernel-wide drop tracing (kfree_skb) is DISABLED by default")
+	log.Println("[INFO] Only GTP/UPF specific drops will be captured via kprobes")
+}
+
+// newSessionStore opens the -session-store. The returned func flushes and
+// closes it, nil for the in-memory store.
+func newSessionStore() (pfcp.SessionStore, func(), error) {
+	switch *sessionStore {
+	case "memory":
+		return pfcp.NewMemoryStore(), nil, nil
+	case "redis":
+	default:
+		return nil, nil, fmt.Errorf("unknown session store %q, must be memory or redis", *sessionStore)
+	}
+
+	var password string
+	if *redisPass != "" {
+		data, err := os.ReadFile(*redisPass)
+		if err != nil {
+			return nil, nil, err
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	key := *redisKey
+	if key == "" {
+		key = "5g-dpop:sessions:" + *nodeName
+	}
+
+	store, err := pfcp.NewRedisStore(pfcp.RedisStoreConfig{
+		Addr:     *redisAddr,
+		Password: password,
+		DB:       *redisDB,
+		Key:      key,
+		OnError: func(n int, err error) {
+			sessionStoreFailures.Add(float64(n))
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, store.Close, nil
+}
+
+// newRemoteWrite builds the remote-write sink from the -remote-write-*
+// flags. Series get the job and instance labels a scrape would add.
+func newRemoteWrite() (*sink.RemoteWrite, error) {
+	var password string
+	if *remoteWritePass != "" {
+		data, err := os.ReadFile(*remoteWritePass)
+		if err != nil {
+			return nil, err
+		}
+		password = strings.TrimSpace(string(data))
+	}
+
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "localhost"
+	}
+
+	return sink.NewRemoteWrite(sink.RemoteWriteConfig{
+		URL:       *remoteWriteURL,
+		Username:  *remoteWriteUser,
+		Password:  password,
+		Interval:  *remoteWriteEvery,
+		BatchSize: *remoteWriteBatch,
+		Labels: map[string]string{
+			"job":      "upf-agent",
+			"instance": instance + ":9100",
+		},
+		OnError: func(n int, err error) {
+			remoteWriteFailures.Add(float64(n))
+		},
+	}, prometheus.DefaultGatherer)
+}
+
+// startHTTPServer serves the metrics and APIs on -http-addr and, if not
+// nil, on the Unix socket listener
+func startHTTPServer(socket net.Listener) {
+	// Prometheus metrics
+	http.Handle("/metrics", promhttp.Handler())
+
+	// Kubernetes probes (/health is kept as an alias of /readyz)
+	http.HandleFunc("/livez", handleLivez)
+	http.HandleFunc("/readyz", handleReadyz)
+	http.HandleFunc("/health", handleReadyz)
+
+	// Drop events API
+	http.HandleFunc("/api/drops", handleDropsAPI)
+
+	// Sessions API
+	http.HandleFunc("/api/sessions", handleSessionsAPI)
+	http.HandleFunc("/api/sessions/", handleSessionTagsAPI)
+
+	// Demo API - inject test data for development
+	http.HandleFunc("/api/demo/inject-drop", handleDemoInjectDrop)
+	http.HandleFunc("/api/demo/inject-session", handleDemoInjectSession)
+
+	// Sync API - sync sessions from free5GC logs
+	http.HandleFunc("/api/sync/sessions", handleSyncSessions)
+
+	// Drop tracing control API
+	http.HandleFunc("/api/config/drop-tracing", handleDropTracingConfig)
+
+	// Log level, adjustable at runtime
+	http.HandleFunc("/api/log-level", handleLogLevelConfig)
+
+	// PFCP message counters
+	http.HandleFunc("/api/pfcp/node-messages", handleNodeMessagesAPI)
+	http.HandleFunc("/api/pfcp/message-stats", handleMessageStatsAPI)
+	http.HandleFunc("/api/pfcp/ie-coverage", handleIECoverageAPI)
+	http.HandleFunc("/api/pfcp/teid-stats", handleTEIDStatsAPI)
+	http.HandleFunc("/api/pfcp/peers", handlePeerHealthAPI)
+	http.HandleFunc("/api/pfcp/decode", handlePFCPDecodeAPI)
+	http.HandleFunc("/api/pfcp/replay", handlePFCPReplayAPI)
+
+	// Loaded eBPF programs and maps (bpftool-like introspection)
+	http.HandleFunc("/api/ebpf/info", handleEBPFInfoAPI)
+
+	// Aggregates for the dashboard summary tiles
+	http.HandleFunc("/api/stats/summary", handleSummaryAPI)
+
+	// Session/TEID/UE IP graph for `dot -Tpng`
+	http.HandleFunc("/api/topology.dot", handleTopologyDOT)
+
+	// Internal state for bug reports (-admin-token-file)
+	http.HandleFunc("/api/debug/dump", handleDebugDumpAPI)
+
+	// net/http/pprof always registers on the default mux, hide it unless
+	// profiling was asked for
+	var handler http.Handler = http.DefaultServeMux
+	if *pprofEnabled {
+		log.Println("[INFO] pprof enabled under /debug/pprof/")
+	} else {
+		handler = withoutPprof(handler)
+	}
+
+	if socket != nil {
+		go func() {
+			log.Printf("[INFO] HTTP server listening on unix socket %s", socket.Addr())
+			if err := http.Serve(socket, handler); err != nil && !errors.Is(err, net.ErrClosed) {
+				log.Printf("HTTP server error on %s: %v", socket.Addr(), err)
+			}
+		}()
+	}
+	if *httpAddr == "" {
+		return
+	}
+
+	log.Printf("[INFO] HTTP server listening on %s", *httpAddr)
+	if err := http.ListenAndServe(*httpAddr, handler); err != nil {
+		log.Printf("HTTP server error: %v", err)
+	}
+}
+
+// withoutPprof answers 404 for /debug/pprof/ instead of passing it to next
+func withoutPprof(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/pprof") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleLivez reports whether the agent process is up. It does not depend on
+// eBPF so that a slow program load doesn't get the pod restarted.
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, true, map[string]string{"process": "ok"})
+}
+
+// handleReadyz reports whether the agent is ready to serve traffic, i.e. the
+// eBPF programs are loaded and at least one hook is attached. Without eBPF
+// under -require-ebpf=false the agent is ready with what it has. A draining
+// agent is never ready.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"ebpf": "ok"}
+	ready := ebpfReady.Load()
+	if ebpfDisabled.Load() {
+		checks["ebpf"] = "disabled"
+		ready = true
+	} else if !ready {
+		checks["ebpf"] = "not attached"
+	}
+	if draining.Load() {
+		checks["shutdown"] = "draining"
+		ready = false
+	}
+	writeProbe(w, ready, checks)
+}
+
+// writeProbe writes a probe response: 200 when ok, 503 otherwise
+func writeProbe(w http.ResponseWriter, ok bool, checks map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	status := "ok"
+	code := http.StatusOK
+	if !ok {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+	w.WriteHeader(code)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":            status,
+		"checks":            checks,
+		"started_at":        startedAt.Format(time.RFC3339),
+		"counters_reset_at": countersResetAt.Format(time.RFC3339),
+	})
+}
+
+// storeDropEvent records a drop event for the API and publishes it on the bus
+func storeDropEvent(dropEvent model.DropEvent) {
+	n := dropEvent.Count
+	if n == 0 {
+		n = 1
+	}
+
+	dropEventsMu.Lock()
+	recentDrops = append(recentDrops, recentDrop{at: time.Now(), event: dropEvent})
+	if len(recentDrops) > recentDropMax {
+		recentDrops = recentDrops[len(recentDrops)-recentDropMax:]
+	}
+	totalDrops += n
+	dropsByReason[dropEvent.Reason] += n
+	dropEventsMu.Unlock()
 
-// SessionJSON is the JSON representation of a session (extended)
-type SessionJSON struct {
-	SEID      string   `json:"seid"`
-	UEIP      string   `json:"ue_ip"`
-	TEIDs     []string `json:"teids"`
-	TEIDUL    string   `json:"teid_ul,omitempty"` // Uplink TEID (gNB -> UPF)
-	TEIDDL    string   `json:"teid_dl,omitempty"` // Downlink TEID (UPF -> gNB)
-	CreatedAt string   `json:"created_at"`
-	PacketsUL uint64   `json:"packets_ul"`
-	PacketsDL uint64   `json:"packets_dl"`
-
-	// Extended fields
-	UPFIP        string `json:"upf_ip,omitempty"`
-	GNBIP        string `json:"gnb_ip,omitempty"`
-	UplinkPeerIP string `json:"uplink_peer_ip,omitempty"`
-	N9PeerIP     string `json:"n9_peer_ip,omitempty"` // N9 peer UPF IP (for ULCL)
-	SUPI         string `json:"supi,omitempty"`
-	DNN          string `json:"dnn,omitempty"`
-	SNssai       string `json:"s_nssai,omitempty"`
-	QFI          uint8  `json:"qfi,omitempty"`
-	SessionType  string `json:"session_type,omitempty"`
-	SessionID    uint8  `json:"pdu_session_id,omitempty"`
-
-	// Traffic statistics
-	BytesUL uint64 `json:"bytes_ul"`
-	BytesDL uint64 `json:"bytes_dl"`
-
-	// QoS parameters
-	QoS5QI      uint8  `json:"qos_5qi,omitempty"`
-	ARPPL       uint8  `json:"arp_priority,omitempty"`
-	GBRUplink   uint64 `json:"gbr_ul_kbps,omitempty"`
-	GBRDownlink uint64 `json:"gbr_dl_kbps,omitempty"`
-	MBRUplink   uint64 `json:"mbr_ul_kbps,omitempty"`
-	MBRDownlink uint64 `json:"mbr_dl_kbps,omitempty"`
-
-	// Status
-	Status     string `json:"status"`
-	Duration   string `json:"duration"`
-	LastActive string `json:"last_active,omitempty"`
+	eventBus.Publish(events.TopicDrops, dropEvent)
 }
 
-func init() {
-	prometheus.MustRegister(packetsTotal)
-	prometheus.MustRegister(bytesTotal)
-	prometheus.MustRegister(packetDropsTotal)
-	prometheus.MustRegister(activeSessions)
+// bootstrapSessions replays a PFCP capture (e.g. a -pfcp-tap file) into the
+// shared correlation so sessions established before the agent started are
+// known. Deletion Requests in the file prune their sessions as they would
+// live, provided the matching Establishment Response is in the file too.
+// Messages of logTypes are logged as for live capture.
+func bootstrapSessions(path string, logTypes []uint8) {
+	log.Printf("[INFO] Bootstrapping PFCP sessions from %s", path)
+	replay := pfcp.NewSnifferFromFile(path, 8805, pfcpCorrelation)
+	replay.SetLogTypes(logTypes)
+	replay.SetMaxIEDepth(*pfcpMaxIEDepth)
+	if err := replay.Start(); err != nil {
+		log.Printf("[WARN] PFCP bootstrap failed: %v", err)
+		return
+	}
+	<-replay.Done()
+	replay.Stop()
+	log.Printf("[OK] PFCP bootstrap done: %d sessions known", pfcpCorrelation.SessionCount())
 }
 
-func main() {
-	flag.Parse()
-
-	log.Println("============================================================")
-	log.Println("    5G-DPOP: UPF Data Plane Observability Agent")
-	log.Println("============================================================")
-
-	// Check if running as root
-	if os.Geteuid() != 0 {
-		log.Fatal("This program must be run as root (for eBPF)")
+// traceDrop records a drop as a child span of the session that owns its
+// TEID. Drops that can't be correlated to a session are not traced.
+func traceDrop(event ebpf.DropEvent, reason, direction string) {
+	sc, ok := pfcpCorrelation.SpanContextByTEID(event.TEID)
+	if !ok {
+		return
 	}
 
-	// Initialize PFCP correlation
-	pfcpCorrelation = pfcp.NewCorrelation()
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	_, span := otel.Tracer("github.com/solar224/5G-DPOP/cmd/agent").Start(ctx, "ebpf.drop",
+		trace.WithAttributes(
+			attribute.String("drop.reason", reason),
+			attribute.String("drop.direction", direction),
+			attribute.String("gtp.teid", fmt.Sprintf("0x%x", event.TEID)),
+			attribute.String("net.src_ip", event.SrcAddr.String()),
+			attribute.String("net.dst_ip", event.DstAddr.String()),
+			attribute.Int("net.pkt_len", int(event.PktLen)),
+		),
+	)
+	span.End()
+}
 
-	// Create eBPF loader
-	loader := ebpf.NewLoader()
+// isKnownUEIP reports whether ip (as read from the kernel) belongs to a session
+func isKnownUEIP(ip uint32) bool {
+	return pfcpCorrelation.HasUEAddr(ebpf.ParseEventIP(ip))
+}
 
-	// Set up event handler for drops
-	loader.OnDropEvent = func(event ebpf.DropEvent) {
-		reason := ebpf.FormatDropReason(event.Reason)
-		direction := ebpf.FormatDirection(event.Direction)
+func handleMessageStatsAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		// DEBUG: Show raw reason code to debug
-		log.Printf("[DROP] reason=%s(code=%d) direction=%s teid=0x%x src=%s dst=%s len=%d",
-			reason, event.Reason, direction,
-			event.TEID,
-			ebpf.FormatIP(event.SrcIP),
-			ebpf.FormatIP(event.DstIP),
-			event.PktLen)
+	counts := pfcpSniffer.MessageCounts()
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
 
-		// Update Prometheus metrics
-		packetDropsTotal.WithLabelValues(reason, direction).Inc()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":              total,
+		"messages":           counts,
+		"parse_errors":       pfcpSniffer.ParseErrors(),
+		"truncated_messages": pfcpSniffer.TruncatedMessages(),
+	})
+}
 
-		// Store drop event for API
-		dropEvent := DropEventJSON{
-			Timestamp: time.Now().Format(time.RFC3339),
-			TEID:      fmt.Sprintf("0x%x", event.TEID),
-			SrcIP:     ebpf.FormatIP(event.SrcIP),
-			DstIP:     ebpf.FormatIP(event.DstIP),
-			SrcPort:   event.SrcPort,
-			DstPort:   event.DstPort,
-			PktLen:    event.PktLen,
-			Reason:    reason,
-			Direction: direction,
-		}
+// handleIECoverageAPI lists how often each IE type was seen (-pfcp-ie-stats)
+func handleIECoverageAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		dropEventsMu.Lock()
-		recentDrops = append([]DropEventJSON{dropEvent}, recentDrops...)
-		if len(recentDrops) > 100 {
-			recentDrops = recentDrops[:100]
-		}
-		totalDrops++
-		dropsByReason[reason]++
-		dropEventsMu.Unlock()
+	coverage, enabled := pfcpSniffer.IECoverage()
+	if !enabled {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "IE statistics disabled, start the agent with -pfcp-ie-stats",
+		})
+		return
 	}
 
-	// Load eBPF programs
-	log.Println("Loading eBPF programs...")
-	if err := loader.Load(); err != nil {
-		log.Fatalf("Failed to load eBPF programs: %v", err)
+	var total, unhandled uint64
+	for _, ie := range coverage {
+		total += ie.Count
+		if !ie.Handled {
+			unhandled += ie.Count
+		}
 	}
-	defer loader.Close()
 
-	// Enable detailed tracing for topology discovery
-	if err := loader.EnableDetailedTracing(true); err != nil {
-		log.Printf("[WARN] Failed to enable detailed tracing: %v", err)
-	} else {
-		log.Println("[INFO] Detailed tracing enabled for topology discovery")
-	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":     total,
+		"unhandled": unhandled,
+		"ie_types":  coverage,
+	})
+}
 
-	// Set up packet event handler
-	loader.OnPacketEvent = func(event ebpf.PacketEvent) {
-		// Only interested in Uplink packets to discover Uplink Peer (gNB or prev UPF)
-		if event.Direction == ebpf.DirectionUplink && event.TEID > 0 {
-			// Convert uint32 IP to net.IP
-			srcIP := net.IPv4(byte(event.SrcIP), byte(event.SrcIP>>8), byte(event.SrcIP>>16), byte(event.SrcIP>>24))
+// handleTEIDStatsAPI reports the range and a histogram of the mapped TEID
+// values, ?buckets= sets the number of histogram buckets
+func handleTEIDStatsAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-			// Update session with Uplink Peer IP
-			pfcpCorrelation.UpdateUplinkPeer(event.TEID, srcIP)
+	buckets := pfcp.DefaultTEIDStatsBuckets
+	if v := r.URL.Query().Get("buckets"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > pfcp.MaxTEIDStatsBuckets {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": fmt.Sprintf("buckets must be between 1 and %d", pfcp.MaxTEIDStatsBuckets),
+			})
+			return
 		}
+		buckets = n
 	}
 
-	// Store loader globally for API access
-	ebpfLoader = loader
-
-	log.Println("[OK] eBPF programs loaded successfully")
+	json.NewEncoder(w).Encode(pfcpCorrelation.TEIDStats(buckets))
+}
 
-	// NOTE: kfree_skb tracing is DISABLED by default because it captures ALL kernel drops
-	// which creates too much noise. Only gtp5g-specific drops are captured via kprobes.
-	// To enable kernel-wide drop tracing, use: POST /api/config/drop-tracing {"enabled": true}
-	log.Println("[INFO] Kernel-wide drop tracing (kfree_skb) is DISABLED by default")
-	log.Println("[INFO] Only GTP/UPF specific drops will be captured via kprobes")
+// handlePeerHealthAPI lists the message inter-arrival jitter and missed
+// heartbeats of each PFCP peer (-pfcp-peer-health)
+func handlePeerHealthAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Start PFCP sniffer
-	pfcpSniffer := pfcp.NewSniffer(*pfcpIface, 8805, pfcpCorrelation)
-	if err := pfcpSniffer.Start(); err != nil {
-		log.Printf("[WARN] Failed to start PFCP sniffer: %v", err)
-		log.Printf("       PDU session tracking will be limited")
-	} else {
-		defer pfcpSniffer.Stop()
-		log.Printf("[OK] PFCP sniffer started on interface %s", *pfcpIface)
+	peers, enabled := pfcpSniffer.PeerHealth()
+	if !enabled {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "peer health disabled, start the agent with -pfcp-peer-health",
+		})
+		return
 	}
 
-	// Start event processing loop
-	loader.StartEventLoop()
-	log.Println("[OK] Event loop started")
-
-	// Start Prometheus HTTP server with additional API endpoints
-	go startHTTPServer()
-
-	// Start periodic stats collection
-	go collectStats(loader)
-
-	// Start periodic session count update
-	go updateSessionCount()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"heartbeat_interval_ms": pfcpSniffer.HeartbeatInterval().Milliseconds(),
+		"peers":                 peers,
+	})
+}
 
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+func handleNodeMessagesAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	log.Println("[INFO] Agent is running. Press Ctrl+C to stop.")
-	log.Println("   Metrics available at http://localhost:9100/metrics")
-	log.Println("   Sessions API: http://localhost:9100/api/sessions")
-	log.Println("   Drops API: http://localhost:9100/api/drops")
-	log.Println("")
+	stats := pfcpSniffer.NodeMessageStats()
+	var total uint64
+	for _, stat := range stats {
+		total += stat.Count
+	}
 
-	<-sigChan
-	log.Println("\n[INFO] Shutting down...")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":    total,
+		"messages": stats,
+	})
 }
 
-func startHTTPServer() {
-	// Prometheus metrics
-	http.Handle("/metrics", promhttp.Handler())
+// handleSummaryAPI returns aggregate session and traffic statistics, so
+// the dashboard does not have to fetch and reduce the full session list
+func handleSummaryAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Health check
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+	ratesMu.RLock()
+	rates := currentRates
+	bytesUL, bytesDL := currentUplinkBytes, currentDownlinkBytes
+	ratesMu.RUnlock()
+
+	json.NewEncoder(w).Encode(struct {
+		Node string `json:"node"`
+		pfcp.Summary
+		BytesUL uint64 `json:"total_bytes_ul"`
+		BytesDL uint64 `json:"total_bytes_dl"`
+		trafficRates
+		Establishments pfcp.EstablishmentStats `json:"establishments"`
+	}{
+		Node:           *nodeName,
+		Summary:        pfcpCorrelation.Summary(),
+		BytesUL:        bytesUL,
+		BytesDL:        bytesDL,
+		trafficRates:   rates,
+		Establishments: pfcpSniffer.EstablishmentStats(),
 	})
+}
 
-	// Drop events API
-	http.HandleFunc("/api/drops", handleDropsAPI)
-
-	// Sessions API
-	http.HandleFunc("/api/sessions", handleSessionsAPI)
-
-	// Demo API - inject test data for development
-	http.HandleFunc("/api/demo/inject-drop", handleDemoInjectDrop)
-	http.HandleFunc("/api/demo/inject-session", handleDemoInjectSession)
-
-	// Sync API - sync sessions from free5GC logs
-	http.HandleFunc("/api/sync/sessions", handleSyncSessions)
+// handleTopologyDOT renders the PFCP correlation as a Graphviz graph,
+// optionally limited to one session with ?seid=0x...
+func handleTopologyDOT(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Drop tracing control API
-	http.HandleFunc("/api/config/drop-tracing", handleDropTracingConfig)
+	var seid uint64
+	if v := r.URL.Query().Get("seid"); v != "" {
+		parsed, err := model.ParseSEID(v)
+		if err != nil || parsed == 0 {
+			http.Error(w, "invalid seid", http.StatusBadRequest)
+			return
+		}
+		seid = parsed
+	}
 
-	log.Println("[INFO] HTTP server listening on :9100")
-	if err := http.ListenAndServe(":9100", nil); err != nil {
-		log.Printf("HTTP server error: %v", err)
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	if err := pfcpCorrelation.WriteDOT(w, seid); err != nil {
+		log.Printf("[DEBUG] topology.dot write failed: %v", err)
 	}
 }
 
@@ -303,21 +1434,25 @@ func handleDropsAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	// Copied under the lock, -drop-recent-max may keep many events to encode
 	dropEventsMu.RLock()
-	defer dropEventsMu.RUnlock()
+	total := totalDrops
+	recent := recentDropEventsLocked()
+	byReason := maps.Clone(dropsByReason)
+	dropEventsMu.RUnlock()
 
 	// Calculate drop rate
 	var dropRate float64
 	totalPackets := prevUplinkPackets + prevDownlinkPackets
 	if totalPackets > 0 {
-		dropRate = float64(totalDrops) / float64(totalPackets) * 100
+		dropRate = float64(total) / float64(totalPackets) * 100
 	}
 
 	response := map[string]interface{}{
-		"total":        totalDrops,
+		"total":        total,
 		"rate_percent": dropRate,
-		"recent_drops": recentDrops,
-		"by_reason":    dropsByReason,
+		"recent_drops": recent,
+		"by_reason":    byReason,
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -327,112 +1462,178 @@ func handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	// The ETag follows the correlation version, taken before the snapshot
+	// so that a concurrent change makes the client refetch. The start time
+	// keeps tags from a previous agent run from matching. Durations are
+	// derived from the clock and do not change the tag.
+	etag := fmt.Sprintf(`"%x-%x"`, startedAt.UnixNano(), pfcpCorrelation.Version())
+	w.Header().Set("ETag", etag)
+	if httpcache.Matches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	sessions := pfcpCorrelation.GetAllSessions()
 
-	sessionList := make([]SessionJSON, 0, len(sessions))
+	now := time.Now()
+	sessionList := make([]model.SessionInfo, 0, len(sessions))
 	for _, s := range sessions {
-		teids := make([]string, 0, len(s.TEIDs))
-		for _, teid := range s.TEIDs {
-			teids = append(teids, fmt.Sprintf("0x%x", teid))
-		}
+		sessionList = append(sessionList, s.Model(now))
+	}
 
-		// Extract UL/DL TEIDs (convention: first is UL, second is DL)
-		teidUL := ""
-		teidDL := ""
-		if len(s.TEIDs) >= 1 {
-			teidUL = fmt.Sprintf("0x%x", s.TEIDs[0])
-		}
-		if len(s.TEIDs) >= 2 {
-			teidDL = fmt.Sprintf("0x%x", s.TEIDs[1])
-		}
+	response := map[string]interface{}{
+		"total":    len(sessionList),
+		"sessions": sessionList,
+	}
 
-		ueIP := "N/A"
-		if s.UEIP != nil {
-			ueIP = s.UEIP.String()
-		}
+	json.NewEncoder(w).Encode(response)
+}
 
-		upfIP := ""
-		if s.UPFIP != nil {
-			upfIP = s.UPFIP.String()
-		}
+// handleSessionTagsAPI replaces the operator tags of a session with PUT
+// /api/sessions/<seid>/tags {"tags": {"key": "value", ...}}, {} clears them
+func handleSessionTagsAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
-		gnbIP := ""
-		if s.GNBIP != nil {
-			gnbIP = s.GNBIP.String()
-		}
+	writeError := func(status int, msg string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
 
-		uplinkPeerIP := ""
-		if s.UplinkPeerIP != nil {
-			uplinkPeerIP = s.UplinkPeerIP.String()
-		}
+	seidParam, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/")
+	if rest != "tags" {
+		writeError(http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	seid, err := model.ParseSEID(seidParam)
+	if err != nil {
+		writeError(http.StatusBadRequest, err.Error())
+		return
+	}
 
-		n9PeerIP := ""
-		if s.N9PeerIP != nil {
-			n9PeerIP = s.N9PeerIP.String()
-		}
+	var req struct {
+		Tags map[string]string `json:"tags"`
+	}
+	if err := jsonbody.Decode(w, r, &req, 1<<16); err != nil {
+		writeError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Tags == nil {
+		writeError(http.StatusBadRequest, `invalid request body: tags is required, {"tags": {}} clears them`)
+		return
+	}
+
+	found, err := pfcpCorrelation.SetSessionTags(seid, req.Tags)
+	if err != nil {
+		writeError(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !found {
+		writeError(http.StatusNotFound, "session not found")
+		return
+	}
+	log.Printf("[INFO] Tags of session SEID=0x%x set to %v", seid, req.Tags)
 
-		// Calculate duration
-		duration := time.Since(s.CreatedAt)
-		durationStr := formatDuration(duration)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"seid": model.FormatSEID(seid),
+		"tags": req.Tags,
+	})
+}
 
-		// Determine status
-		status := "Active"
-		if s.Status != "" {
-			status = s.Status
-		}
+// decodeRequestBody decodes the JSON body of a POST into req with
+// jsonbody.Decode. A missing body leaves req at its defaults; any other
+// problem is answered with 400 and false is returned.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, req interface{}, limit int64) bool {
+	err := jsonbody.Decode(w, r, req, limit)
+	if err == nil || errors.Is(err, jsonbody.ErrEmpty) {
+		return true
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid request body: %v", err)})
+	return false
+}
 
-		lastActive := ""
-		if !s.LastActive.IsZero() {
-			lastActive = s.LastActive.Format(time.RFC3339)
-		}
+// handlePFCPDecodeAPI decodes one PFCP message given as hex or base64 with
+// the sniffer's parser, without changing any session state
+func handlePFCPDecodeAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		sessionList = append(sessionList, SessionJSON{
-			SEID:      fmt.Sprintf("0x%x", s.SEID),
-			UEIP:      ueIP,
-			TEIDs:     teids,
-			TEIDUL:    teidUL,
-			TEIDDL:    teidDL,
-			CreatedAt: s.CreatedAt.Format(time.RFC3339),
-			PacketsUL: s.PacketsUL,
-			PacketsDL: s.PacketsDL,
-
-			// Extended fields
-			UPFIP:        upfIP,
-			GNBIP:        gnbIP,
-			UplinkPeerIP: uplinkPeerIP,
-			N9PeerIP:     n9PeerIP,
-			SUPI:         s.SUPI,
-			DNN:          s.DNN,
-			SNssai:       s.SNssai,
-			QFI:          s.QFI,
-			SessionType:  s.SessionType,
-			SessionID:    s.SessionID,
-
-			// Traffic
-			BytesUL: s.BytesUL,
-			BytesDL: s.BytesDL,
-
-			// QoS
-			QoS5QI:      s.QoS5QI,
-			ARPPL:       s.ARPPL,
-			GBRUplink:   s.GBRUplink,
-			GBRDownlink: s.GBRDownlink,
-			MBRUplink:   s.MBRUplink,
-			MBRDownlink: s.MBRDownlink,
-
-			// Status
-			Status:     status,
-			Duration:   durationStr,
-			LastActive: lastActive,
-		})
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	response := map[string]interface{}{
-		"total":    len(sessionList),
-		"sessions": sessionList,
+	var req struct {
+		Hex    string `json:"hex"`
+		Base64 string `json:"base64"`
+	}
+	writeError := func(status int, msg string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+	if err := jsonbody.Decode(w, r, &req, 1<<16); err != nil {
+		writeError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	var payload []byte
+	var err error
+	switch {
+	case req.Hex != "" && req.Base64 != "":
+		writeError(http.StatusBadRequest, "give either hex or base64, not both")
+		return
+	case req.Hex != "":
+		// Accept Wireshark style dumps: "20 32 00 ..." or "20:32:00:..."
+		payload, err = hex.DecodeString(strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(req.Hex))
+	case req.Base64 != "":
+		payload, err = base64.StdEncoding.DecodeString(strings.TrimSpace(req.Base64))
+	default:
+		writeError(http.StatusBadRequest, "hex or base64 is required")
+		return
+	}
+	if err != nil {
+		writeError(http.StatusBadRequest, fmt.Sprintf("invalid message encoding: %v", err))
+		return
+	}
+
+	msg, err := pfcp.Decode(payload)
+	if err != nil {
+		writeError(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(msg)
+}
+
+// handleEBPFInfoAPI lists the loaded eBPF programs with their attach points
+// and the maps with their sizes and entry counts
+func handleEBPFInfoAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if ebpfLoader == nil || !ebpfReady.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "eBPF programs not loaded"})
+		return
+	}
+
+	info, err := ebpfLoader.Info()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(info)
 }
 
 // handleDropTracingConfig handles enabling/disabling kernel drop tracing
@@ -461,14 +1662,14 @@ func handleDropTracingConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
+	req := struct {
 		Enabled bool `json:"enabled"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		req.Enabled = true // Default to enable
+	}{Enabled: true} // Default to enable
+	if !decodeRequestBody(w, r, &req, jsonbody.MaxBytes) {
+		return
 	}
 
-	if ebpfLoader == nil {
+	if ebpfLoader == nil || !ebpfReady.Load() {
 		http.Error(w, "eBPF loader not initialized", http.StatusInternalServerError)
 		return
 	}
@@ -494,37 +1695,87 @@ func handleDropTracingConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// formatDuration formats a duration into a human-readable string
-func formatDuration(d time.Duration) string {
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
+// handleLogLevelConfig returns (GET) or changes (PUT {"level": "debug"})
+// the minimum level of the log lines printed, see -log-level
+func handleLogLevelConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]string{"level": loglevel.Name(loglevel.Level())})
+		return
+	case http.MethodPut:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Level string `json:"level"`
 	}
-	if d < time.Hour {
-		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	if !decodeRequestBody(w, r, &req, jsonbody.MaxBytes) {
+		return
 	}
-	if d < 24*time.Hour {
-		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	level, err := loglevel.Parse(req.Level)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
 	}
-	days := int(d.Hours()) / 24
-	hours := int(d.Hours()) % 24
-	return fmt.Sprintf("%dd %dh", days, hours)
+
+	previous := loglevel.Level()
+	loglevel.Set(level)
+	log.Printf("[CONFIG] Log level set to %s (was %s)", loglevel.Name(level), loglevel.Name(previous))
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"level":    loglevel.Name(level),
+		"previous": loglevel.Name(previous),
+	})
 }
 
-func updateSessionCount() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// updateSessionCount keeps the active sessions gauge in sync, updating it
+// whenever the correlation changes instead of polling
+func updateSessionCount(stop <-chan struct{}) {
+	changes := pfcpCorrelation.Subscribe()
+	defer pfcpCorrelation.Unsubscribe(changes)
 
-	for range ticker.C {
+	for {
 		count := pfcpCorrelation.SessionCount()
 		activeSessions.Set(float64(count))
+
+		select {
+		case <-stop:
+			return
+		case <-changes:
+		}
 	}
 }
 
-func collectStats(loader *ebpf.Loader) {
-	ticker := time.NewTicker(1 * time.Second)
+// minCollectInterval bounds -collect-interval, below it the map reads cost
+// more than the extra rate resolution is worth
+const minCollectInterval = 100 * time.Millisecond
+
+// collectStats reads the traffic counters every -collect-interval and
+// derives the rates from the time actually elapsed between reads
+func collectStats(loader *ebpf.Loader, stop <-chan struct{}) {
+	ticker := time.NewTicker(*collectInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	// Time of the previous sample, zero until there is one to rate against
+	var lastSample time.Time
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
 		uplink, downlink, err := loader.GetTrafficStats()
 		if err != nil {
 			log.Printf("Error getting stats: %v", err)
@@ -537,6 +1788,28 @@ func collectStats(loader *ebpf.Loader) {
 		uplinkBytesDelta := uplink.Bytes - prevUplinkBytes
 		downlinkBytesDelta := downlink.Bytes - prevDownlinkBytes
 
+		// Rates over the actual interval, the ticker may have skipped ticks
+		now := time.Now()
+		var rates trafficRates
+		if !lastSample.IsZero() {
+			elapsed := now.Sub(lastSample).Seconds()
+			rates = trafficRates{
+				PPSUL: float64(uplinkPktDelta) / elapsed,
+				PPSDL: float64(downlinkPktDelta) / elapsed,
+				BPSUL: float64(uplinkBytesDelta) * 8 / elapsed,
+				BPSDL: float64(downlinkBytesDelta) * 8 / elapsed,
+			}
+		}
+		lastSample = now
+		ratesMu.Lock()
+		currentRates = rates
+		currentUplinkBytes, currentDownlinkBytes = uplink.Bytes, downlink.Bytes
+		ratesMu.Unlock()
+		packetsPerSecond.WithLabelValues("uplink").Set(rates.PPSUL)
+		packetsPerSecond.WithLabelValues("downlink").Set(rates.PPSDL)
+		bitsPerSecond.WithLabelValues("uplink").Set(rates.BPSUL)
+		bitsPerSecond.WithLabelValues("downlink").Set(rates.BPSDL)
+
 		// Update previous values
 		prevUplinkPackets = uplink.Packets
 		prevDownlinkPackets = downlink.Packets
@@ -553,34 +1826,33 @@ func collectStats(loader *ebpf.Loader) {
 			bytesTotal.WithLabelValues("downlink").Add(float64(downlinkBytesDelta))
 		}
 
+		eventBus.Publish(events.TopicTraffic, ebpf.TrafficSnapshot{
+			Uplink:   uplink,
+			Downlink: downlink,
+		})
+
 		// Update per-session stats from eBPF TEID counters
 		updateSessionStatsFromEBPF(loader)
 
 		// Print stats if there's activity
 		if uplinkPktDelta > 0 || downlinkPktDelta > 0 {
-			fmt.Printf("\rUL: %d pkts (%s)  DL: %d pkts (%s)          ",
-				uplink.Packets, formatBytes(uplink.Bytes),
-				downlink.Packets, formatBytes(downlink.Bytes))
+			fmt.Printf("\rUL: %d pkts (%s, %.0f pps)  DL: %d pkts (%s, %.0f pps)          ",
+				uplink.Packets, formatBytes(uplink.Bytes), rates.PPSUL,
+				downlink.Packets, formatBytes(downlink.Bytes), rates.PPSDL)
 		}
 	}
 }
 
 // updateSessionStatsFromEBPF syncs TEID stats from eBPF to session objects
 func updateSessionStatsFromEBPF(loader *ebpf.Loader) {
+	now := time.Now()
+
 	// Update uplink stats from TEID counters
 	teidStats, err := loader.GetAllTEIDStats()
 	if err == nil {
 		for teid, stats := range teidStats {
-			session, found := pfcpCorrelation.GetSessionByTEID(teid)
-			if found && session != nil {
-				// Only update LastActive if traffic increased
-				if stats.Packets > session.PacketsUL || stats.Bytes > session.BytesUL {
-					session.LastActive = time.Now()
-				}
-				// TEID stats are uplink traffic
-				session.PacketsUL = stats.Packets
-				session.BytesUL = stats.Bytes
-			}
+			// TEID stats are uplink traffic
+			pfcpCorrelation.UpdateUplinkStats(teid, stats.Packets, stats.Bytes, now)
 		}
 	}
 
@@ -588,18 +1860,8 @@ func updateSessionStatsFromEBPF(loader *ebpf.Loader) {
 	ueIPStats, err := loader.GetAllUEIPStats()
 	if err == nil {
 		for ueIPUint32, stats := range ueIPStats {
-			// Convert uint32 to IP string
-			ueIP := ebpf.FormatIP(ueIPUint32)
-			session, found := pfcpCorrelation.GetSessionByUEIP(ueIP)
-			if found && session != nil {
-				// Only update LastActive if traffic increased
-				if stats.Packets > session.PacketsDL || stats.Bytes > session.BytesDL {
-					session.LastActive = time.Now()
-				}
-				// UE IP stats are downlink traffic
-				session.PacketsDL = stats.Packets
-				session.BytesDL = stats.Bytes
-			}
+			// UE IP stats are downlink traffic
+			pfcpCorrelation.UpdateDownlinkStats(ebpf.ParseEventIP(ueIPUint32), stats.Packets, stats.Bytes, now)
 		}
 	}
 }
@@ -650,7 +1912,9 @@ func handleDemoInjectDrop(w http.ResponseWriter, r *http.Request) {
 	req.Direction = "uplink"
 	req.Count = 1
 
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeRequestBody(w, r, &req, jsonbody.MaxBytes) {
+		return
+	}
 
 	if req.Count <= 0 {
 		req.Count = 1
@@ -775,7 +2039,7 @@ func handleDemoInjectDrop(w http.ResponseWriter, r *http.Request) {
 		pktSizes := []uint32{64, 128, 256, 512, 576, 1024, 1280, 1400, 1460, 1500}
 		pktLen := pktSizes[time.Now().UnixNano()%int64(len(pktSizes))]
 
-		dropEvent := DropEventJSON{
+		dropEvent := model.DropEvent{
 			Timestamp: time.Now().Format(time.RFC3339),
 			TEID:      fmt.Sprintf("0x%08x", teid),
 			SrcIP:     srcIP,
@@ -791,14 +2055,7 @@ func handleDemoInjectDrop(w http.ResponseWriter, r *http.Request) {
 		packetDropsTotal.WithLabelValues(reason, direction).Inc()
 
 		// Store drop event
-		dropEventsMu.Lock()
-		recentDrops = append([]DropEventJSON{dropEvent}, recentDrops...)
-		if len(recentDrops) > 100 {
-			recentDrops = recentDrops[:100]
-		}
-		totalDrops++
-		dropsByReason[reason]++
-		dropEventsMu.Unlock()
+		storeDropEvent(dropEvent)
 
 		log.Printf("[DEMO DROP] reason=%s direction=%s teid=%s", reason, direction, dropEvent.TEID)
 	}
@@ -843,17 +2100,20 @@ func handleDemoInjectSession(w http.ResponseWriter, r *http.Request) {
 	}
 	req.Count = 1
 
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeRequestBody(w, r, &req, jsonbody.MaxBytes) {
+		return
+	}
 
 	// If specific session info provided, use it
 	if req.SEID != "" || req.UEIP != "" {
-		var seid uint64
-		if len(req.SEID) > 2 && req.SEID[:2] == "0x" {
-			fmt.Sscanf(req.SEID, "0x%x", &seid)
-		} else if req.SEID != "" {
-			fmt.Sscanf(req.SEID, "%d", &seid)
-		} else {
-			seid = uint64(0x1)
+		seid := uint64(0x1)
+		if req.SEID != "" {
+			parsed, err := model.ParseSEID(req.SEID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			seid = parsed
 		}
 
 		ueIP := net.ParseIP(req.UEIP)
@@ -863,11 +2123,10 @@ func handleDemoInjectSession(w http.ResponseWriter, r *http.Request) {
 
 		teids := make([]uint32, 0)
 		for _, teidStr := range req.TEIDs {
-			var teid uint32
-			if len(teidStr) > 2 && teidStr[:2] == "0x" {
-				fmt.Sscanf(teidStr, "0x%x", &teid)
-			} else {
-				fmt.Sscanf(teidStr, "%d", &teid)
+			teid, err := model.ParseTEID(teidStr)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
 			if teid > 0 {
 				teids = append(teids, teid)
@@ -1017,7 +2276,9 @@ func handleSyncSessions(w http.ResponseWriter, r *http.Request) {
 		LogPath string `json:"log_path"`
 	}
 
-	json.NewDecoder(r.Body).Decode(&req)
+	if !decodeRequestBody(w, r, &req, jsonbody.MaxBytes) {
+		return
+	}
 
 	syncedCount := 0
 
@@ -1025,26 +2286,29 @@ func handleSyncSessions(w http.ResponseWriter, r *http.Request) {
 	if len(req.Sessions) > 0 {
 		for _, s := range req.Sessions {
 			// Parse SEID (hex string like "0x1" or decimal)
-			var seid uint64
-			if len(s.SEID) > 2 && s.SEID[:2] == "0x" {
-				fmt.Sscanf(s.SEID, "0x%x", &seid)
-			} else {
-				fmt.Sscanf(s.SEID, "%d", &seid)
+			seid, err := model.ParseSEID(s.SEID)
+			if err != nil {
+				log.Printf("[SYNC] Skipping session: %v", err)
+				continue
 			}
 
 			// Parse TEIDs
-			teids := make([]uint32, 0)
+			teids := make([]uint32, 0, len(s.TEIDs))
+			valid := true
 			for _, teidStr := range s.TEIDs {
-				var teid uint32
-				if len(teidStr) > 2 && teidStr[:2] == "0x" {
-					fmt.Sscanf(teidStr, "0x%x", &teid)
-				} else {
-					fmt.Sscanf(teidStr, "%d", &teid)
+				teid, err := model.ParseTEID(teidStr)
+				if err != nil {
+					log.Printf("[SYNC] Skipping session SEID=0x%x: %v", seid, err)
+					valid = false
+					break
 				}
 				if teid > 0 {
 					teids = append(teids, teid)
 				}
 			}
+			if !valid {
+				continue
+			}
 
 			session := &pfcp.Session{
 				SEID:      seid,
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/ebpf"
+	"github.com/solar224/5G-DPOP/internal/model"
+	"github.com/solar224/5G-DPOP/internal/pfcp"
+	"go.uber.org/goleak"
+)
+
+// quietLog discards the log output until the test ends
+func quietLog(tb testing.TB) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+}
+
+func TestShutdownLeaksNoGoroutines(t *testing.T) {
+	quietLog(t)
+	correlation, retention, summaryEvery := pfcpCorrelation, *dropRetention, *dropSummaryEvery
+	t.Cleanup(func() {
+		pfcpCorrelation, *dropRetention, *dropSummaryEvery = correlation, retention, summaryEvery
+		draining.Store(false)
+	})
+	pfcpCorrelation = pfcp.NewCorrelation()
+	*dropRetention = time.Minute
+	*dropSummaryEvery = time.Minute
+
+	// An eBPF loader that loaded nothing: the collectors run, their map
+	// reads fail
+	loader := ebpf.NewLoader()
+	coalescer := newDropCoalescer(func(model.DropEvent) {})
+	go coalescer.run(time.Minute)
+	stop := make(chan struct{})
+	collectors := startCollectors(loader, true, newDropSummary(5), stop)
+
+	flushed := false
+	shutdown(loader, true, false, coalescer, stop, collectors, []func(){func() { flushed = true }})
+	if !flushed {
+		t.Error("sink not flushed")
+	}
+	goleak.VerifyNone(t)
+}
+
+// withCorrelation gives the handlers an empty correlation until the test
+// ends
+func withCorrelation(t *testing.T) *pfcp.Correlation {
+	correlation := pfcpCorrelation
+	t.Cleanup(func() { pfcpCorrelation = correlation })
+	pfcpCorrelation = pfcp.NewCorrelation()
+	return pfcpCorrelation
+}
+
+func TestInjectSessionTEIDs(t *testing.T) {
+	quietLog(t)
+	c := withCorrelation(t)
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		handleDemoInjectSession(w, httptest.NewRequest(http.MethodPost, "/api/demo/inject-session", strings.NewReader(body)))
+		return w.Code
+	}
+
+	// A leading 0 is decimal, not octal
+	if code := post(`{"seid":"0x5","ue_ip":"10.60.0.5","teids":["010","0x10"]}`); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	for _, teid := range []uint32{10, 0x10} {
+		if session, ok := c.GetSessionByTEID(teid); !ok || session.SEID != 5 {
+			t.Errorf("TEID %d not mapped to SEID 0x5", teid)
+		}
+	}
+	if _, ok := c.GetSessionByTEID(8); ok {
+		t.Error("TEID 010 read as octal")
+	}
+
+	for _, teid := range []string{"0b1", "0o7", "zz", "0x100000000"} {
+		if code := post(`{"seid":"0x6","ue_ip":"10.60.0.6","teids":["` + teid + `"]}`); code != http.StatusBadRequest {
+			t.Errorf("TEID %q: status %d, want 400", teid, code)
+		}
+	}
+	if _, ok := c.GetSessionBySEID(6); ok {
+		t.Error("session with an invalid TEID injected")
+	}
+}
+
+func TestSyncSessionsTEIDs(t *testing.T) {
+	quietLog(t)
+	c := withCorrelation(t)
+
+	body := `{"sessions":[
+		{"seid":"0x5","ue_ip":"10.60.0.5","teids":["010"]},
+		{"seid":"0x6","ue_ip":"10.60.0.6","teids":["0x20","0o7"]}
+	]}`
+	w := httptest.NewRecorder()
+	handleSyncSessions(w, httptest.NewRequest(http.MethodPost, "/api/sessions/sync", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+
+	if session, ok := c.GetSessionByTEID(10); !ok || session.SEID != 5 {
+		t.Error("TEID 010 not synced as decimal 10")
+	}
+	if _, ok := c.GetSessionBySEID(6); ok {
+		t.Error("session with an invalid TEID synced")
+	}
+	if _, ok := c.GetSessionByTEID(0x20); ok {
+		t.Error("TEID of a skipped session mapped")
+	}
+}
+
+func TestIsKnownUEIP(t *testing.T) {
+	quietLog(t)
+	c := withCorrelation(t)
+	c.AddSession(&pfcp.Session{SEID: 0x1001, UEIP: net.ParseIP("10.60.0.1")})
+
+	// As read from the kernel: network byte order loaded little-endian
+	if !isKnownUEIP(0x01003c0a) {
+		t.Error("10.60.0.1 not known")
+	}
+	if isKnownUEIP(0x02003c0a) {
+		t.Error("10.60.0.2 known")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Linux capability bits (linux/capability.h)
+const (
+	capNetAdmin = 12
+	capNetRaw   = 13
+	capSysAdmin = 21
+	capPerfmon  = 38
+	capBPF      = 39
+)
+
+// privileges are the capabilities the agent's features need
+type privileges struct {
+	ebpf    bool // load programs and attach kprobes
+	capture bool // open a raw socket for PFCP capture
+	tc      bool // add the clsact qdisc for -tc-egress-iface
+}
+
+// currentPrivileges reads the effective capability set of the process.
+// Kernels without CAP_BPF/CAP_PERFMON (before 5.8) need CAP_SYS_ADMIN for
+// eBPF. If the set can't be read, root is assumed to have everything.
+func currentPrivileges() privileges {
+	caps, ok := effectiveCaps()
+	if !ok {
+		root := os.Geteuid() == 0
+		return privileges{ebpf: root, capture: root, tc: root}
+	}
+	has := func(bit uint) bool { return caps&(1<<bit) != 0 }
+	return privileges{
+		ebpf:    has(capSysAdmin) || (has(capBPF) && has(capPerfmon)),
+		capture: has(capNetRaw),
+		tc:      has(capNetAdmin),
+	}
+}
+
+// effectiveCaps returns the CapEff mask from /proc/self/status
+func effectiveCaps() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !found {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		return caps, err == nil
+	}
+	return 0, false
+}
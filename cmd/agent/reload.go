@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/solar224/5G-DPOP/internal/configfile"
+	"github.com/solar224/5G-DPOP/internal/ebpf"
+	"github.com/solar224/5G-DPOP/internal/loglevel"
+)
+
+// registerReloadable lists the settings SIGHUP changes at runtime. The
+// others, such as the listen addresses, the eBPF object or the PFCP
+// interface, need a restart, which re-attaches eBPF and loses the sessions.
+func registerReloadable(config *configfile.File) {
+	config.Reloadable("log-level", func(value string) error {
+		level, err := loglevel.Parse(value)
+		if err != nil {
+			return err
+		}
+		loglevel.Set(level)
+		return nil
+	})
+
+	config.Reloadable("drop-reason-map", func(value string) error {
+		if value == "" {
+			ebpf.SetReasonClassifier(nil)
+			return nil
+		}
+		reasons, err := ebpf.LoadReasonMap(value)
+		if err != nil {
+			return err
+		}
+		ebpf.SetReasonClassifier(reasons)
+		return nil
+	})
+
+	config.Reloadable("drop-recent-max", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return errors.New("must be at least 1")
+		}
+		dropEventsMu.Lock()
+		recentDropMax = n
+		if len(recentDrops) > n {
+			recentDrops = append([]recentDrop(nil), recentDrops[len(recentDrops)-n:]...)
+		}
+		dropEventsMu.Unlock()
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/solar224/5G-DPOP/internal/pfcp"
+)
+
+// replayRunning allows one replay at a time, they are CPU bound and the
+// upload is staged on disk
+var replayRunning atomic.Bool
+
+// handlePFCPReplayAPI runs an uploaded pcap (multipart field "file")
+// through a throwaway correlation and returns the resulting sessions,
+// lifecycle events and parse errors. The live sessions are not touched.
+func handlePFCPReplayAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeError := func(status int, msg string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+
+	if !replayRunning.CompareAndSwap(false, true) {
+		writeError(http.StatusTooManyRequests, "another replay is running, retry later")
+		return
+	}
+	defer replayRunning.Store(false)
+
+	r.Body = http.MaxBytesReader(w, r.Body, *replayMaxSize)
+	path, err := stageReplayUpload(r)
+	if path != "" {
+		defer os.Remove(path)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(http.StatusRequestEntityTooLarge, fmt.Sprintf("capture larger than %d bytes (-pfcp-replay-max-size)", *replayMaxSize))
+		return
+	}
+	if err != nil {
+		writeError(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), *replayTimeout)
+	defer cancel()
+	result, err := pfcp.Replay(ctx, path, 8805)
+	if err != nil {
+		writeError(http.StatusBadRequest, fmt.Sprintf("not a readable pcap: %v", err))
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// stageReplayUpload copies the "file" part of a multipart upload into a
+// temporary file, as libpcap only reads captures from files. The caller
+// removes the returned path, which may be set along with an error.
+func stageReplayUpload(r *http.Request) (string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", fmt.Errorf("expected a multipart/form-data upload with a file field: %w", err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", errors.New("no file field in the upload")
+		}
+		if err != nil {
+			return "", err
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		tmp, err := os.CreateTemp("", "pfcp-replay-*.pcap")
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(tmp, part)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		return tmp.Name(), err
+	}
+}
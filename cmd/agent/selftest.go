@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/solar224/5G-DPOP/internal/ebpf"
+)
+
+// runSelfTest checks that the eBPF programs load and attach on this kernel
+// (see ebpf.SelfTest), prints the report and returns the exit code
+func runSelfTest(objectPath, iface string) int {
+	if !currentPrivileges().ebpf {
+		fmt.Fprintln(os.Stderr, "[WARN] Not privileged for eBPF (needs root, or CAP_BPF and CAP_PERFMON), the self-test will fail")
+	}
+
+	report := ebpf.SelfTest(objectPath, iface)
+	fmt.Println("5G-DPOP eBPF self-test")
+	fmt.Printf("   Kernel: %s\n", report.Kernel)
+	fmt.Printf("   BTF:    %s\n", report.BTF)
+	fmt.Printf("   Object: %s\n", report.Object)
+	for _, check := range report.Checks {
+		status := "[PASS]"
+		switch {
+		case !check.OK && check.Optional:
+			status = "[SKIP]"
+		case !check.OK:
+			status = "[FAIL]"
+		}
+		fmt.Printf("%s %s\n", status, check.Name)
+		fmt.Printf("   └─ %s\n", check.Detail)
+	}
+
+	if !report.Passed() {
+		fmt.Println("Self-test FAILED")
+		return 1
+	}
+	fmt.Println("Self-test passed")
+	return 0
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes of APIError. They are stable for clients to switch on, unlike the
+// messages.
+const (
+	ErrCodeInvalidRequest   = "invalid_request"
+	ErrCodeUnauthorized     = "unauthorized"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeSessionNotFound  = "session_not_found"
+	ErrCodeNodeNotFound     = "node_not_found"
+	ErrCodeDisabled         = "feature_disabled" // Needs a flag the server was not started with
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeRateLimited      = "rate_limited"
+	ErrCodeUnavailable      = "unavailable"
+	ErrCodeAgentUnavailable = "agent_unavailable"
+	ErrCodeUpstreamError    = "upstream_error"
+	ErrCodeInternal         = "internal_error"
+)
+
+// ErrorResponse is returned with 4xx/5xx status codes
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes why a request failed
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // Also the X-Request-ID response header
+}
+
+// requestIDHeader carries the request ID, taken from the request if the
+// client (or a proxy) set a usable one
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds the request IDs taken from clients
+const maxRequestIDLen = 128
+
+// requestIDKey is the gin context key of the request ID
+const requestIDKey = "request_id"
+
+// assignRequestID gives every request an ID, returned in X-Request-ID and
+// in its error responses, to match client reports with server logs
+func assignRequestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeader)
+	if !validRequestID(id) {
+		var buf [16]byte
+		rand.Read(buf[:])
+		id = hex.EncodeToString(buf[:])
+	}
+	c.Set(requestIDKey, id)
+	c.Header(requestIDHeader, id)
+	c.Next()
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// respondError answers the request with an ErrorResponse and stops the
+// handler chain
+func respondError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: APIError{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(requestIDKey),
+	}})
+}
+
+// errorCodeForStatus is the code of errors that have nothing more
+// specific, e.g. those of the agent
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrCodeUnauthorized
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return ErrCodeUpstreamError
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	}
+	return ErrCodeInternal
+}
+
+// respondAgentError passes on an error response of the agent, which
+// answers {"error": "message"}, as an ErrorResponse with code
+func respondAgentError(c *gin.Context, resp agentResponse, code string) {
+	var agentErr struct {
+		Error string `json:"error"`
+	}
+	message := http.StatusText(resp.status)
+	if json.Unmarshal(resp.body, &agentErr) == nil && agentErr.Error != "" {
+		message = agentErr.Error
+	}
+	respondError(c, resp.status, code, message)
+}
+
+// handleNoRoute and handleNoMethod answer unknown paths and methods, which
+// gin answers in plain text otherwise
+func handleNoRoute(c *gin.Context) {
+	respondError(c, http.StatusNotFound, ErrCodeNotFound, "no route for "+c.Request.URL.Path)
+}
+
+func handleNoMethod(c *gin.Context) {
+	respondError(c, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, c.Request.Method+" not allowed on "+c.Request.URL.Path)
+}
+
+// handlePanic answers requests whose handler panicked, after gin logged it
+func handlePanic(c *gin.Context, _ interface{}) {
+	respondError(c, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+}
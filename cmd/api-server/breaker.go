@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuitBreaker
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // calls go through
+	breakerOpen                         // calls are rejected until the cooldown is over
+	breakerHalfOpen                     // one trial call decides whether to close again
+)
+
+func (st breakerState) String() string {
+	switch st {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// BreakerStatus is the state of a circuit breaker as served by the API
+type BreakerStatus struct {
+	State               string `json:"state"` // "closed", "open" or "half-open"
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Threshold           int    `json:"threshold"`
+	LastError           string `json:"last_error,omitempty"`
+	OpenedAt            string `json:"opened_at,omitempty"`
+	RetryAt             string `json:"retry_at,omitempty"` // when an open breaker lets a trial call through
+}
+
+// circuitBreaker stops calling something that keeps failing. After
+// threshold consecutive failures it opens and rejects calls; once cooldown
+// has passed it lets a single trial call through, whose outcome closes or
+// reopens it. A threshold of 0 never opens.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	lastErr  error
+	openedAt time.Time
+	trial    bool // the half-open trial call is in progress
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may go ahead, or why not. Every allowed call
+// must be followed by record.
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		retryAt := b.openedAt.Add(b.cooldown)
+		if now.Before(retryAt) {
+			return fmt.Errorf("%d consecutive failures (last: %v), retrying in %s",
+				b.failures, b.lastErr, retryAt.Sub(now).Round(time.Second))
+		}
+		b.state = breakerHalfOpen
+		b.trial = true
+		return nil
+	case breakerHalfOpen:
+		if b.trial {
+			return fmt.Errorf("%d consecutive failures (last: %v), trial in progress", b.failures, b.lastErr)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record reports the outcome of an allowed call and returns the state
+// change it caused, if any (from == to otherwise)
+func (b *circuitBreaker) record(err error, now time.Time) (from, to breakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from = b.state
+	b.trial = false
+	if err == nil {
+		b.state = breakerClosed
+		b.failures = 0
+		b.lastErr = nil
+		return from, b.state
+	}
+
+	b.failures++
+	b.lastErr = err
+	if b.state == breakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+	return from, b.state
+}
+
+// status returns the breaker state for the API
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerStatus{
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+		Threshold:           b.threshold,
+	}
+	if b.lastErr != nil {
+		st.LastError = b.lastErr.Error()
+	}
+	if b.state != breakerClosed {
+		st.OpenedAt = b.openedAt.Format(time.RFC3339)
+		st.RetryAt = b.openedAt.Add(b.cooldown).Format(time.RFC3339)
+	}
+	return st
+}
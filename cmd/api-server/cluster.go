@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// defaultClusterPollInterval is the default -agents-poll-interval
+const defaultClusterPollInterval = 2 * time.Second
+
+// clusterStaleIntervals is how many poll intervals an agent may go without
+// a successful scrape before it is reported stale
+const clusterStaleIntervals = 3
+
+// AgentSummary is the part of an agent's /api/stats/summary that adds up
+// across nodes
+type AgentSummary struct {
+	Sessions          int     `json:"total_sessions"`
+	TEIDs             int     `json:"total_teids"`
+	UEIPs             int     `json:"unique_ue_ips"`
+	CreatedLastMinute int     `json:"sessions_created_last_minute"`
+	DeletedLastMinute int     `json:"sessions_deleted_last_minute"`
+	Buffering         int     `json:"buffering_sessions"`
+	BytesUL           uint64  `json:"total_bytes_ul"`
+	BytesDL           uint64  `json:"total_bytes_dl"`
+	PPSUL             float64 `json:"pps_ul"`
+	PPSDL             float64 `json:"pps_dl"`
+	BPSUL             float64 `json:"bps_ul"`
+	BPSDL             float64 `json:"bps_dl"`
+}
+
+// add accumulates o into a
+func (a *AgentSummary) add(o AgentSummary) {
+	a.Sessions += o.Sessions
+	a.TEIDs += o.TEIDs
+	a.UEIPs += o.UEIPs
+	a.CreatedLastMinute += o.CreatedLastMinute
+	a.DeletedLastMinute += o.DeletedLastMinute
+	a.Buffering += o.Buffering
+	a.BytesUL += o.BytesUL
+	a.BytesDL += o.BytesDL
+	a.PPSUL += o.PPSUL
+	a.PPSDL += o.PPSDL
+	a.BPSUL += o.BPSUL
+	a.BPSDL += o.BPSDL
+}
+
+// ClusterNode is the scrape state of one agent. A stale node keeps the
+// data of its last successful scrape.
+type ClusterNode struct {
+	Node        string        `json:"node"`
+	Name        string        `json:"name,omitempty"` // the agent's -node-name
+	Stale       bool          `json:"stale"`
+	LastSuccess string        `json:"last_success,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+	Sessions    int           `json:"sessions"`
+	Stats       *AgentSummary `json:"stats,omitempty"`
+}
+
+// ClusterStatsResponse holds the stats summed over the nodes that are not
+// stale, and the per-node stats
+type ClusterStatsResponse struct {
+	Nodes      int           `json:"nodes"`
+	StaleNodes int           `json:"stale_nodes"`
+	Total      AgentSummary  `json:"total"`
+	PerNode    []ClusterNode `json:"per_node"`
+}
+
+// ClusterSession is a session tagged with the node whose agent reported it
+type ClusterSession struct {
+	Node string `json:"node"`
+	model.SessionInfo
+}
+
+// ClusterSessionsResponse holds the sessions of the nodes that are not
+// stale (or of the ?node= asked for, even if stale) and the node states
+type ClusterSessionsResponse struct {
+	Total    int              `json:"total"`
+	Sessions []ClusterSession `json:"sessions"`
+	Nodes    []ClusterNode    `json:"nodes"`
+}
+
+// clusterAgent is the last scrape of one agent
+type clusterAgent struct {
+	node        string // host:port as given in -agents
+	name        string // -node-name reported by the agent
+	lastSuccess time.Time
+	lastError   string
+	stats       *AgentSummary
+	sessions    []model.SessionInfo
+	etag        string // of sessions, sent back as If-None-Match
+}
+
+// clusterCollector scrapes the agents of a multi-node UPF cluster
+type clusterCollector struct {
+	agents   []*clusterAgent // in -agents order, never resized
+	mu       sync.RWMutex    // guards the fields of agents
+	client   *http.Client
+	interval time.Duration
+}
+
+// parseAgentList splits a comma-separated -agents value, defaulting the
+// port of entries without one to the agent's 9100
+func parseAgentList(list string) ([]string, error) {
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, ":") || strings.HasSuffix(entry, "]") {
+			entry += ":9100"
+		}
+		if seen[entry] {
+			return nil, fmt.Errorf("agent %s listed twice", entry)
+		}
+		seen[entry] = true
+		nodes = append(nodes, entry)
+	}
+	return nodes, nil
+}
+
+// SetClusterAgents makes the server scrape the agents at nodes (host:port)
+// every interval and serve them under /api/v1/cluster/. It must be called
+// at most once, before Stop.
+func (s *Server) SetClusterAgents(nodes []string, interval time.Duration) {
+	cc := &clusterCollector{
+		client:   &http.Client{Timeout: interval},
+		interval: interval,
+	}
+	for _, node := range nodes {
+		cc.agents = append(cc.agents, &clusterAgent{node: node})
+	}
+
+	s.statsMu.Lock()
+	s.cluster = cc
+	s.statsMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		cc.run(s.stop)
+	}()
+	log.Printf("[INFO] Aggregating %d agents every %v: %s", len(nodes), interval, strings.Join(nodes, ", "))
+}
+
+// clusterView returns the cluster collector, nil without -agents
+func (s *Server) clusterView() *clusterCollector {
+	s.statsMu.RLock()
+	defer s.statsMu.RUnlock()
+	return s.cluster
+}
+
+// run scrapes all agents every interval until stop is closed
+func (cc *clusterCollector) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(cc.interval)
+	defer ticker.Stop()
+
+	for {
+		cc.scrapeAll()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrapeAll scrapes the agents concurrently, so one unreachable agent
+// does not hold up the others
+func (cc *clusterCollector) scrapeAll() {
+	var wg sync.WaitGroup
+	for _, agent := range cc.agents {
+		wg.Add(1)
+		go func(agent *clusterAgent) {
+			defer wg.Done()
+			cc.scrape(agent)
+		}(agent)
+	}
+	wg.Wait()
+}
+
+// scrape fetches the summary and sessions of one agent. Both have to
+// succeed for the scrape to count, so the stats and sessions of a node
+// are always from the same round.
+func (cc *clusterCollector) scrape(agent *clusterAgent) {
+	cc.mu.RLock()
+	etag := agent.etag
+	wasFailing := agent.lastError != ""
+	cc.mu.RUnlock()
+
+	var summary struct {
+		Node string `json:"node"`
+		AgentSummary
+	}
+	err := cc.getJSON("http://"+agent.node+"/api/stats/summary", &summary)
+	var sessions []model.SessionInfo
+	if err == nil {
+		sessions, etag, err = cc.fetchSessions(agent.node, etag)
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if err != nil {
+		if !wasFailing {
+			log.Printf("[WARN] Cluster agent %s unreachable: %v", agent.node, err)
+		}
+		agent.lastError = err.Error()
+		return
+	}
+	if wasFailing {
+		log.Printf("[OK] Cluster agent %s reachable again", agent.node)
+	}
+	agent.lastSuccess = time.Now()
+	agent.lastError = ""
+	agent.name = summary.Node
+	agent.stats = &summary.AgentSummary
+	if sessions != nil {
+		agent.sessions = sessions
+		agent.etag = etag
+	}
+}
+
+// getJSON decodes the JSON response of a GET to url into v
+func (cc *clusterCollector) getJSON(url string, v interface{}) error {
+	resp, err := cc.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	return nil
+}
+
+// fetchSessions fetches the sessions of the agent at node, like
+// fetchAgentSessions: nil sessions mean unchanged since etag
+func (cc *clusterCollector) fetchSessions(node, etag string) ([]model.SessionInfo, string, error) {
+	url := "http://" + node + "/api/sessions"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := cc.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusOK:
+	default:
+		return nil, "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	var result SessionList
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	if result.Sessions == nil {
+		result.Sessions = make([]model.SessionInfo, 0)
+	}
+	return result.Sessions, resp.Header.Get("ETag"), nil
+}
+
+// nodeLocked returns the state of agent, caller must hold cc.mu
+func (cc *clusterCollector) nodeLocked(agent *clusterAgent, now time.Time) ClusterNode {
+	n := ClusterNode{
+		Node:      agent.node,
+		Name:      agent.name,
+		Stale:     agent.lastSuccess.IsZero() || now.Sub(agent.lastSuccess) > clusterStaleIntervals*cc.interval,
+		LastError: agent.lastError,
+		Sessions:  len(agent.sessions),
+		Stats:     agent.stats,
+	}
+	if !agent.lastSuccess.IsZero() {
+		n.LastSuccess = agent.lastSuccess.Format(time.RFC3339)
+	}
+	return n
+}
+
+// known reports whether node is one of the agents
+func (cc *clusterCollector) known(node string) bool {
+	for _, agent := range cc.agents {
+		if agent.node == node {
+			return true
+		}
+	}
+	return false
+}
+
+// stats sums the stats of the agents that are not stale
+func (cc *clusterCollector) stats() ClusterStatsResponse {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	now := time.Now()
+	resp := ClusterStatsResponse{
+		Nodes:   len(cc.agents),
+		PerNode: make([]ClusterNode, 0, len(cc.agents)),
+	}
+	for _, agent := range cc.agents {
+		n := cc.nodeLocked(agent, now)
+		if n.Stale {
+			resp.StaleNodes++
+		} else if n.Stats != nil {
+			resp.Total.add(*n.Stats)
+		}
+		resp.PerNode = append(resp.PerNode, n)
+	}
+	return resp
+}
+
+// sessions merges the sessions of the agents that are not stale, or
+// returns those of node alone (stale or not) if node is set
+func (cc *clusterCollector) sessions(node string) ClusterSessionsResponse {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	now := time.Now()
+	resp := ClusterSessionsResponse{
+		Sessions: make([]ClusterSession, 0),
+		Nodes:    make([]ClusterNode, 0, len(cc.agents)),
+	}
+	for _, agent := range cc.agents {
+		if node != "" && agent.node != node {
+			continue
+		}
+		n := cc.nodeLocked(agent, now)
+		resp.Nodes = append(resp.Nodes, n)
+		if n.Stale && node == "" {
+			continue
+		}
+		for _, session := range agent.sessions {
+			resp.Sessions = append(resp.Sessions, ClusterSession{Node: agent.node, SessionInfo: session})
+		}
+	}
+	sort.SliceStable(resp.Sessions, func(i, j int) bool {
+		return resp.Sessions[i].CreatedAt < resp.Sessions[j].CreatedAt
+	})
+	resp.Total = len(resp.Sessions)
+	return resp
+}
+
+// handleClusterStats serves the merged and per-node stats of the agents
+func (s *Server) handleClusterStats(c *gin.Context) {
+	cc := s.clusterView()
+	if cc == nil {
+		respondError(c, http.StatusNotFound, ErrCodeDisabled, "Cluster view disabled, start the API server with -agents")
+		return
+	}
+	c.JSON(http.StatusOK, cc.stats())
+}
+
+// handleClusterSessions serves the sessions of all agents tagged with
+// their node, optionally limited to one node with ?node=host:port
+func (s *Server) handleClusterSessions(c *gin.Context) {
+	cc := s.clusterView()
+	if cc == nil {
+		respondError(c, http.StatusNotFound, ErrCodeDisabled, "Cluster view disabled, start the API server with -agents")
+		return
+	}
+	node := c.Query("node")
+	if node != "" && !cc.known(node) {
+		respondError(c, http.StatusNotFound, ErrCodeNodeNotFound, fmt.Sprintf("Unknown node %q", node))
+		return
+	}
+	c.JSON(http.StatusOK, cc.sessions(node))
+}
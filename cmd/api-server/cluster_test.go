@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// fakeAgent serves the summary and sessions of an agent named node
+func fakeAgent(node string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"node": node})
+	})
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(SessionList{})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestClusterCollectorStop(t *testing.T) {
+	agent := fakeAgent("upf-1")
+	s := newTestServer(t)
+	s.SetClusterAgents([]string{strings.TrimPrefix(agent.URL, "http://")}, 10*time.Millisecond)
+
+	// Let a few scrapes go through, keeping connections to the agent open
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cc := s.clusterView()
+		cc.mu.RLock()
+		scraped := !cc.agents[0].lastSuccess.IsZero()
+		cc.mu.RUnlock()
+		if scraped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("agent not scraped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Stop()
+	agent.Close()
+	goleak.VerifyNone(t)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsAnyOrigin allows every origin, the default of -cors-origins
+const corsAnyOrigin = "*"
+
+// parseCORSOrigins parses the comma-separated -cors-origins
+func parseCORSOrigins(s string) []string {
+	var origins []string
+	for _, origin := range strings.Split(s, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// SetCORSOrigins sets the browser origins allowed to call the API and open
+// WebSockets, e.g. https://dashboard.example.com; "*" or none allows all.
+// Takes effect for the next requests.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.corsMu.Lock()
+	if len(origins) == 0 || slices.Contains(origins, corsAnyOrigin) {
+		origins = nil
+	}
+	s.corsOrigins = origins
+	s.corsMu.Unlock()
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" if the origin is not allowed
+func (s *Server) allowedOrigin(origin string) string {
+	s.corsMu.RLock()
+	defer s.corsMu.RUnlock()
+
+	if s.corsOrigins == nil {
+		return corsAnyOrigin
+	}
+	if slices.Contains(s.corsOrigins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// checkWSOrigin accepts WebSocket upgrades from the allowed origins, and
+// from clients sending no Origin (not a browser)
+func (s *Server) checkWSOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || s.allowedOrigin(origin) != ""
+}
+
+// handleCORS adds the CORS headers and answers preflight requests
+func (s *Server) handleCORS(c *gin.Context) {
+	allowed := s.allowedOrigin(c.GetHeader("Origin"))
+	if allowed != corsAnyOrigin {
+		c.Header("Vary", "Origin")
+	}
+	if allowed != "" {
+		c.Header("Access-Control-Allow-Origin", allowed)
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Expose-Headers", requestIDHeader)
+	}
+	if c.Request.Method == "OPTIONS" {
+		c.AbortWithStatus(204)
+		return
+	}
+	c.Next()
+}
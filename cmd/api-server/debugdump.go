@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/adminauth"
+	"github.com/solar224/5G-DPOP/internal/jsonbody"
+)
+
+// debugDumpTimeout bounds a debug dump, a ?full=true dump of a large
+// correlation takes a while to encode and transfer
+const debugDumpTimeout = time.Minute
+
+// SetAdminToken enables the admin endpoints, which then require token as a
+// bearer token. The agent checks the same header, so it must be started
+// with the same token.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// requireAdminToken aborts requests without the admin bearer token, and
+// answers 404 while no token is set
+func (s *Server) requireAdminToken(c *gin.Context) {
+	if s.adminToken == "" {
+		respondError(c, http.StatusNotFound, ErrCodeDisabled, "admin endpoints disabled, start the API server with -admin-token-file")
+		return
+	}
+	if !adminauth.Authorized(c.GetHeader("Authorization"), s.adminToken) {
+		c.Header("WWW-Authenticate", `Bearer realm="5g-dpop"`)
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	c.Next()
+}
+
+// proxyDebugDumpToAgent proxies the agent's internal state dump, which may
+// take longer than the other proxied requests
+func (s *Server) proxyDebugDumpToAgent(c *gin.Context) {
+	extendDeadlines(c.Writer, debugDumpTimeout)
+	s.proxyToAgentWithLimits(c, jsonbody.MaxBytes, debugDumpTimeout)
+}
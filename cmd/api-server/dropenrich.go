@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// dropUEIPLocked returns the UE IP to fill into a drop event that lacks
+// one, from the session table: the agent only knows it if PFCP correlation
+// had the TEID when the packet was dropped. Empty if the event has a UE IP,
+// no TEID or a TEID of no known session. statsMu must be held for writing.
+func (s *Server) dropUEIPLocked(event model.DropEvent) string {
+	if event.UEIP != "" || event.TEID == model.FormatTEID(0) {
+		return ""
+	}
+	if s.teidUEIPs == nil {
+		s.teidUEIPs = make(map[string]string)
+		for _, session := range s.sessions {
+			if session.UEIP == "" {
+				continue
+			}
+			for _, teid := range session.TEIDs {
+				s.teidUEIPs[teid] = session.UEIP
+			}
+		}
+	}
+	return s.teidUEIPs[event.TEID]
+}
+
+// enrichRecentDropsLocked fills in the UE IP of the recent drops whose
+// session has become known. The slice is replaced rather than changed in
+// place, as readers use it after releasing statsMu.
+func (s *Server) enrichRecentDropsLocked() {
+	var drops []model.DropEvent
+	for i, event := range s.drops.RecentDrops {
+		ueIP := s.dropUEIPLocked(event)
+		if ueIP == "" {
+			continue
+		}
+		if drops == nil {
+			drops = slices.Clone(s.drops.RecentDrops)
+		}
+		drops[i].UEIP = ueIP
+	}
+	if drops != nil {
+		s.drops.RecentDrops = drops
+	}
+}
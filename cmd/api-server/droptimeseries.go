@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Drop time series: one slot per second, kept for dropSeriesRetention
+const (
+	dropSeriesRetention = time.Hour
+
+	defaultDropSeriesWindow = 5 * time.Minute
+	defaultDropSeriesStep   = 10 * time.Second
+)
+
+// DropRatePoint is the number of drops in the step starting at Timestamp
+type DropRatePoint struct {
+	Timestamp string `json:"timestamp"`
+	Count     uint64 `json:"count"`
+}
+
+// DropTimeseriesResponse is a drop count series for charting
+type DropTimeseriesResponse struct {
+	Window string          `json:"window"`
+	Step   string          `json:"step"`
+	Reason string          `json:"reason,omitempty"` // all reasons if empty
+	Points []DropRatePoint `json:"points"`           // oldest first, the last one is in progress
+}
+
+// dropSeries is a ring of per-second, per-reason drop counts covering the
+// last dropSeriesRetention
+type dropSeries struct {
+	mu    sync.Mutex
+	slots []dropSeriesSlot
+}
+
+type dropSeriesSlot struct {
+	second   int64 // Unix second the slot holds, stale if not the one asked for
+	byReason map[string]uint64
+}
+
+func newDropSeries() *dropSeries {
+	return &dropSeries{slots: make([]dropSeriesSlot, int(dropSeriesRetention/time.Second))}
+}
+
+// add records n drops for reason at t
+func (d *dropSeries) add(reason string, n uint64, t time.Time) {
+	second := t.Unix()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	slot := &d.slots[second%int64(len(d.slots))]
+	if slot.second != second || slot.byReason == nil {
+		slot.second = second
+		slot.byReason = make(map[string]uint64)
+	}
+	slot.byReason[reason] += n
+}
+
+// count returns the drops for reason (all if empty) recorded in second
+func (d *dropSeries) count(second int64, reason string) uint64 {
+	slot := &d.slots[second%int64(len(d.slots))]
+	if slot.second != second {
+		return 0
+	}
+	if reason != "" {
+		return slot.byReason[reason]
+	}
+	var total uint64
+	for _, n := range slot.byReason {
+		total += n
+	}
+	return total
+}
+
+// query sums the per-second counts into step buckets covering the window
+// up to now. Buckets are aligned to multiples of step since the Unix epoch,
+// so repeated queries return the same buckets; when step does not divide
+// window, the first bucket starts before now-window so that it is complete.
+// The last bucket ends at now and is still filling.
+func (d *dropSeries) query(window, step time.Duration, reason string, now time.Time) []DropRatePoint {
+	stepSec := int64(step / time.Second)
+	end := now.Unix()
+	start := end - int64(window/time.Second) + 1
+	start -= ((start % stepSec) + stepSec) % stepSec
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	points := make([]DropRatePoint, 0, (end-start)/stepSec+1)
+	for bucket := start; bucket <= end; bucket += stepSec {
+		var count uint64
+		for second := bucket; second < bucket+stepSec && second <= end; second++ {
+			count += d.count(second, reason)
+		}
+		points = append(points, DropRatePoint{
+			Timestamp: time.Unix(bucket, 0).UTC().Format(time.RFC3339),
+			Count:     count,
+		})
+	}
+	return points
+}
+
+// recordDrops adds the growth of the agent's per-reason drop totals since
+// the previous collection to the series. Totals going backwards (agent
+// restart) start a new baseline.
+func (d *dropSeries) recordDrops(prev, current map[string]uint64, t time.Time) {
+	if prev == nil {
+		return
+	}
+	for reason, total := range current {
+		if last := prev[reason]; total > last {
+			d.add(reason, total-last, t)
+		}
+	}
+}
+
+// parseDropSeriesParams validates window and step: whole seconds, step at
+// most window, window at most the retention
+func parseDropSeriesParams(windowParam, stepParam string) (time.Duration, time.Duration, error) {
+	window, step := defaultDropSeriesWindow, defaultDropSeriesStep
+	var err error
+	if windowParam != "" {
+		if window, err = time.ParseDuration(windowParam); err != nil {
+			return 0, 0, fmt.Errorf("invalid window: %v", err)
+		}
+	}
+	if stepParam != "" {
+		if step, err = time.ParseDuration(stepParam); err != nil {
+			return 0, 0, fmt.Errorf("invalid step: %v", err)
+		}
+	}
+	switch {
+	case window < time.Second || window%time.Second != 0:
+		return 0, 0, fmt.Errorf("window must be a whole number of seconds")
+	case window > dropSeriesRetention:
+		return 0, 0, fmt.Errorf("window must be at most %s", dropSeriesRetention)
+	case step < time.Second || step%time.Second != 0:
+		return 0, 0, fmt.Errorf("step must be a whole number of seconds")
+	case step > window:
+		return 0, 0, fmt.Errorf("step must not exceed window")
+	}
+	return window, step, nil
+}
+
+// handleDropTimeseries serves drop counts per step over a window, e.g. for
+// a drop rate sparkline
+func (s *Server) handleDropTimeseries(c *gin.Context) {
+	window, step, err := parseDropSeriesParams(c.Query("window"), c.Query("step"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	reason := c.Query("reason")
+
+	c.JSON(http.StatusOK, DropTimeseriesResponse{
+		Window: window.String(),
+		Step:   step.String(),
+		Reason: reason,
+		Points: s.dropSeries.query(window, step, reason, time.Now()),
+	})
+}
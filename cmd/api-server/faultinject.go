@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/jsonbody"
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// Defaults of the -fault-breaker-* flags
+const (
+	defaultFaultBreakerThreshold = 5
+	defaultFaultBreakerCooldown  = 30 * time.Second
+)
+
+// maxFaultPackets caps the Count of one injection request
+const maxFaultPackets = 1000
+
+// gtpuPort is the GTP-U UDP port (TS 29.281)
+const gtpuPort = 2152
+
+// Inner addresses of injected packets, from TEST-NET-1/2 so that no PDR
+// of a real UE matches the source
+var (
+	faultSpoofedSource = net.IPv4(192, 0, 2, 1).To4()
+	faultDestination   = net.IPv4(198, 51, 100, 1).To4()
+)
+
+// FaultStatus is the state of fault injection
+type FaultStatus struct {
+	Enabled bool          `json:"enabled"`
+	UPFAddr string        `json:"upf_addr,omitempty"`
+	Breaker BreakerStatus `json:"breaker"`
+}
+
+// faultInjector sends crafted GTP-U packets to the UPF's N3 address over a
+// raw socket, behind a circuit breaker: an interface that is down or a
+// missing CAP_NET_RAW fails every send, and retrying (and logging) on each
+// request helps nobody
+type faultInjector struct {
+	upf     net.IP
+	breaker *circuitBreaker
+
+	mu   sync.Mutex
+	conn net.PacketConn // opened on first use, reopened after a failure
+}
+
+func newFaultInjector(upf net.IP, threshold int, cooldown time.Duration) *faultInjector {
+	return &faultInjector{
+		upf:     upf,
+		breaker: newCircuitBreaker(threshold, cooldown),
+	}
+}
+
+// SetFaultInjection sends injected faults to the UPF N3 address upf, with
+// the injection breaker opening after threshold consecutive failures for
+// cooldown. Without it, /fault/inject answers 503.
+func (s *Server) SetFaultInjection(upf net.IP, threshold int, cooldown time.Duration) {
+	s.faults = newFaultInjector(upf, threshold, cooldown)
+}
+
+// inject sends count G-PDUs with teid and an inner packet from src, unless
+// the breaker is open. errBreakerOpen wraps the reason in that case.
+func (f *faultInjector) inject(teid uint32, src net.IP, count int) error {
+	if err := f.breaker.allow(time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", errBreakerOpen, err)
+	}
+
+	err := f.send(gtpuPacket(teid, src, faultDestination), count)
+
+	from, to := f.breaker.record(err, time.Now())
+	switch {
+	case to == breakerOpen && from != breakerOpen:
+		log.Printf("[WARN] Fault injection disabled for %s after %d consecutive failures: %v",
+			f.breaker.cooldown, f.breaker.status().ConsecutiveFailures, err)
+	case to == breakerClosed && from != breakerClosed:
+		log.Println("[OK] Fault injection working again")
+	}
+	return err
+}
+
+// errBreakerOpen is returned by inject while the breaker is open
+var errBreakerOpen = errors.New("fault injection circuit open")
+
+// send writes packet count times to the UPF
+func (f *faultInjector) send(packet []byte, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.conn == nil {
+		// The kernel adds the IPv4 header, the packet starts at UDP
+		conn, err := net.ListenPacket("ip4:udp", "0.0.0.0")
+		if err != nil {
+			return fmt.Errorf("open raw socket: %w", err)
+		}
+		f.conn = conn
+	}
+	dst := &net.IPAddr{IP: f.upf}
+	for i := 0; i < count; i++ {
+		if _, err := f.conn.WriteTo(packet, dst); err != nil {
+			f.conn.Close()
+			f.conn = nil
+			return fmt.Errorf("send to %s: %w", f.upf, err)
+		}
+	}
+	return nil
+}
+
+// gtpuPacket builds a UDP datagram (without IP header) carrying a G-PDU
+// with teid, whose payload is a small UDP/IPv4 packet from src to dst
+func gtpuPacket(teid uint32, src, dst net.IP) []byte {
+	payload := []byte("5G-DPOP fault injection")
+
+	inner := make([]byte, 28, 28+len(payload))
+	inner[0] = 0x45 // IPv4, 20 byte header
+	binary.BigEndian.PutUint16(inner[2:4], uint16(28+len(payload)))
+	inner[8] = 64 // TTL
+	inner[9] = 17 // UDP
+	copy(inner[12:16], src.To4())
+	copy(inner[16:20], dst.To4())
+	binary.BigEndian.PutUint16(inner[10:12], ipv4Checksum(inner[:20]))
+	binary.BigEndian.PutUint16(inner[20:22], 9)
+	binary.BigEndian.PutUint16(inner[22:24], 9) // discard
+	binary.BigEndian.PutUint16(inner[24:26], uint16(8+len(payload)))
+	inner = append(inner, payload...)
+
+	// UDP header (checksum 0: none, allowed over IPv4) and GTP-U header:
+	// version 1, PT=1, no optional fields, message type 255 (G-PDU)
+	b := make([]byte, 16, 16+len(inner))
+	binary.BigEndian.PutUint16(b[0:2], gtpuPort)
+	binary.BigEndian.PutUint16(b[2:4], gtpuPort)
+	binary.BigEndian.PutUint16(b[4:6], uint16(16+len(inner)))
+	b[8] = 0x30
+	b[9] = 0xff
+	binary.BigEndian.PutUint16(b[10:12], uint16(len(inner)))
+	binary.BigEndian.PutUint32(b[12:16], teid)
+	return append(b, inner...)
+}
+
+// ipv4Checksum computes the IPv4 header checksum of hdr
+func ipv4Checksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// faultTarget resolves the TEID and inner source address of a request:
+// invalid_teid sends to the TEID in Target, which the UPF should not know;
+// no_pdr uses the uplink TEID of the session of UE IP Target with a source
+// address that matches none of its PDRs
+func (s *Server) faultTarget(req FaultInjectRequest) (uint32, net.IP, int, error) {
+	switch req.Type {
+	case "invalid_teid":
+		teid, err := model.ParseTEID(req.Target)
+		if err != nil {
+			return 0, nil, http.StatusBadRequest, fmt.Errorf("invalid_teid needs a TEID (0x hex or decimal) as target")
+		}
+		return teid, faultSpoofedSource, 0, nil
+	case "no_pdr":
+		ip := net.ParseIP(strings.TrimSpace(req.Target))
+		if ip == nil || ip.To4() == nil {
+			return 0, nil, http.StatusBadRequest, fmt.Errorf("no_pdr needs a UE IPv4 address as target")
+		}
+		s.statsMu.RLock()
+		defer s.statsMu.RUnlock()
+		for _, session := range s.sessions {
+			if session.UEIP != ip.String() || session.TEIDUL == "" {
+				continue
+			}
+			teid, err := model.ParseTEID(session.TEIDUL)
+			if err != nil {
+				break
+			}
+			return teid, faultSpoofedSource, 0, nil
+		}
+		return 0, nil, http.StatusNotFound, fmt.Errorf("no session with UE IP %s and an uplink TEID", ip)
+	}
+	return 0, nil, http.StatusBadRequest, fmt.Errorf("unknown fault type %q, must be invalid_teid or no_pdr", req.Type)
+}
+
+// Fault injection
+func (s *Server) handleFaultInject(c *gin.Context) {
+	var req FaultInjectRequest
+	if err := jsonbody.Decode(c.Writer, c.Request, &req, jsonbody.MaxBytes); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 1
+	}
+	if req.Count > maxFaultPackets {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("count must be at most %d", maxFaultPackets))
+		return
+	}
+	if s.faults == nil {
+		respondError(c, http.StatusServiceUnavailable, ErrCodeDisabled, "Fault injection disabled, start the API server with -fault-upf-addr")
+		return
+	}
+
+	teid, src, status, err := s.faultTarget(req)
+	if err != nil {
+		code := ErrCodeInvalidRequest
+		if status == http.StatusNotFound {
+			code = ErrCodeSessionNotFound
+		}
+		respondError(c, status, code, err.Error())
+		return
+	}
+
+	// Failures are logged by inject when they open the breaker only
+	if err := s.faults.inject(teid, src, req.Count); err != nil {
+		if errors.Is(err, errBreakerOpen) {
+			respondError(c, http.StatusServiceUnavailable, ErrCodeUnavailable, err.Error())
+			return
+		}
+		respondError(c, http.StatusBadGateway, ErrCodeUpstreamError, err.Error())
+		return
+	}
+	log.Printf("[FAULT] Injected: type=%s, target=%s, teid=0x%x, count=%d",
+		req.Type, req.Target, teid, req.Count)
+
+	c.JSON(http.StatusOK, FaultInjectResponse{
+		Status: "injected",
+		Type:   req.Type,
+		Target: req.Target,
+		Sent:   req.Count,
+	})
+}
+
+// handleFaultStatus serves whether fault injection is enabled and the
+// state of its circuit breaker
+func (s *Server) handleFaultStatus(c *gin.Context) {
+	if s.faults == nil {
+		c.JSON(http.StatusOK, FaultStatus{Breaker: BreakerStatus{State: breakerClosed.String()}})
+		return
+	}
+	c.JSON(http.StatusOK, FaultStatus{
+		Enabled: true,
+		UPFAddr: s.faults.upf.String(),
+		Breaker: s.faults.breaker.status(),
+	})
+}
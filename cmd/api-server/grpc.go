@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+	"github.com/solar224/5G-DPOP/internal/model"
+	dpopv1 "github.com/solar224/5G-DPOP/proto/dpop/v1"
+)
+
+// grpcService implements dpopv1.ObservabilityServiceServer on top of the
+// same state and event bus used by the REST and WebSocket handlers
+type grpcService struct {
+	dpopv1.UnimplementedObservabilityServiceServer
+	s *Server
+}
+
+// RunGRPC serves the gRPC API on addr until the listener fails
+func (s *Server) RunGRPC(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer()
+	dpopv1.RegisterObservabilityServiceServer(srv, &grpcService{s: s})
+	return srv.Serve(lis)
+}
+
+// StreamDrops sends new drop events until the client goes away
+func (g *grpcService) StreamDrops(_ *dpopv1.StreamDropsRequest, stream dpopv1.ObservabilityService_StreamDropsServer) error {
+	return streamTopic(stream.Context(), g.s.bus, events.TopicDrops, 1024, func(payload interface{}) error {
+		drop, ok := payload.(model.DropEvent)
+		if !ok {
+			return nil
+		}
+		return stream.Send(dropEventToProto(drop))
+	})
+}
+
+// StreamMetrics sends a traffic snapshot after every agent collection
+func (g *grpcService) StreamMetrics(_ *dpopv1.StreamMetricsRequest, stream dpopv1.ObservabilityService_StreamMetricsServer) error {
+	return streamTopic(stream.Context(), g.s.bus, events.TopicTraffic, 1, func(payload interface{}) error {
+		stats, ok := payload.(model.TrafficStats)
+		if !ok {
+			return nil
+		}
+		return stream.Send(trafficStatsToProto(stats))
+	})
+}
+
+// StreamSessions sends the session list after every agent collection
+func (g *grpcService) StreamSessions(_ *dpopv1.StreamSessionsRequest, stream dpopv1.ObservabilityService_StreamSessionsServer) error {
+	return streamTopic(stream.Context(), g.s.bus, events.TopicSessions, 1, func(payload interface{}) error {
+		sessions, ok := payload.([]model.SessionInfo)
+		if !ok {
+			return nil
+		}
+		return stream.Send(sessionListToProto(sessions))
+	})
+}
+
+// GetSessions returns the current session list
+func (g *grpcService) GetSessions(context.Context, *dpopv1.GetSessionsRequest) (*dpopv1.SessionList, error) {
+	g.s.statsMu.RLock()
+	defer g.s.statsMu.RUnlock()
+
+	return sessionListToProto(g.s.sessions), nil
+}
+
+// GetSession returns one session by SEID
+func (g *grpcService) GetSession(_ context.Context, req *dpopv1.GetSessionRequest) (*dpopv1.SessionInfo, error) {
+	parsed, err := model.ParseSEID(req.GetSeid())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	seid := model.FormatSEID(parsed)
+
+	g.s.statsMu.RLock()
+	defer g.s.statsMu.RUnlock()
+
+	for _, session := range g.s.sessions {
+		if session.SEID == seid {
+			return sessionToProto(session), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "session %s not found", req.GetSeid())
+}
+
+// streamTopic subscribes to topic and calls send for each event until ctx
+// is done or send fails
+func streamTopic(ctx context.Context, bus *events.Bus, topic events.Topic, buffer int, send func(interface{}) error) error {
+	sub := bus.Subscribe(topic, buffer)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err := send(event.Payload); err != nil {
+				log.Printf("[DEBUG] gRPC %s stream closed: %v", topic, err)
+				return err
+			}
+		}
+	}
+}
+
+func dropEventToProto(d model.DropEvent) *dpopv1.DropEvent {
+	return &dpopv1.DropEvent{
+		Timestamp: d.Timestamp,
+		Teid:      d.TEID,
+		SrcIp:     d.SrcIP,
+		DstIp:     d.DstIP,
+		SrcPort:   uint32(d.SrcPort),
+		DstPort:   uint32(d.DstPort),
+		Reason:    d.Reason,
+		Direction: d.Direction,
+		PktLen:    d.PktLen,
+	}
+}
+
+func directionStatsToProto(d model.DirectionStats) *dpopv1.DirectionStats {
+	return &dpopv1.DirectionStats{
+		Packets:                d.Packets,
+		Bytes:                  d.Bytes,
+		ThroughputMbps:         d.Throughput,
+		LastUpdated:            d.LastUpdated,
+		ThroughputMbpsSmoothed: d.Smoothed,
+	}
+}
+
+func trafficStatsToProto(t model.TrafficStats) *dpopv1.TrafficStats {
+	return &dpopv1.TrafficStats{
+		Uplink:   directionStatsToProto(t.Uplink),
+		Downlink: directionStatsToProto(t.Downlink),
+	}
+}
+
+func sessionToProto(s model.SessionInfo) *dpopv1.SessionInfo {
+	flows := make([]*dpopv1.FlowTraffic, 0, len(s.FlowTraffic))
+	for _, f := range s.FlowTraffic {
+		flows = append(flows, &dpopv1.FlowTraffic{
+			DestIp:     f.DestIP,
+			Packets:    f.Packets,
+			Bytes:      f.Bytes,
+			LastActive: f.LastActive,
+			OuterDst:   f.OuterDst,
+		})
+	}
+
+	return &dpopv1.SessionInfo{
+		Seid:         s.SEID,
+		UeIp:         s.UEIP,
+		Teids:        s.TEIDs,
+		CreatedAt:    s.CreatedAt,
+		PacketsUl:    s.PacketsUL,
+		PacketsDl:    s.PacketsDL,
+		UpfIp:        s.UPFIP,
+		GnbIp:        s.GNBIP,
+		UplinkPeerIp: s.UplinkPeerIP,
+		N9PeerIp:     s.N9PeerIP,
+		Supi:         s.SUPI,
+		Dnn:          s.DNN,
+		SNssai:       s.SNssai,
+		Qfi:          uint32(s.QFI),
+		SessionType:  s.SessionType,
+		PduSessionId: uint32(s.SessionID),
+		BytesUl:      s.BytesUL,
+		BytesDl:      s.BytesDL,
+		FlowTraffic:  flows,
+		Qos_5Qi:      uint32(s.QoS5QI),
+		ArpPriority:  uint32(s.ARPPL),
+		GbrUlKbps:    s.GBRUplink,
+		GbrDlKbps:    s.GBRDownlink,
+		MbrUlKbps:    s.MBRUplink,
+		MbrDlKbps:    s.MBRDownlink,
+		Status:       s.Status,
+		Duration:     s.Duration,
+		LastActive:   s.LastActive,
+
+		IncompleteEstablishment: s.IncompleteEstablishment,
+		SmfIp:                   s.SMFIP,
+		UeIps:                   s.UEIPs,
+		SdfFilters:              s.SDFFilters,
+		AppIds:                  s.AppIDs,
+	}
+}
+
+func sessionListToProto(sessions []model.SessionInfo) *dpopv1.SessionList {
+	list := &dpopv1.SessionList{
+		Total:    uint32(len(sessions)),
+		Sessions: make([]*dpopv1.SessionInfo, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		list.Sessions = append(list.Sessions, sessionToProto(session))
+	}
+	return list
+}
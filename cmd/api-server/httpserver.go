@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Defaults for the -http-* flags. They bound how long a client may take to
+// send a request or read a response, so slow or idle connections cannot
+// pile up, while leaving room for large exports. WebSocket connections are
+// exempt once upgraded (see clearDeadlines).
+const (
+	defaultHTTPReadHeaderTimeout = 10 * time.Second
+	defaultHTTPReadTimeout       = 30 * time.Second
+	defaultHTTPWriteTimeout      = 60 * time.Second
+	defaultHTTPIdleTimeout       = 120 * time.Second
+	defaultHTTPMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+	defaultHTTP2MaxStreams       = 250
+)
+
+// HTTPConfig are the http.Server settings of the REST/WebSocket listener.
+// Zero timeouts disable the respective limit, as in http.Server.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// Serve HTTP/2 without TLS (h2c), e.g. behind a proxy that speaks
+	// HTTP/2 to its backends, with at most H2MaxStreams concurrent streams
+	// per connection
+	H2C          bool
+	H2MaxStreams uint32
+}
+
+// DefaultHTTPConfig returns the defaults of the -http-* flags
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		ReadHeaderTimeout: defaultHTTPReadHeaderTimeout,
+		ReadTimeout:       defaultHTTPReadTimeout,
+		WriteTimeout:      defaultHTTPWriteTimeout,
+		IdleTimeout:       defaultHTTPIdleTimeout,
+		MaxHeaderBytes:    defaultHTTPMaxHeaderBytes,
+		H2MaxStreams:      defaultHTTP2MaxStreams,
+	}
+}
+
+// SetHTTPConfig sets the http.Server settings used by Run
+func (s *Server) SetHTTPConfig(cfg HTTPConfig) {
+	s.httpConfig = cfg
+}
+
+// httpServer builds the http.Server for addr from the configuration
+func (s *Server) httpServer(addr string) *http.Server {
+	cfg := s.httpConfig
+	var handler http.Handler = s.router
+	if cfg.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{
+			MaxConcurrentStreams: cfg.H2MaxStreams,
+			IdleTimeout:          cfg.IdleTimeout,
+		})
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// clearDeadlines lifts the read and write deadlines the http.Server set for
+// the current request, for handlers that keep the connection open longer
+// than -http-read-timeout/-http-write-timeout (WebSocket upgrades)
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	// ErrNotSupported only for writers that have no deadlines to clear
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// extendDeadlines gives the current request d from now to be read and
+// answered, for handlers slower than -http-read-timeout/-http-write-timeout
+// allow (pcap replays)
+func extendDeadlines(w http.ResponseWriter, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/jsonbody"
+	"github.com/solar224/5G-DPOP/internal/loglevel"
+)
+
+// LogLevel is the body of PUT /log-level and the response of both methods
+type LogLevel struct {
+	Level    string `json:"level"`              // debug, info, warn or error
+	Previous string `json:"previous,omitempty"` // Level before a PUT
+}
+
+// handleGetLogLevel returns the API server's log level
+func (s *Server) handleGetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: loglevel.Name(loglevel.Level())})
+}
+
+// handleSetLogLevel changes the API server's log level. The agent has its
+// own, changed with PUT /api/log-level on the agent.
+func (s *Server) handleSetLogLevel(c *gin.Context) {
+	var req LogLevel
+	if err := jsonbody.Decode(c.Writer, c.Request, &req, jsonbody.MaxBytes); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+	level, err := loglevel.Parse(req.Level)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	previous := loglevel.Level()
+	loglevel.Set(level)
+	log.Printf("[CONFIG] Log level set to %s (was %s)", loglevel.Name(level), loglevel.Name(previous))
+	c.JSON(http.StatusOK, LogLevel{Level: loglevel.Name(level), Previous: loglevel.Name(previous)})
+}
@@ -2,18 +2,37 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof/ on http.DefaultServeMux, mounted by -pprof
+	"os"
+	"os/signal"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/solar224/5G-DPOP/internal/adminauth"
+	"github.com/solar224/5G-DPOP/internal/configfile"
+	"github.com/solar224/5G-DPOP/internal/events"
+	"github.com/solar224/5G-DPOP/internal/httpcache"
+	"github.com/solar224/5G-DPOP/internal/jsonbody"
+	"github.com/solar224/5G-DPOP/internal/loglevel"
+	"github.com/solar224/5G-DPOP/internal/model"
 )
 
 const (
@@ -23,90 +42,70 @@ const (
 	agentSessionsURL = "http://localhost:9100/api/sessions"
 )
 
-// TrafficStats represents traffic statistics
-type TrafficStats struct {
-	Uplink   DirectionStats `json:"uplink"`
-	Downlink DirectionStats `json:"downlink"`
-}
-
-// DirectionStats represents stats for a single direction
-type DirectionStats struct {
-	Packets     uint64  `json:"packets"`
-	Bytes       uint64  `json:"bytes"`
-	Throughput  float64 `json:"throughput_mbps"`
-	LastUpdated string  `json:"last_updated"`
-}
-
 // DropStats represents drop statistics
 type DropStats struct {
 	Total       uint64            `json:"total"`
 	Rate        float64           `json:"rate_percent"`
-	RecentDrops []DropEvent       `json:"recent_drops"`
+	RecentDrops []model.DropEvent `json:"recent_drops"`
 	ByReason    map[string]uint64 `json:"by_reason"`
 }
 
-// DropEvent represents a single drop event
-type DropEvent struct {
-	Timestamp string `json:"timestamp"`
-	TEID      string `json:"teid"`
-	SrcIP     string `json:"src_ip"`
-	DstIP     string `json:"dst_ip"`
-	SrcPort   uint16 `json:"src_port"`
-	DstPort   uint16 `json:"dst_port"`
-	Reason    string `json:"reason"`
-	Direction string `json:"direction"`
-	PktLen    uint32 `json:"pkt_len"`
+// ProbeStatus is the response of the liveness and readiness probes
+type ProbeStatus struct {
+	Status          string            `json:"status"`
+	Timestamp       string            `json:"timestamp"`
+	Version         string            `json:"version"`
+	Checks          map[string]string `json:"checks"`
+	StartedAt       string            `json:"started_at"`
+	CountersResetAt string            `json:"counters_reset_at"`
+}
+
+// SessionList is a list of sessions with its length
+type SessionList struct {
+	Total    int                 `json:"total"`
+	Sessions []model.SessionInfo `json:"sessions"`
+}
+
+// SessionBatchRequest selects sessions by SEID and/or TEID
+type SessionBatchRequest struct {
+	SEIDs []string `json:"seids"`
+	TEIDs []string `json:"teids"`
+}
+
+// SessionBatchResponse holds the sessions found by a batch query and the
+// IDs that matched none
+type SessionBatchResponse struct {
+	SessionList
+	NotFound []string `json:"not_found"`
 }
 
-// FlowTraffic represents per-destination traffic for ULCL path differentiation
-type FlowTraffic struct {
-	DestIP     string `json:"dest_ip"`
-	Packets    uint64 `json:"packets"`
-	Bytes      uint64 `json:"bytes"`
-	LastActive string `json:"last_active,omitempty"`
-	OuterDst   string `json:"outer_dst,omitempty"` // Next hop UPF or gateway
+// SessionSearchResponse holds the sessions matching a search query
+type SessionSearchResponse struct {
+	Query   string                `json:"query"`
+	Total   int                   `json:"total"`
+	Results []SessionSearchResult `json:"results"`
 }
 
-// SessionInfo represents a PDU session (extended)
-type SessionInfo struct {
-	SEID      string   `json:"seid"`
-	UEIP      string   `json:"ue_ip"`
-	TEIDs     []string `json:"teids"`
-	CreatedAt string   `json:"created_at"`
-	PacketsUL uint64   `json:"packets_ul"`
-	PacketsDL uint64   `json:"packets_dl"`
-
-	// Extended fields
-	UPFIP        string `json:"upf_ip,omitempty"`
-	GNBIP        string `json:"gnb_ip,omitempty"`
-	UplinkPeerIP string `json:"uplink_peer_ip,omitempty"`
-	N9PeerIP     string `json:"n9_peer_ip,omitempty"` // N9 peer UPF IP (for ULCL)
-	SUPI         string `json:"supi,omitempty"`
-	DNN          string `json:"dnn,omitempty"`
-	SNssai       string `json:"s_nssai,omitempty"`
-	QFI          uint8  `json:"qfi,omitempty"`
-	SessionType  string `json:"session_type,omitempty"`
-	SessionID    uint8  `json:"pdu_session_id,omitempty"`
-
-	// Traffic statistics
-	BytesUL uint64 `json:"bytes_ul"`
-	BytesDL uint64 `json:"bytes_dl"`
-
-	// Per-flow traffic (for ULCL path differentiation)
-	FlowTraffic []FlowTraffic `json:"flow_traffic,omitempty"`
-
-	// QoS parameters
-	QoS5QI      uint8  `json:"qos_5qi,omitempty"`
-	ARPPL       uint8  `json:"arp_priority,omitempty"`
-	GBRUplink   uint64 `json:"gbr_ul_kbps,omitempty"`
-	GBRDownlink uint64 `json:"gbr_dl_kbps,omitempty"`
-	MBRUplink   uint64 `json:"mbr_ul_kbps,omitempty"`
-	MBRDownlink uint64 `json:"mbr_dl_kbps,omitempty"`
-
-	// Status
-	Status     string `json:"status"`
-	Duration   string `json:"duration,omitempty"`
-	LastActive string `json:"last_active,omitempty"`
+// SessionSearchResult is a session matching a search query and the fields
+// it matched on: "seid", "teid" and/or "ue_ip"
+type SessionSearchResult struct {
+	MatchedOn []string          `json:"matched_on"`
+	Session   model.SessionInfo `json:"session"`
+}
+
+// FaultInjectRequest describes a fault to inject
+type FaultInjectRequest struct {
+	Type   string `json:"type"`   // "invalid_teid", "no_pdr"
+	Target string `json:"target"` // Target TEID or IP
+	Count  int    `json:"count"`  // Number of packets
+}
+
+// FaultInjectResponse acknowledges a fault injection request
+type FaultInjectResponse struct {
+	Status string `json:"status"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Sent   int    `json:"sent"` // Number of packets sent
 }
 
 // Server represents the API server
@@ -115,21 +114,225 @@ type Server struct {
 	upgrader  websocket.Upgrader
 	clients   map[*websocket.Conn]bool
 	clientsMu sync.Mutex
+
+	// WebSocket limits (see SetWebSocketLimits); pendingClients counts
+	// upgrades in progress, so they cannot overshoot maxClients
+	maxClients     int
+	writeTimeout   time.Duration
+	pendingClients int
+
 	broadcast chan interface{}
 
+	// Event bus fed by the agent collector, consumed by the WebSocket broadcaster
+	bus *events.Bus
+
 	// In-memory stats (will be replaced with Prometheus queries)
-	stats    TrafficStats
+	stats    model.TrafficStats
 	drops    DropStats
-	sessions []SessionInfo
+	sessions []model.SessionInfo
+	pipeline PipelineHealth
 	statsMu  sync.RWMutex
+
+	// Agent ETag of sessions, sent back as If-None-Match and served as
+	// the ETag of /sessions
+	sessionsETag string
+
+	// UE IP by TEID of sessions, built when first needed after sessions
+	// change (see dropUEIPLocked)
+	teidUEIPs map[string]string
+
+	// Time of the last successful metrics fetch from the agent (for readiness)
+	lastAgentFetch time.Time
+
+	// Server start time, and when the agent counters last went back to
+	// zero (agent restart) as seen by the collector
+	startedAt       time.Time
+	countersResetAt time.Time
+
+	// How often WebSocket clients receive updates, in nanoseconds (see
+	// SetBroadcastInterval)
+	broadcastInterval atomic.Int64
+
+	// Origins allowed by CORS, nil for any (see SetCORSOrigins)
+	corsMu      sync.RWMutex
+	corsOrigins []string
+
+	// Scrapes the agents given with -agents, nil without
+	cluster *clusterCollector
+
+	// Sends injected faults to -fault-upf-addr, nil without
+	faults *faultInjector
+
+	// Bearer token of the admin endpoints, empty disables them (see
+	// SetAdminToken)
+	adminToken string
+
+	// API key required for WebSocket tickets, empty leaves the WebSocket
+	// endpoints open (see SetAPIKey)
+	apiKey    string
+	wsTickets *wsTickets
+
+	// Smoothed throughput, only touched by collectMetricsFromAgent
+	uplinkSmoother   throughputSmoother
+	downlinkSmoother throughputSmoother
+
+	// Capture pipeline loss, only touched by collectMetricsFromAgent
+	pipelineTracker pipelineTracker
+
+	// Per-second drop counts for /metrics/drops/timeseries
+	dropSeries *dropSeries
+
+	// http.Server settings of Run (see SetHTTPConfig)
+	httpConfig HTTPConfig
+
+	// Stops the broadcaster and the agent collector (see Stop)
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
 }
 
+// Bounds for -broadcast-interval
+const (
+	minBroadcastInterval = 250 * time.Millisecond
+	maxBroadcastInterval = 10 * time.Second
+)
+
 func main() {
+	broadcastInterval := flag.Duration("broadcast-interval", time.Second, "Interval between WebSocket updates (250ms-10s)")
+	throughputWindow := flag.Duration("throughput-smoothing", defaultThroughputWindow, "Time constant of the smoothed throughput (throughput_mbps_smoothed) sent next to the per-sample value (0 disables)")
+	wsMaxClients := flag.Int("ws-max-clients", defaultMaxWSClients, "Maximum concurrent WebSocket clients, further upgrades get 503 (0 = unlimited)")
+	wsWriteTimeout := flag.Duration("ws-write-timeout", defaultWSWriteTimeout, "Disconnect WebSocket clients whose update write takes longer than this")
+	pprofEnabled := flag.Bool("pprof", false, "Serve net/http/pprof profiles under /debug/pprof/ on :8080")
+	agents := flag.String("agents", "", "Comma-separated agents (host:port) of a multi-node UPF cluster to aggregate under /api/v1/cluster/")
+	agentsPollInterval := flag.Duration("agents-poll-interval", defaultClusterPollInterval, "Interval between scrapes of the -agents")
+	faultUPFAddr := flag.String("fault-upf-addr", "", "UPF N3 IPv4 address /fault/inject sends GTP-U to over a raw socket, needs CAP_NET_RAW (disabled if empty)")
+	faultBreakerThreshold := flag.Int("fault-breaker-threshold", defaultFaultBreakerThreshold, "Consecutive failed injections after which /fault/inject answers 503 for -fault-breaker-cooldown (0 = never)")
+	faultBreakerCooldown := flag.Duration("fault-breaker-cooldown", defaultFaultBreakerCooldown, "How long /fault/inject stays disabled before a trial injection")
+	httpReadHeaderTimeout := flag.Duration("http-read-header-timeout", defaultHTTPReadHeaderTimeout, "Maximum time to read request headers (0 = no limit)")
+	httpReadTimeout := flag.Duration("http-read-timeout", defaultHTTPReadTimeout, "Maximum time to read a whole request (0 = no limit)")
+	httpWriteTimeout := flag.Duration("http-write-timeout", defaultHTTPWriteTimeout, "Maximum time to write a response (0 = no limit); WebSocket connections are exempt")
+	httpIdleTimeout := flag.Duration("http-idle-timeout", defaultHTTPIdleTimeout, "Close keep-alive connections idle for this long (0 = use -http-read-timeout)")
+	httpMaxHeaderBytes := flag.Int("http-max-header-bytes", defaultHTTPMaxHeaderBytes, "Maximum size of request headers")
+	httpH2C := flag.Bool("http-h2c", false, "Also serve HTTP/2 without TLS (h2c), e.g. for a proxy speaking HTTP/2 to its backends")
+	http2MaxStreams := flag.Uint("http2-max-concurrent-streams", defaultHTTP2MaxStreams, "Maximum concurrent HTTP/2 streams per connection with -http-h2c")
+	logLevel := flag.String("log-level", "info", "Minimum level of the log lines printed: debug, info, warn or error (changed at runtime with PUT /api/v1/log-level); request logs are info")
+	adminTokenFile := flag.String("admin-token-file", "", "File holding the bearer token required by /api/v1/debug/dump, the same as the agent's (the endpoint is disabled if empty)")
+	apiKeyFile := flag.String("api-key-file", "", "File holding the API key for POST /api/v1/ws-ticket; when set, the WebSocket endpoints require a ticket from it (open if empty)")
+	corsOrigins := flag.String("cors-origins", corsAnyOrigin, "Comma-separated browser origins allowed to call the API and open WebSockets, e.g. https://dashboard.example.com (* allows all)")
+	configPath := flag.String("config", "", "JSON or YAML file of flag values, e.g. {\"log-level\": \"debug\"}; command line flags win, and SIGHUP reloads -log-level, -cors-origins, -broadcast-interval, -ws-max-clients and -ws-write-timeout from it")
+	flag.Parse()
+
+	var config *configfile.File
+	if *configPath != "" {
+		var err error
+		if config, err = configfile.Load(flag.CommandLine, "config"); err != nil {
+			log.Fatalf("Failed to load -config: %v", err)
+		}
+	}
+
+	level, err := loglevel.Parse(*logLevel)
+	if err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	loglevel.Set(level)
+	loglevel.Install(os.Stderr)
+
 	log.Println("============================================================")
 	log.Println("    5G-DPOP: Backend API Server")
 	log.Println("============================================================")
 
-	server := NewServer()
+	if *broadcastInterval < minBroadcastInterval || *broadcastInterval > maxBroadcastInterval {
+		log.Fatalf("-broadcast-interval must be between %v and %v, got %v",
+			minBroadcastInterval, maxBroadcastInterval, *broadcastInterval)
+	}
+
+	if *httpMaxHeaderBytes <= 0 {
+		log.Fatalf("-http-max-header-bytes must be positive, got %d", *httpMaxHeaderBytes)
+	}
+	if *http2MaxStreams == 0 || *http2MaxStreams > math.MaxUint32 {
+		log.Fatalf("-http2-max-concurrent-streams must be between 1 and %d, got %d", uint32(math.MaxUint32), *http2MaxStreams)
+	}
+
+	server := NewServer(*broadcastInterval)
+	server.SetWebSocketLimits(*wsMaxClients, *wsWriteTimeout)
+	server.SetCORSOrigins(parseCORSOrigins(*corsOrigins))
+	server.SetHTTPConfig(HTTPConfig{
+		ReadHeaderTimeout: *httpReadHeaderTimeout,
+		ReadTimeout:       *httpReadTimeout,
+		WriteTimeout:      *httpWriteTimeout,
+		IdleTimeout:       *httpIdleTimeout,
+		MaxHeaderBytes:    *httpMaxHeaderBytes,
+		H2C:               *httpH2C,
+		H2MaxStreams:      uint32(*http2MaxStreams),
+	})
+	server.SetThroughputSmoothing(*throughputWindow)
+	if *agents != "" {
+		nodes, err := parseAgentList(*agents)
+		if err != nil {
+			log.Fatalf("Invalid -agents: %v", err)
+		}
+		if *agentsPollInterval <= 0 {
+			log.Fatalf("-agents-poll-interval must be positive, got %v", *agentsPollInterval)
+		}
+		server.SetClusterAgents(nodes, *agentsPollInterval)
+	}
+	if *faultUPFAddr != "" {
+		upf := net.ParseIP(*faultUPFAddr).To4()
+		if upf == nil {
+			log.Fatalf("Invalid -fault-upf-addr %q: need an IPv4 address", *faultUPFAddr)
+		}
+		server.SetFaultInjection(upf, *faultBreakerThreshold, *faultBreakerCooldown)
+	}
+	if *adminTokenFile != "" {
+		token, err := adminauth.Load(*adminTokenFile)
+		if err != nil {
+			log.Fatalf("Failed to load -admin-token-file: %v", err)
+		}
+		server.SetAdminToken(token)
+	}
+	if *apiKeyFile != "" {
+		key, err := adminauth.Load(*apiKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load -api-key-file: %v", err)
+		}
+		server.SetAPIKey(key)
+	}
+	if *pprofEnabled {
+		// gin does not serve the default mux, so mount the pprof handlers on it
+		server.router.Any("/debug/pprof/*profile", gin.WrapH(http.DefaultServeMux))
+		log.Println("[INFO] pprof enabled on :8080/debug/pprof/")
+	}
+
+	go func() {
+		log.Println("[INFO] Starting gRPC server on :50051")
+		if err := server.RunGRPC(":50051"); err != nil {
+			log.Printf("[WARN] gRPC server error: %v", err)
+		}
+	}()
+
+	// Reload -config on SIGHUP
+	if config != nil {
+		server.registerReloadable(config)
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		go func() {
+			for range hupChan {
+				if err := config.Reload(); err != nil {
+					log.Printf("[WARN] Config reload failed, keeping the current settings: %v", err)
+				}
+			}
+		}()
+	}
+
+	// Stop the collector and close WebSocket clients on SIGINT/SIGTERM
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Println("[INFO] Shutting down...")
+		server.Stop()
+		os.Exit(0)
+	}()
 
 	log.Println("[INFO] Starting API server on :8080")
 	if err := server.Run(":8080"); err != nil {
@@ -137,71 +340,178 @@ func main() {
 	}
 }
 
-// NewServer creates a new API server
-func NewServer() *Server {
+// NewServer creates a new API server that pushes WebSocket updates every
+// broadcastInterval
+func NewServer(broadcastInterval time.Duration) *Server {
 	s := &Server{
-		router: gin.Default(),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
-			},
-		},
+		router:    gin.New(),
 		clients:   make(map[*websocket.Conn]bool),
 		broadcast: make(chan interface{}),
+		bus:       events.NewBus(),
 		drops: DropStats{
-			RecentDrops: make([]DropEvent, 0),
+			RecentDrops: make([]model.DropEvent, 0),
 			ByReason:    make(map[string]uint64),
 		},
-		sessions: make([]SessionInfo, 0),
+		sessions:   make([]model.SessionInfo, 0),
+		dropSeries: newDropSeries(),
+		httpConfig: DefaultHTTPConfig(),
+		startedAt:  time.Now(),
+		stop:       make(chan struct{}),
 	}
+	s.countersResetAt = s.startedAt
+	s.upgrader.CheckOrigin = s.checkWSOrigin
+	s.broadcastInterval.Store(int64(broadcastInterval))
 
 	s.setupRoutes()
-	go s.handleBroadcast()
-	go s.collectMetricsFromAgent() // Start collecting metrics from agent
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		s.handleBroadcast()
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.collectMetricsFromAgent() // Start collecting metrics from agent
+	}()
 
 	return s
 }
 
+// Stop stops the background goroutines, waits for them to exit and
+// disconnects the WebSocket clients. It is safe to call more than once.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+
+	s.clientsMu.Lock()
+	for client := range s.clients {
+		s.removeClientLocked(client)
+	}
+	s.clientsMu.Unlock()
+}
+
 func (s *Server) setupRoutes() {
+	// Request logs are info lines (see -log-level). Errors, including
+	// those of unknown routes and panics, are answered with ErrorResponse.
+	s.router.Use(
+		gin.LoggerWithConfig(gin.LoggerConfig{Output: loglevel.Writer(slog.LevelInfo, gin.DefaultWriter)}),
+		assignRequestID,
+		gin.CustomRecovery(handlePanic),
+	)
+	s.router.HandleMethodNotAllowed = true
+	s.router.NoRoute(handleNoRoute)
+	s.router.NoMethod(handleNoMethod)
+
 	// CORS middleware
-	s.router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
+	s.router.Use(s.handleCORS)
+
+	// Prometheus metrics of the API server itself
+	s.router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Kubernetes probes
+	s.router.GET("/livez", s.handleLivez)
+	s.router.GET("/readyz", s.handleReadyz)
 
 	// API routes
 	api := s.router.Group("/api/v1")
 	{
-		api.GET("/health", s.handleHealth)
+		api.GET("/openapi.json", s.handleOpenAPI)
+		api.GET("/health", s.handleReadyz)
+		api.GET("/livez", s.handleLivez)
+		api.GET("/readyz", s.handleReadyz)
 		api.GET("/metrics/traffic", s.handleTrafficMetrics)
 		api.GET("/metrics/drops", s.handleDropMetrics)
+		api.GET("/metrics/drops/timeseries", s.handleDropTimeseries)
 		api.GET("/sessions", s.handleSessions)
+		api.POST("/sessions/batch", s.handleSessionBatch)
+		api.GET("/sessions/search", s.handleSessionSearch)
 		api.GET("/sessions/:seid", s.handleSessionDetail)
+		api.PUT("/sessions/:seid/tags", s.handleSessionTags)
 		api.GET("/topology", s.handleTopology)
+		api.GET("/topology.dot", s.proxyToAgent)
+		api.GET("/stats/summary", s.proxyToAgent)
+		api.GET("/cluster/stats", s.handleClusterStats)
+		api.GET("/cluster/sessions", s.handleClusterSessions)
 		api.POST("/fault/inject", s.handleFaultInject)
+		api.GET("/fault/status", s.handleFaultStatus)
+		api.GET("/log-level", s.handleGetLogLevel)
+		api.PUT("/log-level", s.handleSetLogLevel)
+		api.POST("/ws-ticket", s.handleWSTicket)
 
 		// Proxy demo APIs to agent
 		api.POST("/demo/inject-drop", s.proxyToAgent)
 		api.POST("/demo/inject-session", s.proxyToAgent)
+
+		// Proxy PFCP signaling stats to agent
+		api.GET("/pfcp/node-messages", s.proxyToAgent)
+		api.GET("/pfcp/message-stats", s.proxyToAgent)
+		api.GET("/pfcp/ie-coverage", s.proxyToAgent)
+		api.GET("/pfcp/teid-stats", s.proxyToAgent)
+		api.GET("/pfcp/peers", s.proxyToAgent)
+		api.POST("/pfcp/decode", s.proxyToAgent)
+		api.POST("/pfcp/replay", s.proxyReplayToAgent)
+
+		// Proxy eBPF introspection to agent
+		api.GET("/ebpf/info", s.proxyToAgent)
+
+		// Agent internal state for bug reports, needs -admin-token-file
+		api.GET("/debug/dump", s.requireAdminToken, s.proxyDebugDumpToAgent)
 	}
 
-	// WebSocket for real-time updates
-	s.router.GET("/ws/metrics", s.handleWebSocket)
-	s.router.GET("/ws/events", s.handleEventsWebSocket)
+	// WebSocket for real-time updates, with a ticket from /ws-ticket when
+	// -api-key-file is set
+	s.router.GET("/ws/metrics", s.requireWSTicket, s.handleWebSocket)
+	s.router.GET("/ws/events", s.requireWSTicket, s.handleEventsWebSocket)
 }
 
-// Health check
-func (s *Server) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   "1.0.0",
+// agentStaleAfter is how long the agent may go without a successful scrape
+// before the API server reports itself as not ready
+const agentStaleAfter = 5 * time.Second
+
+// Liveness probe: the process is up and serving HTTP
+func (s *Server) handleLivez(c *gin.Context) {
+	s.statsMu.RLock()
+	countersResetAt := s.countersResetAt
+	s.statsMu.RUnlock()
+
+	c.JSON(http.StatusOK, ProbeStatus{
+		Status:          "ok",
+		Timestamp:       time.Now().Format(time.RFC3339),
+		Version:         "1.0.0",
+		Checks:          map[string]string{"process": "ok"},
+		StartedAt:       s.startedAt.Format(time.RFC3339),
+		CountersResetAt: countersResetAt.Format(time.RFC3339),
+	})
+}
+
+// Readiness probe: the agent metrics endpoint has been scraped recently
+func (s *Server) handleReadyz(c *gin.Context) {
+	s.statsMu.RLock()
+	lastFetch := s.lastAgentFetch
+	countersResetAt := s.countersResetAt
+	s.statsMu.RUnlock()
+
+	status := "ok"
+	code := http.StatusOK
+	agentCheck := "ok"
+	if lastFetch.IsZero() {
+		agentCheck = "never reached"
+	} else if time.Since(lastFetch) > agentStaleAfter {
+		agentCheck = fmt.Sprintf("unreachable for %s", time.Since(lastFetch).Truncate(time.Second))
+	}
+	if agentCheck != "ok" {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, ProbeStatus{
+		Status:          status,
+		Timestamp:       time.Now().Format(time.RFC3339),
+		Version:         "1.0.0",
+		Checks:          map[string]string{"agent_metrics": agentCheck},
+		StartedAt:       s.startedAt.Format(time.RFC3339),
+		CountersResetAt: countersResetAt.Format(time.RFC3339),
 	})
 }
 
@@ -226,15 +536,29 @@ func (s *Server) handleSessions(c *gin.Context) {
 	s.statsMu.RLock()
 	defer s.statsMu.RUnlock()
 
-	c.JSON(http.StatusOK, gin.H{
-		"total":    len(s.sessions),
-		"sessions": s.sessions,
+	// Same data as the agent's at that ETag, so its ETag is ours
+	if s.sessionsETag != "" {
+		c.Header("ETag", s.sessionsETag)
+		if httpcache.Matches(c.GetHeader("If-None-Match"), s.sessionsETag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, SessionList{
+		Total:    len(s.sessions),
+		Sessions: s.sessions,
 	})
 }
 
 // Session detail
 func (s *Server) handleSessionDetail(c *gin.Context) {
-	seid := c.Param("seid")
+	parsed, err := model.ParseSEID(c.Param("seid"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	seid := model.FormatSEID(parsed)
 
 	s.statsMu.RLock()
 	defer s.statsMu.RUnlock()
@@ -246,37 +570,250 @@ func (s *Server) handleSessionDetail(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "session not found",
-	})
+	respondError(c, http.StatusNotFound, ErrCodeSessionNotFound, "session not found")
 }
 
-// Fault injection
-func (s *Server) handleFaultInject(c *gin.Context) {
-	var req struct {
-		Type   string `json:"type"`   // "invalid_teid", "no_pdr"
-		Target string `json:"target"` // Target TEID or IP
-		Count  int    `json:"count"`  // Number of packets
+// SessionTags is the body of PUT /sessions/:seid/tags and its response
+type SessionTags struct {
+	SEID string            `json:"seid,omitempty"` // Response only
+	Tags map[string]string `json:"tags"`
+}
+
+// Session tags, stored on the agent's session. The cached copy is updated
+// at once, so the session detail shows them before the next agent scrape.
+func (s *Server) handleSessionTags(c *gin.Context) {
+	resp, ok := s.forwardToAgent(c, jsonbody.MaxBytes, defaultProxyTimeout)
+	if !ok {
+		return
+	}
+	var tags SessionTags
+	if resp.status == http.StatusOK && json.Unmarshal(resp.body, &tags) == nil {
+		if len(tags.Tags) == 0 {
+			tags.Tags = nil
+		}
+		s.statsMu.Lock()
+		for i := range s.sessions {
+			if s.sessions[i].SEID == tags.SEID {
+				// Replaced rather than changed in place, as the collector does
+				sessions := slices.Clone(s.sessions)
+				sessions[i].Tags = tags.Tags
+				s.sessions = sessions
+				// Differs from the agent's at that ETag now
+				s.sessionsETag = ""
+				break
+			}
+		}
+		s.statsMu.Unlock()
 	}
+	if resp.status >= http.StatusBadRequest {
+		code := errorCodeForStatus(resp.status)
+		if resp.status == http.StatusNotFound {
+			code = ErrCodeSessionNotFound
+		}
+		respondAgentError(c, resp, code)
+		return
+	}
+	c.Data(resp.status, resp.contentType, resp.body)
+}
+
+// maxBatchSize caps the number of IDs accepted by the batch session query
+const maxBatchSize = 100
 
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// Batch session query: look up several sessions by SEID and/or TEID at once
+func (s *Server) handleSessionBatch(c *gin.Context) {
+	var req SessionBatchRequest
+	if err := jsonbody.Decode(c.Writer, c.Request, &req, jsonbody.MaxBytes); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body: "+err.Error())
 		return
 	}
 
-	// TODO: Implement actual fault injection
-	log.Printf("[FAULT] Injection requested: type=%s, target=%s, count=%d",
-		req.Type, req.Target, req.Count)
+	if len(req.SEIDs)+len(req.TEIDs) > maxBatchSize {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("batch too large: at most %d ids per request", maxBatchSize))
+		return
+	}
+
+	s.statsMu.RLock()
+	defer s.statsMu.RUnlock()
+
+	// Index sessions by SEID and TEID (both stored as canonical 0x hex)
+	bySEID := make(map[string]int, len(s.sessions))
+	byTEID := make(map[string]int)
+	for i, session := range s.sessions {
+		bySEID[session.SEID] = i
+		for _, teid := range session.TEIDs {
+			byTEID[teid] = i
+		}
+	}
+
+	sessions := make([]model.SessionInfo, 0, len(req.SEIDs)+len(req.TEIDs))
+	notFound := make([]string, 0)
+	seen := make(map[int]bool)
+
+	lookup := func(ids []string, index map[string]int, normalize func(string) string) {
+		for _, id := range ids {
+			i, ok := index[normalize(id)]
+			if !ok {
+				notFound = append(notFound, id)
+				continue
+			}
+			if !seen[i] {
+				seen[i] = true
+				sessions = append(sessions, s.sessions[i])
+			}
+		}
+	}
+	lookup(req.SEIDs, bySEID, normalizeSEID)
+	lookup(req.TEIDs, byTEID, normalizeTEID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "injection_started",
-		"type":   req.Type,
-		"target": req.Target,
+	c.JSON(http.StatusOK, SessionBatchResponse{
+		SessionList: SessionList{
+			Total:    len(sessions),
+			Sessions: sessions,
+		},
+		NotFound: notFound,
 	})
 }
 
+// maxSearchResults caps the number of sessions returned by a search
+const maxSearchResults = 100
+
+// handleSessionSearch matches q against the SEID (0x hex or decimal, see
+// model.ParseSEID), the TEIDs (hex with or without 0x, or decimal) and the
+// UE IPs (prefix) of every session, for the dashboard's single search box
+func (s *Server) handleSessionSearch(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "q is required")
+		return
+	}
+
+	// Candidate IDs: the SEID q stands for everywhere else in the API, and
+	// for TEIDs q as hex (with or without 0x) or decimal
+	var seidIDs, teidIDs []string
+	if v, err := model.ParseSEID(q); err == nil {
+		seidIDs = append(seidIDs, model.FormatSEID(v))
+	}
+	if v, err := model.ParseTEID(q); err == nil {
+		teidIDs = append(teidIDs, model.FormatTEID(v))
+	}
+	if v, err := strconv.ParseUint(q, 16, 32); err == nil {
+		teidIDs = append(teidIDs, model.FormatTEID(uint32(v)))
+	}
+	ipPrefix := strings.ToLower(q)
+
+	s.statsMu.RLock()
+	defer s.statsMu.RUnlock()
+
+	results := make([]SessionSearchResult, 0)
+	for _, session := range s.sessions {
+		var matched []string
+		if containsString(seidIDs, session.SEID) {
+			matched = append(matched, "seid")
+		}
+		for _, teid := range session.TEIDs {
+			if containsString(teidIDs, teid) {
+				matched = append(matched, "teid")
+				break
+			}
+		}
+		for _, ip := range append([]string{session.UEIP}, session.UEIPs...) {
+			if ip != "" && strings.HasPrefix(strings.ToLower(ip), ipPrefix) {
+				matched = append(matched, "ue_ip")
+				break
+			}
+		}
+		if len(matched) > 0 {
+			results = append(results, SessionSearchResult{MatchedOn: matched, Session: session})
+			if len(results) == maxSearchResults {
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, SessionSearchResponse{
+		Query:   q,
+		Total:   len(results),
+		Results: results,
+	})
+}
+
+// containsString reports whether list contains v
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSEID converts a SEID given as hex ("0x1a") or decimal ("26")
+// into the canonical form of SessionInfo.SEID. Unparsable SEIDs are
+// returned as-is.
+func normalizeSEID(id string) string {
+	seid, err := model.ParseSEID(id)
+	if err != nil {
+		return id
+	}
+	return model.FormatSEID(seid)
+}
+
+// normalizeTEID converts a TEID given as hex ("0x1a") or decimal ("26")
+// into the canonical form of SessionInfo.TEIDs. Unparsable TEIDs are
+// returned as-is.
+func normalizeTEID(id string) string {
+	teid, err := model.ParseTEID(id)
+	if err != nil {
+		return id
+	}
+	return model.FormatTEID(teid)
+}
+
+// Limits of proxied pcap replays, which the agent bounds further with
+// -pfcp-replay-max-size and -pfcp-replay-timeout
+const (
+	maxReplayUpload     = 64 << 20
+	replayProxyTimeout  = 2 * time.Minute
+	defaultProxyTimeout = 10 * time.Second
+)
+
 // proxyToAgent proxies demo API requests to the agent
 func (s *Server) proxyToAgent(c *gin.Context) {
+	s.proxyToAgentWithLimits(c, jsonbody.MaxBytes, defaultProxyTimeout)
+}
+
+// proxyReplayToAgent proxies pcap uploads to the agent's replay endpoint,
+// which take larger bodies and longer than the other proxied requests
+func (s *Server) proxyReplayToAgent(c *gin.Context) {
+	extendDeadlines(c.Writer, replayProxyTimeout)
+	s.proxyToAgentWithLimits(c, maxReplayUpload, replayProxyTimeout)
+}
+
+// proxyToAgentWithLimits forwards the request to the agent, rejecting
+// bodies over maxBody bytes and giving up after timeout
+func (s *Server) proxyToAgentWithLimits(c *gin.Context, maxBody int64, timeout time.Duration) {
+	resp, ok := s.forwardToAgent(c, maxBody, timeout)
+	if !ok {
+		return
+	}
+	if resp.status >= http.StatusBadRequest {
+		respondAgentError(c, resp, errorCodeForStatus(resp.status))
+		return
+	}
+	c.Data(resp.status, resp.contentType, resp.body)
+}
+
+// agentResponse is a response of the agent to a forwarded request
+type agentResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// forwardToAgent sends the request to the agent and returns its response,
+// for handlers that look at it before passing it on. On failure the error
+// has been answered and false is returned.
+func (s *Server) forwardToAgent(c *gin.Context, maxBody int64, timeout time.Duration) (agentResponse, bool) {
 	// Build the agent URL (agent uses /api/ instead of /api/v1/)
 	path := c.Request.URL.Path
 	if strings.HasPrefix(path, "/api/v1/") {
@@ -287,59 +824,58 @@ func (s *Server) proxyToAgent(c *gin.Context) {
 		agentURL += "?" + c.Request.URL.RawQuery
 	}
 
-	// Create request to agent
-	req, err := http.NewRequest(c.Request.Method, agentURL, c.Request.Body)
+	// Create request to agent. The body is limited here, the agent checks
+	// its contents.
+	reqBody := http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	req, err := http.NewRequest(c.Request.Method, agentURL, reqBody)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
-		return
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create request")
+		return agentResponse{}, false
 	}
 	req.Header.Set("Content-Type", c.GetHeader("Content-Type"))
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
 
 	// Execute request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("invalid request body: request body larger than %d bytes", maxBody))
+		return agentResponse{}, false
+	}
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Agent not available"})
-		return
+		respondError(c, http.StatusBadGateway, ErrCodeAgentUnavailable, "Agent not available")
+		return agentResponse{}, false
 	}
 	defer resp.Body.Close()
 
-	// Copy response
 	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	return agentResponse{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type"), body: body}, true
 }
 
 // WebSocket handler for real-time metrics
 func (s *Server) handleWebSocket(c *gin.Context) {
-	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
-		return
-	}
-
-	s.clientsMu.Lock()
-	s.clients[conn] = true
-	s.clientsMu.Unlock()
-
-	defer func() {
-		s.clientsMu.Lock()
-		delete(s.clients, conn)
-		s.clientsMu.Unlock()
-		conn.Close()
-	}()
-
 	// Send initial data
 	s.statsMu.RLock()
-	conn.WriteJSON(gin.H{
+	initial := gin.H{
 		"type": "initial",
 		"data": gin.H{
 			"traffic":  s.stats,
 			"drops":    s.drops,
 			"sessions": len(s.sessions),
+			"pipeline": s.pipeline,
 		},
-	})
+	}
 	s.statsMu.RUnlock()
 
+	conn := s.upgradeClient(c, initial)
+	if conn == nil {
+		return
+	}
+	defer s.removeClient(conn)
+
 	// Keep connection alive and handle client messages
 	for {
 		_, _, err := conn.ReadMessage()
@@ -351,22 +887,11 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 
 // WebSocket handler for events
 func (s *Server) handleEventsWebSocket(c *gin.Context) {
-	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+	conn := s.upgradeClient(c, nil)
+	if conn == nil {
 		return
 	}
-
-	s.clientsMu.Lock()
-	s.clients[conn] = true
-	s.clientsMu.Unlock()
-
-	defer func() {
-		s.clientsMu.Lock()
-		delete(s.clients, conn)
-		s.clientsMu.Unlock()
-		conn.Close()
-	}()
+	defer s.removeClient(conn)
 
 	for {
 		_, _, err := conn.ReadMessage()
@@ -376,74 +901,154 @@ func (s *Server) handleEventsWebSocket(c *gin.Context) {
 	}
 }
 
-// Broadcast updates to all WebSocket clients
+// Broadcast updates to all WebSocket clients whenever the collector
+// publishes new traffic stats. The one-slot buffer coalesces updates if a
+// broadcast takes longer than a collection interval.
 func (s *Server) handleBroadcast() {
-	ticker := time.NewTicker(1 * time.Second)
+	sub := s.bus.Subscribe(events.TopicTraffic, 1)
+	defer sub.Close()
+
+	interval := s.BroadcastInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.statsMu.RLock()
-		msg := gin.H{
-			"type": "update",
-			"data": gin.H{
-				"traffic":  s.stats,
-				"drops":    s.drops,
-				"sessions": len(s.sessions),
-			},
-			"timestamp": time.Now().Format(time.RFC3339),
+	// Only push when the collector has produced something new since the
+	// last broadcast, so a slow interval skips intermediate snapshots and
+	// a fast one does not resend the same data
+	updated := false
+	for {
+		select {
+		case <-s.stop:
+			return
+		case _, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			updated = true
+		case <-ticker.C:
+			if d := s.BroadcastInterval(); d != interval {
+				interval = d
+				ticker.Reset(interval)
+			}
+			if !updated {
+				continue
+			}
+			updated = false
+			s.broadcastUpdate()
 		}
-		s.statsMu.RUnlock()
+	}
+}
 
-		s.clientsMu.Lock()
-		for client := range s.clients {
-			if err := client.WriteJSON(msg); err != nil {
-				client.Close()
-				delete(s.clients, client)
+// broadcastUpdate sends the current stats snapshot to all WebSocket clients
+func (s *Server) broadcastUpdate() {
+	s.statsMu.RLock()
+	msg := gin.H{
+		"type": "update",
+		"data": gin.H{
+			"traffic":  s.stats,
+			"drops":    s.drops,
+			"sessions": len(s.sessions),
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	s.statsMu.RUnlock()
+
+	s.broadcastMessage(msg)
+}
+
+// broadcastMessage sends msg to all WebSocket clients, evicting those that
+// cannot keep up
+func (s *Server) broadcastMessage(msg interface{}) {
+	s.clientsMu.Lock()
+	for client := range s.clients {
+		if err := writeClient(client, msg, s.writeTimeout); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				wsEvictedTotal.Inc()
+				log.Printf("[WARN] Evicting slow WebSocket client %s", client.RemoteAddr())
 			}
+			s.removeClientLocked(client)
 		}
-		s.clientsMu.Unlock()
 	}
+	s.clientsMu.Unlock()
 }
 
 // UpdateStats updates the traffic statistics (called from agent)
-func (s *Server) UpdateStats(stats TrafficStats) {
+func (s *Server) UpdateStats(stats model.TrafficStats) {
 	s.statsMu.Lock()
 	s.stats = stats
 	s.statsMu.Unlock()
 }
 
 // AddDropEvent adds a drop event
-func (s *Server) AddDropEvent(event DropEvent) {
+func (s *Server) AddDropEvent(event model.DropEvent) {
 	s.statsMu.Lock()
 	defer s.statsMu.Unlock()
 
-	s.drops.Total++
-	s.drops.RecentDrops = append([]DropEvent{event}, s.drops.RecentDrops...)
+	n := event.Count
+	if n == 0 {
+		n = 1
+	}
+	s.drops.Total += n
+	if ueIP := s.dropUEIPLocked(event); ueIP != "" {
+		event.UEIP = ueIP
+	}
+	s.drops.RecentDrops = append([]model.DropEvent{event}, s.drops.RecentDrops...)
 
 	// Keep only last 100 events
 	if len(s.drops.RecentDrops) > 100 {
 		s.drops.RecentDrops = s.drops.RecentDrops[:100]
 	}
 
-	s.drops.ByReason[event.Reason]++
+	s.drops.ByReason[event.Reason] += n
 }
 
 // Run starts the server
 func (s *Server) Run(addr string) error {
-	return s.router.Run(addr)
+	return s.httpServer(addr).ListenAndServe()
+}
+
+// SetBroadcastInterval changes how often WebSocket clients receive
+// updates, from the next update on
+func (s *Server) SetBroadcastInterval(interval time.Duration) {
+	s.broadcastInterval.Store(int64(interval))
+}
+
+// BroadcastInterval returns how often WebSocket clients receive updates
+func (s *Server) BroadcastInterval() time.Duration {
+	return time.Duration(s.broadcastInterval.Load())
+}
+
+// collectInterval returns how often the agent is scraped: at least as
+// often as we broadcast, so short broadcast intervals actually deliver
+// fresher data
+func (s *Server) collectInterval() time.Duration {
+	return min(time.Second, s.BroadcastInterval())
 }
 
 // collectMetricsFromAgent periodically fetches metrics from the eBPF agent
 func (s *Server) collectMetricsFromAgent() {
-	ticker := time.NewTicker(1 * time.Second)
+	interval := s.collectInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	var prevUplinkBytes, prevDownlinkBytes uint64
 	var prevTime time.Time
+	var prevDropTotal uint64
+	var prevDropsByReason map[string]uint64
 
 	log.Println("[INFO] Starting metrics collection from agent at", agentMetricsURL)
 
-	for range ticker.C {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		if d := s.collectInterval(); d != interval {
+			interval = d
+			ticker.Reset(interval)
+		}
+
 		// Fetch Prometheus metrics for traffic
 		metrics, err := s.fetchAgentMetrics()
 		if err != nil {
@@ -451,6 +1056,11 @@ func (s *Server) collectMetricsFromAgent() {
 			continue
 		}
 
+		// Broadcast capture pipeline loss when it changes
+		if health, changed := s.pipelineTracker.update(metrics, time.Now()); changed {
+			s.broadcastPipelineHealth(health)
+		}
+
 		// Fetch drops from agent API
 		dropsData, err := s.fetchAgentDrops()
 		if err != nil {
@@ -458,16 +1068,29 @@ func (s *Server) collectMetricsFromAgent() {
 		}
 
 		// Fetch sessions from agent API
-		sessionsData, err := s.fetchAgentSessions()
+		s.statsMu.RLock()
+		sessionsETag := s.sessionsETag
+		s.statsMu.RUnlock()
+		sessionsData, sessionsETag, err := s.fetchAgentSessions(sessionsETag)
 		if err != nil {
 			log.Printf("[WARN] Failed to fetch sessions: %v", err)
 		}
 
 		now := time.Now()
 
+		// Counters going backwards means the agent restarted; skip the
+		// throughput sample instead of computing a wrapped-around delta
+		countersReset := !prevTime.IsZero() &&
+			(metrics.uplinkBytes < prevUplinkBytes || metrics.downlinkBytes < prevDownlinkBytes)
+		if countersReset {
+			log.Println("[INFO] Agent counters went backwards, assuming agent restart")
+			s.uplinkSmoother.reset()
+			s.downlinkSmoother.reset()
+		}
+
 		// Calculate throughput
 		var uplinkThroughput, downlinkThroughput float64
-		if !prevTime.IsZero() {
+		if !prevTime.IsZero() && !countersReset {
 			elapsed := now.Sub(prevTime).Seconds()
 			if elapsed > 0 {
 				uplinkBytesDelta := metrics.uplinkBytes - prevUplinkBytes
@@ -477,37 +1100,89 @@ func (s *Server) collectMetricsFromAgent() {
 			}
 		}
 
+		// Smoothed over -throughput-smoothing, once there is a first sample
+		uplinkSmoothed, downlinkSmoothed := uplinkThroughput, downlinkThroughput
+		if !prevTime.IsZero() && !countersReset {
+			uplinkSmoothed = s.uplinkSmoother.add(uplinkThroughput, now)
+			downlinkSmoothed = s.downlinkSmoother.add(downlinkThroughput, now)
+		}
+
 		prevUplinkBytes = metrics.uplinkBytes
 		prevDownlinkBytes = metrics.downlinkBytes
 		prevTime = now
 
 		// Update stats
 		s.statsMu.Lock()
-		s.stats = TrafficStats{
-			Uplink: DirectionStats{
+		s.lastAgentFetch = now
+		if countersReset {
+			s.countersResetAt = now
+		}
+		s.stats = model.TrafficStats{
+			Uplink: model.DirectionStats{
 				Packets:     metrics.uplinkPackets,
 				Bytes:       metrics.uplinkBytes,
 				Throughput:  uplinkThroughput,
+				Smoothed:    uplinkSmoothed,
 				LastUpdated: now.Format(time.RFC3339),
 			},
-			Downlink: DirectionStats{
+			Downlink: model.DirectionStats{
 				Packets:     metrics.downlinkPackets,
 				Bytes:       metrics.downlinkBytes,
 				Throughput:  downlinkThroughput,
+				Smoothed:    downlinkSmoothed,
 				LastUpdated: now.Format(time.RFC3339),
 			},
 		}
 
-		// Update drop stats from agent API
+		// Update sessions from agent API
+		if sessionsData != nil {
+			s.sessions = sessionsData
+			s.sessionsETag = sessionsETag
+			s.teidUEIPs = nil
+		}
+
+		// Update drop stats from agent API. The agent leaves out the UE IP
+		// of drops PFCP correlation did not know at the time, also the
+		// earlier ones whose session is known by now.
 		if dropsData != nil {
 			s.drops = *dropsData
 		}
+		if dropsData != nil || sessionsData != nil {
+			s.enrichRecentDropsLocked()
+		}
+		recentDrops := s.drops.RecentDrops
+		traffic := s.stats
+		s.statsMu.Unlock()
 
-		// Update sessions from agent API
+		if dropsData != nil {
+			s.dropSeries.recordDrops(prevDropsByReason, dropsData.ByReason, now)
+			prevDropsByReason = dropsData.ByReason
+		}
+
+		// Publish new drop events (most recent first) since the last fetch.
+		// A coalesced event stands for Count drops of the total.
+		if dropsData != nil {
+			if dropsData.Total > prevDropTotal {
+				newDrops := dropsData.Total - prevDropTotal
+				for i := 0; i < len(recentDrops) && newDrops > 0; i++ {
+					event := recentDrops[i]
+					s.bus.Publish(events.TopicDrops, event)
+					n := event.Count
+					if n == 0 {
+						n = 1
+					}
+					if n > newDrops {
+						n = newDrops
+					}
+					newDrops -= n
+				}
+			}
+			prevDropTotal = dropsData.Total
+		}
 		if sessionsData != nil {
-			s.sessions = sessionsData
+			s.bus.Publish(events.TopicSessions, sessionsData)
 		}
-		s.statsMu.Unlock()
+		s.bus.Publish(events.TopicTraffic, traffic)
 	}
 }
 
@@ -528,22 +1203,35 @@ func (s *Server) fetchAgentDrops() (*DropStats, error) {
 }
 
 // fetchAgentSessions fetches sessions from agent API
-func (s *Server) fetchAgentSessions() ([]SessionInfo, error) {
-	resp, err := http.Get(agentSessionsURL)
+// The agent answers 304 with nil sessions if they are unchanged since the
+// fetch that returned etag
+func (s *Server) fetchAgentSessions(etag string) ([]model.SessionInfo, string, error) {
+	req, err := http.NewRequest(http.MethodGet, agentSessionsURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch sessions: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+
 	var result struct {
-		Total    int           `json:"total"`
-		Sessions []SessionInfo `json:"sessions"`
+		Total    int                 `json:"total"`
+		Sessions []model.SessionInfo `json:"sessions"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+		return nil, "", fmt.Errorf("failed to decode sessions: %w", err)
 	}
 
-	return result.Sessions, nil
+	return result.Sessions, resp.Header.Get("ETag"), nil
 }
 
 // agentMetrics holds parsed metrics from the agent
@@ -554,6 +1242,11 @@ type agentMetrics struct {
 	downlinkBytes   uint64
 	totalDrops      uint64
 	activeSessions  uint64
+
+	// Capture pipeline loss (see PipelineHealth)
+	captureDrops  uint64
+	parseErrors   uint64
+	eventsDropped uint64
 }
 
 // fetchAgentMetrics fetches and parses metrics from the eBPF agent
@@ -576,11 +1269,15 @@ func (s *Server) fetchAgentMetrics() (*agentMetrics, error) {
 func parsePrometheusMetrics(body string) (*agentMetrics, error) {
 	metrics := &agentMetrics{}
 
-	// Regex patterns for different metric formats
-	packetsPattern := regexp.MustCompile(`upf_packets_total\{direction="(\w+)"\}\s+([0-9.e+\-]+)`)
-	bytesPattern := regexp.MustCompile(`upf_bytes_total\{direction="(\w+)"\}\s+([0-9.e+\-]+)`)
+	// Regex patterns for different metric formats. Other labels (such as
+	// the agent's node label) may come before or after the ones matched.
+	packetsPattern := regexp.MustCompile(`upf_packets_total\{(?:[^}]*,)?direction="(\w+)"[^}]*\}\s+([0-9.e+\-]+)`)
+	bytesPattern := regexp.MustCompile(`upf_bytes_total\{(?:[^}]*,)?direction="(\w+)"[^}]*\}\s+([0-9.e+\-]+)`)
 	dropsPattern := regexp.MustCompile(`upf_packet_drops_total\{[^}]*\}\s+([0-9.e+\-]+)`)
-	sessionsPattern := regexp.MustCompile(`upf_active_sessions\s+([0-9.e+\-]+)`)
+	sessionsPattern := regexp.MustCompile(`upf_active_sessions(?:\{[^}]*\})?\s+([0-9.e+\-]+)`)
+	captureDropsPattern := regexp.MustCompile(`upf_pfcp_capture_drops_total(?:\{[^}]*\})?\s+([0-9.e+\-]+)`)
+	parseErrorsPattern := regexp.MustCompile(`upf_pfcp_parse_errors_total(?:\{[^}]*\})?\s+([0-9.e+\-]+)`)
+	eventsDroppedPattern := regexp.MustCompile(`upf_(?:events_dropped|drop_events_lost_due_to_backpressure)_total(?:\{[^}]*\})?\s+([0-9.e+\-]+)`)
 
 	// Parse packets
 	for _, match := range packetsPattern.FindAllStringSubmatch(body, -1) {
@@ -621,6 +1318,19 @@ func parsePrometheusMetrics(body string) (*agentMetrics, error) {
 		metrics.activeSessions = parseNumber(match[1])
 	}
 
+	// Parse capture pipeline loss
+	if match := captureDropsPattern.FindStringSubmatch(body); len(match) == 2 {
+		metrics.captureDrops = parseNumber(match[1])
+	}
+	if match := parseErrorsPattern.FindStringSubmatch(body); len(match) == 2 {
+		metrics.parseErrors = parseNumber(match[1])
+	}
+	for _, match := range eventsDroppedPattern.FindAllStringSubmatch(body, -1) {
+		if len(match) == 2 {
+			metrics.eventsDropped += parseNumber(match[1])
+		}
+	}
+
 	return metrics, nil
 }
 
@@ -667,7 +1377,7 @@ type Topology struct {
 
 // isSessionActive checks if a session has recent traffic activity
 // A session is considered active if it has traffic in the last 10 seconds
-func isSessionActive(session SessionInfo) bool {
+func isSessionActive(session model.SessionInfo) bool {
 	if session.LastActive == "" {
 		// No LastActive timestamp - check if there's any traffic
 		return session.PacketsUL > 0 || session.PacketsDL > 0
@@ -685,7 +1395,7 @@ func isSessionActive(session SessionInfo) bool {
 }
 
 // isFlowActive checks if a specific flow (destination) has recent traffic
-func isFlowActive(flow FlowTraffic) bool {
+func isFlowActive(flow model.FlowTraffic) bool {
 	if flow.LastActive == "" {
 		return flow.Packets > 0
 	}
@@ -701,7 +1411,7 @@ func isFlowActive(flow FlowTraffic) bool {
 
 // getActiveFlowsByOuterDst groups active flows by their outer destination (next hop)
 // This allows us to determine which UPF paths have active traffic
-func getActiveFlowsByOuterDst(session SessionInfo) map[string]bool {
+func getActiveFlowsByOuterDst(session model.SessionInfo) map[string]bool {
 	result := make(map[string]bool)
 	for _, flow := range session.FlowTraffic {
 		if isFlowActive(flow) && flow.OuterDst != "" {
@@ -712,7 +1422,7 @@ func getActiveFlowsByOuterDst(session SessionInfo) map[string]bool {
 }
 
 // hasActiveFlowToN9Peer checks if session has active traffic going through N9 (to PSA-UPF)
-func hasActiveFlowToN9Peer(session SessionInfo) bool {
+func hasActiveFlowToN9Peer(session model.SessionInfo) bool {
 	if session.N9PeerIP == "" {
 		return false
 	}
@@ -726,7 +1436,7 @@ func hasActiveFlowToN9Peer(session SessionInfo) bool {
 
 // hasActiveFlowToLocalBreakout checks if session has active traffic NOT going through N9
 // (i.e., local breakout traffic that exits directly from I-UPF)
-func hasActiveFlowToLocalBreakout(session SessionInfo) bool {
+func hasActiveFlowToLocalBreakout(session model.SessionInfo) bool {
 	for _, flow := range session.FlowTraffic {
 		if isFlowActive(flow) {
 			// If OuterDst is empty or not N9PeerIP, it's local breakout
@@ -749,7 +1459,7 @@ func hasActiveFlowToLocalBreakout(session SessionInfo) bool {
 }
 
 // calculateTrafficRate calculates bytes per second for a session
-func calculateTrafficRate(session SessionInfo) float64 {
+func calculateTrafficRate(session model.SessionInfo) float64 {
 	// Simple calculation: total bytes / session duration
 	if session.CreatedAt == "" {
 		return 0
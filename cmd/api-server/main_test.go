@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/solar224/5G-DPOP/internal/model"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+// newTestServer returns a server stopped when the test ends, logging
+// nowhere. The agents it polls need not be running.
+func newTestServer(tb testing.TB) *Server {
+	tb.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	s := NewServer(time.Second)
+	tb.Cleanup(func() {
+		s.Stop()
+		log.SetOutput(out)
+	})
+	return s
+}
+
+func TestServerStopLeaksNoGoroutines(t *testing.T) {
+	s := newTestServer(t)
+	s.Stop()
+	goleak.VerifyNone(t)
+}
+
+func TestServerStopClosesWebSockets(t *testing.T) {
+	s := newTestServer(t)
+	httpServer := httptest.NewServer(s.router)
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	var conns []*websocket.Conn
+	for _, path := range []string{"/ws/metrics", "/ws/events"} {
+		conn, _, err := websocket.DefaultDialer.Dial(url+path, nil)
+		if err != nil {
+			t.Fatalf("dial %s: %v", path, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	s.Stop()
+	for _, conn := range conns {
+		// The server closed the connection, reads fail
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		conn.Close()
+	}
+	httpServer.Close()
+	goleak.VerifyNone(t)
+}
+
+func TestSessionDetailSEIDForms(t *testing.T) {
+	s := newTestServer(t)
+	s.statsMu.Lock()
+	s.sessions = []model.SessionInfo{{SEID: model.FormatSEID(0x1a2b), UEIP: "10.60.0.1"}}
+	s.statsMu.Unlock()
+
+	tests := []struct {
+		seid string
+		code int
+	}{
+		{"0x1a2b", http.StatusOK},
+		{"0x1A2B", http.StatusOK},
+		{"6699", http.StatusOK},
+		{"0x1a2c", http.StatusNotFound},
+		{"1a2b", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sessions/"+tt.seid, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET /sessions/%s: status %d, want %d", tt.seid, w.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var session model.SessionInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+			t.Fatal(err)
+		}
+		if session.UEIP != "10.60.0.1" {
+			t.Errorf("GET /sessions/%s returned %+v", tt.seid, session)
+		}
+	}
+}
+
+func TestSessionSearchSEIDForms(t *testing.T) {
+	s := newTestServer(t)
+	s.statsMu.Lock()
+	s.sessions = []model.SessionInfo{
+		{SEID: "0x1a", UEIP: "10.60.0.1", TEIDs: []string{"0x100"}},
+		{SEID: "0x26", UEIP: "10.60.0.2", TEIDs: []string{"0x200"}},
+	}
+	s.statsMu.Unlock()
+
+	tests := []struct {
+		q    string
+		want string // SEID of the only session matched on its SEID
+	}{
+		{"26", "0x1a"}, // Decimal, as in /sessions/:seid
+		{"0x26", "0x26"},
+		{"0X1A", "0x1a"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sessions/search?q="+tt.q, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("q=%s: status %d", tt.q, w.Code)
+		}
+		var resp SessionSearchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var bySEID []string
+		for _, r := range resp.Results {
+			if containsString(r.MatchedOn, "seid") {
+				bySEID = append(bySEID, r.Session.SEID)
+			}
+		}
+		if len(bySEID) != 1 || bySEID[0] != tt.want {
+			t.Errorf("q=%s matched SEIDs %v, want %s", tt.q, bySEID, tt.want)
+		}
+	}
+
+	// TEIDs are also matched as bare hex
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sessions/search?q=200", nil))
+	var resp SessionSearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 1 || resp.Results[0].Session.SEID != "0x26" || !containsString(resp.Results[0].MatchedOn, "teid") {
+		t.Errorf("q=200 returned %+v, want the session of TEID 0x200", resp.Results)
+	}
+}
+
+func TestSessionBatchIDForms(t *testing.T) {
+	s := newTestServer(t)
+	s.statsMu.Lock()
+	s.sessions = []model.SessionInfo{
+		{SEID: "0xa", UEIP: "10.60.0.1", TEIDs: []string{"0xa"}},
+		{SEID: "0x8", UEIP: "10.60.0.2", TEIDs: []string{"0x8"}},
+	}
+	s.statsMu.Unlock()
+
+	// "010" is decimal 10 for SEIDs and TEIDs alike, never octal 8
+	body := `{"seids":["010"],"teids":["010","0b1000","0o10"]}`
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/sessions/batch", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp SessionBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 1 || resp.Sessions[0].SEID != "0xa" {
+		t.Errorf("sessions %+v, want only SEID 0xa", resp.Sessions)
+	}
+	if fmt.Sprint(resp.NotFound) != "[0b1000 0o10]" {
+		t.Errorf("not found %v, want the 0b and 0o TEIDs", resp.NotFound)
+	}
+}
+
+// dropEvents returns n drop events over a few TEIDs and reasons
+func dropEvents(n int) []model.DropEvent {
+	reasons := []string{"NO_PDR_MATCH", "TTL_EXPIRED", "QOS_LIMIT", "BUFFER_FULL"}
+	events := make([]model.DropEvent, n)
+	for i := range events {
+		events[i] = model.DropEvent{
+			Timestamp: time.Now().Format(time.RFC3339Nano),
+			TEID:      fmt.Sprintf("0x%x", 0x10000+i%64),
+			SrcIP:     "10.60.0.1",
+			DstIP:     "8.8.8.8",
+			PktLen:    1400,
+			Reason:    reasons[i%len(reasons)],
+			Direction: "uplink",
+		}
+	}
+	return events
+}
+
+func BenchmarkAddDropEvent(b *testing.B) {
+	s := newTestServer(b)
+	events := dropEvents(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.AddDropEvent(events[i%len(events)])
+	}
+}
+
+// BenchmarkAddDropEventParallel adds drops from parallel goroutines, as
+// concurrent gRPC streams from several agents do
+func BenchmarkAddDropEventParallel(b *testing.B) {
+	s := newTestServer(b)
+	events := dropEvents(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.AddDropEvent(events[i%len(events)])
+		}
+	})
+}
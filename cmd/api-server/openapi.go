@@ -0,0 +1,305 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// apiOperation documents one route for the OpenAPI spec. Request and
+// Response are zero values of the Go types the handler binds and returns;
+// their schemas are generated by reflection, so the spec follows the
+// structs. nil means no body (Request) or a free-form JSON object
+// (Response, used for responses passed through from the agent).
+type apiOperation struct {
+	Summary     string
+	Query       []apiParam
+	Request     interface{}
+	Response    interface{}
+	ContentType string // Response content type, application/json if empty
+}
+
+// apiParam documents a query parameter
+type apiParam struct {
+	Name        string
+	Description string
+}
+
+// apiDocs documents the routes by "METHOD path" (gin path syntax). Routes
+// registered without an entry still appear in the spec, undocumented.
+var apiDocs = map[string]apiOperation{
+	"GET /livez":               {Summary: "Liveness probe", Response: ProbeStatus{}},
+	"GET /readyz":              {Summary: "Readiness probe, 503 until the agent has been scraped", Response: ProbeStatus{}},
+	"GET /api/v1/health":       {Summary: "Alias of /readyz", Response: ProbeStatus{}},
+	"GET /api/v1/livez":        {Summary: "Liveness probe", Response: ProbeStatus{}},
+	"GET /api/v1/readyz":       {Summary: "Readiness probe, 503 until the agent has been scraped", Response: ProbeStatus{}},
+	"GET /api/v1/openapi.json": {Summary: "This OpenAPI document"},
+
+	"GET /api/v1/metrics/traffic": {Summary: "Uplink/downlink traffic counters and throughput", Response: model.TrafficStats{}},
+	"GET /api/v1/metrics/drops":   {Summary: "Drop totals, rate and recent drop events", Response: DropStats{}},
+	"GET /api/v1/metrics/drops/timeseries": {
+		Summary: "Drop counts per step over a window (last hour at most), buckets aligned to multiples of step",
+		Query: []apiParam{
+			{Name: "window", Description: "Duration to cover, e.g. 5m (default 5m, at most 1h)"},
+			{Name: "step", Description: "Bucket size in whole seconds, e.g. 10s (default 10s)"},
+			{Name: "reason", Description: "Only count drops with this reason, e.g. NO_PDR (default all)"},
+		},
+		Response: DropTimeseriesResponse{},
+	},
+
+	"GET /api/v1/sessions":        {Summary: "All known PDU sessions", Response: SessionList{}},
+	"POST /api/v1/sessions/batch": {Summary: "Look up several sessions by SEID and/or TEID", Request: SessionBatchRequest{}, Response: SessionBatchResponse{}},
+	"GET /api/v1/sessions/:seid":  {Summary: "One session by SEID (0x hex)", Response: model.SessionInfo{}},
+	"PUT /api/v1/sessions/:seid/tags": {
+		Summary:  "Replace the operator tags of a session (at most 16, keys up to 64 and values up to 256 bytes; {} clears them), kept until the session is deleted (from the agent)",
+		Request:  SessionTags{},
+		Response: SessionTags{},
+	},
+	"GET /api/v1/sessions/search": {
+		Summary:  "Sessions whose SEID (hex), a TEID (hex or decimal) or a UE IP (prefix) matches q",
+		Query:    []apiParam{{Name: "q", Description: "SEID, TEID or UE IP prefix"}},
+		Response: SessionSearchResponse{},
+	},
+
+	"GET /api/v1/topology": {Summary: "Network topology derived from the sessions", Response: Topology{}},
+	"GET /api/v1/topology.dot": {
+		Summary:     "Sessions, TEIDs and UE IPs as a Graphviz DOT graph (from the agent)",
+		Query:       []apiParam{{Name: "seid", Description: "Only render this session (hex or decimal)"}},
+		ContentType: "text/vnd.graphviz",
+	},
+	"GET /api/v1/stats/summary": {Summary: "Aggregate session and traffic counters (from the agent)"},
+
+	"GET /api/v1/cluster/stats": {Summary: "Stats of the -agents summed over the reachable ones, and per node (404 without -agents)", Response: ClusterStatsResponse{}},
+	"GET /api/v1/cluster/sessions": {
+		Summary:  "Sessions of the reachable -agents tagged with their node (404 without -agents)",
+		Query:    []apiParam{{Name: "node", Description: "Only this agent (host:port), also if it is stale"}},
+		Response: ClusterSessionsResponse{},
+	},
+
+	"POST /api/v1/fault/inject": {Summary: "Send GTP-U packets with an unknown TEID (invalid_teid, target TEID) or no matching PDR (no_pdr, target UE IP) to -fault-upf-addr; 503 while disabled or the circuit breaker is open", Request: FaultInjectRequest{}, Response: FaultInjectResponse{}},
+	"GET /api/v1/fault/status":  {Summary: "Whether fault injection is enabled and the state of its circuit breaker", Response: FaultStatus{}},
+
+	"GET /api/v1/log-level": {Summary: "Minimum level of the API server's log lines", Response: LogLevel{}},
+	"PUT /api/v1/log-level": {Summary: "Change the API server's log level (debug, info, warn or error) without a restart; the agent's is PUT /api/log-level on the agent", Request: LogLevel{}, Response: LogLevel{}},
+
+	"POST /api/v1/ws-ticket": {Summary: "Single-use ticket for /ws/metrics or /ws/events (?ticket=), valid for 30s; needs -api-key-file and the key as Authorization: Bearer, the WebSocket endpoints then require a ticket", Response: WSTicket{}},
+
+	"POST /api/v1/demo/inject-drop":    {Summary: "Inject a demo drop event (from the agent)"},
+	"POST /api/v1/demo/inject-session": {Summary: "Inject a demo session (from the agent)"},
+
+	"GET /api/v1/pfcp/node-messages": {Summary: "PFCP node message counters per peer (from the agent)"},
+	"GET /api/v1/pfcp/message-stats": {Summary: "PFCP message counters per type (from the agent)"},
+	"POST /api/v1/pfcp/decode":       {Summary: "Decode one PFCP message ({\"hex\": ...} or {\"base64\": ...}) with the sniffer's parser (from the agent)"},
+	"GET /api/v1/pfcp/ie-coverage":   {Summary: "IE types seen in PFCP traffic, needs agent -pfcp-ie-stats (from the agent)"},
+	"GET /api/v1/pfcp/peers":         {Summary: "Message inter-arrival jitter and missed heartbeats per PFCP peer, needs agent -pfcp-peer-health (from the agent)"},
+	"GET /api/v1/pfcp/teid-stats": {
+		Summary: "Count, range and histogram of the mapped TEID values, to spot TEID pool allocation problems (from the agent)",
+		Query:   []apiParam{{Name: "buckets", Description: "Number of histogram buckets over min..max, 1-256 (default 16)"}},
+	},
+
+	"GET /api/v1/ebpf/info": {Summary: "Loaded eBPF programs with attach points and maps with sizes and entry counts (from the agent)"},
+
+	"GET /api/v1/debug/dump": {
+		Summary: "Internal state for bug reports: sessions, TEID/SEID maps, PFCP peers, counters, flags and build, with SUPIs redacted; needs -admin-token-file and an Authorization: Bearer header (from the agent)",
+		Query:   []apiParam{{Name: "full", Description: "true to include all sessions instead of the first 1000 by SEID"}},
+	},
+}
+
+// handleOpenAPI serves an OpenAPI 3 document of the registered routes
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPISpec())
+}
+
+// openAPISpec builds the OpenAPI document from the router's routes and
+// apiDocs
+func (s *Server) openAPISpec() gin.H {
+	schemas := gin.H{}
+	paths := gin.H{}
+
+	errorSchema := jsonSchema(reflect.TypeOf(ErrorResponse{}), schemas)
+
+	routes := s.router.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	for _, route := range routes {
+		// WebSocket and profiling endpoints are not REST resources
+		if strings.HasPrefix(route.Path, "/ws/") || strings.HasPrefix(route.Path, "/debug/") {
+			continue
+		}
+
+		path, pathParams := openAPIPath(route.Path)
+		doc := apiDocs[route.Method+" "+route.Path]
+
+		var params []gin.H
+		for _, name := range pathParams {
+			params = append(params, gin.H{
+				"name": name, "in": "path", "required": true,
+				"schema": gin.H{"type": "string"},
+			})
+		}
+		for _, q := range doc.Query {
+			params = append(params, gin.H{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": gin.H{"type": "string"},
+			})
+		}
+
+		contentType := doc.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		responseSchema := gin.H{"type": "object"}
+		if contentType != "application/json" {
+			responseSchema = gin.H{"type": "string"}
+		} else if doc.Response != nil {
+			responseSchema = jsonSchema(reflect.TypeOf(doc.Response), schemas)
+		}
+
+		op := gin.H{
+			"summary": doc.Summary,
+			"responses": gin.H{
+				"200": gin.H{
+					"description": "OK",
+					"content":     gin.H{contentType: gin.H{"schema": responseSchema}},
+				},
+				"default": gin.H{
+					"description": "Error",
+					"content":     gin.H{"application/json": gin.H{"schema": errorSchema}},
+				},
+			},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if doc.Request != nil {
+			op["requestBody"] = gin.H{
+				"required": true,
+				"content": gin.H{"application/json": gin.H{
+					"schema": jsonSchema(reflect.TypeOf(doc.Request), schemas),
+				}},
+			}
+		}
+
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "5G-DPOP API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": gin.H{"schemas": schemas},
+	}
+}
+
+// openAPIPath converts a gin path ("/sessions/:seid") to OpenAPI syntax
+// ("/sessions/{seid}") and returns the names of its path parameters
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			params = append(params, seg[1:])
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema returns the JSON schema of t as encoding/json would marshal
+// it. Named structs are added to schemas and referenced.
+func jsonSchema(t reflect.Type, schemas gin.H) gin.H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return gin.H{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return gin.H{"type": "string", "format": "byte"}
+		}
+		return gin.H{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t == timeType {
+			return gin.H{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = gin.H{} // placeholder, for recursive types
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	// interface{} and anything else: any JSON value
+	return gin.H{}
+}
+
+// structSchema returns the object schema of a struct, inlining the fields
+// of embedded structs the way encoding/json does
+func structSchema(t reflect.Type, schemas gin.H) gin.H {
+	properties := gin.H{}
+	var required []string
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				addFields(field.Type)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type, schemas)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pipelineDegradedWindow is how long the capture pipeline stays degraded
+// after the last loss, so a banner does not flicker between collections
+const pipelineDegradedWindow = time.Minute
+
+// PipelineHealth is the loss seen by the agent's capture pipeline. Counters
+// are totals since the agent started.
+type PipelineHealth struct {
+	CaptureDrops   uint64   `json:"capture_drops"`    // packets dropped by libpcap on the PFCP capture
+	ParseErrors    uint64   `json:"parse_errors"`     // PFCP messages dropped as malformed
+	ParseErrorRate float64  `json:"parse_error_rate"` // parse errors per second since the previous collection
+	EventsDropped  uint64   `json:"events_dropped"`   // drop/session events discarded because consumers fell behind
+	Degraded       bool     `json:"degraded"`         // loss within the last minute
+	Reasons        []string `json:"reasons,omitempty"`
+}
+
+// pipelineTracker turns the agent's loss counters into PipelineHealth. Only
+// touched by collectMetricsFromAgent.
+type pipelineTracker struct {
+	prev     agentMetrics
+	prevTime time.Time
+
+	// Last time each counter went up, by PipelineHealth JSON name
+	lossAt map[string]time.Time
+
+	// Last health broadcast, to send only changes
+	last *PipelineHealth
+}
+
+// update computes the health from a new agent sample and reports whether it
+// differs from the last one returned as changed
+func (p *pipelineTracker) update(m *agentMetrics, now time.Time) (PipelineHealth, bool) {
+	if p.lossAt == nil {
+		p.lossAt = make(map[string]time.Time)
+	}
+
+	health := PipelineHealth{
+		CaptureDrops:  m.captureDrops,
+		ParseErrors:   m.parseErrors,
+		EventsDropped: m.eventsDropped,
+	}
+
+	// Counters going backwards mean the agent restarted, which is no loss
+	if !p.prevTime.IsZero() {
+		if m.captureDrops > p.prev.captureDrops {
+			p.lossAt["capture_drops"] = now
+		}
+		if m.parseErrors > p.prev.parseErrors {
+			p.lossAt["parse_errors"] = now
+			if elapsed := now.Sub(p.prevTime).Seconds(); elapsed > 0 {
+				health.ParseErrorRate = float64(m.parseErrors-p.prev.parseErrors) / elapsed
+			}
+		}
+		if m.eventsDropped > p.prev.eventsDropped {
+			p.lossAt["events_dropped"] = now
+		}
+	}
+	p.prev = *m
+	p.prevTime = now
+
+	for _, reason := range []string{"capture_drops", "parse_errors", "events_dropped"} {
+		if at, ok := p.lossAt[reason]; ok && now.Sub(at) < pipelineDegradedWindow {
+			health.Reasons = append(health.Reasons, reason)
+		}
+	}
+	health.Degraded = len(health.Reasons) > 0
+
+	if p.last != nil && p.last.equal(health) {
+		return health, false
+	}
+	p.last = &health
+	return health, true
+}
+
+func (h PipelineHealth) equal(o PipelineHealth) bool {
+	if h.CaptureDrops != o.CaptureDrops || h.ParseErrors != o.ParseErrors ||
+		h.ParseErrorRate != o.ParseErrorRate || h.EventsDropped != o.EventsDropped ||
+		h.Degraded != o.Degraded || len(h.Reasons) != len(o.Reasons) {
+		return false
+	}
+	for i := range h.Reasons {
+		if h.Reasons[i] != o.Reasons[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// broadcastPipelineHealth sends a pipeline_health message to all WebSocket
+// clients, so dashboards can warn that sessions or drops may be missing
+func (s *Server) broadcastPipelineHealth(health PipelineHealth) {
+	s.statsMu.Lock()
+	s.pipeline = health
+	s.statsMu.Unlock()
+
+	s.broadcastMessage(gin.H{
+		"type":      "pipeline_health",
+		"data":      health,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/configfile"
+	"github.com/solar224/5G-DPOP/internal/loglevel"
+)
+
+// registerReloadable lists the settings SIGHUP changes at runtime. The
+// others, such as the listen addresses, the HTTP timeouts or -agents, need
+// a restart.
+func (s *Server) registerReloadable(config *configfile.File) {
+	config.Reloadable("log-level", func(value string) error {
+		level, err := loglevel.Parse(value)
+		if err != nil {
+			return err
+		}
+		loglevel.Set(level)
+		return nil
+	})
+
+	config.Reloadable("cors-origins", func(value string) error {
+		s.SetCORSOrigins(parseCORSOrigins(value))
+		return nil
+	})
+
+	config.Reloadable("broadcast-interval", func(value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if interval < minBroadcastInterval || interval > maxBroadcastInterval {
+			return fmt.Errorf("must be between %v and %v", minBroadcastInterval, maxBroadcastInterval)
+		}
+		s.SetBroadcastInterval(interval)
+		return nil
+	})
+
+	config.Reloadable("ws-max-clients", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return errors.New("must not be negative")
+		}
+		s.clientsMu.Lock()
+		writeTimeout := s.writeTimeout
+		s.clientsMu.Unlock()
+		s.SetWebSocketLimits(n, writeTimeout)
+		return nil
+	})
+
+	config.Reloadable("ws-write-timeout", func(value string) error {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return errors.New("must be positive")
+		}
+		s.clientsMu.Lock()
+		maxClients := s.maxClients
+		s.clientsMu.Unlock()
+		s.SetWebSocketLimits(maxClients, timeout)
+		return nil
+	})
+}
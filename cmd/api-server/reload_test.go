@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/solar224/5G-DPOP/internal/configfile"
+)
+
+// TestReloadDuringUpgrades reloads the WebSocket limits while clients
+// connect; run with -race
+func TestReloadDuringUpgrades(t *testing.T) {
+	s := newTestServer(t)
+	s.SetWebSocketLimits(0, time.Second)
+
+	path := filepath.Join(t.TempDir(), "api.yaml")
+	writeConfig := func(timeout time.Duration) {
+		data := fmt.Sprintf("ws-write-timeout: %s\nws-max-clients: %d\n", timeout, 1000+timeout.Milliseconds())
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(time.Second)
+	fs := flag.NewFlagSet("api-server", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.Int("ws-max-clients", defaultMaxWSClients, "")
+	fs.Duration("ws-write-timeout", defaultWSWriteTimeout, "")
+	if err := fs.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := configfile.Load(fs, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.registerReloadable(config)
+
+	httpServer := httptest.NewServer(s.router)
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws/metrics"
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			conn.Close()
+		}
+	}()
+	last := 0
+	for reloading := true; reloading; {
+		select {
+		case <-done:
+			reloading = false
+		default:
+		}
+		last++
+		writeConfig(time.Duration(last) * time.Millisecond)
+		if err := config.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if want := time.Duration(last) * time.Millisecond; s.writeTimeout != want || s.maxClients != 1000+last {
+		t.Errorf("limits %d clients, %s, want %d and %s", s.maxClients, s.writeTimeout, 1000+last, want)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// defaultThroughputWindow is the default -throughput-smoothing time constant
+const defaultThroughputWindow = 5 * time.Second
+
+// throughputSmoother is an exponentially weighted moving average with a time
+// constant instead of a fixed alpha: a sample taken dt after the previous
+// one moves the average by alpha = 1 - exp(-dt/window), so the smoothing
+// does not depend on how often the agent is scraped. A zero window passes
+// samples through unchanged.
+type throughputSmoother struct {
+	window time.Duration
+	value  float64
+	last   time.Time
+}
+
+// add feeds the sample v taken at t and returns the smoothed value
+func (e *throughputSmoother) add(v float64, t time.Time) float64 {
+	if e.window <= 0 || e.last.IsZero() {
+		e.value = v
+	} else {
+		alpha := 1 - math.Exp(-t.Sub(e.last).Seconds()/e.window.Seconds())
+		e.value += alpha * (v - e.value)
+	}
+	e.last = t
+	return e.value
+}
+
+// reset forgets the history, the next sample starts the average afresh
+func (e *throughputSmoother) reset() {
+	e.value = 0
+	e.last = time.Time{}
+}
+
+// SetThroughputSmoothing sets the time constant of the smoothed throughput
+// sent next to the instantaneous one (0 disables smoothing). Must be called
+// before Run.
+func (s *Server) SetThroughputSmoothing(window time.Duration) {
+	s.uplinkSmoother.window = window
+	s.downlinkSmoother.window = window
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Defaults for -ws-max-clients and -ws-write-timeout
+const (
+	defaultMaxWSClients   = 256
+	defaultWSWriteTimeout = 5 * time.Second
+)
+
+var (
+	wsClientsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "dpop_api_websocket_clients",
+		Help: "Number of connected WebSocket clients",
+	})
+	wsClientsMaxGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "dpop_api_websocket_clients_max",
+		Help: "Maximum number of concurrent WebSocket clients (-ws-max-clients, 0 = unlimited)",
+	})
+	wsRejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dpop_api_websocket_rejected_total",
+		Help: "Total number of WebSocket connections rejected because the client limit was reached",
+	})
+	wsEvictedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dpop_api_websocket_evicted_total",
+		Help: "Total number of WebSocket clients disconnected because a write did not complete in time",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(wsClientsGauge, wsClientsMaxGauge, wsRejectedTotal, wsEvictedTotal)
+}
+
+// SetWebSocketLimits caps the number of concurrent WebSocket clients (0 =
+// unlimited) and sets how long a write to one client may take before the
+// client is disconnected, so a stalled browser tab cannot hold up updates
+// for everyone else
+func (s *Server) SetWebSocketLimits(maxClients int, writeTimeout time.Duration) {
+	s.clientsMu.Lock()
+	s.maxClients = maxClients
+	s.writeTimeout = writeTimeout
+	s.clientsMu.Unlock()
+	wsClientsMaxGauge.Set(float64(maxClients))
+}
+
+// upgradeClient reserves a client slot, upgrades the connection and sends
+// it the initial message (if any) before adding it to the broadcast set.
+// Over the limit it answers 503 and returns nil.
+func (s *Server) upgradeClient(c *gin.Context, initial interface{}) *websocket.Conn {
+	s.clientsMu.Lock()
+	if s.maxClients > 0 && len(s.clients)+s.pendingClients >= s.maxClients {
+		s.clientsMu.Unlock()
+		wsRejectedTotal.Inc()
+		respondError(c, http.StatusServiceUnavailable, ErrCodeUnavailable, "too many WebSocket clients")
+		return nil
+	}
+	s.pendingClients++
+	writeTimeout := s.writeTimeout
+	s.clientsMu.Unlock()
+
+	release := func() {
+		s.clientsMu.Lock()
+		s.pendingClients--
+		s.clientsMu.Unlock()
+	}
+
+	// The connection outlives the request, so the server's read and write
+	// timeouts must not apply; writes are bounded by writeTimeout instead
+	clearDeadlines(c.Writer)
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		release()
+		return nil
+	}
+
+	// Written before the client joins the broadcast set, gorilla
+	// connections support only one concurrent writer
+	if initial != nil {
+		if err := writeClient(conn, initial, writeTimeout); err != nil {
+			release()
+			conn.Close()
+			return nil
+		}
+	}
+
+	s.clientsMu.Lock()
+	s.pendingClients--
+	s.clients[conn] = true
+	wsClientsGauge.Set(float64(len(s.clients)))
+	s.clientsMu.Unlock()
+	return conn
+}
+
+// removeClient drops conn from the broadcast set and closes it
+func (s *Server) removeClient(conn *websocket.Conn) {
+	s.clientsMu.Lock()
+	s.removeClientLocked(conn)
+	s.clientsMu.Unlock()
+}
+
+func (s *Server) removeClientLocked(conn *websocket.Conn) {
+	delete(s.clients, conn)
+	wsClientsGauge.Set(float64(len(s.clients)))
+	conn.Close()
+}
+
+// writeClient sends msg to conn within timeout (0 = none). Callers read
+// the timeout under clientsMu, SetWebSocketLimits changes it on reload.
+func writeClient(conn *websocket.Conn, msg interface{}, timeout time.Duration) error {
+	if timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	return conn.WriteJSON(msg)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/solar224/5G-DPOP/internal/adminauth"
+)
+
+// WebSocket tickets: how long one is valid, and how many may be waiting to
+// be used at once
+const (
+	wsTicketTTL         = 30 * time.Second
+	maxPendingWSTickets = 1024
+)
+
+// WSTicket is the response of POST /ws-ticket
+type WSTicket struct {
+	Ticket    string `json:"ticket"`     // Pass as ?ticket= to /ws/metrics or /ws/events
+	ExpiresAt string `json:"expires_at"` // RFC 3339
+}
+
+// wsTickets holds the issued tickets that were not used yet, with their
+// expiry time
+type wsTickets struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newWSTickets() *wsTickets {
+	return &wsTickets{expires: make(map[string]time.Time)}
+}
+
+// issue returns a new ticket valid for wsTicketTTL, or ok false while
+// maxPendingWSTickets tickets are waiting to be used
+func (t *wsTickets) issue(now time.Time) (ticket string, expires time.Time, ok bool) {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", time.Time{}, false
+	}
+	ticket = base64.RawURLEncoding.EncodeToString(buf[:])
+	expires = now.Add(wsTicketTTL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for pending, pendingExpires := range t.expires {
+		if !now.Before(pendingExpires) {
+			delete(t.expires, pending)
+		}
+	}
+	if len(t.expires) >= maxPendingWSTickets {
+		return "", time.Time{}, false
+	}
+	t.expires[ticket] = expires
+	return ticket, expires, true
+}
+
+// consume reports whether ticket was issued and has not expired, and
+// invalidates it either way
+func (t *wsTickets) consume(ticket string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	expires, ok := t.expires[ticket]
+	delete(t.expires, ticket)
+	return ok && now.Before(expires)
+}
+
+// SetAPIKey requires a ticket on the WebSocket endpoints, issued by POST
+// /ws-ticket to clients sending key as a bearer token. Browsers cannot set
+// headers on a WebSocket, and a long-lived key in the URL would end up in
+// access logs, so the URL only ever carries a single-use ticket.
+func (s *Server) SetAPIKey(key string) {
+	s.apiKey = key
+	s.wsTickets = newWSTickets()
+}
+
+// handleWSTicket issues a WebSocket ticket
+func (s *Server) handleWSTicket(c *gin.Context) {
+	if s.apiKey == "" {
+		respondError(c, http.StatusNotFound, ErrCodeDisabled, "WebSocket tickets disabled, start the API server with -api-key-file")
+		return
+	}
+	if !adminauth.Authorized(c.GetHeader("Authorization"), s.apiKey) {
+		c.Header("WWW-Authenticate", `Bearer realm="5g-dpop"`)
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing or invalid API key")
+		return
+	}
+
+	ticket, expires, ok := s.wsTickets.issue(time.Now())
+	if !ok {
+		respondError(c, http.StatusServiceUnavailable, ErrCodeUnavailable, "too many unused WebSocket tickets, try again later")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, WSTicket{Ticket: ticket, ExpiresAt: expires.UTC().Format(time.RFC3339)})
+}
+
+// requireWSTicket aborts WebSocket upgrades without a valid ?ticket= while
+// an API key is set, consuming the ticket
+func (s *Server) requireWSTicket(c *gin.Context) {
+	if s.apiKey == "" {
+		c.Next()
+		return
+	}
+	if !s.wsTickets.consume(c.Query("ticket"), time.Now()) {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing, used or expired WebSocket ticket, get one with POST /api/v1/ws-ticket")
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSTicketsConsume(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tickets := newWSTickets()
+
+	ticket, expires, ok := tickets.issue(now)
+	if !ok {
+		t.Fatal("ticket not issued")
+	}
+	if !expires.Equal(now.Add(wsTicketTTL)) {
+		t.Errorf("expires %v, want %v", expires, now.Add(wsTicketTTL))
+	}
+	if other, _, _ := tickets.issue(now); other == ticket {
+		t.Error("same ticket issued twice")
+	}
+	if !tickets.consume(ticket, now.Add(time.Second)) {
+		t.Fatal("valid ticket rejected")
+	}
+	if tickets.consume(ticket, now.Add(time.Second)) {
+		t.Error("ticket accepted twice")
+	}
+
+	expired, _, _ := tickets.issue(now)
+	if tickets.consume(expired, now.Add(wsTicketTTL)) {
+		t.Error("expired ticket accepted")
+	}
+	for _, ticket := range []string{"", "not-issued"} {
+		if tickets.consume(ticket, now) {
+			t.Errorf("ticket %q accepted", ticket)
+		}
+	}
+}
+
+func TestWSTicketsPendingLimit(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tickets := newWSTickets()
+	var first string
+	for i := 0; i < maxPendingWSTickets; i++ {
+		ticket, _, ok := tickets.issue(now)
+		if !ok {
+			t.Fatalf("ticket %d not issued", i)
+		}
+		if i == 0 {
+			first = ticket
+		}
+	}
+	if _, _, ok := tickets.issue(now.Add(time.Second)); ok {
+		t.Error("ticket issued over the pending limit")
+	}
+
+	// Using one makes room for one
+	if !tickets.consume(first, now) {
+		t.Fatal("valid ticket rejected")
+	}
+	if _, _, ok := tickets.issue(now); !ok {
+		t.Error("no ticket issued after one was used")
+	}
+
+	// Expired ones are dropped on the next issue
+	if _, _, ok := tickets.issue(now.Add(wsTicketTTL)); !ok {
+		t.Error("no ticket issued after the pending ones expired")
+	}
+	if n := len(tickets.expires); n != 1 {
+		t.Errorf("%d pending tickets, want 1", n)
+	}
+}
+
+func TestWSTicketEndpoints(t *testing.T) {
+	s := newTestServer(t)
+	s.SetAPIKey("secret")
+	httpServer := httptest.NewServer(s.router)
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	getTicket := func(authorization string) (int, WSTicket) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ws-ticket", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		var ticket WSTicket
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &ticket); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, ticket
+	}
+	for _, authorization := range []string{"", "Bearer wrong", "Basic secret"} {
+		if code, _ := getTicket(authorization); code != http.StatusUnauthorized {
+			t.Errorf("ticket with Authorization %q: status %d, want 401", authorization, code)
+		}
+	}
+
+	for _, path := range []string{"/ws/metrics", "/ws/events"} {
+		for _, query := range []string{"", "?ticket=", "?ticket=not-issued"} {
+			_, resp, err := websocket.DefaultDialer.Dial(url+path+query, nil)
+			if err == nil {
+				t.Errorf("%s%s: upgraded without a valid ticket", path, query)
+				continue
+			}
+			if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("%s%s: %v, want status 401", path, query, err)
+			}
+		}
+
+		code, ticket := getTicket("Bearer secret")
+		if code != http.StatusOK {
+			t.Fatalf("ticket status %d, want 200", code)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url+path+"?ticket="+ticket.Ticket, nil)
+		if err != nil {
+			t.Fatalf("%s with a ticket: %v", path, err)
+		}
+		conn.Close()
+		_, resp, err := websocket.DefaultDialer.Dial(url+path+"?ticket="+ticket.Ticket, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s with a used ticket: %v, want status 401", path, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Command pfcp-gen emits synthetic PFCP session traffic over UDP/8805, so
+// the agent's sniffer and correlation can be exercised without a real
+// SMF/UPF. Each session is an Establishment Request/Response, a
+// Modification Request/Response pointing downlink at the gNB, and finally
+// a Deletion Request/Response.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	upfAddr     = flag.String("upf", "127.0.0.2:8805", "Address requests are sent to")
+	smfAddr     = flag.String("smf", "127.0.0.1:0", "Local address requests are sent from")
+	responses   = flag.Bool("responses", true, "Also send the UPF's responses from -upf, which must then be a local address not used by a real UPF")
+	numSessions = flag.Int("sessions", 10, "Number of sessions kept established")
+	rate        = flag.Float64("rate", 10, "Session establishments per second while ramping up to -sessions")
+	churn       = flag.Float64("churn", 0, "Sessions deleted and re-established per second once -sessions are up (0 = none)")
+	ieLevel     = flag.String("ies", "standard", "IE richness: minimal (F-TEID, UE IP), standard (+ DNN, QFI, QER with MBR) or full (+ SDF filter, GBR, URR, BAR)")
+	duration    = flag.Duration("duration", 0, "Stop after this long (0 = until interrupted)")
+	uePool      = flag.String("ue-pool", "10.60.0.0/16", "IPv4 pool UE addresses are taken from")
+	gnbIPFlag   = flag.String("gnb", "10.100.200.15", "gNB N3 address used in the downlink Outer Header Creation")
+	n3IPFlag    = flag.String("n3", "", "UPF N3 address used in the uplink F-TEID (default: the -upf address)")
+	dnn         = flag.String("dnn", "internet", "DNN sent as Network Instance")
+	keep        = flag.Bool("keep", false, "Leave the sessions established on exit instead of deleting them")
+)
+
+// mbrSteps are the QER MBRs handed out in turn, in kbps
+var mbrSteps = []uint64{100000, 200000, 500000, 1000000}
+
+// generator plays the SMF (and, with -responses, the UPF) side of the
+// sessions it creates
+type generator struct {
+	smf     *net.UDPConn
+	upf     *net.UDPConn // nil without -responses
+	upfAddr *net.UDPAddr
+	smfIP   net.IP
+	upfIP   net.IP
+	n3IP    net.IP
+	gnbIP   net.IP
+	level   ieRichness
+
+	pool   netip.Prefix
+	nextUE netip.Addr
+
+	seq      uint32
+	sessions uint64 // sessions created so far, seeds SEIDs and TEIDs
+	active   []*session
+
+	established, modified, deleted, sendErrors int
+}
+
+func main() {
+	flag.Parse()
+
+	gen, err := newGenerator()
+	if err != nil {
+		log.Fatalf("pfcp-gen: %v", err)
+	}
+	defer gen.close()
+
+	log.Printf("[INFO] Sending PFCP from %s to %s (%d sessions, %s IEs, churn %.1f/s)",
+		gen.smf.LocalAddr(), gen.upfAddr, *numSessions, *ieLevel, *churn)
+	if gen.upf == nil {
+		log.Println("[INFO] Responses disabled: the sniffer cannot learn the UP SEIDs used by modifications and deletions")
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	var stop <-chan time.Time
+	if *duration > 0 {
+		stop = time.After(*duration)
+	}
+
+	gen.run(sigChan, stop)
+
+	if !*keep {
+		for len(gen.active) > 0 {
+			gen.delete()
+		}
+	}
+	log.Printf("[OK] Established %d, modified %d, deleted %d sessions (%d send errors)",
+		gen.established, gen.modified, gen.deleted, gen.sendErrors)
+}
+
+func newGenerator() (*generator, error) {
+	level, err := parseRichness(*ieLevel)
+	if err != nil {
+		return nil, err
+	}
+	if *numSessions < 1 || *rate <= 0 || *churn < 0 {
+		return nil, fmt.Errorf("-sessions and -rate must be positive and -churn not negative")
+	}
+	pool, err := netip.ParsePrefix(*uePool)
+	if err != nil || !pool.Addr().Is4() {
+		return nil, fmt.Errorf("invalid -ue-pool %q: need an IPv4 prefix", *uePool)
+	}
+	gnbIP := net.ParseIP(*gnbIPFlag).To4()
+	if gnbIP == nil {
+		return nil, fmt.Errorf("invalid -gnb %q: need an IPv4 address", *gnbIPFlag)
+	}
+
+	upfUDP, err := net.ResolveUDPAddr("udp4", *upfAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -upf: %w", err)
+	}
+	smfUDP, err := net.ResolveUDPAddr("udp4", *smfAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -smf: %w", err)
+	}
+	n3IP := upfUDP.IP.To4()
+	if *n3IPFlag != "" {
+		if n3IP = net.ParseIP(*n3IPFlag).To4(); n3IP == nil {
+			return nil, fmt.Errorf("invalid -n3 %q: need an IPv4 address", *n3IPFlag)
+		}
+	}
+
+	smf, err := net.ListenUDP("udp4", smfUDP)
+	if err != nil {
+		return nil, fmt.Errorf("listen on -smf: %w", err)
+	}
+	gen := &generator{
+		smf:     smf,
+		upfAddr: upfUDP,
+		smfIP:   smf.LocalAddr().(*net.UDPAddr).IP.To4(),
+		upfIP:   upfUDP.IP.To4(),
+		n3IP:    n3IP,
+		gnbIP:   gnbIP,
+		level:   level,
+		pool:    pool.Masked(),
+		nextUE:  pool.Masked().Addr(),
+	}
+	if gen.smfIP.IsUnspecified() {
+		gen.smfIP = net.IPv4(127, 0, 0, 1).To4()
+	}
+	go drain(smf)
+
+	if *responses {
+		if gen.upf, err = net.ListenUDP("udp4", upfUDP); err != nil {
+			smf.Close()
+			return nil, fmt.Errorf("listen on -upf for responses (use -responses=false for a remote UPF): %w", err)
+		}
+		go drain(gen.upf)
+	}
+	return gen, nil
+}
+
+func parseRichness(s string) (ieRichness, error) {
+	switch s {
+	case "minimal":
+		return richnessMinimal, nil
+	case "standard":
+		return richnessStandard, nil
+	case "full":
+		return richnessFull, nil
+	}
+	return 0, fmt.Errorf("invalid -ies %q: must be minimal, standard or full", s)
+}
+
+// drain discards whatever arrives on conn so its receive buffer never
+// fills up; it returns once conn is closed
+func drain(conn *net.UDPConn) {
+	buf := make([]byte, 65535)
+	for {
+		if _, _, err := conn.ReadFromUDP(buf); err != nil {
+			return
+		}
+	}
+}
+
+func (g *generator) close() {
+	g.smf.Close()
+	if g.upf != nil {
+		g.upf.Close()
+	}
+}
+
+// run ramps up to -sessions at -rate, then replaces the oldest session at
+// -churn until a signal or the -duration timer fires
+func (g *generator) run(sigChan <-chan os.Signal, stop <-chan time.Time) {
+	ramp := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer ramp.Stop()
+
+	rampC := ramp.C
+	var churnC <-chan time.Time
+	for {
+		select {
+		case <-sigChan:
+			return
+		case <-stop:
+			return
+		case <-rampC:
+			g.establish()
+			if len(g.active) < *numSessions {
+				continue
+			}
+			rampC = nil
+			log.Printf("[OK] %d sessions established", len(g.active))
+			if *churn > 0 {
+				churnTicker := time.NewTicker(time.Duration(float64(time.Second) / *churn))
+				defer churnTicker.Stop()
+				churnC = churnTicker.C
+			}
+		case <-churnC:
+			g.delete()
+			g.establish()
+		}
+	}
+}
+
+// establish creates a session and moves its downlink to the gNB
+func (g *generator) establish() {
+	g.sessions++
+	n := g.sessions
+	s := &session{
+		cpSEID:  n,
+		upSEID:  0x10000000 + n,
+		ueIP:    g.allocUEIP(),
+		ulTEID:  uint32(n),
+		gnbTEID: 0x100000 + uint32(n),
+		qfi:     1,
+		mbrUL:   mbrSteps[n%uint64(len(mbrSteps))],
+		mbrDL:   mbrSteps[(n+1)%uint64(len(mbrSteps))],
+	}
+
+	seq := g.nextSeq()
+	g.request(establishmentRequest(s, seq, g.smfIP, g.n3IP, *dnn, g.level))
+	g.respond(establishmentResponse(s, seq, g.upfIP))
+	g.established++
+	g.active = append(g.active, s)
+
+	seq = g.nextSeq()
+	g.request(modificationRequest(s, seq, g.gnbIP))
+	g.respond(response(msgSessionModificationResponse, s, seq))
+	g.modified++
+}
+
+// delete removes the oldest session
+func (g *generator) delete() {
+	s := g.active[0]
+	g.active = g.active[1:]
+
+	seq := g.nextSeq()
+	g.request(deletionRequest(s, seq))
+	g.respond(response(msgSessionDeletionResponse, s, seq))
+	g.deleted++
+}
+
+func (g *generator) nextSeq() uint32 {
+	g.seq = (g.seq + 1) & 0xFFFFFF
+	return g.seq
+}
+
+// allocUEIP hands out pool addresses in order, skipping the network
+// address and wrapping around at the end of the pool
+func (g *generator) allocUEIP() net.IP {
+	g.nextUE = g.nextUE.Next()
+	if !g.pool.Contains(g.nextUE) {
+		g.nextUE = g.pool.Addr().Next()
+	}
+	return net.IP(g.nextUE.AsSlice())
+}
+
+func (g *generator) request(msg []byte) {
+	if _, err := g.smf.WriteToUDP(msg, g.upfAddr); err != nil {
+		g.sendError(err)
+	}
+}
+
+func (g *generator) respond(msg []byte) {
+	if g.upf == nil {
+		return
+	}
+	if _, err := g.upf.WriteToUDP(msg, g.smf.LocalAddr().(*net.UDPAddr)); err != nil {
+		g.sendError(err)
+	}
+}
+
+func (g *generator) sendError(err error) {
+	if g.sendErrors == 0 {
+		log.Printf("[WARN] Send failed: %v", err)
+	}
+	g.sendErrors++
+}
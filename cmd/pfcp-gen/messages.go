@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// PFCP message types (3GPP TS 29.244 7.3)
+const (
+	msgSessionEstablishmentRequest  = 50
+	msgSessionEstablishmentResponse = 51
+	msgSessionModificationRequest   = 52
+	msgSessionModificationResponse  = 53
+	msgSessionDeletionRequest       = 54
+	msgSessionDeletionResponse      = 55
+)
+
+// PFCP IE types (3GPP TS 29.244 8.1.2)
+const (
+	ieCreatePDR                  = 1
+	iePDI                        = 2
+	ieCreateFAR                  = 3
+	ieForwardingParameters       = 4
+	ieCreateURR                  = 6
+	ieCreateQER                  = 7
+	ieUpdateFAR                  = 10
+	ieUpdateForwardingParameters = 11
+	ieCause                      = 19
+	ieSourceInterface            = 20
+	ieFTEID                      = 21
+	ieNetworkInstance            = 22
+	ieSDFFilter                  = 23
+	ieGateStatus                 = 25
+	ieMBR                        = 26
+	ieGBR                        = 27
+	iePrecedence                 = 29
+	ieVolumeThreshold            = 31
+	ieReportingTriggers          = 37
+	ieDestinationInterface       = 42
+	ieApplyAction                = 44
+	iePDRID                      = 56
+	ieFSEID                      = 57
+	ieNodeID                     = 60
+	ieMeasurementMethod          = 62
+	ieURRID                      = 81
+	ieOuterHeaderCreation        = 84
+	ieCreateBAR                  = 85
+	ieBARID                      = 88
+	ieUEIPAddress                = 93
+	ieOuterHeaderRemoval         = 95
+	ieFARID                      = 108
+	ieQERID                      = 109
+	ieQFI                        = 124
+	ieSuggestedBufferingPackets  = 140
+)
+
+// Interface values of Source/Destination Interface IEs
+const (
+	ifaceAccess = 0
+	ifaceCore   = 1
+)
+
+// Apply Action flags
+const (
+	applyForward = 0x02
+	applyBuffer  = 0x04
+)
+
+// causeAccepted is the Cause value of a successful response
+const causeAccepted = 1
+
+// ieRichness selects how many optional IEs the generator adds
+type ieRichness int
+
+const (
+	richnessMinimal  ieRichness = iota // F-TEID, UE IP and the rules referencing them
+	richnessStandard                   // plus precedence, DNN, QFI and a QER with MBR
+	richnessFull                       // plus SDF filter, GBR, a volume URR and a BAR
+)
+
+// session is the state the generator keeps for one PDU session
+type session struct {
+	cpSEID  uint64 // SMF side, from the request's F-SEID
+	upSEID  uint64 // UPF side, from the response's F-SEID
+	ueIP    net.IP
+	ulTEID  uint32 // UPF N3 F-TEID
+	gnbTEID uint32 // gNB N3 TEID, sent in the modification
+	qfi     uint8
+	mbrUL   uint64 // kbps
+	mbrDL   uint64 // kbps
+}
+
+// ie encodes one IE: type (2) | length (2) | value
+func ie(ieType uint16, value ...[]byte) []byte {
+	n := 0
+	for _, v := range value {
+		n += len(v)
+	}
+	b := make([]byte, 4, 4+n)
+	binary.BigEndian.PutUint16(b[0:2], ieType)
+	binary.BigEndian.PutUint16(b[2:4], uint16(n))
+	for _, v := range value {
+		b = append(b, v...)
+	}
+	return b
+}
+
+func u8(v uint8) []byte { return []byte{v} }
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// bitrate encodes an UL/DL pair of 40-bit kbps values (MBR and GBR IEs)
+func bitrate(ul, dl uint64) []byte {
+	b := make([]byte, 10)
+	for i := 0; i < 5; i++ {
+		b[4-i] = byte(ul >> (8 * i))
+		b[9-i] = byte(dl >> (8 * i))
+	}
+	return b
+}
+
+func nodeID(ip net.IP) []byte {
+	return ie(ieNodeID, u8(0), ip.To4()) // type 0: IPv4 address
+}
+
+func fseid(seid uint64, ip net.IP) []byte {
+	return ie(ieFSEID, u8(0x02), binary.BigEndian.AppendUint64(nil, seid), ip.To4())
+}
+
+func fteid(teid uint32, ip net.IP) []byte {
+	return ie(ieFTEID, u8(0x01), u32(teid), ip.To4())
+}
+
+// ueIPAddress encodes a UE IP Address IE, as destination for downlink PDIs
+func ueIPAddress(ip net.IP, destination bool) []byte {
+	flags := uint8(0x02) // V4
+	if destination {
+		flags |= 0x04 // S/D
+	}
+	return ie(ieUEIPAddress, u8(flags), ip.To4())
+}
+
+// networkInstance encodes a DNN as a single-label APN
+func networkInstance(dnn string) []byte {
+	return ie(ieNetworkInstance, u8(uint8(len(dnn))), []byte(dnn))
+}
+
+// header encodes a PFCP session message header (S=1) for a message whose
+// IEs are body
+func header(msgType uint8, seid uint64, seq uint32, body []byte) []byte {
+	b := make([]byte, 16, 16+len(body))
+	b[0] = 1<<5 | 0x01 // version 1, S flag
+	b[1] = msgType
+	binary.BigEndian.PutUint16(b[2:4], uint16(12+len(body)))
+	binary.BigEndian.PutUint64(b[4:12], seid)
+	binary.BigEndian.PutUint32(b[12:16], seq<<8)
+	return append(b, body...)
+}
+
+// establishmentRequest builds the SMF's request creating an uplink and a
+// downlink PDR. The downlink FAR buffers until the modification supplies
+// the gNB tunnel, as an SMF does before the N2 response.
+func establishmentRequest(s *session, seq uint32, smfIP, upfIP net.IP, dnn string, level ieRichness) []byte {
+	ulPDI := [][]byte{ie(ieSourceInterface, u8(ifaceAccess)), fteid(s.ulTEID, upfIP), ueIPAddress(s.ueIP, false)}
+	dlPDI := [][]byte{ie(ieSourceInterface, u8(ifaceCore)), ueIPAddress(s.ueIP, true)}
+	if level >= richnessStandard {
+		ulPDI = append(ulPDI, networkInstance(dnn), ie(ieQFI, u8(s.qfi)))
+		dlPDI = append(dlPDI, networkInstance(dnn))
+	}
+	if level >= richnessFull {
+		// Flow Description only: flags (1) | spare (1) | length (2) | description
+		desc := "permit out ip from any to assigned"
+		dlPDI = append(dlPDI, ie(ieSDFFilter, u8(0x01), u8(0), u16(uint16(len(desc))), []byte(desc)))
+	}
+
+	ulPDR := [][]byte{ie(iePDRID, u16(1)), ie(iePDI, ulPDI...), ie(ieOuterHeaderRemoval, u8(0)), ie(ieFARID, u32(1))}
+	dlPDR := [][]byte{ie(iePDRID, u16(2)), ie(iePDI, dlPDI...), ie(ieFARID, u32(2))}
+	if level >= richnessStandard {
+		ulPDR = append(ulPDR, ie(iePrecedence, u32(255)), ie(ieQERID, u32(1)))
+		dlPDR = append(dlPDR, ie(iePrecedence, u32(255)), ie(ieQERID, u32(1)))
+	}
+	if level >= richnessFull {
+		ulPDR = append(ulPDR, ie(ieURRID, u32(1)))
+		dlPDR = append(dlPDR, ie(ieURRID, u32(1)))
+	}
+
+	body := append(nodeID(smfIP), fseid(s.cpSEID, smfIP)...)
+	body = append(body, ie(ieCreatePDR, ulPDR...)...)
+	body = append(body, ie(ieCreatePDR, dlPDR...)...)
+	body = append(body, ie(ieCreateFAR,
+		ie(ieFARID, u32(1)),
+		ie(ieApplyAction, u8(applyForward)),
+		ie(ieForwardingParameters, ie(ieDestinationInterface, u8(ifaceCore))),
+	)...)
+	dlFAR := [][]byte{ie(ieFARID, u32(2)), ie(ieApplyAction, u8(applyBuffer))}
+	if level >= richnessFull {
+		dlFAR = append(dlFAR, ie(ieBARID, u8(1)))
+	}
+	body = append(body, ie(ieCreateFAR, dlFAR...)...)
+
+	if level >= richnessStandard {
+		qer := [][]byte{
+			ie(ieQERID, u32(1)),
+			ie(ieGateStatus, u8(0)), // UL and DL open
+			ie(ieMBR, bitrate(s.mbrUL, s.mbrDL)),
+			ie(ieQFI, u8(s.qfi)),
+		}
+		if level >= richnessFull {
+			qer = append(qer, ie(ieGBR, bitrate(s.mbrUL/2, s.mbrDL/2)))
+		}
+		body = append(body, ie(ieCreateQER, qer...)...)
+	}
+	if level >= richnessFull {
+		body = append(body, ie(ieCreateURR,
+			ie(ieURRID, u32(1)),
+			ie(ieMeasurementMethod, u8(0x02)),        // VOLUM
+			ie(ieReportingTriggers, u8(0x01), u8(0)), // VOLTH
+			ie(ieVolumeThreshold, u8(0x01), binary.BigEndian.AppendUint64(nil, 1<<30)),
+		)...)
+		body = append(body, ie(ieCreateBAR, ie(ieBARID, u8(1)), ie(ieSuggestedBufferingPackets, u8(10)))...)
+	}
+
+	// Establishment Requests carry SEID 0, the SMF's SEID is in the F-SEID
+	return header(msgSessionEstablishmentRequest, 0, seq, body)
+}
+
+// establishmentResponse builds the UPF's accepting response, whose F-SEID
+// carries the SEID used by the SMF from then on
+func establishmentResponse(s *session, seq uint32, upfIP net.IP) []byte {
+	body := append(nodeID(upfIP), ie(ieCause, u8(causeAccepted))...)
+	body = append(body, fseid(s.upSEID, upfIP)...)
+	return header(msgSessionEstablishmentResponse, s.cpSEID, seq, body)
+}
+
+// modificationRequest points the downlink FAR at the gNB tunnel
+func modificationRequest(s *session, seq uint32, gnbIP net.IP) []byte {
+	// Outer Header Creation: description (2, GTP-U/UDP/IPv4) | TEID | IPv4
+	ohc := ie(ieOuterHeaderCreation, u16(0x0100), u32(s.gnbTEID), gnbIP.To4())
+	body := ie(ieUpdateFAR,
+		ie(ieFARID, u32(2)),
+		ie(ieApplyAction, u8(applyForward)),
+		ie(ieUpdateForwardingParameters, ie(ieDestinationInterface, u8(ifaceAccess)), ohc),
+	)
+	return header(msgSessionModificationRequest, s.upSEID, seq, body)
+}
+
+func deletionRequest(s *session, seq uint32) []byte {
+	return header(msgSessionDeletionRequest, s.upSEID, seq, nil)
+}
+
+// response builds a Modification or Deletion Response carrying only a Cause
+func response(msgType uint8, s *session, seq uint32) []byte {
+	return header(msgType, s.cpSEID, seq, ie(ieCause, u8(causeAccepted)))
+}
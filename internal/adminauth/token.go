@@ -0,0 +1,34 @@
+// Package adminauth implements the bearer token check of the admin
+// endpoints shared by the agent and the API server, also used for the API
+// server's API key.
+package adminauth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Load reads a token from a file, ignoring surrounding whitespace
+func Load(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
+// Authorized reports whether an Authorization header value carries token
+// as a bearer token. An empty token authorizes nothing.
+func Authorized(header, token string) bool {
+	scheme, credentials, ok := strings.Cut(header, " ")
+	if token == "" || !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(credentials)), []byte(token)) == 1
+}
@@ -0,0 +1,160 @@
+// Package configfile sets command line flags from a JSON or YAML file of
+// flag names and values, e.g. {"log-level": "debug", "drop-recent-max":
+// 500}, and reloads it on SIGHUP for the agent and the API server. Flags
+// given on the command line win over the file. On reload only the flags
+// registered with Reloadable change at runtime; changes to the others are
+// logged as needing a restart.
+package configfile
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file whose values were applied to a flag set
+type File struct {
+	path     string
+	fs       *flag.FlagSet
+	pathFlag string
+
+	mu      sync.Mutex
+	cmdline map[string]bool               // Flags set on the command line
+	values  map[string]string             // Values in effect, by flag name
+	reload  map[string]func(string) error // See Reloadable
+}
+
+// Load reads the config file named by the flag pathFlag of fs and sets the
+// flags it names, except those set on the command line. fs must have been
+// parsed; the file may not name flags fs does not define, nor pathFlag.
+func Load(fs *flag.FlagSet, pathFlag string) (*File, error) {
+	f := &File{
+		path:     fs.Lookup(pathFlag).Value.String(),
+		fs:       fs,
+		pathFlag: pathFlag,
+		cmdline:  make(map[string]bool),
+		values:   make(map[string]string),
+		reload:   make(map[string]func(string) error),
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f.cmdline[fl.Name] = true
+	})
+
+	values, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range values {
+		if f.cmdline[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: -%s: %w", f.path, name, err)
+		}
+		f.values[name] = value
+	}
+	return f, nil
+}
+
+// Path returns the path of the config file
+func (f *File) Path() string {
+	return f.path
+}
+
+// Reloadable makes Reload apply new values of the flag name by calling
+// apply, which should validate the value and return an error to keep the
+// current one. apply does not change the flag itself.
+func (f *File) Reloadable(name string, apply func(value string) error) {
+	f.mu.Lock()
+	f.reload[name] = apply
+	f.mu.Unlock()
+}
+
+// Reload re-reads the config file and applies the values that changed
+// since it was loaded or last reloaded, logging each change. A flag
+// removed from the file goes back to its default. New values of flags that
+// are not reloadable are ignored with a warning, those of flags set on the
+// command line silently. If the file cannot be read or names unknown
+// flags, nothing changes.
+func (f *File) Reload() error {
+	values, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(values)+len(f.values))
+	for name := range values {
+		names = append(names, name)
+	}
+	for name := range f.values {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changed := 0
+	for _, name := range names {
+		if f.cmdline[name] {
+			continue
+		}
+		current, ok := f.values[name]
+		if !ok {
+			current = f.fs.Lookup(name).DefValue
+		}
+		value, ok := values[name]
+		if !ok {
+			value = f.fs.Lookup(name).DefValue
+		}
+		if value == current {
+			continue
+		}
+
+		apply, reloadable := f.reload[name]
+		if !reloadable {
+			log.Printf("[WARN] Config: -%s changed to %q in %s, keeping %q until restart", name, value, f.path, current)
+			continue
+		}
+		if err := apply(value); err != nil {
+			log.Printf("[WARN] Config: invalid -%s %q in %s, keeping %q: %v", name, value, f.path, current, err)
+			continue
+		}
+		log.Printf("[CONFIG] -%s changed from %q to %q", name, current, value)
+		f.values[name] = value
+		changed++
+	}
+	log.Printf("[CONFIG] Reloaded %s: %d settings changed", f.path, changed)
+	return nil
+}
+
+// read parses the config file into flag values
+func (f *File) read() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, one decoder reads both; scalars of any type
+	// decode as their text, which flag.Value.Set parses
+	var values map[string]string
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", f.path, err)
+	}
+	for name := range values {
+		if f.fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown flag -%s", f.path, name)
+		}
+		if name == f.pathFlag {
+			return nil, fmt.Errorf("%s: -%s cannot be set from the config file", f.path, name)
+		}
+	}
+	return values, nil
+}
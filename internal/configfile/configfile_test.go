@@ -0,0 +1,221 @@
+package configfile
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testFlags returns a flag set with -config and three settings, parsed
+// from args after -config path
+func testFlags(t *testing.T, path string, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "config file")
+	fs.String("log-level", "info", "log level")
+	fs.Int("max", 100, "maximum")
+	fs.Int("port", 8080, "port")
+	if err := fs.Parse(append([]string{"-config", path}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureLog returns the log output of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+	return &buf
+}
+
+func flagValues(fs *flag.FlagSet) string {
+	var values []string
+	for _, name := range []string{"log-level", "max", "port"} {
+		values = append(values, name+"="+fs.Lookup(name).Value.String())
+	}
+	return strings.Join(values, " ")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name, content string
+		args          []string
+		want          string
+	}{
+		{"JSON", `{"log-level": "debug", "max": 500}`, nil, "log-level=debug max=500 port=8080"},
+		{"YAML", "log-level: debug\nport: 9000\n", nil, "log-level=debug max=100 port=9000"},
+		{"command line wins", `{"log-level": "debug", "max": 500}`, []string{"-max", "7"}, "log-level=debug max=7 port=8080"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config")
+			writeFile(t, path, tt.content)
+			fs := testFlags(t, path, tt.args...)
+			if _, err := Load(fs, "config"); err != nil {
+				t.Fatal(err)
+			}
+			if got := flagValues(fs); got != tt.want {
+				t.Errorf("flags %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name, content string
+	}{
+		{"unknown flag", `{"nope": 1}`},
+		{"config path", `{"config": "other"}`},
+		{"invalid value", `{"max": "many"}`},
+		{"not a map", `[1, 2]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config")
+			writeFile(t, path, tt.content)
+			if _, err := Load(testFlags(t, path), "config"); err == nil {
+				t.Errorf("Load of %s succeeded", tt.content)
+			}
+		})
+	}
+	if _, err := Load(testFlags(t, filepath.Join(dir, "missing")), "config"); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestReload(t *testing.T) {
+	logs := captureLog(t)
+	path := filepath.Join(t.TempDir(), "config")
+	writeFile(t, path, `{"log-level": "debug", "max": 500, "port": 9000}`)
+	fs := testFlags(t, path)
+	f, err := Load(fs, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var applied []string
+	f.Reloadable("log-level", func(value string) error {
+		applied = append(applied, "log-level="+value)
+		return nil
+	})
+	f.Reloadable("max", func(value string) error {
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("not a positive number")
+		}
+		applied = append(applied, "max="+value)
+		return nil
+	})
+
+	// log-level removed goes back to its default, the invalid max keeps
+	// 500, port is not reloadable
+	writeFile(t, path, `{"max": -1, "port": 9001}`)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(applied, " "); got != "log-level=info" {
+		t.Errorf("applied %q, want log-level=info", got)
+	}
+	for _, want := range []string{
+		`invalid -max "-1"`,
+		`-port changed to "9001"`,
+		`-log-level changed from "debug" to "info"`,
+		"1 settings changed",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("no %q logged in:\n%s", want, logs)
+		}
+	}
+	if got := flagValues(fs); got != "log-level=debug max=500 port=9000" {
+		t.Errorf("Reload changed flags to %s", got)
+	}
+
+	// Fixing max applies it, the unchanged port is still only warned about
+	applied = nil
+	logs.Reset()
+	writeFile(t, path, `{"max": 50, "port": 9001}`)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(applied, " "); got != "max=50" {
+		t.Errorf("applied %q, want max=50", got)
+	}
+	if !strings.Contains(logs.String(), `-port changed to "9001" in `+path+`, keeping "9000"`) {
+		t.Errorf("port change not warned about:\n%s", logs)
+	}
+
+	// Back to the applied values: nothing changes
+	applied = nil
+	writeFile(t, path, `{"max": 50, "port": 9000}`)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("applied %q, want nothing", applied)
+	}
+}
+
+func TestReloadCommandLineWins(t *testing.T) {
+	captureLog(t)
+	path := filepath.Join(t.TempDir(), "config")
+	writeFile(t, path, `{"log-level": "debug"}`)
+	f, err := Load(testFlags(t, path, "-log-level", "warn"), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Reloadable("log-level", func(value string) error {
+		t.Errorf("-log-level set on the command line reloaded to %q", value)
+		return nil
+	})
+
+	writeFile(t, path, `{"log-level": "error"}`)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, `{}`)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadInvalidFile(t *testing.T) {
+	captureLog(t)
+	path := filepath.Join(t.TempDir(), "config")
+	writeFile(t, path, `{"log-level": "debug"}`)
+	f, err := Load(testFlags(t, path), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Reloadable("log-level", func(value string) error {
+		t.Errorf("-log-level reloaded to %q from an invalid file", value)
+		return nil
+	})
+
+	for _, content := range []string{`{"log-level": "warn", "nope": 1}`, `{"log-level": `} {
+		writeFile(t, path, content)
+		if err := f.Reload(); err == nil {
+			t.Errorf("Reload of %s succeeded", content)
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(); err == nil {
+		t.Error("Reload of a missing file succeeded")
+	}
+}
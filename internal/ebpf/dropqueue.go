@@ -0,0 +1,41 @@
+package ebpf
+
+// DefaultDropQueueSize is the number of decoded drop events buffered between
+// the ring buffer reader and OnDropEvent
+const DefaultDropQueueSize = 4096
+
+// SetDropQueueSize sets how many decoded drop events may wait for
+// OnDropEvent. Must be called before StartEventLoop; n <= 0 keeps the
+// default.
+func (l *Loader) SetDropQueueSize(n int) {
+	if n > 0 {
+		l.dropQueueSize = n
+	}
+}
+
+// LostDueToBackpressure returns the number of drop events discarded because
+// OnDropEvent fell behind and the queue was full. The ring buffer reader
+// never waits for the callback, so a slow consumer costs these events
+// instead of stalling the reader and losing events in the kernel.
+func (l *Loader) LostDueToBackpressure() uint64 {
+	return l.dropLost.Load()
+}
+
+// enqueueDropEvent queues event for dispatchDropEvents without blocking
+func (l *Loader) enqueueDropEvent(event DropEvent) {
+	select {
+	case l.dropQueue <- event:
+	default:
+		l.dropLost.Add(1)
+	}
+}
+
+// dispatchDropEvents calls OnDropEvent for each queued event until the
+// reader closes the queue, so events read before a stop are still delivered
+func (l *Loader) dispatchDropEvents() {
+	for event := range l.dropQueue {
+		if l.OnDropEvent != nil {
+			l.OnDropEvent(event)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package ebpf
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// Attachment is a hook a program is attached to
+type Attachment struct {
+	Program string `json:"program"`
+	Kind    string `json:"kind"`   // kprobe, kretprobe, tracepoint
+	Target  string `json:"target"` // kernel function or tracepoint group/name
+}
+
+// ProgramInfo describes a loaded eBPF program
+type ProgramInfo struct {
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	ID         uint32       `json:"id,omitempty"`
+	AttachedTo []Attachment `json:"attached_to"`
+	Unattached bool         `json:"unattached,omitempty"` // loaded, but no hook attached
+}
+
+// MapInfo describes a loaded eBPF map. Entries is the number of keys
+// present, nil for maps without keys (ring buffers).
+type MapInfo struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	ID         uint32 `json:"id,omitempty"`
+	KeySize    uint32 `json:"key_size"`
+	ValueSize  uint32 `json:"value_size"`
+	MaxEntries uint32 `json:"max_entries"`
+	Entries    *int   `json:"entries,omitempty"`
+}
+
+// Info describes what the loader has loaded into the kernel
+type Info struct {
+	Object   string        `json:"object"` // eBPF object path, "embedded" for the built-in one
+	Programs []ProgramInfo `json:"programs"`
+	Maps     []MapInfo     `json:"maps"`
+}
+
+// addLink records an attached hook
+func (l *Loader) addLink(lnk link.Link, program, kind, target string) {
+	l.links = append(l.links, lnk)
+	l.attachments = append(l.attachments, Attachment{Program: program, Kind: kind, Target: target})
+}
+
+// Info lists the loaded programs with their attach points and the maps with
+// their geometry and current entry count, like `bpftool prog/map show`
+func (l *Loader) Info() (Info, error) {
+	if l.objs == nil {
+		return Info{}, fmt.Errorf("eBPF objects not loaded")
+	}
+
+	info := Info{Object: l.objectPath}
+	if info.Object == "" {
+		info.Object = "embedded"
+	}
+
+	progs := reflect.ValueOf(&l.objs.upfMonitorPrograms).Elem()
+	for i := 0; i < progs.NumField(); i++ {
+		name := progs.Type().Field(i).Tag.Get("ebpf")
+		prog, _ := progs.Field(i).Interface().(*ebpf.Program)
+		if prog == nil {
+			continue
+		}
+
+		pi := ProgramInfo{Name: name, Type: prog.Type().String(), AttachedTo: l.programAttachments(name)}
+		if kernelInfo, err := prog.Info(); err == nil {
+			if id, ok := kernelInfo.ID(); ok {
+				pi.ID = uint32(id)
+			}
+		}
+		pi.Unattached = len(pi.AttachedTo) == 0
+		info.Programs = append(info.Programs, pi)
+	}
+
+	maps := reflect.ValueOf(&l.objs.upfMonitorMaps).Elem()
+	for i := 0; i < maps.NumField(); i++ {
+		name := maps.Type().Field(i).Tag.Get("ebpf")
+		m, _ := maps.Field(i).Interface().(*ebpf.Map)
+		if m == nil {
+			continue
+		}
+
+		mi := MapInfo{
+			Name:       name,
+			Type:       m.Type().String(),
+			KeySize:    m.KeySize(),
+			ValueSize:  m.ValueSize(),
+			MaxEntries: m.MaxEntries(),
+		}
+		if kernelInfo, err := m.Info(); err == nil {
+			if id, ok := kernelInfo.ID(); ok {
+				mi.ID = uint32(id)
+			}
+		}
+		if n, ok := countEntries(m); ok {
+			mi.Entries = &n
+		}
+		info.Maps = append(info.Maps, mi)
+	}
+
+	if l.tcEgress != nil {
+		info.Programs = append(info.Programs, ProgramInfo{
+			Name:       tcEgressProgramName,
+			Type:       l.tcEgress.prog.Type().String(),
+			AttachedTo: l.programAttachments(tcEgressProgramName),
+		})
+		m := l.tcEgress.stats
+		entries := int(m.MaxEntries())
+		info.Maps = append(info.Maps, MapInfo{
+			Name:       tcEgressMapName,
+			Type:       m.Type().String(),
+			KeySize:    m.KeySize(),
+			ValueSize:  m.ValueSize(),
+			MaxEntries: m.MaxEntries(),
+			Entries:    &entries,
+		})
+	}
+
+	return info, nil
+}
+
+// programAttachments returns the hooks the named program is attached to
+func (l *Loader) programAttachments(program string) []Attachment {
+	attached := []Attachment{}
+	for _, a := range l.attachments {
+		if a.Program == program {
+			attached = append(attached, a)
+		}
+	}
+	return attached
+}
+
+// countEntries returns the number of keys in m. Arrays always hold
+// max_entries keys; hash maps are walked key by key, at most max_entries
+// steps since a concurrent delete can restart the walk.
+func countEntries(m *ebpf.Map) (int, bool) {
+	switch m.Type() {
+	case ebpf.RingBuf, ebpf.PerfEventArray:
+		return 0, false
+	case ebpf.Array, ebpf.PerCPUArray:
+		return int(m.MaxEntries()), true
+	}
+
+	count := 0
+	var key interface{} // nil returns the first key
+	for count < int(m.MaxEntries()) {
+		next, err := m.NextKeyBytes(key)
+		if err != nil {
+			return 0, false
+		}
+		if next == nil {
+			break
+		}
+		count++
+		key = next
+	}
+	return count, true
+}
@@ -7,6 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/netip"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cilium/ebpf"
@@ -19,8 +22,12 @@ import (
 const (
 	DirectionUplink   = 0
 	DirectionDownlink = 1
+	DirectionUnknown  = 2 // Never emitted by the kernel, set by InferDirection
 )
 
+// GTPUPort is the GTP-U UDP port used on N3/N9
+const GTPUPort = 2152
+
 // Drop reason constants - Direct mapping from gtp5g error codes (1:1)
 // These match exactly with gtp5g/src/gtpu/encap.c definitions
 const (
@@ -51,6 +58,12 @@ type TrafficCounter struct {
 	Timestamp uint64
 }
 
+// TrafficSnapshot is a point-in-time read of both direction counters
+type TrafficSnapshot struct {
+	Uplink   TrafficCounter
+	Downlink TrafficCounter
+}
+
 // DropEvent represents a packet drop event from kernel
 type DropEvent struct {
 	Timestamp uint64
@@ -63,6 +76,10 @@ type DropEvent struct {
 	Reason    uint8
 	Direction uint8
 	_         [2]byte // padding
+
+	// Decoded addresses (not part of the kernel struct)
+	SrcAddr netip.Addr
+	DstAddr netip.Addr
 }
 
 // PacketEvent represents a packet event for detailed tracing
@@ -76,6 +93,10 @@ type PacketEvent struct {
 	Direction uint8
 	QFI       uint8
 	_         [2]byte // padding
+
+	// Decoded addresses (not part of the kernel struct)
+	SrcAddr netip.Addr
+	DstAddr netip.Addr
 }
 
 // SessionInfo represents a PFCP session
@@ -90,11 +111,40 @@ type SessionInfo struct {
 type Loader struct {
 	objs         *upfMonitorObjects
 	links        []link.Link
+	attachments  []Attachment
 	reader       *ringbuf.Reader
 	packetReader *ringbuf.Reader
 	stopChan     chan struct{}
-
-	// Callbacks for events
+	stopOnce     sync.Once
+
+	// Compiled eBPF object to load, the embedded one if empty
+	objectPath string
+
+	// Optional downlink counter at TC egress (see SetTCEgress)
+	tcEgressIface string
+	tcEgress      *tcEgress
+
+	// Short-lived cache of the stats map reads (see SetStatsCacheTTL)
+	cacheMu      sync.Mutex
+	cacheTTL     time.Duration
+	trafficCache TrafficSnapshot
+	trafficAt    time.Time
+	teidCache    map[uint32]TrafficCounter
+	teidAt       time.Time
+	ueIPCache    map[uint32]TrafficCounter
+	ueIPAt       time.Time
+	mapSyscalls  atomic.Uint64
+
+	// Event loop readers, waited for before the maps are closed
+	wg sync.WaitGroup
+
+	// Decoded drop events waiting for OnDropEvent (see SetDropQueueSize)
+	dropQueueSize int
+	dropQueue     chan DropEvent
+	dropLost      atomic.Uint64
+
+	// Callbacks for events. OnDropEvent runs on its own goroutine, fed by
+	// a bounded queue; OnPacketEvent runs on the ring buffer reader.
 	OnDropEvent   func(event DropEvent)
 	OnPacketEvent func(event PacketEvent)
 }
@@ -103,6 +153,9 @@ type Loader struct {
 func NewLoader() *Loader {
 	return &Loader{
 		stopChan: make(chan struct{}),
+		cacheTTL: DefaultStatsCacheTTL,
+
+		dropQueueSize: DefaultDropQueueSize,
 	}
 }
 
@@ -114,8 +167,7 @@ func (l *Loader) Load() error {
 	}
 
 	// Load pre-compiled eBPF programs
-	l.objs = &upfMonitorObjects{}
-	if err := loadUpfMonitorObjects(l.objs, nil); err != nil {
+	if err := l.loadObjects(); err != nil {
 		return fmt.Errorf("failed to load eBPF objects: %w", err)
 	}
 
@@ -130,7 +182,7 @@ func (l *Loader) Load() error {
 		log.Printf("  -> Make sure gtp5g module is compiled with EXPORT_SYMBOL_GPL(gtp5g_trace_drop)")
 		log.Printf("  -> Rebuild gtp5g: cd /path/to/gtp5g && make clean && make && sudo rmmod gtp5g && sudo insmod gtp5g.ko")
 	} else {
-		l.links = append(l.links, kpTraceDrop)
+		l.addLink(kpTraceDrop, "kprobe_gtp5g_trace_drop", "kprobe", "gtp5g_trace_drop")
 		log.Println("✓ Attached kprobe to gtp5g_trace_drop (PRIMARY drop detection)")
 	}
 
@@ -144,7 +196,7 @@ func (l *Loader) Load() error {
 		log.Printf("Warning: failed to attach kprobe to gtp5g_encap_recv: %v", err)
 		log.Printf("Make sure gtp5g module is loaded: sudo insmod /path/to/gtp5g.ko")
 	} else {
-		l.links = append(l.links, kpEncapRecv)
+		l.addLink(kpEncapRecv, "kprobe_gtp5g_encap_recv", "kprobe", "gtp5g_encap_recv")
 		log.Println("✓ Attached kprobe to gtp5g_encap_recv (uplink traffic stats)")
 	}
 
@@ -153,7 +205,7 @@ func (l *Loader) Load() error {
 	if err != nil {
 		log.Printf("Warning: failed to attach kprobe to gtp5g_dev_xmit: %v", err)
 	} else {
-		l.links = append(l.links, kpDevXmit)
+		l.addLink(kpDevXmit, "kprobe_gtp5g_dev_xmit", "kprobe", "gtp5g_dev_xmit")
 		log.Println("✓ Attached kprobe to gtp5g_dev_xmit (downlink traffic stats)")
 	}
 
@@ -167,7 +219,7 @@ func (l *Loader) Load() error {
 	if err != nil {
 		log.Printf("Warning: failed to attach kretprobe to pdr_find_by_gtp1u: %v", err)
 	} else {
-		l.links = append(l.links, krpPdrFindGtp1u)
+		l.addLink(krpPdrFindGtp1u, "kretprobe_pdr_find_by_gtp1u", "kretprobe", "pdr_find_by_gtp1u")
 		log.Println("✓ Attached kretprobe to pdr_find_by_gtp1u (uplink PDR lookup)")
 	}
 
@@ -176,7 +228,7 @@ func (l *Loader) Load() error {
 	if err != nil {
 		log.Printf("Warning: failed to attach kretprobe to pdr_find_by_ipv4: %v", err)
 	} else {
-		l.links = append(l.links, krpPdrFindIpv4)
+		l.addLink(krpPdrFindIpv4, "kretprobe_pdr_find_by_ipv4", "kretprobe", "pdr_find_by_ipv4")
 		log.Println("✓ Attached kretprobe to pdr_find_by_ipv4 (downlink PDR lookup)")
 	}
 
@@ -190,10 +242,23 @@ func (l *Loader) Load() error {
 	if err != nil {
 		log.Printf("Warning: failed to attach tracepoint to kfree_skb: %v", err)
 	} else {
-		l.links = append(l.links, tpKfreeSkb)
+		l.addLink(tpKfreeSkb, "tracepoint_kfree_skb", "tracepoint", "skb/kfree_skb")
 		log.Println("✓ Attached tracepoint to skb/kfree_skb (general kernel drops, disabled by default)")
 	}
 
+	// =========================================================================
+	// OPTIONAL: Downlink counter at TC egress of the N3 interface
+	// Enable with SetTCEgress(iface), replaces the gtp5g_dev_xmit count
+	// =========================================================================
+	if l.tcEgressIface != "" {
+		if err := l.attachTCEgress(l.tcEgressIface); err != nil {
+			log.Printf("Warning: failed to attach TC egress counter to %s: %v", l.tcEgressIface, err)
+			log.Printf("  -> Downlink stats fall back to the gtp5g_dev_xmit kprobe")
+		} else {
+			log.Printf("✓ Attached TC egress counter to %s (downlink traffic stats)", l.tcEgressIface)
+		}
+	}
+
 	// Open ring buffer for drop events
 	l.reader, err = ringbuf.NewReader(l.objs.DropEvents)
 	if err != nil {
@@ -209,10 +274,28 @@ func (l *Loader) Load() error {
 	return nil
 }
 
+// AttachedCount returns the number of hooks currently attached
+func (l *Loader) AttachedCount() int {
+	return len(l.links)
+}
+
 // StartEventLoop starts processing events from ring buffers
 func (l *Loader) StartEventLoop() {
-	go l.readDropEvents()
-	go l.readPacketEvents()
+	l.dropQueue = make(chan DropEvent, l.dropQueueSize)
+	l.wg.Add(3)
+	go func() {
+		defer l.wg.Done()
+		defer close(l.dropQueue)
+		l.readDropEvents()
+	}()
+	go func() {
+		defer l.wg.Done()
+		l.dispatchDropEvents()
+	}()
+	go func() {
+		defer l.wg.Done()
+		l.readPacketEvents()
+	}()
 }
 
 func (l *Loader) readDropEvents() {
@@ -247,23 +330,40 @@ func (l *Loader) readDropEvents() {
 			PktLen:    binary.LittleEndian.Uint32(record.RawSample[24:28]),
 			Reason:    record.RawSample[28],
 			Direction: record.RawSample[29],
+			SrcAddr:   netip.AddrFrom4([4]byte(record.RawSample[12:16])),
+			DstAddr:   netip.AddrFrom4([4]byte(record.RawSample[16:20])),
 		}
 
-		if l.OnDropEvent != nil {
-			l.OnDropEvent(event)
-		}
+		l.enqueueDropEvent(event)
 	}
 }
 
-// GetTrafficStats retrieves current traffic statistics
+// GetTrafficStats retrieves current traffic statistics. Reads within the
+// stats cache TTL share one map read (see SetStatsCacheTTL).
 func (l *Loader) GetTrafficStats() (uplink, downlink TrafficCounter, err error) {
 	if l.objs == nil {
 		return uplink, downlink, fmt.Errorf("eBPF objects not loaded")
 	}
 
+	l.cacheMu.Lock()
+	defer l.cacheMu.Unlock()
+	if !l.cacheFresh(l.trafficAt) {
+		up, down, err := l.readTrafficStats()
+		if err != nil {
+			return uplink, downlink, err
+		}
+		l.trafficCache = TrafficSnapshot{Uplink: up, Downlink: down}
+		l.trafficAt = time.Now()
+	}
+	return l.trafficCache.Uplink, l.trafficCache.Downlink, nil
+}
+
+// readTrafficStats reads and sums the per-CPU direction counters
+func (l *Loader) readTrafficStats() (uplink, downlink TrafficCounter, err error) {
 	// Read uplink stats
 	uplinkKey := uint32(DirectionUplink)
 	var uplinkCounters []TrafficCounter
+	l.mapSyscalls.Add(1)
 	if err := l.objs.TrafficStats.Lookup(&uplinkKey, &uplinkCounters); err != nil {
 		return uplink, downlink, fmt.Errorf("failed to read uplink stats: %w", err)
 	}
@@ -279,6 +379,7 @@ func (l *Loader) GetTrafficStats() (uplink, downlink TrafficCounter, err error)
 	// Read downlink stats
 	downlinkKey := uint32(DirectionDownlink)
 	var downlinkCounters []TrafficCounter
+	l.mapSyscalls.Add(1)
 	if err := l.objs.TrafficStats.Lookup(&downlinkKey, &downlinkCounters); err != nil {
 		return uplink, downlink, fmt.Errorf("failed to read downlink stats: %w", err)
 	}
@@ -291,6 +392,15 @@ func (l *Loader) GetTrafficStats() (uplink, downlink TrafficCounter, err error)
 		}
 	}
 
+	// Downlink measured at TC egress takes precedence over the kprobe
+	// count, adding both would count every packet twice
+	if l.tcEgress != nil {
+		downlink, err = l.readTCEgress()
+		if err != nil {
+			return uplink, downlink, err
+		}
+	}
+
 	return uplink, downlink, nil
 }
 
@@ -302,6 +412,7 @@ func (l *Loader) GetTEIDStats(teid uint32) (TrafficCounter, error) {
 		return counter, fmt.Errorf("eBPF objects not loaded")
 	}
 
+	l.mapSyscalls.Add(1)
 	if err := l.objs.TeidStats.Lookup(&teid, &counter); err != nil {
 		return counter, err // Not found is also an error
 	}
@@ -309,50 +420,43 @@ func (l *Loader) GetTEIDStats(teid uint32) (TrafficCounter, error) {
 	return counter, nil
 }
 
-// GetAllTEIDStats retrieves traffic statistics for all TEIDs
+// GetAllTEIDStats retrieves traffic statistics for all TEIDs. The returned
+// map is the caller's; reads within the stats cache TTL share one map read.
 func (l *Loader) GetAllTEIDStats() (map[uint32]TrafficCounter, error) {
-	result := make(map[uint32]TrafficCounter)
-
 	if l.objs == nil {
-		return result, fmt.Errorf("eBPF objects not loaded")
-	}
-
-	var key uint32
-	var value TrafficCounter
-
-	iter := l.objs.TeidStats.Iterate()
-	for iter.Next(&key, &value) {
-		result[key] = value
+		return make(map[uint32]TrafficCounter), fmt.Errorf("eBPF objects not loaded")
 	}
 
-	if err := iter.Err(); err != nil {
-		return result, fmt.Errorf("failed to iterate teid_stats: %w", err)
+	l.cacheMu.Lock()
+	defer l.cacheMu.Unlock()
+	if !l.cacheFresh(l.teidAt) {
+		stats, err := l.readCounterMap(l.objs.TeidStats)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read teid_stats: %w", err)
+		}
+		l.teidCache, l.teidAt = stats, time.Now()
 	}
-
-	return result, nil
+	return copyCounters(l.teidCache), nil
 }
 
-// GetAllUEIPStats retrieves traffic statistics for all UE IPs (downlink)
+// GetAllUEIPStats retrieves traffic statistics for all UE IPs (downlink).
+// The returned map is the caller's; reads within the stats cache TTL share
+// one map read.
 func (l *Loader) GetAllUEIPStats() (map[uint32]TrafficCounter, error) {
-	result := make(map[uint32]TrafficCounter)
-
 	if l.objs == nil {
-		return result, fmt.Errorf("eBPF objects not loaded")
-	}
-
-	var key uint32
-	var value TrafficCounter
-
-	iter := l.objs.UeIpStats.Iterate()
-	for iter.Next(&key, &value) {
-		result[key] = value
+		return make(map[uint32]TrafficCounter), fmt.Errorf("eBPF objects not loaded")
 	}
 
-	if err := iter.Err(); err != nil {
-		return result, fmt.Errorf("failed to iterate ue_ip_stats: %w", err)
+	l.cacheMu.Lock()
+	defer l.cacheMu.Unlock()
+	if !l.cacheFresh(l.ueIPAt) {
+		stats, err := l.readCounterMap(l.objs.UeIpStats)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read ue_ip_stats: %w", err)
+		}
+		l.ueIPCache, l.ueIPAt = stats, time.Now()
 	}
-
-	return result, nil
+	return copyCounters(l.ueIPCache), nil
 }
 
 // UpdateSessionMapping adds or updates a TEID to session mapping
@@ -403,74 +507,61 @@ func (l *Loader) EnableDropTracing(enabled bool) error {
 	return l.objs.AgentConfig.Update(&key, &value, ebpf.UpdateAny)
 }
 
-// Close cleans up resources
-func (l *Loader) Close() {
-	close(l.stopChan)
+// StopEventLoop stops reading the ring buffers, so no more events reach the
+// callbacks, while the programs stay attached and the maps keep counting.
+// It returns once the drop events already queued and the callbacks in
+// flight are done.
+func (l *Loader) StopEventLoop() {
+	l.stopOnce.Do(func() {
+		close(l.stopChan)
 
-	if l.reader != nil {
-		l.reader.Close()
-	}
+		if l.reader != nil {
+			l.reader.Close()
+		}
 
-	if l.packetReader != nil {
-		l.packetReader.Close()
-	}
+		if l.packetReader != nil {
+			l.packetReader.Close()
+		}
+	})
+
+	// Closing the readers unblocks Read, wait for the callbacks in flight
+	// before the objects they may touch go away
+	l.wg.Wait()
+}
+
+// Close cleans up resources
+func (l *Loader) Close() {
+	l.StopEventLoop()
 
 	for _, lnk := range l.links {
 		lnk.Close()
 	}
 
+	if l.tcEgress != nil {
+		l.tcEgress.detach()
+		l.tcEgress.close()
+	}
+
 	if l.objs != nil {
 		l.objs.Close()
 	}
 }
 
+// ParseEventIP converts an IPv4 address as read from a kernel struct
+// (network byte order, loaded as little-endian uint32) to a netip.Addr
+func ParseEventIP(ip uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(ip), byte(ip >> 8), byte(ip >> 16), byte(ip >> 24)})
+}
+
 // FormatIP converts a uint32 IP to string
 func FormatIP(ip uint32) string {
-	return fmt.Sprintf("%d.%d.%d.%d",
-		byte(ip), byte(ip>>8), byte(ip>>16), byte(ip>>24))
+	return ParseEventIP(ip).String()
 }
 
-// FormatDropReason converts drop reason code to string
-// Direct 1:1 mapping with gtp5g error codes
+// FormatDropReason converts drop reason code to string using the current
+// ReasonClassifier (see SetReasonClassifier)
 func FormatDropReason(reason uint8) string {
-	switch reason {
-	case DropReasonPktDropped:
-		return "PKT_DROPPED"
-	case DropReasonEchoRespCreate:
-		return "ECHO_RESP_CREATE"
-	case DropReasonNoRoute:
-		return "NO_ROUTE"
-	case DropReasonPullFailed:
-		return "PULL_FAILED"
-	case DropReasonInvalidExtHdr:
-		return "INVALID_EXT_HDR"
-	case DropReasonNoPDR:
-		return "NO_PDR"
-	case DropReasonGeneral:
-		return "GENERAL"
-	case DropReasonULGateClosed:
-		return "UL_GATE_CLOSED"
-	case DropReasonDLGateClosed:
-		return "DL_GATE_CLOSED"
-	case DropReasonPDRNull:
-		return "PDR_NULL"
-	case DropReasonNoFTEID:
-		return "NO_F_TEID"
-	case DropReasonURRReportFail:
-		return "URR_REPORT_FAIL"
-	case DropReasonREDPacket:
-		return "RED_PACKET"
-	case DropReasonIPXmitFail:
-		return "IP_XMIT_FAIL"
-	case DropReasonNotTPDU:
-		return "NOT_TPDU"
-	case DropReasonPullHdrFail:
-		return "PULL_HDR_FAIL"
-	case DropReasonNetifRxFail:
-		return "NETIF_RX_FAIL"
-	default:
-		return "UNKNOWN"
-	}
+	return currentReasons().Name(uint32(reason))
 }
 
 // FormatDirection converts direction code to string
@@ -485,6 +576,42 @@ func FormatDirection(direction uint8) string {
 	}
 }
 
+// InferDirection resolves the direction of a drop event.
+//
+// The kernel program only knows the direction when it can see a GTP-U port
+// on the dropped skb; otherwise it leaves the field at 0, which would be
+// indistinguishable from a real uplink drop. The rules, in order, are:
+//
+//  1. dst port 2152: uplink (GTP-U arriving on N3/N9)
+//  2. src port 2152: downlink (GTP-U leaving towards the gNB/peer UPF)
+//  3. non-zero TEID: uplink (only encapsulated N3/N9 traffic carries a TEID)
+//  4. dst IP is a known UE IP: downlink (arrived on N6 towards the UE)
+//  5. src IP is a known UE IP: uplink (decapsulated packet from the UE)
+//  6. otherwise: DirectionUnknown
+//
+// isUEIP may be nil, in which case rules 4 and 5 are skipped.
+func InferDirection(event DropEvent, isUEIP func(ip uint32) bool) uint8 {
+	switch {
+	case event.DstPort == GTPUPort:
+		return DirectionUplink
+	case event.SrcPort == GTPUPort:
+		return DirectionDownlink
+	case event.TEID != 0:
+		return DirectionUplink
+	}
+
+	if isUEIP != nil {
+		if event.DstIP != 0 && isUEIP(event.DstIP) {
+			return DirectionDownlink
+		}
+		if event.SrcIP != 0 && isUEIP(event.SrcIP) {
+			return DirectionUplink
+		}
+	}
+
+	return DirectionUnknown
+}
+
 // FormatTimestamp converts nanosecond timestamp to time.Time
 func FormatTimestamp(ns uint64) time.Time {
 	return time.Unix(0, int64(ns))
@@ -522,6 +649,8 @@ func (l *Loader) readPacketEvents() {
 			PktLen:    binary.LittleEndian.Uint32(record.RawSample[24:28]),
 			Direction: record.RawSample[28],
 			QFI:       record.RawSample[29],
+			SrcAddr:   netip.AddrFrom4([4]byte(record.RawSample[12:16])),
+			DstAddr:   netip.AddrFrom4([4]byte(record.RawSample[16:20])),
 		}
 
 		if l.OnPacketEvent != nil {
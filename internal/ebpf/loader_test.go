@@ -0,0 +1,53 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/cilium/ebpf/rlimit"
+	"go.uber.org/goleak"
+)
+
+// newRingBufReader returns a reader of a new, empty ring buffer, skipping
+// the test where BPF maps cannot be created (no CAP_BPF)
+func newRingBufReader(t *testing.T) *ringbuf.Reader {
+	t.Helper()
+	if err := rlimit.RemoveMemlock(); err != nil {
+		t.Skipf("cannot remove memlock limit: %v", err)
+	}
+	m, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: 4096})
+	if err != nil {
+		t.Skipf("cannot create ring buffer map: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	reader, err := ringbuf.NewReader(m)
+	if err != nil {
+		t.Fatalf("ring buffer reader: %v", err)
+	}
+	return reader
+}
+
+func TestCloseStopsEventLoop(t *testing.T) {
+	l := NewLoader()
+	l.reader = newRingBufReader(t)
+	l.packetReader = newRingBufReader(t)
+	l.OnDropEvent = func(DropEvent) {}
+	l.OnPacketEvent = func(PacketEvent) {}
+
+	l.StartEventLoop()
+	l.Close()
+	goleak.VerifyNone(t)
+}
+
+func TestStopEventLoopTwice(t *testing.T) {
+	l := NewLoader()
+	l.reader = newRingBufReader(t)
+	l.packetReader = newRingBufReader(t)
+
+	l.StartEventLoop()
+	l.StopEventLoop()
+	l.StopEventLoop()
+	l.Close()
+	goleak.VerifyNone(t)
+}
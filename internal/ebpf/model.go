@@ -0,0 +1,23 @@
+package ebpf
+
+import (
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// Model converts the drop event to its JSON representation. The direction
+// is taken as-is, so resolve it with InferDirection first.
+func (e DropEvent) Model(ts time.Time) model.DropEvent {
+	return model.DropEvent{
+		Timestamp: ts.Format(time.RFC3339),
+		TEID:      model.FormatTEID(e.TEID),
+		SrcIP:     e.SrcAddr.String(),
+		DstIP:     e.DstAddr.String(),
+		SrcPort:   e.SrcPort,
+		DstPort:   e.DstPort,
+		PktLen:    e.PktLen,
+		Reason:    FormatDropReason(e.Reason),
+		Direction: FormatDirection(e.Direction),
+	}
+}
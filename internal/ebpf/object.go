@@ -0,0 +1,96 @@
+package ebpf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// NewLoaderWithObject creates a loader that loads the compiled eBPF object
+// at path instead of the one embedded at build time, for trying out new
+// programs without rebuilding the agent. An empty path uses the embedded
+// object. The object is read and validated by Load.
+func NewLoaderWithObject(path string) *Loader {
+	l := NewLoader()
+	l.objectPath = path
+	return l
+}
+
+// ObjectPath returns the eBPF object the loader uses, or "" for the
+// embedded one
+func (l *Loader) ObjectPath() string {
+	return l.objectPath
+}
+
+// loadObjects loads the eBPF object into l.objs, from l.objectPath if set
+func (l *Loader) loadObjects() error {
+	l.objs = &upfMonitorObjects{}
+	if l.objectPath == "" {
+		return loadUpfMonitorObjects(l.objs, nil)
+	}
+
+	spec, err := ebpf.LoadCollectionSpec(l.objectPath)
+	if err != nil {
+		return fmt.Errorf("failed to read eBPF object %s: %w", l.objectPath, err)
+	}
+	if err := validateObjectSpec(spec); err != nil {
+		return fmt.Errorf("eBPF object %s is not compatible with this agent: %w", l.objectPath, err)
+	}
+	return spec.LoadAndAssign(l.objs, nil)
+}
+
+// validateObjectSpec checks that spec has every program and map the agent
+// uses, and that the maps have the key and value sizes of the embedded
+// object, since the agent decodes their entries into fixed Go structs
+func validateObjectSpec(spec *ebpf.CollectionSpec) error {
+	embedded, err := loadUpfMonitor()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, name := range specNames(reflect.TypeOf(upfMonitorProgramSpecs{})) {
+		if _, ok := spec.Programs[name]; !ok {
+			problems = append(problems, fmt.Sprintf("missing program %q", name))
+		}
+	}
+	for _, name := range specNames(reflect.TypeOf(upfMonitorMapSpecs{})) {
+		m, ok := spec.Maps[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing map %q", name))
+			continue
+		}
+		want, ok := embedded.Maps[name]
+		if !ok {
+			continue
+		}
+		if m.Type != want.Type {
+			problems = append(problems, fmt.Sprintf("map %q is %s, want %s", name, m.Type, want.Type))
+		}
+		if m.KeySize != want.KeySize || m.ValueSize != want.ValueSize {
+			problems = append(problems, fmt.Sprintf("map %q has key/value size %d/%d, want %d/%d",
+				name, m.KeySize, m.ValueSize, want.KeySize, want.ValueSize))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// specNames returns the object names of a bpf2go specs struct, from its
+// `ebpf` field tags
+func specNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("ebpf"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
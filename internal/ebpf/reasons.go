@@ -0,0 +1,119 @@
+package ebpf
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnknownDropReason is the name of drop reason codes a classifier does not
+// know, so that unexpected codes cannot grow the label set
+const UnknownDropReason = "UNKNOWN"
+
+// ReasonClassifier names the numeric drop reasons emitted by the eBPF
+// program. Forked programs may use other codes than gtp5g's.
+type ReasonClassifier interface {
+	// Name returns the label for code, UnknownDropReason if unmapped
+	Name(code uint32) string
+
+	// All returns every mapped code and its name
+	All() map[uint32]string
+}
+
+// ReasonMap is a ReasonClassifier backed by a fixed code to name map
+type ReasonMap map[uint32]string
+
+// Name implements ReasonClassifier
+func (m ReasonMap) Name(code uint32) string {
+	if name, ok := m[code]; ok {
+		return name
+	}
+	return UnknownDropReason
+}
+
+// All implements ReasonClassifier
+func (m ReasonMap) All() map[uint32]string {
+	all := make(map[uint32]string, len(m))
+	for code, name := range m {
+		all[code] = name
+	}
+	return all
+}
+
+// DefaultReasons maps the gtp5g error codes of the embedded program
+var DefaultReasons = ReasonMap{
+	DropReasonPktDropped:     "PKT_DROPPED",
+	DropReasonEchoRespCreate: "ECHO_RESP_CREATE",
+	DropReasonNoRoute:        "NO_ROUTE",
+	DropReasonPullFailed:     "PULL_FAILED",
+	DropReasonInvalidExtHdr:  "INVALID_EXT_HDR",
+	DropReasonNoPDR:          "NO_PDR",
+	DropReasonGeneral:        "GENERAL",
+	DropReasonULGateClosed:   "UL_GATE_CLOSED",
+	DropReasonDLGateClosed:   "DL_GATE_CLOSED",
+	DropReasonPDRNull:        "PDR_NULL",
+	DropReasonNoFTEID:        "NO_F_TEID",
+	DropReasonURRReportFail:  "URR_REPORT_FAIL",
+	DropReasonREDPacket:      "RED_PACKET",
+	DropReasonIPXmitFail:     "IP_XMIT_FAIL",
+	DropReasonNotTPDU:        "NOT_TPDU",
+	DropReasonPullHdrFail:    "PULL_HDR_FAIL",
+	DropReasonNetifRxFail:    "NETIF_RX_FAIL",
+	DropReasonUnknown:        UnknownDropReason,
+}
+
+// reasonClassifier is used by FormatDropReason
+var reasonClassifier atomic.Value // of reasonClassifierBox
+
+// reasonClassifierBox gives atomic.Value a single concrete type
+type reasonClassifierBox struct{ ReasonClassifier }
+
+// SetReasonClassifier makes FormatDropReason use c, DefaultReasons if nil
+func SetReasonClassifier(c ReasonClassifier) {
+	if c == nil {
+		c = DefaultReasons
+	}
+	reasonClassifier.Store(reasonClassifierBox{c})
+}
+
+func currentReasons() ReasonClassifier {
+	if box, ok := reasonClassifier.Load().(reasonClassifierBox); ok {
+		return box.ReasonClassifier
+	}
+	return DefaultReasons
+}
+
+// LoadReasonMap reads drop reason names from a JSON or YAML file mapping
+// codes (decimal or 0x hex) to names, e.g. {"6": "NO_PDR", "0x20": "MY_DROP"}.
+// The file overrides DefaultReasons code by code; codes it does not list
+// keep their default name.
+func LoadReasonMap(path string) (ReasonMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, one decoder reads both
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	reasons := DefaultReasons.All()
+	for key, name := range raw {
+		code, err := strconv.ParseUint(strings.TrimSpace(key), 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid drop reason code %q", path, key)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("%s: empty name for drop reason code %s", path, key)
+		}
+		reasons[uint32(code)] = name
+	}
+	return reasons, nil
+}
@@ -0,0 +1,232 @@
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+)
+
+// SelfTestCheck is the outcome of one self-test step. Optional steps
+// depend on something other than the kernel (the gtp5g module) and do not
+// fail the self-test.
+type SelfTestCheck struct {
+	Name     string
+	OK       bool
+	Optional bool
+	Detail   string
+}
+
+// SelfTestReport is the result of SelfTest
+type SelfTestReport struct {
+	Kernel string // kernel release, e.g. 5.15.0-91-generic
+	BTF    string // vmlinux BTF availability
+	Object string // eBPF object tested, "embedded" for the built-in one
+	Checks []SelfTestCheck
+}
+
+// Passed reports whether every required check passed
+func (r *SelfTestReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.OK && !c.Optional {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *SelfTestReport) add(name string, err error, detail string) bool {
+	check := SelfTestCheck{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+	return err == nil
+}
+
+// selfTestKprobes are the gtp5g hooks Load attaches. They need the gtp5g
+// module, so they are optional in the self-test.
+var selfTestKprobes = []struct {
+	program string
+	symbol  string
+	ret     bool
+}{
+	{"kprobe_gtp5g_trace_drop", "gtp5g_trace_drop", false},
+	{"kprobe_gtp5g_encap_recv", "gtp5g_encap_recv", false},
+	{"kprobe_gtp5g_dev_xmit", "gtp5g_dev_xmit", false},
+	{"kretprobe_pdr_find_by_gtp1u", "pdr_find_by_gtp1u", true},
+	{"kretprobe_pdr_find_by_ipv4", "pdr_find_by_ipv4", true},
+}
+
+// SelfTest checks that the eBPF object (objectPath, empty for the embedded
+// one) works on the running kernel: it loads the programs, checks the maps
+// against the embedded object, reads the freshly created counters, which
+// must be zero, attaches the kfree_skb tracepoint, the TC egress counter on
+// iface (e.g. lo) and the gtp5g kprobes if the module is loaded, then
+// detaches everything. Needs the same privileges as the agent.
+func SelfTest(objectPath, iface string) *SelfTestReport {
+	report := &SelfTestReport{
+		Kernel: kernelRelease(),
+		BTF:    btfAvailability(),
+		Object: objectPath,
+	}
+	if report.Object == "" {
+		report.Object = "embedded"
+	}
+
+	if !report.add("memlock", rlimit.RemoveMemlock(), "memlock limit removed") {
+		return report
+	}
+
+	l := NewLoaderWithObject(objectPath)
+	defer l.Close()
+	if !report.add("load", l.loadObjects(), "programs and maps loaded") {
+		return report
+	}
+
+	report.add("maps", l.checkMaps(), "all maps present with the expected type, key and value sizes")
+	report.add("counters", l.checkZeroCounters(), "traffic_stats, teid_stats and ue_ip_stats read, all zero")
+
+	tp, err := link.Tracepoint("skb", "kfree_skb", l.objs.TracepointKfreeSkb, nil)
+	if report.add("attach tracepoint skb/kfree_skb", err, "attached") {
+		l.addLink(tp, "tracepoint_kfree_skb", "tracepoint", "skb/kfree_skb")
+	}
+
+	report.add("attach tc egress "+iface, l.attachTCEgress(iface), "clsact qdisc and filter added")
+
+	_, gtp5gErr := os.Stat("/sys/module/gtp5g")
+	progs := l.programsByName()
+	for _, kp := range selfTestKprobes {
+		name := "attach kprobe " + kp.symbol
+		if kp.ret {
+			name = "attach kretprobe " + kp.symbol
+		}
+		if gtp5gErr != nil {
+			report.Checks = append(report.Checks, SelfTestCheck{Name: name, Optional: true, Detail: "skipped, gtp5g module not loaded"})
+			continue
+		}
+		attach, kind := link.Kprobe, "kprobe"
+		if kp.ret {
+			attach, kind = link.Kretprobe, "kretprobe"
+		}
+		lnk, err := attach(kp.symbol, progs[kp.program], nil)
+		report.add(name, err, "attached")
+		report.Checks[len(report.Checks)-1].Optional = true
+		if err == nil {
+			l.addLink(lnk, kp.program, kind, kp.symbol)
+		}
+	}
+
+	hooks := len(l.links)
+	if l.tcEgress != nil {
+		hooks++
+	}
+	report.add("detach", l.detach(), fmt.Sprintf("%d hooks detached", hooks))
+	return report
+}
+
+// checkMaps compares the loaded maps with the embedded object
+func (l *Loader) checkMaps() error {
+	embedded, err := loadUpfMonitor()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	maps := reflect.ValueOf(&l.objs.upfMonitorMaps).Elem()
+	for i := 0; i < maps.NumField(); i++ {
+		name := maps.Type().Field(i).Tag.Get("ebpf")
+		m, _ := maps.Field(i).Interface().(*ebpf.Map)
+		want, ok := embedded.Maps[name]
+		switch {
+		case m == nil:
+			problems = append(problems, fmt.Sprintf("map %q missing", name))
+		case !ok:
+		case m.Type() != want.Type:
+			problems = append(problems, fmt.Sprintf("map %q is %s, want %s", name, m.Type(), want.Type))
+		case m.KeySize() != want.KeySize || m.ValueSize() != want.ValueSize:
+			problems = append(problems, fmt.Sprintf("map %q has key/value size %d/%d, want %d/%d",
+				name, m.KeySize(), m.ValueSize(), want.KeySize, want.ValueSize))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkZeroCounters reads the counter maps, which nothing has written to
+// before any program is attached
+func (l *Loader) checkZeroCounters() error {
+	uplink, downlink, err := l.readTrafficStats()
+	if err != nil {
+		return err
+	}
+	if uplink.Packets != 0 || downlink.Packets != 0 {
+		return fmt.Errorf("traffic_stats not zero after load: %d uplink, %d downlink packets", uplink.Packets, downlink.Packets)
+	}
+	for name, m := range map[string]*ebpf.Map{"teid_stats": l.objs.TeidStats, "ue_ip_stats": l.objs.UeIpStats} {
+		counters, err := l.iterateCounterMap(m)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(counters) != 0 {
+			return fmt.Errorf("%s has %d entries after load", name, len(counters))
+		}
+	}
+	return nil
+}
+
+// programsByName returns the loaded programs by their object name
+func (l *Loader) programsByName() map[string]*ebpf.Program {
+	progs := make(map[string]*ebpf.Program)
+	v := reflect.ValueOf(&l.objs.upfMonitorPrograms).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if prog, ok := v.Field(i).Interface().(*ebpf.Program); ok && prog != nil {
+			progs[v.Type().Field(i).Tag.Get("ebpf")] = prog
+		}
+	}
+	return progs
+}
+
+// detach closes the links and removes the TC egress filter, reporting the
+// first failure. Close then only releases the objects.
+func (l *Loader) detach() error {
+	var first error
+	for _, lnk := range l.links {
+		if err := lnk.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	l.links = nil
+	if l.tcEgress != nil {
+		l.tcEgress.detach()
+		l.tcEgress.close()
+		l.tcEgress = nil
+	}
+	return first
+}
+
+// kernelRelease returns the running kernel release
+func kernelRelease() string {
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return "unknown (" + err.Error() + ")"
+	}
+	return strings.TrimSpace(string(release))
+}
+
+// btfAvailability describes whether the kernel exposes its BTF, which
+// CO-RE relocations of the programs need
+func btfAvailability() string {
+	if _, err := btf.LoadKernelSpec(); err != nil {
+		return "unavailable (" + err.Error() + ")"
+	}
+	return "available (/sys/kernel/btf/vmlinux)"
+}
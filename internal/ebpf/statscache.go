@@ -0,0 +1,94 @@
+package ebpf
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// DefaultStatsCacheTTL is how long a stats map read is reused by later
+// callers, unless changed with SetStatsCacheTTL
+const DefaultStatsCacheTTL = 500 * time.Millisecond
+
+// cacheFresh reports whether a map read taken at "at" may still be
+// reused, caller must hold l.cacheMu
+func (l *Loader) cacheFresh(at time.Time) bool {
+	return !at.IsZero() && time.Since(at) < l.cacheTTL
+}
+
+// SetStatsCacheTTL sets how long GetTrafficStats, GetAllTEIDStats and
+// GetAllUEIPStats reuse a map read, so several callers within the window
+// (stats collector, API handlers, scrapes) cost one set of BPF syscalls.
+// 0 disables the cache.
+func (l *Loader) SetStatsCacheTTL(ttl time.Duration) {
+	l.cacheMu.Lock()
+	l.cacheTTL = ttl
+	l.cacheMu.Unlock()
+}
+
+// MapSyscalls returns the number of BPF syscalls issued to read the stats
+// maps, for comparing cache and batch settings
+func (l *Loader) MapSyscalls() uint64 {
+	return l.mapSyscalls.Load()
+}
+
+// readCounterMap reads a TrafficCounter hash map with BPF_MAP_LOOKUP_BATCH,
+// falling back to key-by-key iteration (two syscalls per entry) on kernels
+// without batch support (< 5.6)
+func (l *Loader) readCounterMap(m *ebpf.Map) (map[uint32]TrafficCounter, error) {
+	result := make(map[uint32]TrafficCounter)
+
+	// One batch covers the whole map unless entries are added while it
+	// is read
+	size := int(m.MaxEntries())
+	keys := make([]uint32, size)
+	values := make([]TrafficCounter, size)
+
+	var prev interface{} // nil starts at the first key
+	var next uint32
+	for {
+		l.mapSyscalls.Add(1)
+		n, err := m.BatchLookup(prev, &next, keys, values, nil)
+		for i := 0; i < n; i++ {
+			result[keys[i]] = values[i]
+		}
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return result, nil
+		}
+		if errors.Is(err, ebpf.ErrNotSupported) {
+			return l.iterateCounterMap(m)
+		}
+		if err != nil {
+			return result, err
+		}
+		prev = next
+	}
+}
+
+// iterateCounterMap reads a TrafficCounter hash map key by key
+func (l *Loader) iterateCounterMap(m *ebpf.Map) (map[uint32]TrafficCounter, error) {
+	result := make(map[uint32]TrafficCounter)
+
+	var key uint32
+	var value TrafficCounter
+
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		l.mapSyscalls.Add(2) // next key + lookup
+		result[key] = value
+	}
+	l.mapSyscalls.Add(1) // final next key
+
+	return result, iter.Err()
+}
+
+// copyCounters returns a copy of a cached counter map, so callers cannot
+// modify the cache
+func copyCounters(m map[uint32]TrafficCounter) map[uint32]TrafficCounter {
+	out := make(map[uint32]TrafficCounter, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+)
+
+// newCounterMap returns a TrafficCounter hash map holding entries counters,
+// skipping the test where BPF maps cannot be created (no CAP_BPF)
+func newCounterMap(tb testing.TB, entries int) *ebpf.Map {
+	tb.Helper()
+	if err := rlimit.RemoveMemlock(); err != nil {
+		tb.Skipf("cannot remove memlock limit: %v", err)
+	}
+	m, err := ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Hash,
+		KeySize:    4,
+		ValueSize:  uint32(binary.Size(TrafficCounter{})),
+		MaxEntries: 1024,
+	})
+	if err != nil {
+		tb.Skipf("cannot create hash map: %v", err)
+	}
+	tb.Cleanup(func() { m.Close() })
+	for i := 0; i < entries; i++ {
+		if err := m.Put(uint32(i), TrafficCounter{Packets: uint64(i), Bytes: uint64(i) * 100}); err != nil {
+			tb.Fatalf("put %d: %v", i, err)
+		}
+	}
+	return m
+}
+
+func checkCounters(t *testing.T, got map[uint32]TrafficCounter, entries int) {
+	t.Helper()
+	if len(got) != entries {
+		t.Fatalf("read %d counters, want %d", len(got), entries)
+	}
+	for key, counter := range got {
+		if counter.Packets != uint64(key) || counter.Bytes != uint64(key)*100 {
+			t.Errorf("counter %d = %+v", key, counter)
+		}
+	}
+}
+
+func TestIterateCounterMapSyscalls(t *testing.T) {
+	const entries = 100
+	m := newCounterMap(t, entries)
+	l := NewLoader()
+
+	got, err := l.iterateCounterMap(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCounters(t, got, entries)
+	if n := l.MapSyscalls(); n != 2*entries+1 {
+		t.Errorf("%d syscalls, want %d", n, 2*entries+1)
+	}
+}
+
+func TestReadCounterMapSyscalls(t *testing.T) {
+	const entries = 100
+	m := newCounterMap(t, entries)
+	l := NewLoader()
+
+	got, err := l.readCounterMap(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCounters(t, got, entries)
+	// One batch covers the map, or the failed batch and the iteration on
+	// kernels without batch lookups
+	if n := l.MapSyscalls(); n != 1 && n != 1+2*entries+1 {
+		t.Errorf("%d syscalls, want 1 or %d", n, 1+2*entries+1)
+	}
+}
+
+func BenchmarkReadCounterMap(b *testing.B) {
+	m := newCounterMap(b, 1000)
+	for _, read := range []struct {
+		name string
+		read func(*Loader, *ebpf.Map) (map[uint32]TrafficCounter, error)
+	}{
+		{"batch", (*Loader).readCounterMap},
+		{"iterate", (*Loader).iterateCounterMap},
+	} {
+		b.Run(read.name, func(b *testing.B) {
+			l := NewLoader()
+			for i := 0; i < b.N; i++ {
+				if _, err := read.read(l, m); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(l.MapSyscalls())/float64(b.N), "syscalls/op")
+		})
+	}
+}
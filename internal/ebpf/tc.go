@@ -0,0 +1,335 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"syscall"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+// TC egress downlink counter
+//
+// The gtp5g kprobes count downlink in gtp5g_dev_xmit. Where those hooks are
+// unavailable, or to measure what actually leaves the box, SetTCEgress also
+// attaches a classifier at TC egress of the N3 interface. It counts the
+// GTP-U packets (UDP dst port 2152, IPv4 over Ethernet) leaving towards the
+// gNB and always lets them pass.
+//
+// Attaching performs the equivalent of
+//
+//	tc qdisc add dev <iface> clsact
+//	tc filter add dev <iface> egress prio <tcFilterPriority> bpf direct-action obj ...
+//
+// Close deletes the filter, and the clsact qdisc if the loader created it.
+const (
+	tcEgressProgramName = "dpop_tc_egress"
+	tcEgressMapName     = "tc_egress_stats"
+	tcFilterPriority    = 0xd0f
+)
+
+// SetTCEgress attaches the downlink counter at TC egress of iface (empty
+// disables it). Must be called before Load.
+func (l *Loader) SetTCEgress(iface string) {
+	l.tcEgressIface = iface
+}
+
+// TCEgressAttached reports whether the TC egress counter is attached, in
+// which case downlink traffic stats come from it
+func (l *Loader) TCEgressAttached() bool {
+	return l.tcEgress != nil
+}
+
+// tcEgress is an attached TC egress counter
+type tcEgress struct {
+	prog         *ebpf.Program
+	stats        *ebpf.Map
+	ifindex      int
+	createdQdisc bool
+}
+
+// attachTCEgress loads the egress counter and attaches it to iface
+func (l *Loader) attachTCEgress(iface string) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+
+	stats, err := ebpf.NewMap(&ebpf.MapSpec{
+		Name:       tcEgressMapName,
+		Type:       ebpf.PerCPUArray,
+		KeySize:    4,
+		ValueSize:  24, // struct traffic_counter
+		MaxEntries: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s map: %w", tcEgressMapName, err)
+	}
+
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         tcEgressProgramName,
+		Type:         ebpf.SchedCLS,
+		License:      "GPL",
+		Instructions: tcEgressInstructions(stats.FD()),
+	})
+	if err != nil {
+		stats.Close()
+		return fmt.Errorf("failed to load TC egress program: %w", err)
+	}
+
+	tc := &tcEgress{prog: prog, stats: stats, ifindex: ifi.Index}
+	created, err := tcAddClsact(ifi.Index)
+	if err != nil {
+		tc.close()
+		return fmt.Errorf("failed to add clsact qdisc: %w", err)
+	}
+	tc.createdQdisc = created
+	if err := tcAddEgressFilter(ifi.Index, prog.FD()); err != nil {
+		tc.detach()
+		tc.close()
+		return fmt.Errorf("failed to add egress filter: %w", err)
+	}
+
+	l.tcEgress = tc
+	l.attachments = append(l.attachments, Attachment{Program: tcEgressProgramName, Kind: "tc-egress", Target: iface})
+	return nil
+}
+
+// readTCEgress sums the per-CPU egress counters
+func (l *Loader) readTCEgress() (TrafficCounter, error) {
+	var total TrafficCounter
+	var counters []TrafficCounter
+	key := uint32(0)
+	l.mapSyscalls.Add(1)
+	if err := l.tcEgress.stats.Lookup(&key, &counters); err != nil {
+		return total, fmt.Errorf("failed to read TC egress stats: %w", err)
+	}
+	for _, c := range counters {
+		total.Packets += c.Packets
+		total.Bytes += c.Bytes
+		if c.Timestamp > total.Timestamp {
+			total.Timestamp = c.Timestamp
+		}
+	}
+	return total, nil
+}
+
+// detach removes the filter and, if we added it, the clsact qdisc
+func (tc *tcEgress) detach() {
+	if err := tcDelEgressFilter(tc.ifindex); err != nil && !errors.Is(err, syscall.ENOENT) {
+		log.Printf("Warning: failed to delete TC egress filter: %v", err)
+	}
+	if tc.createdQdisc {
+		if err := tcDelClsact(tc.ifindex); err != nil && !errors.Is(err, syscall.ENOENT) {
+			log.Printf("Warning: failed to delete clsact qdisc: %v", err)
+		}
+	}
+}
+
+func (tc *tcEgress) close() {
+	tc.prog.Close()
+	tc.stats.Close()
+}
+
+// tcEgressInstructions is the egress classifier: count skb->len of IPv4
+// UDP packets to port 2152 into the map, return TC_ACT_OK for everything.
+// Offsets assume an Ethernet device and a little-endian host.
+func tcEgressInstructions(statsFD int) asm.Instructions {
+	const (
+		ethHdrLen   = 14
+		ipProtoUDP  = 17
+		ethPIPv4    = 0x0008 // htons(ETH_P_IP) as loaded from skb->protocol
+		gtpuPortBE  = 0x6808 // htons(2152)
+		skbLen      = 0      // offsetof(struct __sk_buff, len)
+		skbProtocol = 16     // offsetof(struct __sk_buff, protocol)
+		ipHdrSlot   = -24    // 20 byte IPv4 header on the stack
+		portSlot    = -28    // UDP destination port
+		keySlot     = -4     // map key
+	)
+
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.LoadMem(asm.R1, asm.R6, skbProtocol, asm.Word),
+		asm.JNE.Imm(asm.R1, ethPIPv4, "out"),
+
+		// IPv4 header
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethHdrLen),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, ipHdrSlot),
+		asm.Mov.Imm(asm.R4, 20),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.RFP, ipHdrSlot+9, asm.Byte),
+		asm.JNE.Imm(asm.R1, ipProtoUDP, "out"),
+
+		// UDP destination port, after IHL*4 bytes of IP header
+		asm.LoadMem(asm.R2, asm.RFP, ipHdrSlot, asm.Byte),
+		asm.And.Imm(asm.R2, 0x0f),
+		asm.LSh.Imm(asm.R2, 2),
+		asm.Add.Imm(asm.R2, ethHdrLen+2),
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, portSlot),
+		asm.Mov.Imm(asm.R4, 2),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.RFP, portSlot, asm.Half),
+		asm.JNE.Imm(asm.R1, gtpuPortBE, "out"),
+
+		// counter = map[0]
+		asm.StoreImm(asm.RFP, keySlot, 0, asm.Word),
+		asm.LoadMapPtr(asm.R1, statsFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keySlot),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.Mov.Reg(asm.R7, asm.R0),
+
+		// Per-CPU value, no atomics needed
+		asm.LoadMem(asm.R1, asm.R7, 0, asm.DWord),
+		asm.Add.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R7, 0, asm.R1, asm.DWord),
+		asm.LoadMem(asm.R2, asm.R6, skbLen, asm.Word),
+		asm.LoadMem(asm.R1, asm.R7, 8, asm.DWord),
+		asm.Add.Reg(asm.R1, asm.R2),
+		asm.StoreMem(asm.R7, 8, asm.R1, asm.DWord),
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.R7, 16, asm.R0, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("out"), // TC_ACT_OK
+		asm.Return(),
+	}
+}
+
+// rtnetlink traffic control, see linux/rtnetlink.h and linux/pkt_sched.h
+const (
+	tcHClsact    = 0xfffffff1 // TC_H_CLSACT, also the qdisc's parent
+	tcHClsactMaj = 0xffff0000 // TC_H_MAKE(TC_H_CLSACT, 0), the qdisc's handle
+	tcHEgress    = 0xfffffff3 // TC_H_MAKE(TC_H_CLSACT, TC_H_MIN_EGRESS)
+
+	tcaKind    = 1
+	tcaOptions = 2
+
+	tcaBPFFD            = 6
+	tcaBPFName          = 7
+	tcaBPFFlags         = 8
+	tcaBPFFlagActDirect = 1
+
+	ethPAll = 0x0003
+)
+
+// tcAddClsact adds a clsact qdisc to the interface. Returns false if one
+// was already there.
+func tcAddClsact(ifindex int) (bool, error) {
+	msg := tcMsg(ifindex, tcHClsactMaj, tcHClsact, 0)
+	msg = appendAttr(msg, tcaKind, []byte("clsact\x00"))
+	err := rtnetlinkRequest(syscall.RTM_NEWQDISC, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, msg)
+	if errors.Is(err, syscall.EEXIST) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func tcDelClsact(ifindex int) error {
+	msg := tcMsg(ifindex, tcHClsactMaj, tcHClsact, 0)
+	msg = appendAttr(msg, tcaKind, []byte("clsact\x00"))
+	return rtnetlinkRequest(syscall.RTM_DELQDISC, 0, msg)
+}
+
+func tcAddEgressFilter(ifindex, progFD int) error {
+	var opts []byte
+	opts = appendAttr(opts, tcaBPFFD, binary.LittleEndian.AppendUint32(nil, uint32(progFD)))
+	opts = appendAttr(opts, tcaBPFName, []byte(tcEgressProgramName+"\x00"))
+	opts = appendAttr(opts, tcaBPFFlags, binary.LittleEndian.AppendUint32(nil, tcaBPFFlagActDirect))
+
+	msg := tcMsg(ifindex, 0, tcHEgress, tcFilterInfo())
+	msg = appendAttr(msg, tcaKind, []byte("bpf\x00"))
+	msg = appendAttr(msg, tcaOptions, opts)
+	return rtnetlinkRequest(syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, msg)
+}
+
+func tcDelEgressFilter(ifindex int) error {
+	msg := tcMsg(ifindex, 0, tcHEgress, tcFilterInfo())
+	msg = appendAttr(msg, tcaKind, []byte("bpf\x00"))
+	return rtnetlinkRequest(syscall.RTM_DELTFILTER, 0, msg)
+}
+
+// tcFilterInfo is tcm_info of our filter: priority and protocol (ETH_P_ALL,
+// in network byte order)
+func tcFilterInfo() uint32 {
+	return tcFilterPriority<<16 | uint32(ethPAll)<<8
+}
+
+// tcMsg encodes a struct tcmsg
+func tcMsg(ifindex int, handle, parent, info uint32) []byte {
+	b := make([]byte, 20)
+	b[0] = syscall.AF_UNSPEC
+	binary.LittleEndian.PutUint32(b[4:], uint32(ifindex))
+	binary.LittleEndian.PutUint32(b[8:], handle)
+	binary.LittleEndian.PutUint32(b[12:], parent)
+	binary.LittleEndian.PutUint32(b[16:], info)
+	return b
+}
+
+// appendAttr appends a netlink attribute, padded to 4 bytes
+func appendAttr(b []byte, typ uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(syscall.SizeofRtAttr+len(value)))
+	b = binary.LittleEndian.AppendUint16(b, typ)
+	b = append(b, value...)
+	for len(b)%syscall.NLMSG_ALIGNTO != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// rtnetlinkRequest sends one rtnetlink request and waits for its ack
+func rtnetlinkRequest(msgType uint16, flags int, body []byte) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	const seq = 1
+	msg := make([]byte, syscall.NLMSG_HDRLEN, syscall.NLMSG_HDRLEN+len(body))
+	binary.LittleEndian.PutUint32(msg[0:], uint32(syscall.NLMSG_HDRLEN+len(body)))
+	binary.LittleEndian.PutUint16(msg[4:], msgType)
+	binary.LittleEndian.PutUint16(msg[6:], uint16(syscall.NLM_F_REQUEST|syscall.NLM_F_ACK|flags))
+	binary.LittleEndian.PutUint32(msg[8:], seq)
+	msg = append(msg, body...)
+	if err := syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8192)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != seq || m.Header.Type != syscall.NLMSG_ERROR {
+				continue
+			}
+			if len(m.Data) < 4 {
+				return fmt.Errorf("short netlink error message")
+			}
+			if errno := int32(binary.LittleEndian.Uint32(m.Data)); errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topic identifies a stream of events on the bus
+type Topic string
+
+// Well-known topics
+const (
+	TopicDrops    Topic = "drops"    // Packet drop events
+	TopicSessions Topic = "sessions" // PFCP session lifecycle events
+	TopicTraffic  Topic = "traffic"  // Periodic traffic statistics
+)
+
+// DefaultBufferSize is the per-subscriber buffer used when none is given
+const DefaultBufferSize = 256
+
+// Event is a single message published on the bus
+type Event struct {
+	Topic     Topic
+	Timestamp time.Time
+	Payload   interface{}
+}
+
+// Subscription receives events for one topic on a buffered channel.
+// If the subscriber falls behind and the buffer is full, new events are
+// dropped for that subscriber only (see Dropped).
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	topic   Topic
+	bus     *Bus
+	dropped atomic.Uint64
+	closed  bool // guarded by bus.mu
+}
+
+// Dropped returns the number of events discarded because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Drain calls fn for every event already buffered, without waiting for
+// more. Sinks use it on shutdown so that the last batch is not lost.
+func (s *Subscription) Drain(fn func(Event)) {
+	for {
+		select {
+		case event, ok := <-s.ch:
+			if !ok {
+				return
+			}
+			fn(event)
+		default:
+			return
+		}
+	}
+}
+
+// Close unsubscribes and closes the event channel
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Bus is a small in-process pub/sub used to decouple event producers
+// (eBPF loader, PFCP sniffer, metric collectors) from consumers (WebSocket
+// broadcaster, file/Kafka sinks, ...). Publishing never blocks.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[Topic][]*Subscription
+
+	// Events discarded for any subscriber, including closed ones
+	dropped atomic.Uint64
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[Topic][]*Subscription),
+	}
+}
+
+// Subscribe registers a new subscriber for topic with the given buffer size
+func (b *Bus) Subscribe(topic Topic, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+
+	ch := make(chan Event, buffer)
+	sub := &Subscription{
+		C:     ch,
+		ch:    ch,
+		topic: topic,
+		bus:   b,
+	}
+
+	b.mu.Lock()
+	b.subs[topic] = append(b.subs[topic], sub)
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *Bus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+
+	subs := b.subs[sub.topic]
+	for i, s := range subs {
+		if s == sub {
+			b.subs[sub.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	close(sub.ch)
+}
+
+// Publish delivers payload to every subscriber of topic without blocking.
+// A nil bus is valid and discards everything, so producers don't need to
+// check whether a bus was configured.
+func (b *Bus) Publish(topic Topic, payload interface{}) {
+	if b == nil {
+		return
+	}
+
+	event := Event{
+		Topic:     topic,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs[topic] {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of events discarded because a subscriber's
+// buffer was full, over all subscribers since the bus was created
+func (b *Bus) Dropped() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.dropped.Load()
+}
+
+// SubscriberCount returns the number of subscribers for topic
+func (b *Bus) SubscriberCount(topic Topic) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[topic])
+}
@@ -0,0 +1,23 @@
+// Package httpcache implements the conditional request helpers shared by
+// the agent and the API server.
+package httpcache
+
+import "strings"
+
+// Matches reports whether an If-None-Match header value matches etag, i.e.
+// the client's cached copy is current and 304 Not Modified can be sent.
+// The header may list several tags or be "*"; weak tags (W/"...") compare
+// equal to their strong form, as RFC 9110 prescribes for If-None-Match.
+func Matches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Package jsonbody implements the strict JSON request body decoding shared
+// by the agent and the API server.
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxBytes is the default limit on request bodies
+const MaxBytes = 1 << 20
+
+// ErrEmpty is returned by Decode for a request without a body, which
+// handlers with defaults for every field may accept
+var ErrEmpty = errors.New("request body is empty")
+
+// Decode reads the JSON request body into v. Unlike BindJSON it rejects
+// fields v does not have, so a typo in a field name is reported instead of
+// ignored, data after the JSON value, and bodies over limit bytes, which
+// are not read further. The errors are meant to be returned to the client
+// with 400 Bad Request.
+func Decode(w http.ResponseWriter, r *http.Request, v any, limit int64) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return describe(err, limit)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return describe(err, limit)
+		}
+		return errors.New("request body must hold a single JSON value")
+	}
+	return nil
+}
+
+// describe turns a decoding error into a message naming the problem
+func describe(err error, limit int64) error {
+	var (
+		tooLarge  *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return fmt.Errorf("request body larger than %d bytes", limit)
+	case errors.Is(err, io.EOF):
+		return ErrEmpty
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("request body is truncated JSON")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at byte %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Errorf("request body must be a JSON %s, got %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value)
+		}
+		return fmt.Errorf("field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// DisallowUnknownFields has no error type of its own
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return err
+}
+
+// jsonKind names the JSON type a Go kind is decoded from
+func jsonKind(kind string) string {
+	switch kind {
+	case "struct", "map":
+		return "object"
+	case "slice", "array":
+		return "array"
+	}
+	return kind
+}
@@ -0,0 +1,137 @@
+// Package loglevel filters the standard logger by level, for the agent and
+// the API server. Log lines are leveled by their tag: [DEBUG] is debug,
+// [WARN] (or an untagged "Warning: ...") warn, [ERROR] error and other
+// tags ([INFO], [OK], [PFCP], ...) info. Lines without a tag, which include
+// the log.Fatal messages, and [CONFIG] lines reporting runtime changes are
+// always printed. Indented lines ("   └─ ...") follow the line above them.
+package loglevel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// always is the level of the lines printed at every level
+const always = slog.LevelError + 1
+
+// level is the minimum level printed, info until Set
+var level slog.LevelVar
+
+// Parse parses a level name: debug, info, warn or error
+func Parse(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be debug, info, warn or error", s)
+}
+
+// Name returns the name Parse accepts for l
+func Name(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
+
+// Set changes the minimum level printed, it takes effect immediately
+func Set(l slog.Level) {
+	level.Set(l)
+}
+
+// Level returns the minimum level printed
+func Level() slog.Level {
+	return level.Level()
+}
+
+// Install filters the standard logger's output to w. The logger's date
+// and time header is written by the filter, after the line was leveled.
+func Install(w io.Writer) {
+	log.SetOutput(&filter{out: w, header: log.Flags()&(log.Ldate|log.Ltime) != 0})
+	log.SetFlags(0)
+}
+
+// Writer returns a writer passing writes to w while messages at l are
+// printed, e.g. for the log of a library leveling nothing itself
+func Writer(l slog.Level, w io.Writer) io.Writer {
+	return &levelWriter{level: l, out: w}
+}
+
+type levelWriter struct {
+	level slog.Level
+	out   io.Writer
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if w.level < level.Level() {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// filter is the output of the standard logger, which writes one line per
+// call
+type filter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	header  bool
+	printed bool // The last unindented line was printed
+}
+
+func (f *filter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, continued := lineLevel(p)
+	if !continued {
+		f.printed = l >= level.Level()
+	}
+	if !f.printed {
+		return len(p), nil
+	}
+	if f.header {
+		if _, err := io.WriteString(f.out, time.Now().Format("2006/01/02 15:04:05 ")); err != nil {
+			return 0, err
+		}
+	}
+	return f.out.Write(p)
+}
+
+// lineLevel returns the level of a log line, or whether it continues the
+// line above
+func lineLevel(line []byte) (l slog.Level, continued bool) {
+	line = bytes.TrimLeft(line, "\n")
+	if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+		return 0, true
+	}
+	if bytes.HasPrefix(line, []byte("Warning")) {
+		return slog.LevelWarn, false
+	}
+	if len(line) == 0 || line[0] != '[' {
+		return always, false
+	}
+	tag, _, ok := bytes.Cut(line[1:], []byte("]"))
+	if !ok {
+		return always, false
+	}
+	switch string(tag) {
+	case "DEBUG":
+		return slog.LevelDebug, false
+	case "WARN":
+		return slog.LevelWarn, false
+	case "ERROR":
+		return slog.LevelError, false
+	case "CONFIG":
+		return always, false
+	}
+	return slog.LevelInfo, false
+}
@@ -0,0 +1,227 @@
+// Package model defines the JSON data transfer objects shared by the agent
+// and the API server. The agent serializes these types and the API server
+// decodes the very same types, so the two sides cannot drift apart.
+//
+// This package must stay free of dependencies on the eBPF and PFCP packages
+// so that the API server does not pull in libpcap or the BPF loader;
+// conversions from the domain types live next to those types instead
+// (ebpf.DropEvent.Model, pfcp.Session.Model).
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrafficStats represents traffic statistics
+type TrafficStats struct {
+	Uplink   DirectionStats `json:"uplink"`
+	Downlink DirectionStats `json:"downlink"`
+}
+
+// DirectionStats represents stats for a single direction
+type DirectionStats struct {
+	Packets     uint64  `json:"packets"`
+	Bytes       uint64  `json:"bytes"`
+	Throughput  float64 `json:"throughput_mbps"`
+	Smoothed    float64 `json:"throughput_mbps_smoothed"` // EWMA of Throughput
+	LastUpdated string  `json:"last_updated"`
+}
+
+// DropEvent represents a single drop event
+type DropEvent struct {
+	Timestamp string `json:"timestamp"`
+	TEID      string `json:"teid"`
+	SrcIP     string `json:"src_ip"`
+	DstIP     string `json:"dst_ip"`
+	SrcPort   uint16 `json:"src_port"`
+	DstPort   uint16 `json:"dst_port"`
+	PktLen    uint32 `json:"pkt_len"`
+	Reason    string `json:"reason"`
+	Direction string `json:"direction"`
+
+	// Number of identical drops (same TEID, reason and direction) this
+	// event stands for when the agent coalesces drops, 0 otherwise
+	Count uint64 `json:"count,omitempty"`
+
+	// Whether PFCP correlation knows the TEID, nil for drops without one.
+	// false means the control and data plane are out of sync, or the
+	// agent missed the PFCP messages announcing the TEID.
+	Correlated *bool `json:"correlated,omitempty"`
+
+	// UE IP of the session owning the TEID, empty while no known session
+	// does. The API server fills it in for drops correlated later.
+	UEIP string `json:"ue_ip,omitempty"`
+}
+
+// PartitionKey keys drop events by TEID, or by UE IP for non-GTP traffic,
+// so that events for one session stay in the same Kafka partition
+func (d DropEvent) PartitionKey() string {
+	if d.TEID != FormatTEID(0) {
+		return d.TEID
+	}
+	if d.Direction == "downlink" {
+		return d.DstIP
+	}
+	return d.SrcIP
+}
+
+// FlowTraffic represents per-destination traffic for ULCL path differentiation
+type FlowTraffic struct {
+	DestIP     string `json:"dest_ip"`
+	Packets    uint64 `json:"packets"`
+	Bytes      uint64 `json:"bytes"`
+	LastActive string `json:"last_active,omitempty"`
+	OuterDst   string `json:"outer_dst,omitempty"` // Next hop UPF or gateway
+}
+
+// BARInfo is the Buffering Action Rule of a session
+type BARInfo struct {
+	ID                  uint8  `json:"id"`
+	NotificationDelayMs uint32 `json:"dl_data_notification_delay_ms,omitempty"`
+	SuggestedPackets    uint8  `json:"suggested_buffering_packets,omitempty"`
+}
+
+// PDRInfo is a Packet Detection Rule of a session. Sessions list them in
+// the order the UPF evaluates them: by precedence, lowest first, then by ID.
+type PDRInfo struct {
+	ID              uint16 `json:"id"`
+	Precedence      uint32 `json:"precedence"`
+	SourceInterface string `json:"source_interface,omitempty"`
+	FTEID           string `json:"f_teid,omitempty"`    // Local TEID matched by the PDR
+	FTEIDAddr       string `json:"f_teid_ip,omitempty"` // Local address of the F-TEID
+	FARID           uint32 `json:"far_id,omitempty"`
+}
+
+// ReportedUsageInfo is the traffic volume the UPF reported for a session in
+// PFCP Usage Reports, summed over all reports
+type ReportedUsageInfo struct {
+	TotalBytes    uint64 `json:"total_bytes"`
+	UplinkBytes   uint64 `json:"uplink_bytes"`
+	DownlinkBytes uint64 `json:"downlink_bytes"`
+	Reports       uint64 `json:"reports"`
+	LastReport    string `json:"last_report"`
+}
+
+// SessionInfo represents a PDU session (extended)
+type SessionInfo struct {
+	SEID      string   `json:"seid"`
+	UEIP      string   `json:"ue_ip"`
+	UEIPs     []string `json:"ue_ips,omitempty"` // All UE IPs (dual-stack)
+	TEIDs     []string `json:"teids"`
+	TEIDUL    string   `json:"teid_ul,omitempty"` // Uplink TEID (gNB -> UPF)
+	TEIDDL    string   `json:"teid_dl,omitempty"` // Downlink TEID (UPF -> gNB)
+	CreatedAt string   `json:"created_at"`
+	PacketsUL uint64   `json:"packets_ul"`
+	PacketsDL uint64   `json:"packets_dl"`
+
+	// Extended fields
+	UPFIP        string `json:"upf_ip,omitempty"`
+	SMFIP        string `json:"smf_ip,omitempty"` // Controlling SMF
+	GNBIP        string `json:"gnb_ip,omitempty"`
+	UplinkPeerIP string `json:"uplink_peer_ip,omitempty"`
+	N9PeerIP     string `json:"n9_peer_ip,omitempty"` // N9 peer UPF IP (for ULCL)
+	SUPI         string `json:"supi,omitempty"`
+	DNN          string `json:"dnn,omitempty"`
+	SNssai       string `json:"s_nssai,omitempty"`
+	QFI          uint8  `json:"qfi,omitempty"`
+	SessionType  string `json:"session_type,omitempty"`
+	SessionID    uint8  `json:"pdu_session_id,omitempty"`
+
+	// Application context installed on the PDRs
+	SDFFilters []string `json:"sdf_filters,omitempty"`
+	AppIDs     []string `json:"app_ids,omitempty"`
+
+	// PDRs in evaluation order, to find rules shadowing each other
+	PDRs []PDRInfo `json:"pdrs,omitempty"`
+
+	// Traffic statistics
+	BytesUL uint64 `json:"bytes_ul"`
+	BytesDL uint64 `json:"bytes_dl"`
+
+	// Volumes reported by the UPF, to cross-check against BytesUL/BytesDL
+	ReportedUsage *ReportedUsageInfo `json:"reported_usage,omitempty"`
+
+	// Per-flow traffic (for ULCL path differentiation)
+	FlowTraffic []FlowTraffic `json:"flow_traffic,omitempty"`
+
+	// QoS parameters
+	QoS5QI      uint8  `json:"qos_5qi,omitempty"`
+	ARPPL       uint8  `json:"arp_priority,omitempty"`
+	GBRUplink   uint64 `json:"gbr_ul_kbps,omitempty"`
+	GBRDownlink uint64 `json:"gbr_dl_kbps,omitempty"`
+	MBRUplink   uint64 `json:"mbr_ul_kbps,omitempty"`
+	MBRDownlink uint64 `json:"mbr_dl_kbps,omitempty"`
+
+	// Downlink buffering (UE idle): a FAR buffers, drops and latency are
+	// expected rather than packet loss
+	Buffering bool     `json:"buffering,omitempty"`
+	BAR       *BARInfo `json:"bar,omitempty"`
+
+	// Status
+	Status                  string `json:"status"`
+	Duration                string `json:"duration,omitempty"`
+	LastActive              string `json:"last_active,omitempty"`
+	IncompleteEstablishment bool   `json:"incomplete_establishment,omitempty"`
+
+	// Operator annotations (PUT /sessions/:seid/tags), not from PFCP
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// FormatTEID formats a TEID the way it appears in the JSON APIs
+func FormatTEID(teid uint32) string {
+	return fmt.Sprintf("0x%x", teid)
+}
+
+// FormatSEID formats a SEID the way it appears in the JSON APIs: lowercase
+// hex with a 0x prefix, the only form SessionInfo.SEID is stored in
+func FormatSEID(seid uint64) string {
+	return fmt.Sprintf("0x%x", seid)
+}
+
+// ParseSEID parses a SEID given by a client, either 0x-prefixed hex or
+// decimal. FormatSEID of the result is the canonical form to match
+// SessionInfo.SEID against.
+func ParseSEID(s string) (uint64, error) {
+	return parseID(s, "SEID", 64)
+}
+
+// ParseTEID parses a TEID given by a client with the rules of ParseSEID.
+// FormatTEID of the result is the canonical form of SessionInfo.TEIDs.
+func ParseTEID(s string) (uint32, error) {
+	teid, err := parseID(s, "TEID", 32)
+	return uint32(teid), err
+}
+
+// parseID parses a bits wide ID, either 0x-prefixed hex or decimal. A
+// leading 0 is not octal and other prefixes (0b, 0o) are not accepted.
+func parseID(s, name string, bits int) (uint64, error) {
+	s = strings.TrimSpace(s)
+	digits, base := s, 10
+	if hex, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		digits, base = hex, 16
+	}
+	id, err := strconv.ParseUint(digits, base, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q, must be 0x hex or decimal", name, s)
+	}
+	return id, nil
+}
+
+// FormatDuration formats a duration into a human-readable string
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
@@ -0,0 +1,81 @@
+package model
+
+import "testing"
+
+func TestParseSEID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"0x1a2b", 0x1a2b},
+		{"0X1A2B", 0x1a2b},
+		{"6699", 0x1a2b},
+		{" 0x1a2b ", 0x1a2b},
+		{"0", 0},
+		{"0xffffffffffffffff", 1<<64 - 1},
+		{"18446744073709551615", 1<<64 - 1},
+	}
+	for _, tt := range tests {
+		got, err := ParseSEID(tt.in)
+		if err != nil {
+			t.Errorf("ParseSEID(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSEID(%q) = 0x%x, want 0x%x", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSEIDInvalid(t *testing.T) {
+	for _, in := range []string{"", "0x", "1a2b", "-1", "0x1g", "0x10000000000000000"} {
+		if _, err := ParseSEID(in); err == nil {
+			t.Errorf("ParseSEID(%q) accepted", in)
+		}
+	}
+}
+
+func TestFormatSEIDRoundTrip(t *testing.T) {
+	if got := FormatSEID(0x1A2B); got != "0x1a2b" {
+		t.Errorf("FormatSEID(0x1a2b) = %q, want 0x1a2b", got)
+	}
+	// Both input forms of one SEID meet in the canonical form
+	for _, in := range []string{"0x1A2B", "6699"} {
+		seid, err := ParseSEID(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatSEID(seid); got != "0x1a2b" {
+			t.Errorf("FormatSEID(ParseSEID(%q)) = %q, want 0x1a2b", in, got)
+		}
+	}
+}
+
+func TestParseTEID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"0x1a", 0x1a},
+		{"26", 26},
+		{"010", 10}, // Not octal
+		{"0xffffffff", 1<<32 - 1},
+	}
+	for _, tt := range tests {
+		got, err := ParseTEID(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTEID(%q) = 0x%x, %v, want 0x%x", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "0b1", "0o7", "1a", "0x100000000", "4294967296"} {
+		if _, err := ParseTEID(in); err == nil {
+			t.Errorf("ParseTEID(%q) accepted", in)
+		}
+	}
+	// TEIDs and SEIDs given alike are the same number
+	teid, _ := ParseTEID("010")
+	seid, _ := ParseSEID("010")
+	if uint64(teid) != seid {
+		t.Errorf("\"010\" is TEID %d but SEID %d", teid, seid)
+	}
+}
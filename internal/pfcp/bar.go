@@ -0,0 +1,172 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"slices"
+	"time"
+)
+
+// IE types of buffering rules and of the FAR fields that decide whether a
+// FAR buffers (TS 29.244 8.1.2)
+const (
+	IETypeApplyAction                   = 44  // Apply Action
+	IETypeDLDataNotificationDelay       = 46  // Downlink Data Notification Delay (within a BAR)
+	IETypeCreateBAR                     = 85  // Create BAR
+	IETypeUpdateBAR                     = 86  // Update BAR (Session Modification Request)
+	IETypeRemoveBAR                     = 87  // Remove BAR
+	IETypeBARID                         = 88  // BAR ID
+	IETypeFARID                         = 108 // FAR ID
+	IETypeSuggestedBufferingPacketCount = 140 // Suggested Buffering Packets Count
+)
+
+// applyActionBUFF is the Apply Action flag telling the UPF to buffer
+// downlink packets, typically while the UE is idle (TS 29.244 8.2.26)
+const applyActionBUFF = 0x04
+
+// dlDataNotificationDelayUnit is the unit of Downlink Data Notification
+// Delay values (TS 29.244 8.2.28)
+const dlDataNotificationDelayUnit = 50 * time.Millisecond
+
+// BAR is the Buffering Action Rule of a session
+type BAR struct {
+	ID uint8
+
+	// How long the UPF waits before notifying the SMF of buffered
+	// downlink data, and how many packets the SMF suggests buffering
+	// (0 if not given)
+	NotificationDelay time.Duration
+	SuggestedPackets  uint8
+}
+
+// Buffering reports whether one of the session's FARs buffers downlink
+// packets. Drops and latency of a buffering session come from the UE being
+// idle, not from packet loss.
+func (s *Session) Buffering() bool {
+	return len(s.bufferingFARs) > 0
+}
+
+// extractBuffering applies the BAR and FAR IEs of an Establishment or
+// Modification Request to the session: Create/Update/Remove BAR, and the
+// BUFF flag of the Apply Action of created, updated and removed FARs
+func (s *Sniffer) extractBuffering(vlog msgLog, ieData []byte, session *Session) {
+	wasBuffering := session.Buffering()
+
+	walkIEs(ieData, func(ieType uint16, ieValue []byte) {
+		switch ieType {
+		case IETypeCreateBAR:
+			session.BAR = parseBAR(ieValue, nil)
+			vlog.Printf("   └─ Found BAR: ID=%d, notification delay=%v, suggested packets=%d",
+				session.BAR.ID, session.BAR.NotificationDelay, session.BAR.SuggestedPackets)
+		case IETypeUpdateBAR:
+			session.BAR = parseBAR(ieValue, session.BAR)
+		case IETypeRemoveBAR:
+			session.BAR = nil
+		case IETypeCreateFAR, 10: // Create FAR, Update FAR
+			farID, action, ok := parseFARAction(ieValue)
+			if !ok {
+				return
+			}
+			if action&applyActionBUFF != 0 {
+				if !slices.Contains(session.bufferingFARs, farID) {
+					session.bufferingFARs = append(session.bufferingFARs, farID)
+				}
+			} else {
+				session.bufferingFARs = slices.DeleteFunc(session.bufferingFARs, func(id uint32) bool { return id == farID })
+			}
+		case 16: // Remove FAR
+			if farID, ok := findFARID(ieValue); ok {
+				session.bufferingFARs = slices.DeleteFunc(session.bufferingFARs, func(id uint32) bool { return id == farID })
+			}
+		}
+	})
+
+	if buffering := session.Buffering(); buffering != wasBuffering {
+		vlog.Printf("   └─ Downlink buffering: %v (FARs %v)", buffering, session.bufferingFARs)
+	}
+}
+
+// parseBAR reads a Create or Update BAR grouped IE. An Update starts from
+// the current BAR (nil for a Create) and only changes the IEs it carries.
+func parseBAR(barData []byte, current *BAR) *BAR {
+	bar := &BAR{}
+	if current != nil {
+		*bar = *current
+	}
+	// Walked flat: IE 46 means Notification Delay only inside a BAR
+	walkIEs(barData, func(ieType uint16, ieValue []byte) {
+		if len(ieValue) < 1 {
+			return
+		}
+		switch ieType {
+		case IETypeBARID:
+			bar.ID = ieValue[0]
+		case IETypeDLDataNotificationDelay:
+			bar.NotificationDelay = time.Duration(ieValue[0]) * dlDataNotificationDelayUnit
+		case IETypeSuggestedBufferingPacketCount:
+			bar.SuggestedPackets = ieValue[0]
+		}
+	})
+	return bar
+}
+
+// parseFARAction returns the FAR ID and Apply Action of a Create or Update
+// FAR grouped IE. ok is false without both.
+func parseFARAction(farData []byte) (farID uint32, action uint8, ok bool) {
+	var hasID, hasAction bool
+	walkIEs(farData, func(ieType uint16, ieValue []byte) {
+		switch ieType {
+		case IETypeFARID:
+			if len(ieValue) >= 4 {
+				farID = binary.BigEndian.Uint32(ieValue[0:4])
+				hasID = true
+			}
+		case IETypeApplyAction:
+			if len(ieValue) >= 1 {
+				action = ieValue[0]
+				hasAction = true
+			}
+		}
+	})
+	return farID, action, hasID && hasAction
+}
+
+// findFARID returns the FAR ID of a grouped IE
+func findFARID(data []byte) (uint32, bool) {
+	var farID uint32
+	var found bool
+	walkIEs(data, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypeFARID && len(ieValue) >= 4 {
+			farID = binary.BigEndian.Uint32(ieValue[0:4])
+			found = true
+		}
+	})
+	return farID, found
+}
+
+// walkIEs calls fn for each top-level IE of data, without descending into
+// grouped IEs
+func walkIEs(data []byte, fn func(ieType uint16, ieValue []byte)) {
+	for offset := 0; offset+4 <= len(data); {
+		ieType := binary.BigEndian.Uint16(data[offset : offset+2])
+		ieLen := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if offset+4+ieLen > len(data) {
+			return
+		}
+		fn(ieType, data[offset+4:offset+4+ieLen])
+		offset += 4 + ieLen
+	}
+}
+
+// BufferingSessions returns the number of sessions with a buffering FAR
+func (c *Correlation) BufferingSessions() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	n := 0
+	for _, session := range c.store.All() {
+		if session.Buffering() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,272 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// IETypeCause and the QER ID are only read by the decoder
+const (
+	IETypeCause = 19  // Cause
+	IETypeQERID = 109 // QER ID
+)
+
+// causeNames names the Cause values of TS 29.244 Table 8.2.1-1
+var causeNames = map[uint8]string{
+	1:  "Request accepted",
+	2:  "More Usage Report to send",
+	64: "Request rejected",
+	65: "Session context not found",
+	66: "Mandatory IE missing",
+	67: "Conditional IE missing",
+	68: "Invalid length",
+	69: "Mandatory IE incorrect",
+	70: "Invalid Forwarding Policy",
+	71: "Invalid F-TEID allocation option",
+	72: "No established PFCP Association",
+	73: "Rule creation/modification Failure",
+	74: "PFCP entity in congestion",
+	75: "No resources available",
+	76: "Service not supported",
+	77: "System failure",
+	78: "Redirection Requested",
+}
+
+// pfcpHeader is the decoded PFCP message header (TS 29.244 7.2.2)
+type pfcpHeader struct {
+	version   uint8
+	msgType   uint8
+	msgLen    uint16 // length after the first 4 bytes
+	hasSEID   bool
+	seid      uint64
+	seq       uint32
+	ieOffset  int
+	ieEnd     int
+	truncated bool // msgLen runs past the payload, IEs cut at the payload end
+}
+
+// parseHeader parses the header of a PFCP message. The sniffer and the
+// decoder both use it, so both accept and reject the same messages.
+func parseHeader(payload []byte) (pfcpHeader, error) {
+	// Byte 0: Version (3 bits) + Spare (3 bits) + MP (1 bit) + S (1 bit)
+	// Byte 1: Message Type
+	// Bytes 2-3: Message Length (excludes first 4 bytes of header)
+	// If S=1: Bytes 4-11: SEID, then Bytes 12-15: Sequence Number + Spare
+	// If S=0: Bytes 4-7: Sequence Number + Spare
+	var h pfcpHeader
+	if len(payload) < 8 {
+		return h, fmt.Errorf("message too short (%d bytes)", len(payload))
+	}
+	h.version = payload[0] >> 5
+	h.msgType = payload[1]
+	h.msgLen = binary.BigEndian.Uint16(payload[2:4])
+
+	// Check if it's a session message (has SEID) - S bit is bit 0
+	h.hasSEID = (payload[0] & 0x01) != 0
+
+	// Node messages never carry a SEID and session messages always do;
+	// anything else is malformed and its header offsets can't be trusted
+	if err := validateSEIDFlag(h.msgType, h.hasSEID); err != nil {
+		return h, err
+	}
+
+	if h.hasSEID {
+		if len(payload) < 16 {
+			return h, fmt.Errorf("session message too short (%d bytes)", len(payload))
+		}
+		h.seid = binary.BigEndian.Uint64(payload[4:12])
+		h.seq = binary.BigEndian.Uint32(payload[12:16]) >> 8
+		h.ieOffset = 16 // Header (4) + SEID (8) + SeqNum (4) = 16
+	} else {
+		h.seq = binary.BigEndian.Uint32(payload[4:8]) >> 8
+		h.ieOffset = 8 // Header (4) + SeqNum (4) = 8
+	}
+
+	// msgLen is the length of everything after the first 4 bytes
+	h.ieEnd = 4 + int(h.msgLen)
+	if h.ieEnd > len(payload) {
+		h.truncated = true
+		h.ieEnd = len(payload)
+	}
+	return h, nil
+}
+
+// DecodedMessage is a single PFCP message as the sniffer interprets it
+type DecodedMessage struct {
+	Version     uint8  `json:"version"`
+	MessageType uint8  `json:"message_type"`
+	MessageName string `json:"message_name"`
+	Length      uint16 `json:"length"`
+	SEID        string `json:"seid,omitempty"`
+	Sequence    uint32 `json:"sequence"`
+	Truncated   bool   `json:"truncated,omitempty"`
+
+	// Every IE in parse order, grouped IEs followed by their children
+	IEs []DecodedIE `json:"ies"`
+
+	// What the session handlers extract from the IEs
+	TEIDs             []string           `json:"teids,omitempty"`
+	UEIPs             []string           `json:"ue_ips,omitempty"`
+	FSEID             string             `json:"f_seid,omitempty"`
+	QERs              []DecodedQER       `json:"qers,omitempty"`
+	Causes            []DecodedCause     `json:"causes,omitempty"`
+	RecoveryTimeStamp uint32             `json:"recovery_time_stamp,omitempty"`
+	Session           *SessionAttributes `json:"session,omitempty"`
+}
+
+// DecodedIE is one IE of a decoded message
+type DecodedIE struct {
+	Type   uint16 `json:"type"`
+	Name   string `json:"name"`
+	Length int    `json:"length"`
+	Value  string `json:"value"` // hex
+}
+
+// DecodedQER is a Create/Update QER with the fields the sniffer reads
+type DecodedQER struct {
+	ID          uint32 `json:"id,omitempty"`
+	QFI         uint8  `json:"qfi,omitempty"`
+	MBRUplink   uint64 `json:"mbr_ul_kbps,omitempty"`
+	MBRDownlink uint64 `json:"mbr_dl_kbps,omitempty"`
+	GBRUplink   uint64 `json:"gbr_ul_kbps,omitempty"`
+	GBRDownlink uint64 `json:"gbr_dl_kbps,omitempty"`
+}
+
+// DecodedCause is a Cause IE
+type DecodedCause struct {
+	Value uint8  `json:"value"`
+	Name  string `json:"name"`
+}
+
+// SessionAttributes are the session fields extractSessionInfo fills in
+type SessionAttributes struct {
+	DNN         string   `json:"dnn,omitempty"`
+	SessionType string   `json:"session_type,omitempty"`
+	QFI         uint8    `json:"qfi,omitempty"`
+	QoS5QI      uint8    `json:"qos_5qi,omitempty"`
+	ARPPL       uint8    `json:"arp_priority_level,omitempty"`
+	SNssai      string   `json:"s_nssai,omitempty"`
+	MBRUplink   uint64   `json:"mbr_ul_kbps,omitempty"`
+	MBRDownlink uint64   `json:"mbr_dl_kbps,omitempty"`
+	GBRUplink   uint64   `json:"gbr_ul_kbps,omitempty"`
+	GBRDownlink uint64   `json:"gbr_dl_kbps,omitempty"`
+	SDFFilters  []string `json:"sdf_filters,omitempty"`
+	AppIDs      []string `json:"app_ids,omitempty"`
+}
+
+// Decode parses one PFCP message (the UDP payload) with the same code the
+// sniffer uses for live traffic, without touching any session state. It is
+// meant for reproducing parser behaviour from a hex dump.
+func Decode(payload []byte) (*DecodedMessage, error) {
+	h, err := parseHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &DecodedMessage{
+		Version:     h.version,
+		MessageType: h.msgType,
+		MessageName: MessageTypeName(h.msgType),
+		Length:      h.msgLen,
+		Sequence:    h.seq,
+		Truncated:   h.truncated,
+		IEs:         []DecodedIE{},
+	}
+	if h.hasSEID {
+		msg.SEID = model.FormatSEID(h.seid)
+	}
+	if h.ieOffset >= h.ieEnd {
+		return msg, nil
+	}
+	ieData := payload[h.ieOffset:h.ieEnd]
+	if err := checkIEDepth(ieData, DefaultMaxIEDepth); err != nil {
+		return nil, err
+	}
+
+	// A sniffer without correlation: the extract helpers only parse
+	s := &Sniffer{}
+
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		msg.IEs = append(msg.IEs, DecodedIE{
+			Type:   ieType,
+			Name:   IETypeName(ieType),
+			Length: len(ieValue),
+			Value:  hex.EncodeToString(ieValue),
+		})
+		switch ieType {
+		case IETypeCause:
+			if len(ieValue) >= 1 {
+				msg.Causes = append(msg.Causes, DecodedCause{Value: ieValue[0], Name: causeName(ieValue[0])})
+			}
+		case IETypeCreateQER, 14: // Create QER, Update QER
+			msg.QERs = append(msg.QERs, decodeQER(s, ieValue))
+		}
+	})
+
+	teids := s.extractUniqueTEIDs(quiet, ieData, nil)
+	sort.Slice(teids, func(i, j int) bool { return teids[i] < teids[j] })
+	for _, teid := range teids {
+		msg.TEIDs = append(msg.TEIDs, fmt.Sprintf("0x%x", teid))
+	}
+	for _, ip := range s.extractUEIPs(quiet, ieData) {
+		msg.UEIPs = append(msg.UEIPs, ip.String())
+	}
+	if seid := s.extractFSEID(ieData); seid != 0 {
+		msg.FSEID = model.FormatSEID(seid)
+	}
+	msg.RecoveryTimeStamp = s.extractRecoveryTimeStamp(ieData)
+
+	// Session messages: what an Establishment/Modification would store
+	if h.hasSEID {
+		session := &Session{}
+		s.extractSessionInfo(quiet, ieData, session)
+		msg.Session = &SessionAttributes{
+			DNN:         session.DNN,
+			SessionType: session.SessionType,
+			QFI:         session.QFI,
+			QoS5QI:      session.QoS5QI,
+			ARPPL:       session.ARPPL,
+			SNssai:      session.SNssai,
+			MBRUplink:   session.MBRUplink,
+			MBRDownlink: session.MBRDownlink,
+			GBRUplink:   session.GBRUplink,
+			GBRDownlink: session.GBRDownlink,
+			SDFFilters:  session.SDFFilters,
+			AppIDs:      session.AppIDs,
+		}
+	}
+
+	return msg, nil
+}
+
+// decodeQER reads a Create/Update QER grouped IE with extractSessionInfo,
+// so rates are interpreted exactly as for sessions
+func decodeQER(s *Sniffer, qerData []byte) DecodedQER {
+	var qer DecodedQER
+	s.parseIEsRecursive(qerData, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypeQERID && len(ieValue) >= 4 {
+			qer.ID = binary.BigEndian.Uint32(ieValue[0:4])
+		}
+	})
+
+	session := &Session{}
+	s.extractSessionInfo(quiet, qerData, session)
+	qer.QFI = session.QFI
+	qer.MBRUplink = session.MBRUplink
+	qer.MBRDownlink = session.MBRDownlink
+	qer.GBRUplink = session.GBRUplink
+	qer.GBRDownlink = session.GBRDownlink
+	return qer
+}
+
+// causeName names a Cause value
+func causeName(cause uint8) string {
+	if name, ok := causeNames[cause]; ok {
+		return name
+	}
+	return fmt.Sprintf("Cause %d", cause)
+}
@@ -0,0 +1,86 @@
+package pfcp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MaxDOTSessions bounds how many sessions WriteDOT renders, since Graphviz
+// becomes unusable long before the correlation gets large
+const MaxDOTSessions = 500
+
+// WriteDOT renders the correlation as a Graphviz DOT graph: one node per
+// session labelled with its SEID and UE IP, linked to a node per TEID and
+// to the UE IP. If seid is non-zero only that session is rendered.
+//
+// The graph is built under the read lock and written afterwards, so a slow
+// client does not hold up PFCP processing.
+func (c *Correlation) WriteDOT(w io.Writer, seid uint64) error {
+	var buf bytes.Buffer
+
+	c.mu.RLock()
+	var sessions []*Session
+	if seid != 0 {
+		if session, ok := c.store.GetBySEID(seid); ok {
+			sessions = append(sessions, session)
+		}
+	} else {
+		sessions = make([]*Session, 0, c.store.Count())
+		for _, session := range c.store.All() {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SEID < sessions[j].SEID
+	})
+
+	truncated := 0
+	if len(sessions) > MaxDOTSessions {
+		truncated = len(sessions) - MaxDOTSessions
+		sessions = sessions[:MaxDOTSessions]
+	}
+
+	buf.WriteString("digraph correlation {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for _, session := range sessions {
+		sessionNode := fmt.Sprintf("seid_0x%x", session.SEID)
+		label := fmt.Sprintf("SEID 0x%x", session.SEID)
+		if session.SUPI != "" {
+			label += "\n" + session.SUPI // %q renders this as the DOT \n escape
+		}
+		fmt.Fprintf(&buf, "  %q [shape=box, label=%q];\n", sessionNode, label)
+
+		for _, addr := range ueAddrs(session) {
+			ueNode := "ue_" + addr.String()
+			fmt.Fprintf(&buf, "  %q [shape=ellipse, label=%q];\n", ueNode, "UE "+addr.String())
+			fmt.Fprintf(&buf, "  %q -> %q [label=\"ue_ip\"];\n", sessionNode, ueNode)
+		}
+
+		for i, teid := range session.TEIDs {
+			teidNode := fmt.Sprintf("teid_0x%x", teid)
+			fmt.Fprintf(&buf, "  %q [shape=diamond, label=%q];\n", teidNode, fmt.Sprintf("TEID 0x%x", teid))
+
+			// Convention: first TEID is UL, second is DL
+			edge := "teid"
+			switch i {
+			case 0:
+				edge = "ul"
+			case 1:
+				edge = "dl"
+			}
+			fmt.Fprintf(&buf, "  %q -> %q [label=%q];\n", sessionNode, teidNode, edge)
+		}
+	}
+	c.mu.RUnlock()
+
+	if truncated > 0 {
+		fmt.Fprintf(&buf, "  // truncated: %d more sessions not shown\n", truncated)
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
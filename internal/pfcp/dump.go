@@ -0,0 +1,119 @@
+package pfcp
+
+import (
+	"slices"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// DumpSession is a session with the fields the API leaves out
+type DumpSession struct {
+	model.SessionInfo
+	LocalSEID  string `json:"local_seid,omitempty"`  // UP SEID (UPF side)
+	RemoteSEID string `json:"remote_seid,omitempty"` // CP SEID (SMF side)
+	ModifiedAt string `json:"modified_at,omitempty"`
+	PDRCount   int    `json:"pdr_count"`
+	FARCount   int    `json:"far_count"`
+}
+
+// StateDump is the internal state of a Correlation, for bug reports. When
+// limited, it holds the sessions with the lowest SEIDs and only the map
+// entries pointing at them; the totals count everything.
+type StateDump struct {
+	Sessions      []DumpSession `json:"sessions"`
+	TotalSessions int           `json:"total_sessions"`
+
+	// TEID -> SEID, as used to correlate traffic (may lack evicted TEIDs)
+	TEIDMap    map[string]string `json:"teid_map"`
+	TotalTEIDs int               `json:"total_teids"`
+
+	// PFCP SEIDs on the wire -> SEID
+	CPSEIDMap map[string]string `json:"cp_seid_map"`
+	UPSEIDMap map[string]string `json:"up_seid_map"`
+
+	// SMF/UPF IP -> SEIDs of its sessions
+	Peers map[string][]string `json:"peers"`
+
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Dump returns the sessions and the maps correlating them, with at most
+// maxSessions sessions (0 = all)
+func (c *Correlation) Dump(maxSessions int) StateDump {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sessions := c.store.All()
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		if a.SEID < b.SEID {
+			return -1
+		}
+		if a.SEID > b.SEID {
+			return 1
+		}
+		return 0
+	})
+
+	dump := StateDump{
+		TotalSessions: len(sessions),
+		TotalTEIDs:    c.store.TEIDCount(),
+		TEIDMap:       make(map[string]string),
+		CPSEIDMap:     make(map[string]string),
+		UPSEIDMap:     make(map[string]string),
+		Peers:         make(map[string][]string),
+	}
+	if maxSessions > 0 && len(sessions) > maxSessions {
+		sessions = sessions[:maxSessions]
+		dump.Truncated = true
+	}
+
+	now := time.Now()
+	included := make(map[uint64]bool, len(sessions))
+	dump.Sessions = make([]DumpSession, 0, len(sessions))
+	for _, session := range sessions {
+		included[session.SEID] = true
+		seid := model.FormatSEID(session.SEID)
+		ds := DumpSession{
+			SessionInfo: session.Model(now),
+			PDRCount:    session.PDRCount,
+			FARCount:    session.FARCount,
+		}
+		if session.LocalSEID != 0 {
+			ds.LocalSEID = model.FormatSEID(session.LocalSEID)
+		}
+		if session.RemoteSEID != 0 {
+			ds.RemoteSEID = model.FormatSEID(session.RemoteSEID)
+		}
+		if !session.ModifiedAt.IsZero() {
+			ds.ModifiedAt = session.ModifiedAt.Format(time.RFC3339)
+		}
+		dump.Sessions = append(dump.Sessions, ds)
+
+		// The index, not Session.TEIDs, decides where traffic goes
+		for _, teid := range session.TEIDs {
+			if owner, ok := c.store.TEIDOwner(teid); ok && owner == session.SEID {
+				dump.TEIDMap[model.FormatTEID(teid)] = seid
+			}
+		}
+	}
+
+	for wire, seid := range c.cpSEIDMap {
+		if included[seid] {
+			dump.CPSEIDMap[model.FormatSEID(wire)] = model.FormatSEID(seid)
+		}
+	}
+	for wire, seid := range c.upSEIDMap {
+		if included[seid] {
+			dump.UPSEIDMap[model.FormatSEID(wire)] = model.FormatSEID(seid)
+		}
+	}
+	for peer, seids := range c.peerMap {
+		for _, seid := range sortedSEIDs(seids) {
+			if included[seid] {
+				dump.Peers[peer] = append(dump.Peers[peer], model.FormatSEID(seid))
+			}
+		}
+	}
+	return dump
+}
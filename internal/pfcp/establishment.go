@@ -0,0 +1,169 @@
+package pfcp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// causeRequestAccepted is the Cause of successful responses (TS 29.244 8.2.1)
+const causeRequestAccepted = 1
+
+// EstablishmentWindow is the sliding window of the establishment success
+// ratio, long enough to smooth over single rejections, short enough to
+// alert on a UPF running out of resources
+const EstablishmentWindow = 5 * time.Minute
+
+// EstablishmentStats are the Session Establishments seen: Requests, and
+// Responses by whether their Cause was Request accepted
+type EstablishmentStats struct {
+	Requested     uint64            `json:"requested"`
+	Succeeded     uint64            `json:"succeeded"`
+	Failed        uint64            `json:"failed"`
+	FailedByCause map[string]uint64 `json:"failed_by_cause,omitempty"`
+
+	// Responses in the last Window and the share of them that succeeded,
+	// nil if there were none
+	Window          string   `json:"window"`
+	WindowSucceeded uint64   `json:"window_succeeded"`
+	WindowFailed    uint64   `json:"window_failed"`
+	SuccessRatio    *float64 `json:"success_ratio"`
+}
+
+// establishmentCounters count Session Establishments, with per-second
+// outcome slots covering EstablishmentWindow for the success ratio
+type establishmentCounters struct {
+	requested atomic.Uint64
+	byCause   [256]atomic.Uint64 // Responses by Cause value
+
+	mu    sync.Mutex
+	slots [EstablishmentWindow / time.Second]establishmentSlot
+
+	// Capture timestamp of the newest response and when it was recorded,
+	// the clock the window is read on (see now)
+	latest     time.Time
+	recordedAt time.Time
+}
+
+type establishmentSlot struct {
+	second    int64 // Unix second the slot holds, stale if not the one asked for
+	succeeded uint64
+	failed    uint64
+}
+
+// responseCause returns the Cause of a response, false if it has none
+func responseCause(ieData []byte) (cause uint8, ok bool) {
+	walkIEs(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypeCause && len(ieValue) >= 1 && !ok {
+			cause, ok = ieValue[0], true
+		}
+	})
+	return cause, ok
+}
+
+// requestAccepted reports whether the Cause IE of a response, if any, is
+// Request accepted
+func requestAccepted(ieData []byte) bool {
+	cause, ok := responseCause(ieData)
+	return !ok || cause == causeRequestAccepted
+}
+
+// recordResponse counts an Establishment Response with cause captured at ts
+func (e *establishmentCounters) recordResponse(cause uint8, ts time.Time) {
+	e.byCause[cause].Add(1)
+
+	second := ts.Unix()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if ts.After(e.latest) {
+		e.latest, e.recordedAt = ts, time.Now()
+	}
+
+	slot := &e.slots[second%int64(len(e.slots))]
+	if second < slot.second {
+		return // older than the window, e.g. out of order in a replay
+	}
+	if slot.second != second {
+		*slot = establishmentSlot{second: second}
+	}
+	if cause == causeRequestAccepted {
+		slot.succeeded++
+	} else {
+		slot.failed++
+	}
+}
+
+// now returns the current time on the capture clock the slots are filled
+// on: the newest response's timestamp, advanced by the time since it was
+// recorded. Live this is the wall clock; for a replayed capture it stays
+// at the capture's end rather than leaving the window empty. e.mu must be
+// held.
+func (e *establishmentCounters) now() time.Time {
+	if e.latest.IsZero() {
+		return time.Now()
+	}
+	return e.latest.Add(time.Since(e.recordedAt))
+}
+
+// window sums the outcomes of the EstablishmentWindow up to now on the
+// capture clock
+func (e *establishmentCounters) window() (succeeded, failed uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	end := e.now().Unix()
+	start := end - int64(len(e.slots)) + 1
+
+	for _, slot := range e.slots {
+		if slot.second >= start && slot.second <= end {
+			succeeded += slot.succeeded
+			failed += slot.failed
+		}
+	}
+	return succeeded, failed
+}
+
+// handleEstablishmentOutcome counts the outcome of an Establishment
+// Response. A response without Cause is malformed and not counted.
+func (s *Sniffer) handleEstablishmentOutcome(vlog msgLog, ieData []byte, ts time.Time) {
+	cause, ok := responseCause(ieData)
+	if !ok {
+		return
+	}
+	s.establishments.recordResponse(cause, ts)
+	if cause != causeRequestAccepted {
+		vlog.Printf("   └─ Establishment rejected: %s", causeName(cause))
+	}
+}
+
+// EstablishmentStats returns the Session Establishment counters and the
+// success ratio over the last EstablishmentWindow of capture time
+func (s *Sniffer) EstablishmentStats() EstablishmentStats {
+	stats := EstablishmentStats{
+		Requested: s.establishments.requested.Load(),
+		Window:    EstablishmentWindow.String(),
+	}
+	for cause := range s.establishments.byCause {
+		n := s.establishments.byCause[cause].Load()
+		if n == 0 {
+			continue
+		}
+		if cause == causeRequestAccepted {
+			stats.Succeeded = n
+			continue
+		}
+		if stats.FailedByCause == nil {
+			stats.FailedByCause = make(map[string]uint64)
+		}
+		stats.Failed += n
+		stats.FailedByCause[causeName(uint8(cause))] = n
+	}
+
+	stats.WindowSucceeded, stats.WindowFailed = s.establishments.window()
+	if total := stats.WindowSucceeded + stats.WindowFailed; total > 0 {
+		ratio := float64(stats.WindowSucceeded) / float64(total)
+		stats.SuccessRatio = &ratio
+	}
+	return stats
+}
@@ -0,0 +1,88 @@
+package pfcp
+
+import (
+	"testing"
+	"time"
+)
+
+// rejectedResponse is an Establishment Response to cpSEID with cause
+func rejectedResponse(seq uint32, cpSEID uint64, cause uint8) []byte {
+	return message(MsgTypeSessionEstablishmentResponse, cpSEID, seq, ie(IETypeCause, u8(cause)))
+}
+
+// establishOutcomes feeds n accepted and m rejected establishments
+// captured at ts, each of a session of its own
+func establishOutcomes(t *testing.T, s *Sniffer, n, m int, ts time.Time) {
+	t.Helper()
+	base := s.establishments.requested.Load()
+	for i := 0; i < n+m; i++ {
+		seq := uint32(base) + uint32(i) + 1
+		cpSEID := 0x1000 + uint64(seq)
+		s.processPacket(packetAt(t, testSMF, testUPF, establishmentRequest(seq, cpSEID, testSMF, "10.60.0.1", 0), ts))
+		response := establishmentResponse(seq, cpSEID, 0x2000+cpSEID)
+		if i >= n {
+			response = rejectedResponse(seq, cpSEID, 75) // No resources available
+		}
+		s.processPacket(packetAt(t, testUPF, testSMF, response, ts))
+	}
+}
+
+func TestEstablishmentStatsLive(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	establishOutcomes(t, s, 3, 1, time.Now())
+
+	stats := s.EstablishmentStats()
+	if stats.Requested != 4 || stats.Succeeded != 3 || stats.Failed != 1 {
+		t.Errorf("requested %d, succeeded %d, failed %d, want 4, 3 and 1", stats.Requested, stats.Succeeded, stats.Failed)
+	}
+	if stats.WindowSucceeded != 3 || stats.WindowFailed != 1 {
+		t.Errorf("window: succeeded %d, failed %d, want 3 and 1", stats.WindowSucceeded, stats.WindowFailed)
+	}
+	if stats.SuccessRatio == nil || *stats.SuccessRatio != 0.75 {
+		t.Errorf("success ratio %v, want 0.75", stats.SuccessRatio)
+	}
+}
+
+func TestEstablishmentStatsCaptureClock(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+
+	// A replayed capture from yesterday: the window ends at its last
+	// response, not at the wall clock
+	end := time.Now().Add(-24 * time.Hour)
+	establishOutcomes(t, s, 1, 3, end.Add(-2*EstablishmentWindow))
+	establishOutcomes(t, s, 1, 1, end)
+
+	stats := s.EstablishmentStats()
+	if stats.Succeeded != 2 || stats.Failed != 4 {
+		t.Errorf("succeeded %d, failed %d, want 2 and 4", stats.Succeeded, stats.Failed)
+	}
+	if stats.WindowSucceeded != 1 || stats.WindowFailed != 1 {
+		t.Errorf("window: succeeded %d, failed %d, want 1 and 1", stats.WindowSucceeded, stats.WindowFailed)
+	}
+	if stats.SuccessRatio == nil || *stats.SuccessRatio != 0.5 {
+		t.Errorf("success ratio %v, want 0.5", stats.SuccessRatio)
+	}
+}
+
+func TestEstablishmentWindowAdvances(t *testing.T) {
+	var e establishmentCounters
+	e.recordResponse(causeRequestAccepted, time.Now().Add(-time.Hour))
+
+	if succeeded, _ := e.window(); succeeded != 1 {
+		t.Fatalf("window: succeeded %d, want 1", succeeded)
+	}
+	// No responses since: the capture clock moves on with the wall clock
+	e.recordedAt = e.recordedAt.Add(-EstablishmentWindow)
+	if succeeded, failed := e.window(); succeeded != 0 || failed != 0 {
+		t.Errorf("window after %s: succeeded %d, failed %d, want none", EstablishmentWindow, succeeded, failed)
+	}
+}
+
+func TestEstablishmentStatsEmpty(t *testing.T) {
+	stats := newTestSniffer().EstablishmentStats()
+	if stats.SuccessRatio != nil {
+		t.Errorf("success ratio %v without responses, want nil", *stats.SuccessRatio)
+	}
+}
@@ -0,0 +1,148 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"net"
+	"slices"
+)
+
+// extractFSEID returns the SEID of the first F-SEID IE in ieData, or 0.
+// F-SEID layout: flags (1 byte, V4/V6) | SEID (8 bytes) | IPv4 | IPv6
+func (s *Sniffer) extractFSEID(ieData []byte) uint64 {
+	var seid uint64
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypeFSEID && seid == 0 && len(ieValue) >= 9 {
+			seid = binary.BigEndian.Uint64(ieValue[1:9])
+		}
+	})
+	return seid
+}
+
+// handleSessionEstablishmentResponse learns the UPF-assigned SEID. The
+// header carries the SMF's SEID (from the request's CP F-SEID) and the
+// UP F-SEID IE carries the SEID the SMF will use in later requests.
+// smfIP and upfIP are the destination and source of the response.
+func (s *Sniffer) handleSessionEstablishmentResponse(vlog msgLog, cpSEID uint64, ieData []byte, smfIP, upfIP net.IP) {
+	upSEID := s.extractFSEID(ieData)
+	if upSEID == 0 {
+		return
+	}
+	if internalSEID, ok := s.correlation.BindLocalSEID(cpSEID, upSEID); ok {
+		vlog.Printf("   └─ UP SEID 0x%x bound to session SEID=0x%x", upSEID, internalSEID)
+		s.correlation.SetSessionPeers(internalSEID, smfIP, upfIP)
+	}
+	if csids := s.extractCSIDs(ieData); len(csids) > 0 {
+		s.correlation.AddSessionCSIDs(cpSEID, csids)
+	}
+
+	// Created PDRs carry the F-TEIDs the UPF chose for CHOOSE F-TEIDs of
+	// the request, which had no TEID to correlate traffic with
+	if !requestAccepted(ieData) {
+		return
+	}
+	s.correlation.SetCreatedPDRTEIDs(cpSEID, ieData)
+	if teids := s.extractTEIDs(vlog, ieData); len(teids) > 0 {
+		if internalSEID, ok := s.correlation.AddAssignedTEIDs(cpSEID, teids); ok {
+			vlog.Printf("   └─ UPF-assigned TEIDs %#x added to session SEID=0x%x", teids, internalSEID)
+		}
+	}
+}
+
+// AddAssignedTEIDs adds teids, chosen by the UPF and reported in the
+// Establishment Response, to the session whose SMF-side SEID is cpSEID and
+// maps them to it. Returns the internal SEID of that session.
+func (c *Correlation) AddAssignedTEIDs(cpSEID uint64, teids []uint32) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.cpSEIDMap[cpSEID]
+	if !ok {
+		return 0, false
+	}
+	current, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return 0, false
+	}
+
+	updated := current.Clone()
+	updated.span = current.span
+	added := false
+	for _, teid := range teids {
+		if teid != 0 && !slices.Contains(updated.TEIDs, teid) {
+			updated.TEIDs = append(updated.TEIDs, teid)
+			added = true
+		}
+	}
+	if !added {
+		return seid, true
+	}
+
+	c.putLocked(updated)
+	for _, teid := range updated.TEIDs {
+		c.mapTEIDLocked(teid, seid)
+	}
+	c.publish(SessionEventUpdated, updated)
+	return seid, true
+}
+
+// BindLocalSEID records upSEID as the UPF-side SEID of the session whose
+// SMF-side SEID is cpSEID. Returns the internal SEID of that session.
+func (c *Correlation) BindLocalSEID(cpSEID, upSEID uint64) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.cpSEIDMap[cpSEID]
+	if !ok {
+		return 0, false
+	}
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return 0, false
+	}
+	if session.LocalSEID != 0 && c.upSEIDMap[session.LocalSEID] == seid {
+		delete(c.upSEIDMap, session.LocalSEID)
+	}
+	session.LocalSEID = upSEID
+	c.upSEIDMap[upSEID] = seid
+	c.saveLocked(session)
+	return seid, true
+}
+
+// GetSessionByPFCPSEID looks up a session by the UPF-side SEID carried in
+// the header of Session Modification/Deletion Requests. The returned
+// session is a copy (see Session.Clone).
+func (c *Correlation) GetSessionByPFCPSEID(upSEID uint64) (*Session, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if seid, ok := c.upSEIDMap[upSEID]; ok {
+		session, ok := c.store.GetBySEID(seid)
+		return cloned(session, ok)
+	}
+	return nil, false
+}
+
+// SetSessionPeers fills in the SMF and UPF IPs of a session if they are
+// not known yet, e.g. when only the Establishment Response was captured
+func (c *Correlation) SetSessionPeers(seid uint64, smfIP, upfIP net.IP) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return
+	}
+	changed := false
+	if session.SMFIP == nil && smfIP != nil {
+		session.SMFIP = smfIP
+		changed = true
+	}
+	if session.UPFIP == nil && upfIP != nil {
+		session.UPFIP = upfIP
+		changed = true
+	}
+	if changed {
+		c.indexPeersLocked(session)
+		c.saveLocked(session)
+	}
+}
@@ -0,0 +1,74 @@
+package pfcp
+
+import (
+	"testing"
+)
+
+// chooseRequest is an Establishment Request whose uplink PDR asks the UPF
+// to choose the F-TEID
+func chooseRequest(seq uint32, cpSEID uint64, ueIP string) []byte {
+	return message(MsgTypeSessionEstablishmentRequest, 0, seq,
+		fseidIE(cpSEID, testSMF),
+		createPDRIE(1, chooseFTEIDIE(), ueIPIE(ueIP)),
+	)
+}
+
+func TestEstablishmentResponseAssignsTEIDs(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+
+	s.processPacket(packet(t, testSMF, testUPF, chooseRequest(1, 0x1001, "10.60.0.1")))
+	s.processPacket(packet(t, testUPF, testSMF, establishmentResponse(1, 0x1001, 0x2001, 0x30001)))
+
+	session, ok := c.GetSessionByTEID(0x30001)
+	if !ok {
+		t.Fatal("UPF-assigned TEID not mapped")
+	}
+	if session.UEIP.String() != "10.60.0.1" {
+		t.Errorf("TEID maps to UE %s, want 10.60.0.1", session.UEIP)
+	}
+	if session.LocalSEID != 0x2001 || session.RemoteSEID != 0x1001 {
+		t.Errorf("SEIDs UP 0x%x CP 0x%x, want 0x2001 and 0x1001", session.LocalSEID, session.RemoteSEID)
+	}
+	if len(session.PDRs) != 1 || session.PDRs[0].TEID != 0x30001 {
+		t.Errorf("PDR 1 not given the assigned TEID: %+v", session.PDRs)
+	}
+
+	// Later requests address the session by the UP SEID
+	s.processPacket(packet(t, testSMF, testUPF, modificationRequest(2, 0x2001, 2, 0x30002)))
+	if got, ok := c.GetSessionByTEID(0x30002); !ok || got.SEID != session.SEID {
+		t.Error("Modification by UP SEID did not reach the session")
+	}
+}
+
+func TestEstablishmentResponseRejected(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+
+	s.processPacket(packet(t, testSMF, testUPF, chooseRequest(1, 0x1001, "10.60.0.1")))
+	s.processPacket(packet(t, testUPF, testSMF, message(MsgTypeSessionEstablishmentResponse, 0x1001, 1,
+		ie(IETypeCause, u8(75)), // No resources available
+		fseidIE(0x2001, testUPF),
+		ie(IETypeCreatedPDR, ie(IETypePDRID, u16(1)), fteidIE(0x30001, testUPF)),
+	)))
+
+	if _, ok := s.correlation.GetSessionByTEID(0x30001); ok {
+		t.Error("TEID of a rejected Establishment mapped")
+	}
+}
+
+func TestEstablishmentResponseWithoutRequest(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+
+	// The request was not captured
+	s.processPacket(packet(t, testUPF, testSMF, establishmentResponse(1, 0x1001, 0x2001, 0x30001)))
+
+	if n := s.correlation.SessionCount(); n != 0 {
+		t.Errorf("%d sessions from a lone response, want 0", n)
+	}
+	if _, ok := s.correlation.GetSessionByPFCPSEID(0x2001); ok {
+		t.Error("UP SEID bound without a session")
+	}
+}
@@ -0,0 +1,92 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// F-TEID IE flags (TS 29.244 8.2.3)
+const (
+	fteidFlagV4 = 0x01
+	fteidFlagV6 = 0x02
+	fteidFlagCH = 0x04 // Choose: the UPF allocates TEID and address
+)
+
+// Outer Header Creation Description bits (TS 29.244 8.2.56, first octet)
+const (
+	ohcGTPUIPv4 = 0x01
+	ohcGTPUIPv6 = 0x02
+	ohcUDPIPv4  = 0x04
+	ohcUDPIPv6  = 0x08
+	ohcIPv4     = 0x10
+	ohcIPv6     = 0x20
+)
+
+// parseFTEIDTEID returns the TEID of an F-TEID IE. ok is false if the IE is
+// too short or has the CH flag set: the UPF is asked to choose the TEID,
+// so the request carries none (at most a CHOOSE ID). The chosen TEID shows
+// up in the Establishment Response or a later Modification.
+func parseFTEIDTEID(ieValue []byte) (teid uint32, ok bool) {
+	if len(ieValue) < 1 || ieValue[0]&fteidFlagCH != 0 || len(ieValue) < 5 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ieValue[1:5]), true
+}
+
+// parseFTEIDAddr returns the address of an F-TEID IE: flags (1) | TEID (4)
+// | IPv4 (4, if V4) | IPv6 (16, if V6). IPv4 is preferred for dual-stack
+// F-TEIDs. Returns nil if the UPF is asked to choose the address.
+func parseFTEIDAddr(ieValue []byte) net.IP {
+	if len(ieValue) < 5 || ieValue[0]&fteidFlagCH != 0 {
+		return nil
+	}
+	flags := ieValue[0]
+	offset := 5
+	if flags&fteidFlagV4 != 0 {
+		if len(ieValue) < offset+4 {
+			return nil
+		}
+		return cloneIP(ieValue[offset : offset+4])
+	}
+	if flags&fteidFlagV6 != 0 && len(ieValue) >= offset+16 {
+		return cloneIP(ieValue[offset : offset+16])
+	}
+	return nil
+}
+
+// parseOuterHeaderCreation returns the TEID (GTP-U only, else 0) and peer
+// address of an Outer Header Creation IE: description (2) | TEID (4) |
+// IPv4 (4) | IPv6 (16) | port (2) ..., each present depending on the
+// description. IPv4 is preferred if both addresses are present.
+func parseOuterHeaderCreation(ieValue []byte) (uint32, net.IP, bool) {
+	if len(ieValue) < 2 {
+		return 0, nil, false
+	}
+	desc := ieValue[0]
+	offset := 2
+
+	var teid uint32
+	if desc&(ohcGTPUIPv4|ohcGTPUIPv6) != 0 {
+		if len(ieValue) < offset+4 {
+			return 0, nil, false
+		}
+		teid = binary.BigEndian.Uint32(ieValue[offset : offset+4])
+		offset += 4
+	}
+
+	var ip net.IP
+	if desc&(ohcGTPUIPv4|ohcUDPIPv4|ohcIPv4) != 0 {
+		if len(ieValue) < offset+4 {
+			return teid, nil, false
+		}
+		ip = cloneIP(ieValue[offset : offset+4])
+		offset += 4
+	}
+	if ip == nil && desc&(ohcGTPUIPv6|ohcUDPIPv6|ohcIPv6) != 0 {
+		if len(ieValue) < offset+16 {
+			return teid, nil, false
+		}
+		ip = cloneIP(ieValue[offset : offset+16])
+	}
+	return teid, ip, ip != nil
+}
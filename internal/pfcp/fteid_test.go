@@ -0,0 +1,121 @@
+package pfcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestEstablishmentOverIPv6(t *testing.T) {
+	quietLog(t)
+	const (
+		smf6 = "2001:db8:4::1"
+		upf6 = "2001:db8:4::3"
+		gnb6 = "2001:db8:5::1"
+	)
+	s := newTestSniffer()
+	c := s.correlation
+
+	request := message(MsgTypeSessionEstablishmentRequest, 0, 1,
+		fseidIE(0x1001, smf6),
+		createPDRIE(1, fteidIE(0x10001, upf6), ueIPIE("2001:db8:60::1")),
+	)
+	response := message(MsgTypeSessionEstablishmentResponse, 0x1001, 1,
+		ie(IETypeCause, u8(causeRequestAccepted)),
+		fseidIE(0x2001, upf6),
+	)
+	s.processPacket(packet(t, smf6, upf6, request))
+	s.processPacket(packet(t, upf6, smf6, response))
+
+	session, ok := c.GetSessionByTEID(0x10001)
+	if !ok {
+		t.Fatal("no session for the TEID of an Establishment over IPv6")
+	}
+	if !session.SMFIP.Equal(net.ParseIP(smf6)) || !session.UPFIP.Equal(net.ParseIP(upf6)) {
+		t.Errorf("SMF %s, UPF %s, want %s and %s", session.SMFIP, session.UPFIP, smf6, upf6)
+	}
+	if session.UEIP.String() != "2001:db8:60::1" {
+		t.Errorf("UE IP %s", session.UEIP)
+	}
+	if len(session.PDRs) != 1 || !session.PDRs[0].TEIDAddr.Equal(net.ParseIP(upf6)) {
+		t.Errorf("PDR F-TEID address not the IPv6 UPF: %+v", session.PDRs)
+	}
+	if _, ok := c.GetSessionByPFCPSEID(0x2001); !ok {
+		t.Error("UP SEID of the IPv6 Response not bound")
+	}
+	if peers := c.GetSessionsByPeer(net.ParseIP(smf6)); len(peers) != 1 || peers[0].SEID != session.SEID {
+		t.Errorf("%d sessions for the IPv6 SMF, want the session", len(peers))
+	}
+
+	// The Modification gives the gNB tunnel endpoint, GTP-U/UDP/IPv6
+	s.processPacket(packet(t, smf6, upf6, message(MsgTypeSessionModificationRequest, 0x2001, 2,
+		ie(IETypeCreateFAR,
+			ie(IETypeFARID, u32(2)),
+			ie(IETypeForwardingParameters,
+				ie(IETypeOuterHeaderCreation, u16(0x0200), u32(0x9001), ipBytes(gnb6)),
+			),
+		),
+	)))
+	session, _ = c.GetSessionByTEID(0x10001)
+	if !session.GNBIP.Equal(net.ParseIP(gnb6)) {
+		t.Errorf("gNB IP %s, want %s", session.GNBIP, gnb6)
+	}
+}
+
+func TestParseFTEIDAddr(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []byte
+		want  string
+	}{
+		{"IPv4", fteidIE(1, "10.0.0.3")[4:], "10.0.0.3"},
+		{"IPv6", fteidIE(1, "2001:db8::3")[4:], "2001:db8::3"},
+		{"dual stack", append(append([]byte{fteidFlagV4 | fteidFlagV6, 0, 0, 0, 1}, ipBytes("10.0.0.3")...), ipBytes("2001:db8::3")...), "10.0.0.3"},
+		{"CHOOSE", chooseFTEIDIE()[4:], "<nil>"},
+		{"short IPv6", []byte{fteidFlagV6, 0, 0, 0, 1, 0x20, 0x01}, "<nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFTEIDAddr(tt.value).String(); got != tt.want {
+				t.Errorf("parseFTEIDAddr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureFilterIPv6(t *testing.T) {
+	s := newTestSniffer()
+	s.SetGTPUErrorIndications(true)
+	if filter := s.captureFilter(); !strings.Contains(filter, "ip6[49]") {
+		t.Errorf("filter %q matches Error Indications over IPv4 only", filter)
+	}
+}
+
+func TestChooseFTEID(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+
+	// Uplink PDR whose TEID the UPF is to choose, next to one with a TEID
+	s.processPacket(packet(t, testSMF, testUPF, message(MsgTypeSessionEstablishmentRequest, 0, 1,
+		fseidIE(0x1001, testSMF),
+		createPDRIE(1, chooseFTEIDIE(), ueIPIE("10.60.0.1")),
+		createPDRIE(2, fteidIE(0x10002, testUPF), nil),
+	)))
+
+	if _, ok := c.GetSessionByTEID(0); ok {
+		t.Error("TEID 0 of the CHOOSE F-TEID mapped")
+	}
+	session, ok := c.GetSessionByUEIP("10.60.0.1")
+	if !ok {
+		t.Fatal("no session for the Establishment")
+	}
+	if len(session.TEIDs) != 1 || session.TEIDs[0] != 0x10002 {
+		t.Errorf("TEIDs %v, want only 0x10002", session.TEIDs)
+	}
+	for _, pdr := range session.PDRs {
+		if pdr.ID == 1 && (pdr.TEID != 0 || pdr.TEIDAddr != nil) {
+			t.Errorf("CHOOSE PDR has F-TEID 0x%x %s", pdr.TEID, pdr.TEIDAddr)
+		}
+	}
+}
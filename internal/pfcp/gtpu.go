@@ -0,0 +1,199 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GTP-U (TS 29.281)
+const (
+	GTPUPort = 2152
+
+	gtpuMsgErrorIndication = 26
+
+	gtpuIETEIDDataI   = 16  // TV, 4 byte TEID
+	gtpuIEPeerAddress = 133 // TLV, IPv4 or IPv6 address
+)
+
+// SessionEventBearerError is published when a GTP-U peer reports one of the
+// session's TEIDs as unknown (GTP-U Error Indication)
+const SessionEventBearerError = "bearer_error"
+
+// ErrorIndication is a decoded GTP-U Error Indication: Peer reports that it
+// received G-PDUs for TEID, which it does not know
+type ErrorIndication struct {
+	TEID uint32
+	Peer net.IP // GTP-U Peer Address IE, or nil if absent
+}
+
+// SetGTPUErrorIndications also captures GTP-U Error Indications (port
+// 2152, message type 26 only) on the sniffer's interface, which must see
+// N3/N9 traffic for this. Must be called before Start.
+func (s *Sniffer) SetGTPUErrorIndications(enabled bool) {
+	s.gtpuErrors = enabled
+}
+
+// captureFilter returns the BPF filter for the sniffer. "udp port" matches
+// IPv4 and IPv6 alike. Error Indications are selected in the kernel by the
+// GTP-U message type (second byte of the UDP payload), so G-PDUs are never
+// copied to user space. libpcap's udp[] only indexes IPv4 packets, so for
+// IPv6 the byte is read at a fixed offset (40 byte header + 8 byte UDP),
+// which "udp port" already limits to packets without extension headers.
+func (s *Sniffer) captureFilter() string {
+	filter := fmt.Sprintf("udp port %d", s.port)
+	if s.gtpuErrors {
+		filter = fmt.Sprintf("(%s) or (udp port %d and (udp[9] == %d or ip6[49] == %d))",
+			filter, GTPUPort, gtpuMsgErrorIndication, gtpuMsgErrorIndication)
+	}
+	return filter
+}
+
+// ErrorIndications returns the number of GTP-U Error Indications seen
+func (s *Sniffer) ErrorIndications() uint64 {
+	return s.errorIndications.Load()
+}
+
+// handleGTPU processes a GTP-U message captured alongside PFCP. srcIP is
+// the node that sent the Error Indication.
+func (s *Sniffer) handleGTPU(payload []byte, srcIP net.IP) {
+	ind, err := parseErrorIndication(payload)
+	if err != nil {
+		s.parseErrors.Add(1)
+		log.Printf("[GTPU-WARN] Dropping message from %s: %v", srcIP, err)
+		return
+	}
+	if ind == nil {
+		return // not an Error Indication
+	}
+	s.errorIndications.Add(1)
+
+	peer := ind.Peer
+	if peer == nil {
+		peer = srcIP
+	}
+	if session, ok := s.correlation.ReportBearerError(ind.TEID, peer); ok {
+		log.Printf("[GTPU] Error Indication from %s: TEID 0x%x unknown, bearer of SEID=0x%x UE_IP=%s broken",
+			srcIP, ind.TEID, session.SEID, session.UEIP)
+	} else {
+		log.Printf("[GTPU] Error Indication from %s: TEID 0x%x unknown (no matching session)", srcIP, ind.TEID)
+	}
+}
+
+// parseErrorIndication decodes a GTP-U message. It returns nil and no
+// error for messages other than Error Indication.
+func parseErrorIndication(payload []byte) (*ErrorIndication, error) {
+	if len(payload) < 8 {
+		return nil, fmt.Errorf("GTP-U message too short (%d bytes)", len(payload))
+	}
+	flags := payload[0]
+	if flags>>5 != 1 {
+		return nil, fmt.Errorf("unsupported GTP version %d", flags>>5)
+	}
+	if payload[1] != gtpuMsgErrorIndication {
+		return nil, nil
+	}
+
+	end := 8 + int(binary.BigEndian.Uint16(payload[2:4]))
+	if end > len(payload) {
+		return nil, fmt.Errorf("GTP-U length %d exceeds payload (%d)", end-8, len(payload)-8)
+	}
+
+	offset := 8
+	if flags&0x07 != 0 {
+		// Sequence Number, N-PDU Number and Next Extension Header Type
+		if offset+4 > end {
+			return nil, fmt.Errorf("truncated GTP-U optional fields")
+		}
+		next := payload[offset+3]
+		offset += 4
+		// Extension headers: length in 4-octet units, last octet is the
+		// next extension header type
+		for next != 0 {
+			if offset >= end || payload[offset] == 0 {
+				return nil, fmt.Errorf("invalid GTP-U extension header")
+			}
+			extLen := int(payload[offset]) * 4
+			if offset+extLen > end {
+				return nil, fmt.Errorf("truncated GTP-U extension header")
+			}
+			next = payload[offset+extLen-1]
+			offset += extLen
+		}
+	}
+
+	ind := &ErrorIndication{}
+	haveTEID := false
+	for offset < end {
+		ieType := payload[offset]
+		if ieType < 128 {
+			// TV IEs; the only ones defined for Error Indication
+			if ieType != gtpuIETEIDDataI || offset+5 > end {
+				return nil, fmt.Errorf("unexpected GTP-U IE %d", ieType)
+			}
+			ind.TEID = binary.BigEndian.Uint32(payload[offset+1 : offset+5])
+			haveTEID = true
+			offset += 5
+			continue
+		}
+
+		if offset+3 > end {
+			return nil, fmt.Errorf("truncated GTP-U IE %d", ieType)
+		}
+		ieLen := int(binary.BigEndian.Uint16(payload[offset+1 : offset+3]))
+		value := payload[offset+3:]
+		if 3+ieLen > end-offset {
+			return nil, fmt.Errorf("truncated GTP-U IE %d", ieType)
+		}
+		value = value[:ieLen]
+		if ieType == gtpuIEPeerAddress && (ieLen == net.IPv4len || ieLen == net.IPv6len) {
+			ind.Peer = net.IP(append([]byte(nil), value...))
+		}
+		offset += 3 + ieLen
+	}
+
+	if !haveTEID {
+		return nil, fmt.Errorf("error indication without Tunnel Endpoint Identifier Data I")
+	}
+	return ind, nil
+}
+
+// ReportBearerError publishes a SessionEventBearerError for the session
+// owning teid, after a GTP-U peer reported the TEID as unknown. The
+// session itself is not changed. Returns a copy of the session, or false if
+// no session owns teid.
+func (c *Correlation) ReportBearerError(teid uint32, peer net.IP) (*Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.store.GetByTEID(teid)
+	if !ok {
+		return nil, false
+	}
+
+	c.bearerErrors.Add(1)
+	if session.span != nil {
+		session.span.AddEvent("gtpu.error_indication", trace.WithAttributes(
+			attribute.String("gtpu.teid", fmt.Sprintf("0x%x", teid)),
+			attribute.String("gtpu.peer", ipString(peer)),
+		))
+	}
+	c.bus.Publish(events.TopicSessions, SessionEvent{
+		Type:  SessionEventBearerError,
+		SEID:  session.SEID,
+		UEIP:  session.UEIP,
+		TEIDs: []uint32{teid},
+	})
+	return session.Clone(), true
+}
+
+// BearerErrors returns the number of Error Indications that matched a
+// session
+func (c *Correlation) BearerErrors() uint64 {
+	return c.bearerErrors.Load()
+}
@@ -0,0 +1,236 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// Addresses of the test N4 peers and UPF
+const (
+	testSMF = "10.100.200.1"
+	testUPF = "10.100.200.3"
+)
+
+// ie encodes a PFCP IE: type (2) | length (2) | value, the values being
+// concatenated (for grouped IEs, the encoded child IEs)
+func ie(ieType uint16, values ...[]byte) []byte {
+	var value []byte
+	for _, v := range values {
+		value = append(value, v...)
+	}
+	b := make([]byte, 4, 4+len(value))
+	binary.BigEndian.PutUint16(b[0:2], ieType)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(value)))
+	return append(b, value...)
+}
+
+// message encodes a PFCP message. Session messages (type 50 and up) carry
+// seid in their header, node messages none.
+func message(msgType uint8, seid uint64, seq uint32, ies ...[]byte) []byte {
+	var b []byte
+	if msgType >= MsgTypeSessionEstablishmentRequest {
+		b = make([]byte, 16)
+		b[0] = 0x21 // Version 1, S=1
+		binary.BigEndian.PutUint64(b[4:12], seid)
+		binary.BigEndian.PutUint32(b[12:16], seq<<8)
+	} else {
+		b = make([]byte, 8)
+		b[0] = 0x20 // Version 1, S=0
+		binary.BigEndian.PutUint32(b[4:8], seq<<8)
+	}
+	b[1] = msgType
+	for _, e := range ies {
+		b = append(b, e...)
+	}
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)-4))
+	return b
+}
+
+func u8(v uint8) []byte { return []byte{v} }
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+func ipBytes(ip string) []byte {
+	addr := net.ParseIP(ip)
+	if v4 := addr.To4(); v4 != nil {
+		return v4
+	}
+	return addr
+}
+
+// fteidIE is an F-TEID with a TEID and an IPv4 or IPv6 address
+func fteidIE(teid uint32, ip string) []byte {
+	flags := uint8(fteidFlagV4)
+	if net.ParseIP(ip).To4() == nil {
+		flags = fteidFlagV6
+	}
+	return ie(IETypeFTEID, u8(flags), u32(teid), ipBytes(ip))
+}
+
+// chooseFTEIDIE is an F-TEID asking the UPF to choose TEID and address
+func chooseFTEIDIE() []byte {
+	return ie(IETypeFTEID, u8(fteidFlagV4|fteidFlagCH))
+}
+
+// ueIPIE is a UE IP Address IE with one IPv4 or IPv6 address
+func ueIPIE(ip string) []byte {
+	flags := uint8(ueIPFlagV4)
+	if net.ParseIP(ip).To4() == nil {
+		flags = ueIPFlagV6
+	}
+	return ie(IETypeUEIPAddr, u8(flags), ipBytes(ip))
+}
+
+// fseidIE is an F-SEID with an IPv4 or IPv6 address
+func fseidIE(seid uint64, ip string) []byte {
+	flags := uint8(0x02) // V4
+	if net.ParseIP(ip).To4() == nil {
+		flags = 0x01 // V6
+	}
+	return ie(IETypeFSEID, u8(flags), u64(seid), ipBytes(ip))
+}
+
+// createPDRIE is a Create PDR whose PDI holds fteid and ueIP (either may
+// be nil)
+func createPDRIE(pdrID uint16, fteid, ueIP []byte) []byte {
+	return ie(IETypeCreatePDR,
+		ie(IETypePDRID, u16(pdrID)),
+		ie(IETypePrecedence, u32(255)),
+		ie(IETypePDI, ie(IETypeSourceInterface, u8(0)), fteid, ueIP),
+	)
+}
+
+// establishmentRequest is a Session Establishment Request from the SMF at
+// smfIP for a UE at ueIP with one uplink PDR on teid, plus extra IEs
+func establishmentRequest(seq uint32, cpSEID uint64, smfIP, ueIP string, teid uint32, extra ...[]byte) []byte {
+	ies := [][]byte{
+		fseidIE(cpSEID, smfIP),
+		createPDRIE(1, fteidIE(teid, testUPF), ueIPIE(ueIP)),
+	}
+	return message(MsgTypeSessionEstablishmentRequest, 0, seq, append(ies, extra...)...)
+}
+
+// establishmentResponse is an accepted Session Establishment Response to
+// the SMF's cpSEID, assigning upSEID and, for CHOOSE requests, teids
+func establishmentResponse(seq uint32, cpSEID, upSEID uint64, teids ...uint32) []byte {
+	ies := [][]byte{
+		ie(IETypeCause, u8(causeRequestAccepted)),
+		fseidIE(upSEID, testUPF),
+	}
+	for i, teid := range teids {
+		ies = append(ies, ie(IETypeCreatedPDR, ie(IETypePDRID, u16(uint16(i+1))), fteidIE(teid, testUPF)))
+	}
+	return message(MsgTypeSessionEstablishmentResponse, cpSEID, seq, ies...)
+}
+
+// modificationRequest is a Session Modification Request to the UPF's
+// upSEID adding a PDR with teid
+func modificationRequest(seq uint32, upSEID uint64, pdrID uint16, teid uint32, extra ...[]byte) []byte {
+	ies := append([][]byte{createPDRIE(pdrID, fteidIE(teid, testUPF), nil)}, extra...)
+	return message(MsgTypeSessionModificationRequest, upSEID, seq, ies...)
+}
+
+// packetAt wraps a PFCP message into an Ethernet/IP/UDP packet from src to
+// dst (IPv4 or IPv6) captured at ts
+func packetAt(tb testing.TB, src, dst string, payload []byte, ts time.Time) gopacket.Packet {
+	tb.Helper()
+
+	srcIP, dstIP := net.ParseIP(src), net.ParseIP(dst)
+	udp := &layers.UDP{SrcPort: 8805, DstPort: 8805}
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x02, 0, 0, 0, 0, 1},
+		DstMAC:       net.HardwareAddr{0x02, 0, 0, 0, 0, 2},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	var ip gopacket.SerializableLayer
+	if srcIP.To4() != nil {
+		ip4 := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: srcIP.To4(), DstIP: dstIP.To4()}
+		udp.SetNetworkLayerForChecksum(ip4)
+		ip = ip4
+	} else {
+		ip6 := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolUDP, SrcIP: srcIP, DstIP: dstIP}
+		udp.SetNetworkLayerForChecksum(ip6)
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ip = ip6
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, udp, gopacket.Payload(payload)); err != nil {
+		tb.Fatalf("serialize packet: %v", err)
+	}
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	packet.Metadata().Timestamp = ts
+	packet.Metadata().CaptureLength = len(buf.Bytes())
+	packet.Metadata().Length = len(buf.Bytes())
+	return packet
+}
+
+// packet is packetAt captured now
+func packet(tb testing.TB, src, dst string, payload []byte) gopacket.Packet {
+	tb.Helper()
+	return packetAt(tb, src, dst, payload, time.Now())
+}
+
+// writePcap writes packets to a capture file of linkType in a temporary
+// directory and returns its path
+func writePcap(tb testing.TB, linkType layers.LinkType, packets []gopacket.Packet) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "capture.pcap")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(65535, linkType); err != nil {
+		tb.Fatal(err)
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p.Metadata().CaptureInfo, p.Data()); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return path
+}
+
+// newTestSniffer returns a sniffer that is never started, fed with
+// processPacket
+func newTestSniffer() *Sniffer {
+	return NewSniffer("", 8805, NewCorrelation())
+}
+
+// establish sends an accepted Establishment Request/Response pair for a UE
+// through s and returns the internal SEID of the session
+func establish(tb testing.TB, s *Sniffer, seq uint32, cpSEID, upSEID uint64, ueIP string, teid uint32, extra ...[]byte) uint64 {
+	tb.Helper()
+	s.processPacket(packet(tb, testSMF, testUPF, establishmentRequest(seq, cpSEID, testSMF, ueIP, teid, extra...)))
+	s.processPacket(packet(tb, testUPF, testSMF, establishmentResponse(seq, cpSEID, upSEID)))
+	session, ok := s.correlation.GetSessionByTEID(teid)
+	if !ok {
+		tb.Fatalf("no session for TEID 0x%x after establishment", teid)
+	}
+	return session.SEID
+}
+
+// quietLog discards the log output of the sniffer until the test ends, for
+// benchmarks and tests feeding many messages
+func quietLog(tb testing.TB) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+}
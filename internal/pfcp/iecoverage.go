@@ -0,0 +1,169 @@
+package pfcp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ieTypeNames names the IE types this package knows about (TS 29.244
+// Table 8.1.2-1)
+var ieTypeNames = map[uint16]string{
+	IETypeCreatePDR:                     "Create PDR",
+	IETypePDI:                           "PDI",
+	IETypeCreateFAR:                     "Create FAR",
+	IETypeForwardingParameters:          "Forwarding Parameters",
+	5:                                   "Duplicating Parameters",
+	IETypeCreateURR:                     "Create URR",
+	IETypeCreateQER:                     "Create QER",
+	8:                                   "Created PDR",
+	9:                                   "Update PDR",
+	10:                                  "Update FAR",
+	11:                                  "Update Forwarding Parameters",
+	12:                                  "Update BAR (Session Report Response)",
+	13:                                  "Update URR",
+	14:                                  "Update QER",
+	15:                                  "Remove PDR",
+	16:                                  "Remove FAR",
+	IETypeCause:                         "Cause",
+	IETypeSourceInterface:               "Source Interface",
+	IETypeFTEID:                         "F-TEID",
+	IETypeNetworkInstance:               "Network Instance",
+	IETypeSDFFilter:                     "SDF Filter",
+	IETypeApplicationID:                 "Application ID",
+	IETypeGateStatus:                    "Gate Status",
+	IETypeMBR:                           "MBR",
+	IETypeGBR:                           "GBR",
+	IETypeQERCorrelationID:              "QER Correlation ID",
+	IETypePrecedence:                    "Precedence",
+	IETypeApplyAction:                   "Apply Action",
+	IETypeVolumeMeasurement:             "Volume Measurement",
+	IEType5QI:                           "5QI",
+	IETypeARP:                           "ARP",
+	IETypeFSEID:                         "F-SEID",
+	IETypeOuterHeaderCreation:           "Outer Header Creation",
+	IETypeUsageReportModification:       "Usage Report (Session Modification Response)",
+	IETypeUsageReportDeletion:           "Usage Report (Session Deletion Response)",
+	IETypeUsageReportSessionReport:      "Usage Report (Session Report Request)",
+	IETypeURRID:                         "URR ID",
+	IETypeCreateBAR:                     "Create BAR",
+	IETypeUpdateBAR:                     "Update BAR",
+	IETypeRemoveBAR:                     "Remove BAR",
+	IETypeBARID:                         "BAR ID",
+	IETypePDUSessionType:                "PDU Session Type",
+	IETypeUEIPAddr:                      "UE IP Address",
+	IETypeOuterHeaderRemoval:            "Outer Header Removal",
+	IETypeRecoveryTimeStamp:             "Recovery Time Stamp",
+	IETypeFQCSID:                        "FQ-CSID",
+	IETypeFARID:                         "FAR ID",
+	IETypeQERID:                         "QER ID",
+	IETypeQFI:                           "QFI",
+	IETypeSuggestedBufferingPacketCount: "Suggested Buffering Packets Count",
+	IETypeSNSSAI:                        "S-NSSAI",
+	IEType3GPPInterfaceType:             "3GPP Interface Type",
+}
+
+// handledIETypes are the IE types the parser extracts data from or
+// descends into
+var handledIETypes = map[uint16]bool{
+	1: true, 2: true, 3: true, 4: true, 5: true, 6: true, 7: true, 8: true,
+	9: true, 10: true, 11: true, 12: true, 13: true, 14: true, 15: true, 16: true,
+	IETypeFTEID:                    true,
+	IETypeNetworkInstance:          true,
+	IETypeSDFFilter:                true,
+	IETypeApplicationID:            true,
+	IETypeMBR:                      true,
+	IETypeGBR:                      true,
+	IETypePrecedence:               true,
+	IETypeApplyAction:              true,
+	IEType5QI:                      true,
+	IETypeARP:                      true,
+	IETypeFSEID:                    true,
+	IETypeOuterHeaderCreation:      true,
+	IETypeVolumeMeasurement:        true,
+	IETypeUsageReportModification:  true,
+	IETypeUsageReportDeletion:      true,
+	IETypeUsageReportSessionReport: true,
+	IETypeCreateBAR:                true,
+	IETypeUpdateBAR:                true,
+	IETypeRemoveBAR:                true,
+	IETypePDUSessionType:           true,
+	IETypeUEIPAddr:                 true,
+	IETypeRecoveryTimeStamp:        true,
+	IETypeFQCSID:                   true,
+	IETypeQFI:                      true,
+	IETypeSNSSAI:                   true,
+}
+
+// IETypeName returns the name of an IE type, or "IE <n>" if unknown
+func IETypeName(ieType uint16) string {
+	if name, ok := ieTypeNames[ieType]; ok {
+		return name
+	}
+	return fmt.Sprintf("IE %d", ieType)
+}
+
+// IECoverage is the number of times an IE type was seen in captured messages
+type IECoverage struct {
+	Type    uint16 `json:"type"`
+	Name    string `json:"name"`
+	Handled bool   `json:"handled"` // Whether the parser uses this IE
+	Count   uint64 `json:"count"`
+}
+
+// ieCounter counts IE types over all captured messages
+type ieCounter struct {
+	mu     sync.Mutex
+	counts map[uint16]uint64
+}
+
+// SetIEStats enables counting every IE type in captured messages, nested
+// ones included, whether the parser uses it or not (see IECoverage). Off by
+// default since it walks each message once more. Must be called before
+// Start.
+func (s *Sniffer) SetIEStats(enabled bool) {
+	if enabled {
+		s.ieStats = &ieCounter{counts: make(map[uint16]uint64)}
+	} else {
+		s.ieStats = nil
+	}
+}
+
+// countIEs adds the IEs in ieData to the IE type counts, if enabled
+func (s *Sniffer) countIEs(ieData []byte) {
+	if s.ieStats == nil {
+		return
+	}
+	s.ieStats.mu.Lock()
+	defer s.ieStats.mu.Unlock()
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		s.ieStats.counts[ieType]++
+	})
+}
+
+// IECoverage returns the IE types seen so far, most frequent first, and
+// whether IE counting is enabled at all
+func (s *Sniffer) IECoverage() ([]IECoverage, bool) {
+	if s.ieStats == nil {
+		return nil, false
+	}
+	s.ieStats.mu.Lock()
+	coverage := make([]IECoverage, 0, len(s.ieStats.counts))
+	for ieType, count := range s.ieStats.counts {
+		coverage = append(coverage, IECoverage{
+			Type:    ieType,
+			Name:    IETypeName(ieType),
+			Handled: handledIETypes[ieType],
+			Count:   count,
+		})
+	}
+	s.ieStats.mu.Unlock()
+
+	sort.Slice(coverage, func(i, j int) bool {
+		if coverage[i].Count != coverage[j].Count {
+			return coverage[i].Count > coverage[j].Count
+		}
+		return coverage[i].Type < coverage[j].Type
+	})
+	return coverage, true
+}
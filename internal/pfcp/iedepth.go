@@ -0,0 +1,72 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DefaultMaxIEDepth is the default nesting limit of grouped IEs. Real
+// messages nest three levels at most (e.g. Create PDR > PDI > F-TEID).
+const DefaultMaxIEDepth = 8
+
+// SetMaxIEDepth sets how deep grouped IEs may nest, the top-level IEs being
+// level 1. Messages nesting deeper are dropped as malformed (see
+// ParseErrors) before any of their IEs is used. n <= 0 keeps
+// DefaultMaxIEDepth, which Decode and Replay always use. Must be called
+// before Start.
+func (s *Sniffer) SetMaxIEDepth(n int) {
+	s.maxIEDepth = n
+}
+
+// ieDepthLimit returns the nesting limit of grouped IEs
+func (s *Sniffer) ieDepthLimit() int {
+	if s.maxIEDepth > 0 {
+		return s.maxIEDepth
+	}
+	return DefaultMaxIEDepth
+}
+
+// checkIEDepth returns an error if the grouped IEs of ieData nest deeper
+// than limit levels
+func checkIEDepth(ieData []byte, limit int) error {
+	if !walkIETree(ieData, 1, limit, nil) {
+		return fmt.Errorf("grouped IEs nested deeper than %d levels", limit)
+	}
+	return nil
+}
+
+// isGroupedIE reports whether the IEs of ieType contain IEs themselves:
+// Create PDR (1), PDI (2), Create FAR (3), Forwarding Parameters (4),
+// Duplicating Parameters (5), Create URR (6), Create QER (7), Created PDR
+// (8), Update PDR (9), Update FAR (10), Update Forwarding Parameters (11),
+// Update BAR (12), Update URR (13), Update QER (14), Remove PDR (15) and
+// Remove FAR (16)
+func isGroupedIE(ieType uint16) bool {
+	return ieType >= 1 && ieType <= 16
+}
+
+// walkIETree calls callback (if not nil) for each IE of data at depth and
+// below, grouped IEs followed by their children. It stops and returns
+// false at the first IE nested deeper than limit.
+func walkIETree(data []byte, depth, limit int, callback func(ieType uint16, ieValue []byte)) bool {
+	for offset := 0; offset < len(data)-4; {
+		ieType := binary.BigEndian.Uint16(data[offset : offset+2])
+		ieLen := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if ieLen == 0 || offset+4+ieLen > len(data) {
+			break
+		}
+		if depth > limit {
+			return false
+		}
+
+		ieValue := data[offset+4 : offset+4+ieLen]
+		if callback != nil {
+			callback(ieType, ieValue)
+		}
+		if isGroupedIE(ieType) && !walkIETree(ieValue, depth+1, limit, callback) {
+			return false
+		}
+		offset += 4 + ieLen
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// nestedIEs returns a PDR ID IE at the bottom of depth levels of PDI IEs,
+// the top-level PDI being level 1
+func nestedIEs(depth int) []byte {
+	b := make([]byte, 4*depth+2)
+	for level := 0; level < depth; level++ {
+		h := b[4*level:]
+		ieType := uint16(IETypePDI)
+		if level == depth-1 {
+			ieType = IETypePDRID
+		}
+		binary.BigEndian.PutUint16(h[0:2], ieType)
+		binary.BigEndian.PutUint16(h[2:4], uint16(len(b)-4*level-4))
+	}
+	binary.BigEndian.PutUint16(b[len(b)-2:], 1)
+	return b
+}
+
+// nestedRequest is an Establishment Request with a second Create PDR
+// holding the nested IEs, depth+1 levels deep
+func nestedRequest(depth int) []byte {
+	return establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001,
+		ie(IETypeCreatePDR, ie(IETypePDRID, u16(2)), nestedIEs(depth)),
+	)
+}
+
+func TestCheckIEDepth(t *testing.T) {
+	for _, depth := range []int{1, 2, DefaultMaxIEDepth} {
+		if err := checkIEDepth(nestedIEs(depth), DefaultMaxIEDepth); err != nil {
+			t.Errorf("depth %d: %v", depth, err)
+		}
+	}
+	if err := checkIEDepth(nestedIEs(DefaultMaxIEDepth+1), DefaultMaxIEDepth); err == nil {
+		t.Errorf("depth %d accepted", DefaultMaxIEDepth+1)
+	}
+	// A message's real IEs nest 3 levels
+	msg := establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001)
+	if err := checkIEDepth(msg[16:], 3); err != nil {
+		t.Errorf("Establishment Request: %v", err)
+	}
+	if err := checkIEDepth(msg[16:], 2); err == nil {
+		t.Error("Establishment Request accepted at limit 2")
+	}
+}
+
+func TestProcessPacketIEDepth(t *testing.T) {
+	quietLog(t)
+	tests := []struct {
+		name     string
+		limit    int
+		depth    int
+		rejected bool
+	}{
+		{"at default limit", 0, DefaultMaxIEDepth - 1, false},
+		{"past default limit", 0, DefaultMaxIEDepth, true},
+		{"past lowered limit", 3, 3, true},
+		{"within raised limit", 32, 31, false},
+		// As deep as a 64 KiB message allows: must not exhaust the stack
+		{"pathological", 0, 16000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSniffer()
+			s.SetMaxIEDepth(tt.limit)
+			s.processPacket(packet(t, testSMF, testUPF, nestedRequest(tt.depth)))
+
+			wantErrors, wantSessions := uint64(0), 1
+			if tt.rejected {
+				wantErrors, wantSessions = 1, 0
+			}
+			if n := s.parseErrors.Load(); n != wantErrors {
+				t.Errorf("%d parse errors, want %d", n, wantErrors)
+			}
+			if n := s.correlation.SessionCount(); n != wantSessions {
+				t.Errorf("%d sessions, want %d", n, wantSessions)
+			}
+		})
+	}
+}
+
+func TestDecodeIEDepth(t *testing.T) {
+	if _, err := Decode(nestedRequest(DefaultMaxIEDepth - 1)); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+	if _, err := Decode(nestedRequest(16000)); err == nil {
+		t.Error("pathological nesting decoded")
+	}
+}
+
+// FuzzProcessPacket feeds arbitrary IEs to the Establishment Request
+// handling. It must never panic, and messages nesting past the limit must
+// be dropped whole.
+func FuzzProcessPacket(f *testing.F) {
+	f.Add(establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001)[16:])
+	f.Add(nestedRequest(DefaultMaxIEDepth)[16:])
+	f.Add(nestedRequest(DefaultMaxIEDepth - 1)[16:])
+	f.Add(nestedIEs(1000))
+	// A grouped IE whose length runs past its parent
+	f.Add(ie(IETypeCreatePDR, []byte{0, IETypePDI, 0xff, 0xff}))
+
+	f.Fuzz(func(t *testing.T, ieData []byte) {
+		if len(ieData) > 0xffff-12 {
+			return
+		}
+		quietLog(t)
+		s := newTestSniffer()
+		s.processPacket(packet(t, testSMF, testUPF, message(MsgTypeSessionEstablishmentRequest, 0, 1, ieData)))
+
+		if checkIEDepth(ieData, DefaultMaxIEDepth) != nil {
+			if s.parseErrors.Load() != 1 || s.correlation.SessionCount() != 0 {
+				t.Error("message nesting past the limit not dropped")
+			}
+		}
+		if _, err := Decode(message(MsgTypeSessionEstablishmentRequest, 0, 1, ieData)); err == nil && checkIEDepth(ieData, DefaultMaxIEDepth) != nil {
+			t.Error("Decode accepted a message nesting past the limit")
+		}
+	})
+}
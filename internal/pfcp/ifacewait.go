@@ -0,0 +1,118 @@
+package pfcp
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Backoff between checks for the capture interface
+const (
+	ifaceWaitInitialBackoff = 500 * time.Millisecond
+	ifaceWaitMaxBackoff     = 5 * time.Second
+)
+
+// SetInterfaceWait makes Start wait up to timeout for the capture interface
+// to appear, for containers where the N4 interface is plugged in after the
+// agent starts. 0 fails immediately if it is missing. Must be called before
+// Start.
+func (s *Sniffer) SetInterfaceWait(timeout time.Duration) {
+	s.ifaceWait = timeout
+}
+
+// waitForInterface polls for the capture interface with exponential
+// backoff until it exists, the wait times out or the sniffer is stopped
+func (s *Sniffer) waitForInterface() error {
+	// "any" is a libpcap pseudo-device, not a kernel interface
+	if s.ifaceWait <= 0 || s.iface == "any" {
+		return nil
+	}
+
+	deadline := time.Now().Add(s.ifaceWait)
+	backoff := ifaceWaitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		_, err := net.InterfaceByName(s.iface)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("[OK] PFCP: interface %s is up after %d attempts", s.iface, attempt)
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("interface %s did not appear within %v: %w", s.iface, s.ifaceWait, err)
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		log.Printf("[WARN] PFCP: interface %s not available (attempt %d), retrying in %v", s.iface, attempt, backoff)
+
+		select {
+		case <-s.stopChan:
+			return fmt.Errorf("stopped while waiting for interface %s", s.iface)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > ifaceWaitMaxBackoff {
+			backoff = ifaceWaitMaxBackoff
+		}
+	}
+}
+
+// FindInterfaceByIP returns the name of the interface that has ip assigned,
+// so the capture interface can be given as the UPF's N4 address
+func FindInterfaceByIP(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	return findInterface(func(ipNet *net.IPNet) bool {
+		return ipNet.IP.Equal(addr)
+	}, "IP "+ip)
+}
+
+// FindInterfaceBySubnet returns the name of the first interface with an
+// address in cidr
+func FindInterfaceBySubnet(cidr string) (string, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid subnet %q: %w", cidr, err)
+	}
+	return findInterface(func(ipNet *net.IPNet) bool {
+		return subnet.Contains(ipNet.IP)
+	}, "subnet "+cidr)
+}
+
+// findInterface returns the first interface with an address matching
+// match. The error lists the interfaces and their addresses, to make a
+// typo in the configuration easy to spot.
+func findInterface(match func(*net.IPNet) bool, what string) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	var available []string
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		var ips []string
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if match(ipNet) {
+				return iface.Name, nil
+			}
+			ips = append(ips, ipNet.String())
+		}
+		available = append(available, fmt.Sprintf("%s [%s]", iface.Name, strings.Join(ips, " ")))
+	}
+	return "", fmt.Errorf("no interface with %s, available: %s", what, strings.Join(available, ", "))
+}
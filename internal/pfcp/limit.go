@@ -0,0 +1,161 @@
+package pfcp
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"time"
+)
+
+// sessionEvictionLogInterval rate limits the warning about sessions evicted
+// by OverflowEvictOldest, a flood would otherwise log every Establishment
+const sessionEvictionLogInterval = time.Minute
+
+// OverflowPolicy decides what happens to a new session once the
+// correlation holds the maximum number of sessions
+type OverflowPolicy int
+
+const (
+	// OverflowReject drops the new session
+	OverflowReject OverflowPolicy = iota
+	// OverflowEvictOldest removes the session created first to make room
+	OverflowEvictOldest
+)
+
+// ParseOverflowPolicy parses "reject" or "evict-oldest"
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "reject":
+		return OverflowReject, nil
+	case "evict-oldest":
+		return OverflowEvictOldest, nil
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q (want reject or evict-oldest)", s)
+}
+
+func (p OverflowPolicy) String() string {
+	if p == OverflowEvictOldest {
+		return "evict-oldest"
+	}
+	return "reject"
+}
+
+// SetSessionLimit bounds the number of sessions kept, so a flood of
+// Session Establishments cannot grow the maps until the agent runs out of
+// memory. max <= 0 disables the limit.
+func (c *Correlation) SetSessionLimit(max int, policy OverflowPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxSessions = max
+	c.overflowPolicy = policy
+}
+
+// makeRoomLocked checks the session limit before a new session is stored.
+// It returns false if the session must be rejected. Caller must hold c.mu.
+func (c *Correlation) makeRoomLocked() bool {
+	if c.maxSessions <= 0 || c.store.Count() < c.maxSessions {
+		c.overflowLogged = false
+		return true
+	}
+	c.overflows.Add(1)
+
+	if c.overflowPolicy == OverflowReject {
+		// Only log the first rejection of a burst, a flood would spam the log
+		if !c.overflowLogged {
+			log.Printf("[WARN] Session limit (%d) reached, rejecting new sessions", c.maxSessions)
+			c.overflowLogged = true
+		}
+		return false
+	}
+
+	seid, ok := c.creation.oldest()
+	if !ok {
+		return true
+	}
+	if now := time.Now(); now.Sub(c.evictionLogged) >= sessionEvictionLogInterval {
+		log.Printf("[WARN] Session limit (%d) reached, evicting oldest sessions (SEID=0x%x first)", c.maxSessions, seid)
+		c.evictionLogged = now
+	}
+	c.removeSessionLocked(seid, SessionEventEvicted)
+	return true
+}
+
+// SessionOverflows returns how many new sessions hit the session limit
+func (c *Correlation) SessionOverflows() uint64 {
+	return c.overflows.Load()
+}
+
+// creationOrder orders the stored sessions by creation time, oldest first,
+// for OverflowEvictOldest. It is a min-heap kept in step with the store by
+// putLocked and deleteLocked, so the oldest session is found without a
+// scan of the store.
+type creationOrder struct {
+	entries []creationEntry
+	index   map[uint64]int // SEID -> position in entries
+}
+
+type creationEntry struct {
+	seid      uint64
+	createdAt time.Time
+}
+
+func newCreationOrder() *creationOrder {
+	return &creationOrder{index: make(map[uint64]int)}
+}
+
+// put adds a session or moves it if its creation time changed
+func (o *creationOrder) put(session *Session) {
+	if i, ok := o.index[session.SEID]; ok {
+		if !o.entries[i].createdAt.Equal(session.CreatedAt) {
+			o.entries[i].createdAt = session.CreatedAt
+			heap.Fix(o, i)
+		}
+		return
+	}
+	heap.Push(o, creationEntry{seid: session.SEID, createdAt: session.CreatedAt})
+}
+
+func (o *creationOrder) remove(seid uint64) {
+	if i, ok := o.index[seid]; ok {
+		heap.Remove(o, i)
+	}
+}
+
+// oldest returns the SEID of the session created first, ties going to the
+// lowest SEID
+func (o *creationOrder) oldest() (uint64, bool) {
+	if len(o.entries) == 0 {
+		return 0, false
+	}
+	return o.entries[0].seid, true
+}
+
+func (o *creationOrder) Len() int { return len(o.entries) }
+
+func (o *creationOrder) Less(i, j int) bool {
+	a, b := o.entries[i], o.entries[j]
+	if !a.createdAt.Equal(b.createdAt) {
+		return a.createdAt.Before(b.createdAt)
+	}
+	return a.seid < b.seid
+}
+
+func (o *creationOrder) Swap(i, j int) {
+	o.entries[i], o.entries[j] = o.entries[j], o.entries[i]
+	o.index[o.entries[i].seid] = i
+	o.index[o.entries[j].seid] = j
+}
+
+func (o *creationOrder) Push(x any) {
+	entry := x.(creationEntry)
+	o.index[entry.seid] = len(o.entries)
+	o.entries = append(o.entries, entry)
+}
+
+func (o *creationOrder) Pop() any {
+	last := len(o.entries) - 1
+	entry := o.entries[last]
+	o.entries = o.entries[:last]
+	delete(o.index, entry.seid)
+	return entry
+}
@@ -0,0 +1,184 @@
+package pfcp
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// establishAt establishes session i (UE 10.60.0.i, CP SEID 0x1000+i, UP
+// SEID 0x2000+i, TEID 0x10000+i) captured at ts
+func establishAt(t *testing.T, s *Sniffer, i int, ts time.Time) {
+	t.Helper()
+	seq, cpSEID := uint32(i), uint64(0x1000+i)
+	request := establishmentRequest(seq, cpSEID, testSMF, fmt.Sprintf("10.60.0.%d", i), uint32(0x10000+i))
+	s.processPacket(packetAt(t, testSMF, testUPF, request, ts))
+	s.processPacket(packetAt(t, testUPF, testSMF, establishmentResponse(seq, cpSEID, 0x2000+uint64(i)), ts))
+}
+
+// liveTEIDs returns which of the TEIDs of sessions 1 to n are mapped
+func liveTEIDs(c *Correlation, n int) []int {
+	var live []int
+	for i := 1; i <= n; i++ {
+		if _, ok := c.GetSessionByTEID(uint32(0x10000 + i)); ok {
+			live = append(live, i)
+		}
+	}
+	return live
+}
+
+func TestSessionLimitReject(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+	c.SetSessionLimit(3, OverflowReject)
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		establishAt(t, s, i, start.Add(time.Duration(i)*time.Second))
+	}
+	if got := fmt.Sprint(liveTEIDs(c, 5)); got != "[1 2 3]" {
+		t.Errorf("sessions %s kept, want the first 3", got)
+	}
+	if n := c.SessionOverflows(); n != 2 {
+		t.Errorf("SessionOverflows() = %d, want 2", n)
+	}
+
+	// Room made by a deletion is used again
+	session, _ := c.GetSessionByTEID(0x10002)
+	c.RemoveSession(session.SEID)
+	establishAt(t, s, 6, start.Add(6*time.Second))
+	if got := fmt.Sprint(liveTEIDs(c, 6)); got != "[1 3 6]" {
+		t.Errorf("sessions %s kept, want 1, 3 and 6", got)
+	}
+	if n := c.SessionOverflows(); n != 2 {
+		t.Errorf("SessionOverflows() = %d after a deletion, want 2", n)
+	}
+}
+
+func TestSessionLimitEvictOldest(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+	c.SetSessionLimit(3, OverflowEvictOldest)
+
+	// Captured out of order: session 2 is the oldest, then 3, then 1
+	start := time.Now()
+	establishAt(t, s, 1, start.Add(3*time.Second))
+	establishAt(t, s, 2, start.Add(1*time.Second))
+	establishAt(t, s, 3, start.Add(2*time.Second))
+
+	establishAt(t, s, 4, start.Add(4*time.Second))
+	if got := fmt.Sprint(liveTEIDs(c, 4)); got != "[1 3 4]" {
+		t.Errorf("sessions %s kept, want session 2 evicted", got)
+	}
+	establishAt(t, s, 5, start.Add(5*time.Second))
+	if got := fmt.Sprint(liveTEIDs(c, 5)); got != "[1 4 5]" {
+		t.Errorf("sessions %s kept, want session 3 evicted next", got)
+	}
+	if n := c.SessionCount(); n != 3 {
+		t.Errorf("%d sessions, want the limit of 3", n)
+	}
+	if n := c.SessionOverflows(); n != 2 {
+		t.Errorf("SessionOverflows() = %d, want 2", n)
+	}
+
+	// Modifications keep a session's place
+	s.processPacket(packet(t, testSMF, testUPF, modificationRequest(9, 0x2001, 2, 0x20001)))
+	establishAt(t, s, 6, start.Add(6*time.Second))
+	if got := fmt.Sprint(liveTEIDs(c, 6)); got != "[4 5 6]" {
+		t.Errorf("sessions %s kept, want session 1 evicted", got)
+	}
+}
+
+func TestSessionEvictionLogThrottled(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	s := newTestSniffer()
+	s.correlation.SetSessionLimit(1, OverflowEvictOldest)
+	start := time.Now()
+	for i := 1; i <= 20; i++ {
+		establishAt(t, s, i, start.Add(time.Duration(i)*time.Second))
+	}
+
+	if n := s.correlation.SessionOverflows(); n != 19 {
+		t.Errorf("SessionOverflows() = %d, want 19", n)
+	}
+	if n := strings.Count(buf.String(), "Session limit (1) reached"); n != 1 {
+		t.Errorf("eviction logged %d times, want once per %s", n, sessionEvictionLogInterval)
+	}
+}
+
+func TestCreationOrder(t *testing.T) {
+	o := newCreationOrder()
+	if _, ok := o.oldest(); ok {
+		t.Fatal("oldest of an empty order")
+	}
+
+	start := time.Now()
+	for i, offset := range []int{5, 3, 8, 1, 3} {
+		o.put(&Session{SEID: uint64(i + 1), CreatedAt: start.Add(time.Duration(offset) * time.Second)})
+	}
+	next := func() uint64 {
+		seid, ok := o.oldest()
+		if !ok {
+			t.Fatal("order empty")
+		}
+		o.remove(seid)
+		return seid
+	}
+
+	if seid := next(); seid != 4 {
+		t.Errorf("oldest SEID %d, want 4", seid)
+	}
+	// Ties go to the lowest SEID
+	if seid := next(); seid != 2 {
+		t.Errorf("oldest SEID %d, want 2", seid)
+	}
+	// A changed creation time moves the session
+	o.put(&Session{SEID: 3, CreatedAt: start})
+	if seid := next(); seid != 3 {
+		t.Errorf("oldest SEID %d after moving SEID 3 first, want 3", seid)
+	}
+	o.remove(42) // Unknown SEIDs are ignored
+	if seid := next(); seid != 5 {
+		t.Errorf("oldest SEID %d, want 5", seid)
+	}
+	if seid := next(); seid != 1 {
+		t.Errorf("oldest SEID %d, want 1", seid)
+	}
+	if o.Len() != 0 || len(o.index) != 0 {
+		t.Errorf("%d entries, %d indexed after removing all", o.Len(), len(o.index))
+	}
+}
+
+func TestSessionLimitEvictsLoadedSessions(t *testing.T) {
+	quietLog(t)
+	start := time.Now()
+	store := NewMemoryStore()
+	for i := 1; i <= 2; i++ {
+		store.Add(&Session{
+			SEID:      uint64(100 + i),
+			UEIP:      net.ParseIP(fmt.Sprintf("10.61.0.%d", i)),
+			CreatedAt: start.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	s := NewSniffer("", 8805, NewCorrelationWithStore(store))
+	c := s.correlation
+	c.SetSessionLimit(2, OverflowEvictOldest)
+
+	establishAt(t, s, 1, start)
+	if _, ok := c.GetSessionBySEID(102); ok {
+		t.Error("oldest loaded session not evicted")
+	}
+	if _, ok := c.GetSessionBySEID(101); !ok {
+		t.Error("newer loaded session evicted")
+	}
+}
@@ -0,0 +1,82 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Link types gopacket has no decoder for. gopacket's LinkType is 8 bits
+// and pcap handles truncate the DLT into it, so LINKTYPE_LINUX_SLL2 (276,
+// the "any" pseudo-interface of recent tcpdump captures) shows up as 20,
+// which is not assigned to anything else.
+const (
+	linkTypeLinuxSLL2 = layers.LinkType(276 & 0xff)
+	linkTypeSLL2Value = 276
+
+	sll2HeaderLen = 20
+
+	// Name gopacket gives the link types it has no decoder for
+	unknownLinkTypeName = "UnknownLinkType"
+)
+
+// linkTypeDecoder returns the decoder of the capture's link layer, down to
+// the IP layer processPacket needs. Ethernet, Linux cooked (SLL) and raw IP
+// captures are decoded by gopacket; SLL2 and the LINKTYPE_IPV4/IPV6 raw
+// types are added here. ok is false for link types gopacket does not know.
+func linkTypeDecoder(linkType layers.LinkType) (decoder gopacket.Decoder, ok bool) {
+	switch linkType {
+	case linkTypeLinuxSLL2:
+		return gopacket.DecodeFunc(decodeLinuxSLL2), true
+	case layers.LinkTypeIPv4:
+		return layers.LayerTypeIPv4, true
+	case layers.LinkTypeIPv6:
+		return layers.LayerTypeIPv6, true
+	}
+	return linkType, layers.LinkTypeMetadata[linkType].Name != unknownLinkTypeName
+}
+
+// linkTypeName names a link type for log messages
+func linkTypeName(linkType layers.LinkType) string {
+	switch linkType {
+	case linkTypeLinuxSLL2:
+		return "Linux SLL2"
+	case layers.LinkTypeIPv4:
+		return "IPv4"
+	case layers.LinkTypeIPv6:
+		return "IPv6"
+	}
+	if name := layers.LinkTypeMetadata[linkType].Name; name != unknownLinkTypeName {
+		return name
+	}
+	return fmt.Sprintf("link type %d", linkType)
+}
+
+// decodeLinuxSLL2 decodes a Linux cooked v2 header (protocol type,
+// reserved, interface index, ARPHRD type, packet type, address length and
+// 8 address bytes) and passes the rest on by its EtherType. The header is
+// not kept as a layer, PFCP processing starts at the IP layer.
+func decodeLinuxSLL2(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < sll2HeaderLen {
+		p.SetTruncated()
+		return fmt.Errorf("Linux SLL2 header of %d bytes, need %d", len(data), sll2HeaderLen)
+	}
+	proto := layers.EthernetType(binary.BigEndian.Uint16(data[0:2]))
+	return proto.Decode(data[sll2HeaderLen:], p)
+}
+
+// pcapFileLinkType returns the LINKTYPE to write into a pcap file header
+// for the link type of a capture handle: the untruncated SLL2 value, and
+// LINKTYPE_RAW for DLT_RAW, whose DLT value differs between systems
+func pcapFileLinkType(linkType layers.LinkType) uint32 {
+	switch {
+	case linkType == linkTypeLinuxSLL2:
+		return linkTypeSLL2Value
+	case linkType == 12 && runtime.GOOS != "openbsd", linkType == 14 && runtime.GOOS == "openbsd":
+		return uint32(layers.LinkTypeRaw)
+	}
+	return uint32(linkType)
+}
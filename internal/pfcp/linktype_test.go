@@ -0,0 +1,124 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// ipDatagram returns the IP datagram of a PFCP message from src to dst,
+// without a link layer
+func ipDatagram(tb testing.TB, src, dst string, payload []byte) []byte {
+	tb.Helper()
+	return packet(tb, src, dst, payload).Data()[14:] // Ethernet header
+}
+
+// sllHeader is a Linux cooked v1 header of a packet sent by this host
+func sllHeader(proto layers.EthernetType) []byte {
+	h := make([]byte, 16)
+	binary.BigEndian.PutUint16(h[0:2], 4) // Sent by us
+	binary.BigEndian.PutUint16(h[2:4], 1) // ARPHRD_ETHER
+	binary.BigEndian.PutUint16(h[4:6], 6) // Address length
+	copy(h[6:12], []byte{0x02, 0, 0, 0, 0, 1})
+	binary.BigEndian.PutUint16(h[14:16], uint16(proto))
+	return h
+}
+
+// sll2Header is a Linux cooked v2 header of a packet sent by this host
+func sll2Header(proto layers.EthernetType) []byte {
+	h := make([]byte, sll2HeaderLen)
+	binary.BigEndian.PutUint16(h[0:2], uint16(proto))
+	binary.BigEndian.PutUint32(h[4:8], 2)  // Interface index
+	binary.BigEndian.PutUint16(h[8:10], 1) // ARPHRD_ETHER
+	h[10] = 4                              // Sent by us
+	h[11] = 6                              // Address length
+	copy(h[12:18], []byte{0x02, 0, 0, 0, 0, 1})
+	return h
+}
+
+func TestLinkTypes(t *testing.T) {
+	quietLog(t)
+	tests := []struct {
+		name     string
+		linkType layers.LinkType
+		src, dst string
+		frame    func(ip []byte, proto layers.EthernetType) []byte
+	}{
+		{"SLL", layers.LinkTypeLinuxSLL, testSMF, testUPF, func(ip []byte, proto layers.EthernetType) []byte {
+			return append(sllHeader(proto), ip...)
+		}},
+		{"SLL IPv6", layers.LinkTypeLinuxSLL, "fd00::1", "fd00::3", func(ip []byte, proto layers.EthernetType) []byte {
+			return append(sllHeader(proto), ip...)
+		}},
+		{"SLL2", linkTypeLinuxSLL2, testSMF, testUPF, func(ip []byte, proto layers.EthernetType) []byte {
+			return append(sll2Header(proto), ip...)
+		}},
+		{"SLL2 IPv6", linkTypeLinuxSLL2, "fd00::1", "fd00::3", func(ip []byte, proto layers.EthernetType) []byte {
+			return append(sll2Header(proto), ip...)
+		}},
+		{"raw", layers.LinkTypeRaw, testSMF, testUPF, func(ip []byte, _ layers.EthernetType) []byte { return ip }},
+		{"IPv4", layers.LinkTypeIPv4, testSMF, testUPF, func(ip []byte, _ layers.EthernetType) []byte { return ip }},
+		{"IPv6", layers.LinkTypeIPv6, "fd00::1", "fd00::3", func(ip []byte, _ layers.EthernetType) []byte { return ip }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, ok := linkTypeDecoder(tt.linkType)
+			if !ok {
+				t.Fatalf("no decoder for %s", linkTypeName(tt.linkType))
+			}
+			proto := layers.EthernetTypeIPv4
+			if tt.src != testSMF {
+				proto = layers.EthernetTypeIPv6
+			}
+			msg := establishmentRequest(1, 0x1001, tt.src, "10.60.0.1", 0x10001)
+			data := tt.frame(ipDatagram(t, tt.src, tt.dst, msg), proto)
+
+			p := gopacket.NewPacket(data, decoder, gopacket.Default)
+			if p.Layer(layers.LayerTypeUDP) == nil {
+				t.Fatalf("UDP layer not decoded: %v", p.ErrorLayer())
+			}
+
+			s := newTestSniffer()
+			s.processPacket(p)
+			session, ok := s.correlation.GetSessionByTEID(0x10001)
+			if !ok {
+				t.Fatal("session not created")
+			}
+			if session.SMFIP.String() != tt.src {
+				t.Errorf("SMF %s, want %s", session.SMFIP, tt.src)
+			}
+		})
+	}
+}
+
+func TestLinkTypeSLL2Truncated(t *testing.T) {
+	decoder, _ := linkTypeDecoder(linkTypeLinuxSLL2)
+	p := gopacket.NewPacket(sll2Header(layers.EthernetTypeIPv4)[:12], decoder, gopacket.Default)
+	if p.ErrorLayer() == nil {
+		t.Error("short SLL2 header decoded without error")
+	}
+	if p.Layer(layers.LayerTypeUDP) != nil {
+		t.Error("UDP layer decoded from a short SLL2 header")
+	}
+}
+
+func TestLinkTypeUnknown(t *testing.T) {
+	if _, ok := linkTypeDecoder(layers.LinkType(250)); ok {
+		t.Error("decoder returned for an unknown link type")
+	}
+	if got := linkTypeName(layers.LinkType(250)); got != "link type 250" {
+		t.Errorf("linkTypeName(250) = %q", got)
+	}
+}
+
+func TestPcapFileLinkType(t *testing.T) {
+	if got := pcapFileLinkType(linkTypeLinuxSLL2); got != linkTypeSLL2Value {
+		t.Errorf("SLL2 written as %d, want %d", got, linkTypeSLL2Value)
+	}
+	if got := pcapFileLinkType(layers.LinkTypeLinuxSLL); got != uint32(layers.LinkTypeLinuxSLL) {
+		t.Errorf("SLL written as %d, want %d", got, layers.LinkTypeLinuxSLL)
+	}
+}
@@ -0,0 +1,145 @@
+package pfcp
+
+import (
+	"maps"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// Model converts the session to its JSON representation, computing the
+// duration relative to now
+func (s *Session) Model(now time.Time) model.SessionInfo {
+	teids := make([]string, 0, len(s.TEIDs))
+	for _, teid := range s.TEIDs {
+		teids = append(teids, model.FormatTEID(teid))
+	}
+
+	// Extract UL/DL TEIDs (convention: first is UL, second is DL)
+	teidUL := ""
+	teidDL := ""
+	if len(s.TEIDs) >= 1 {
+		teidUL = model.FormatTEID(s.TEIDs[0])
+	}
+	if len(s.TEIDs) >= 2 {
+		teidDL = model.FormatTEID(s.TEIDs[1])
+	}
+
+	ueIP := "N/A"
+	if s.UEIP != nil {
+		ueIP = s.UEIP.String()
+	}
+
+	var ueIPs []string
+	for _, addr := range ueAddrs(s) {
+		ueIPs = append(ueIPs, addr.String())
+	}
+
+	status := "Active"
+	if s.Status != "" {
+		status = s.Status
+	}
+
+	lastActive := ""
+	if !s.LastActive.IsZero() {
+		lastActive = s.LastActive.Format(time.RFC3339)
+	}
+
+	var bar *model.BARInfo
+	if s.BAR != nil {
+		bar = &model.BARInfo{
+			ID:                  s.BAR.ID,
+			NotificationDelayMs: uint32(s.BAR.NotificationDelay.Milliseconds()),
+			SuggestedPackets:    s.BAR.SuggestedPackets,
+		}
+	}
+
+	var pdrs []model.PDRInfo
+	for _, pdr := range s.PDRs {
+		info := model.PDRInfo{
+			ID:              pdr.ID,
+			Precedence:      pdr.Precedence,
+			SourceInterface: pdr.SourceInterface,
+			FTEIDAddr:       ipString(pdr.TEIDAddr),
+			FARID:           pdr.FARID,
+		}
+		if pdr.TEID != 0 {
+			info.FTEID = model.FormatTEID(pdr.TEID)
+		}
+		pdrs = append(pdrs, info)
+	}
+
+	var usage *model.ReportedUsageInfo
+	if s.ReportedUsage != nil {
+		usage = &model.ReportedUsageInfo{
+			TotalBytes:    s.ReportedUsage.TotalBytes,
+			UplinkBytes:   s.ReportedUsage.UplinkBytes,
+			DownlinkBytes: s.ReportedUsage.DownlinkBytes,
+			Reports:       s.ReportedUsage.Reports,
+			LastReport:    s.ReportedUsage.LastReport.Format(time.RFC3339),
+		}
+	}
+
+	return model.SessionInfo{
+		SEID:      model.FormatSEID(s.SEID),
+		UEIP:      ueIP,
+		UEIPs:     ueIPs,
+		TEIDs:     teids,
+		TEIDUL:    teidUL,
+		TEIDDL:    teidDL,
+		CreatedAt: s.CreatedAt.Format(time.RFC3339),
+		PacketsUL: s.PacketsUL,
+		PacketsDL: s.PacketsDL,
+
+		// Extended fields
+		UPFIP:        ipString(s.UPFIP),
+		SMFIP:        ipString(s.SMFIP),
+		GNBIP:        ipString(s.GNBIP),
+		UplinkPeerIP: ipString(s.UplinkPeerIP),
+		N9PeerIP:     ipString(s.N9PeerIP),
+		SUPI:         s.SUPI,
+		DNN:          s.DNN,
+		SNssai:       s.SNssai,
+		QFI:          s.QFI,
+		SessionType:  s.SessionType,
+		SessionID:    s.SessionID,
+		SDFFilters:   slices.Clone(s.SDFFilters),
+		AppIDs:       slices.Clone(s.AppIDs),
+		PDRs:         pdrs,
+
+		// Traffic
+		BytesUL:       s.BytesUL,
+		BytesDL:       s.BytesDL,
+		ReportedUsage: usage,
+
+		// QoS
+		QoS5QI:      s.QoS5QI,
+		ARPPL:       s.ARPPL,
+		GBRUplink:   s.GBRUplink,
+		GBRDownlink: s.GBRDownlink,
+		MBRUplink:   s.MBRUplink,
+		MBRDownlink: s.MBRDownlink,
+
+		// Buffering
+		Buffering: s.Buffering(),
+		BAR:       bar,
+
+		// Status
+		Status:                  status,
+		Duration:                model.FormatDuration(now.Sub(s.CreatedAt)),
+		LastActive:              lastActive,
+		IncompleteEstablishment: s.IncompleteEstablishment,
+
+		Tags: maps.Clone(s.Tags),
+	}
+}
+
+// ipString returns ip as a string, or "" if it is unset
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,91 @@
+package pfcp
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+)
+
+// ApplyModification updates an existing session for a Session Modification
+// Request. The session is looked up by UE IP, then by the UPF-side SEID
+// from the message header, then by internal SEID. modify is applied to a
+// copy of the session, which then replaces the stored one together with
+// its TEID, UE IP and peer index entries, all under the write lock; the
+// live session is never mutated, so readers holding a session see a
+// consistent value.
+//
+// modify, which decodes the message's IEs, runs outside the lock so that
+// workers (see Sniffer.SetWorkers) decode in parallel. If the session was
+// stored again in the meantime, the copy is dropped and modify runs a
+// second time, under the lock, on the current session.
+//
+// Returns a copy of the updated session, or false if no session matched
+// (modify is not called in that case).
+func (c *Correlation) ApplyModification(seid uint64, ueIPs []net.IP, modify func(*Session)) (*Session, bool) {
+	c.mu.RLock()
+	current, _, ok := c.lookupForModificationLocked(seid, ueIPs)
+	var updated *Session
+	if ok {
+		updated = current.Clone()
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	modify(updated)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, found, ok := c.lookupForModificationLocked(seid, ueIPs)
+	if !ok {
+		return nil, false
+	}
+	if current.SEID != updated.SEID || current.revision != updated.revision {
+		updated = current.Clone()
+		modify(updated)
+	}
+	log.Printf("   └─ Found session by %s (SEID=0x%x)", found, current.SEID)
+
+	updated.span = current.span
+	updated.SEID = current.SEID
+
+	c.putLocked(updated)
+	for _, teid := range updated.TEIDs {
+		c.mapTEIDLocked(teid, updated.SEID)
+	}
+	c.indexUEAddrsLocked(updated, captureTime(updated.LastActive))
+	c.indexPeersLocked(updated)
+	c.publish(SessionEventUpdated, updated)
+
+	return updated.Clone(), true
+}
+
+// lookupForModificationLocked finds the session a Modification Request
+// refers to and says by which key, caller must hold c.mu
+func (c *Correlation) lookupForModificationLocked(seid uint64, ueIPs []net.IP) (session *Session, found string, ok bool) {
+	// First by UE IP (our primary key)
+	addrs := make([]netip.Addr, 0, len(ueIPs))
+	for _, ip := range ueIPs {
+		addrs = append(addrs, ipToAddr(ip))
+	}
+	if addr, internalSEID, ok := c.lookupUEAddrsLocked(addrs); ok {
+		if session, ok := c.store.GetBySEID(internalSEID); ok {
+			return session, fmt.Sprintf("UE IP %s", addr), true
+		}
+	}
+
+	// Then by the UP SEID learnt from the Establishment Response
+	if internalSEID, ok := c.upSEIDMap[seid]; ok {
+		if session, ok := c.store.GetBySEID(internalSEID); ok {
+			return session, fmt.Sprintf("PFCP SEID 0x%x", seid), true
+		}
+	}
+
+	// Then by SEID (fallback, partial sessions are stored by PFCP SEID)
+	if session, ok := c.store.GetBySEID(seid); ok {
+		return session, fmt.Sprintf("SEID 0x%x", seid), true
+	}
+	return nil, "", false
+}
@@ -0,0 +1,157 @@
+package pfcp
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// TestApplyModificationConcurrent modifies one session from several
+// goroutines while others read the session value stored before; run with
+// -race
+func TestApplyModificationConcurrent(t *testing.T) {
+	quietLog(t)
+	const writers, perWriter = 4, 50
+	s := newTestSniffer()
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	s.correlation.mu.RLock()
+	before, _ := s.correlation.store.GetBySEID(seid)
+	s.correlation.mu.RUnlock()
+	modifiedAt := before.ModifiedAt
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	for r := 0; r < 2; r++ {
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// The stored value is replaced, never written
+				if len(before.TEIDs) != 1 || before.ModifiedAt != modifiedAt {
+					t.Error("session value changed after it was stored")
+					return
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				teid := uint32(0x20000 + w*perWriter + i)
+				_, ok := s.correlation.ApplyModification(0x2001, nil, func(session *Session) {
+					session.TEIDs = append(session.TEIDs, teid)
+				})
+				if !ok {
+					t.Errorf("session not found for TEID 0x%x", teid)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	session, _ := s.correlation.GetSessionBySEID(seid)
+	if len(session.TEIDs) != 1+writers*perWriter {
+		t.Fatalf("%d TEIDs, want %d: modifications were lost", len(session.TEIDs), 1+writers*perWriter)
+	}
+	for teid := uint32(0x20000); teid < 0x20000+writers*perWriter; teid++ {
+		if got, ok := s.correlation.GetSessionByTEID(teid); !ok || got.SEID != seid {
+			t.Fatalf("TEID 0x%x not mapped to the session", teid)
+		}
+	}
+}
+
+func TestApplyModificationLookup(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	tests := []struct {
+		name  string
+		seid  uint64
+		ueIPs []net.IP
+		found bool
+	}{
+		{"by UE IP", 0xdead, []net.IP{net.ParseIP("10.60.0.1")}, true},
+		{"by UP SEID", 0x2001, nil, true},
+		{"by internal SEID", seid, nil, true},
+		{"unknown", 0xdead, []net.IP{net.ParseIP("10.60.0.2")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			session, ok := s.correlation.ApplyModification(tt.seid, tt.ueIPs, func(session *Session) {
+				called = true
+				session.DNN = tt.name
+			})
+			if ok != tt.found || called != tt.found {
+				t.Fatalf("found %v, modify called %v, want %v", ok, called, tt.found)
+			}
+			if !ok {
+				return
+			}
+			if session.SEID != seid || session.DNN != tt.name {
+				t.Errorf("updated session SEID 0x%x DNN %q", session.SEID, session.DNN)
+			}
+			// The returned session is a copy
+			session.TEIDs[0] = 0
+			if stored, _ := s.correlation.GetSessionBySEID(seid); !slices.Contains(stored.TEIDs, 0x10001) {
+				t.Error("returned session shares memory with the stored one")
+			}
+		})
+	}
+}
+
+// TestApplyModificationRetry changes the session while a modification is
+// decoded outside the lock: the stale copy must be dropped and the
+// modification applied again to the current session
+func TestApplyModificationRetry(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	calls := 0
+	session, ok := c.ApplyModification(0x2001, nil, func(session *Session) {
+		calls++
+		if calls == 1 {
+			// A concurrent change, e.g. from the API
+			if ok, err := c.SetSessionTags(seid, map[string]string{"team": "core"}); !ok || err != nil {
+				t.Fatalf("SetSessionTags: %v, %v", ok, err)
+			}
+		}
+		session.TEIDs = append(session.TEIDs, 0x20001)
+	})
+	if !ok {
+		t.Fatal("session not found")
+	}
+	if calls != 2 {
+		t.Errorf("modify called %d times, want 2", calls)
+	}
+	if session.Tags["team"] != "core" {
+		t.Error("concurrent change lost")
+	}
+	if !slices.Contains(session.TEIDs, 0x20001) || len(session.TEIDs) != 2 {
+		t.Errorf("TEIDs %v, want 0x10001 and 0x20001", session.TEIDs)
+	}
+
+	// Without a concurrent change the decoded copy is kept
+	calls = 0
+	c.ApplyModification(0x2001, nil, func(session *Session) { calls++ })
+	if calls != 1 {
+		t.Errorf("modify called %d times without a concurrent change, want 1", calls)
+	}
+}
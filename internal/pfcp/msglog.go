@@ -0,0 +1,83 @@
+package pfcp
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// msgLog prints the verbose lines logged while processing one PFCP message
+// (what was found in it, what it did to the session store). It is off for
+// message types not selected with SetLogTypes. Warnings about malformed
+// messages and store limits are logged regardless.
+type msgLog bool
+
+// quiet is the msgLog of messages decoded on request rather than captured
+const quiet msgLog = false
+
+func (l msgLog) Printf(format string, args ...any) {
+	if l {
+		log.Printf(format, args...)
+	}
+}
+
+// SetLogTypes selects the message types logged verbosely, none by default.
+// Must be called before Start. Message counters are kept for all types.
+func (s *Sniffer) SetLogTypes(types []uint8) {
+	s.logTypes = [256]bool{}
+	for _, t := range types {
+		s.logTypes[t] = true
+	}
+}
+
+// msgLog returns the logger for a message of type msgType
+func (s *Sniffer) msgLog(msgType uint8) msgLog {
+	return msgLog(s.logTypes[msgType])
+}
+
+// ParseMessageTypes parses a comma-separated list of PFCP message types,
+// given by name ("Session Establishment Request", case, spaces, dashes and
+// underscores ignored, so SessionEstablishmentRequest works too) or number.
+// "all" selects every type, including unknown ones.
+func ParseMessageTypes(list string) ([]uint8, error) {
+	var types []uint8
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.EqualFold(item, "all") {
+			types = make([]uint8, 256)
+			for t := range types {
+				types[t] = uint8(t)
+			}
+			return types, nil
+		}
+		if n, err := strconv.ParseUint(item, 10, 8); err == nil {
+			types = append(types, uint8(n))
+			continue
+		}
+		t, ok := lookupMessageType(item)
+		if !ok {
+			return nil, fmt.Errorf("unknown PFCP message type %q", item)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// lookupMessageType finds a message type by name, see ParseMessageTypes
+func lookupMessageType(name string) (uint8, bool) {
+	key := normalizeMessageTypeName(name)
+	for t, n := range messageTypeNames {
+		if normalizeMessageTypeName(n) == key {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeMessageTypeName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
@@ -0,0 +1,170 @@
+package pfcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// messageTypeNames maps PFCP message types to their names in TS 29.244
+var messageTypeNames = map[uint8]string{
+	MsgTypeHeartbeatRequest:             "Heartbeat Request",
+	MsgTypeHeartbeatResponse:            "Heartbeat Response",
+	MsgTypePFDManagementRequest:         "PFD Management Request",
+	MsgTypePFDManagementResponse:        "PFD Management Response",
+	MsgTypeAssociationSetupRequest:      "Association Setup Request",
+	MsgTypeAssociationSetupResponse:     "Association Setup Response",
+	MsgTypeAssociationUpdateRequest:     "Association Update Request",
+	MsgTypeAssociationUpdateResponse:    "Association Update Response",
+	MsgTypeAssociationReleaseRequest:    "Association Release Request",
+	MsgTypeAssociationReleaseResponse:   "Association Release Response",
+	MsgTypeVersionNotSupportedResponse:  "Version Not Supported Response",
+	MsgTypeNodeReportRequest:            "Node Report Request",
+	MsgTypeNodeReportResponse:           "Node Report Response",
+	MsgTypeSessionSetDeletionRequest:    "Session Set Deletion Request",
+	MsgTypeSessionSetDeletionResponse:   "Session Set Deletion Response",
+	MsgTypeSessionEstablishmentRequest:  "Session Establishment Request",
+	MsgTypeSessionEstablishmentResponse: "Session Establishment Response",
+	MsgTypeSessionModificationRequest:   "Session Modification Request",
+	MsgTypeSessionModificationResponse:  "Session Modification Response",
+	MsgTypeSessionDeletionRequest:       "Session Deletion Request",
+	MsgTypeSessionDeletionResponse:      "Session Deletion Response",
+	MsgTypeSessionReportRequest:         "Session Report Request",
+	MsgTypeSessionReportResponse:        "Session Report Response",
+}
+
+// MessageTypeName returns the human-readable name of a PFCP message type
+func MessageTypeName(msgType uint8) string {
+	if name, ok := messageTypeNames[msgType]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", msgType)
+}
+
+// MessageCounts returns the number of messages seen per recognized message
+// type, keyed by message name. Types that were never seen report 0.
+func (s *Sniffer) MessageCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(messageTypeNames))
+	for msgType, name := range messageTypeNames {
+		counts[name] = s.msgCounts[msgType].Load()
+	}
+	return counts
+}
+
+// ParseErrors returns the number of messages dropped as malformed
+func (s *Sniffer) ParseErrors() uint64 {
+	return s.parseErrors.Load()
+}
+
+// TruncatedMessages returns the number of messages skipped because their
+// declared length exceeds the captured payload
+func (s *Sniffer) TruncatedMessages() uint64 {
+	return s.truncatedMessages.Load()
+}
+
+// CaptureDrops returns the number of packets libpcap dropped on the live
+// capture, because its buffer was full or the interface dropped them. PFCP
+// messages are among them, so sessions may be missing or stale.
+func (s *Sniffer) CaptureDrops() uint64 {
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+
+	if s.captureHandle != nil {
+		if stats, err := s.captureHandle.Stats(); err == nil {
+			s.captureDrops = uint64(stats.PacketsDropped) + uint64(stats.PacketsIfDropped)
+		}
+	}
+	return s.captureDrops
+}
+
+// IncompleteEstablishments returns the number of sessions first seen in a
+// Modification Request (see Session.IncompleteEstablishment)
+func (s *Sniffer) IncompleteEstablishments() uint64 {
+	return s.incompleteEstablishments.Load()
+}
+
+// isSessionMessage reports whether msgType is a session-related message
+// (TS 29.244 Table 7.3-1: types 50-99 always carry a SEID)
+func isSessionMessage(msgType uint8) bool {
+	return msgType >= MsgTypeSessionEstablishmentRequest && msgType < 100
+}
+
+// validateSEIDFlag checks the S flag of the header against the message type
+func validateSEIDFlag(msgType uint8, hasSEID bool) error {
+	switch {
+	case isNodeMessage(msgType) && hasSEID:
+		return fmt.Errorf("%s has the SEID flag set", MessageTypeName(msgType))
+	case isSessionMessage(msgType) && !hasSEID:
+		return fmt.Errorf("%s is missing the SEID flag", MessageTypeName(msgType))
+	}
+	return nil
+}
+
+// isNodeMessage reports whether msgType is a node-related message
+// (TS 29.244 Table 7.3-1: types 1-49 are node messages without SEID)
+func isNodeMessage(msgType uint8) bool {
+	return msgType >= MsgTypeHeartbeatRequest && msgType < MsgTypeSessionEstablishmentRequest
+}
+
+// NodeMessageStat counts node-related messages of one type from one peer
+type NodeMessageStat struct {
+	MsgType  uint8     `json:"msg_type"`
+	Name     string    `json:"name"`
+	Peer     string    `json:"peer"` // Node that sent the message
+	Count    uint64    `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type nodeStatKey struct {
+	msgType uint8
+	peer    string
+}
+
+// handleNodeMessage counts a node-related message captured at ts.
+// Heartbeats are only counted since they arrive every few seconds per peer.
+func (s *Sniffer) handleNodeMessage(vlog msgLog, msgType uint8, srcIP, dstIP net.IP, ts time.Time) {
+	peer := "unknown"
+	if srcIP != nil {
+		peer = srcIP.String()
+	}
+
+	key := nodeStatKey{msgType: msgType, peer: peer}
+	s.nodeStatsMu.Lock()
+	stat, ok := s.nodeStats[key]
+	if !ok {
+		stat = &NodeMessageStat{
+			MsgType: msgType,
+			Name:    MessageTypeName(msgType),
+			Peer:    peer,
+		}
+		s.nodeStats[key] = stat
+	}
+	stat.Count++
+	stat.LastSeen = ts
+	s.nodeStatsMu.Unlock()
+
+	if msgType == MsgTypeHeartbeatRequest || msgType == MsgTypeHeartbeatResponse {
+		return
+	}
+	vlog.Printf("[PFCP] %s: %s -> %s", MessageTypeName(msgType), peer, dstIP)
+}
+
+// NodeMessageStats returns the node-related message counters, ordered by
+// message type and peer
+func (s *Sniffer) NodeMessageStats() []NodeMessageStat {
+	s.nodeStatsMu.Lock()
+	stats := make([]NodeMessageStat, 0, len(s.nodeStats))
+	for _, stat := range s.nodeStats {
+		stats = append(stats, *stat)
+	}
+	s.nodeStatsMu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MsgType != stats[j].MsgType {
+			return stats[i].MsgType < stats[j].MsgType
+		}
+		return stats[i].Peer < stats[j].Peer
+	})
+	return stats
+}
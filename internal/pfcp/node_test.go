@@ -0,0 +1,89 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestValidateSEIDFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType uint8
+		hasSEID bool
+		wantErr bool
+	}{
+		{"node message", MsgTypeHeartbeatRequest, false, false},
+		{"node message with SEID", MsgTypeHeartbeatRequest, true, true},
+		{"last node message with SEID", MsgTypeSessionEstablishmentRequest - 1, true, true},
+		{"session message", MsgTypeSessionModificationRequest, true, false},
+		{"session message without SEID", MsgTypeSessionModificationRequest, false, true},
+		{"establishment without SEID", MsgTypeSessionEstablishmentRequest, false, true},
+		{"unknown type 0", 0, true, false},
+		{"unknown type 0 without SEID", 0, false, false},
+		{"type 100 and up", 100, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSEIDFlag(tt.msgType, tt.hasSEID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSEIDFlag(%d, %v) = %v, want error %v", tt.msgType, tt.hasSEID, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessPacketInconsistentSEIDFlag(t *testing.T) {
+	// A Heartbeat Request with S=1, too short for a SEID header
+	heartbeat := message(MsgTypeHeartbeatRequest, 0, 1, ie(IETypeRecoveryTimeStamp, u32(1)))
+	heartbeat[0] |= 0x01
+
+	// An Establishment Request with S=0: the IEs would be read from the
+	// middle of the SEID
+	establishment := establishmentRequest(2, 0x1001, testSMF, "10.60.0.1", 0x10001)
+	establishment[0] &^= 0x01
+
+	// A Modification Request with S=0 and an 8-byte header
+	modification := message(MsgTypeHeartbeatRequest, 0, 3, createPDRIE(1, fteidIE(0x10002, testUPF), nil))
+	modification[1] = MsgTypeSessionModificationRequest
+
+	s := newTestSniffer()
+	for i, payload := range [][]byte{heartbeat, establishment, modification} {
+		s.processPacket(packet(t, testSMF, testUPF, payload))
+		if got := s.ParseErrors(); got != uint64(i+1) {
+			t.Fatalf("message %d: %d parse errors, want %d", i, got, i+1)
+		}
+	}
+	if n := s.correlation.SessionCount(); n != 0 {
+		t.Errorf("%d sessions from inconsistent messages, want 0", n)
+	}
+	if got := s.msgCounts[MsgTypeHeartbeatRequest].Load(); got != 0 {
+		t.Errorf("%d Heartbeat Requests counted, want 0", got)
+	}
+}
+
+func TestParseHeaderSEIDFlag(t *testing.T) {
+	h, err := parseHeader(message(MsgTypeSessionDeletionRequest, 0x1122334455667788, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.hasSEID || h.seid != 0x1122334455667788 || h.seq != 7 || h.ieOffset != 16 {
+		t.Errorf("session header %+v", h)
+	}
+
+	payload := message(MsgTypeHeartbeatResponse, 0, 9)
+	h, err = parseHeader(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.hasSEID || h.seq != 9 || h.ieOffset != 8 {
+		t.Errorf("node header %+v", h)
+	}
+
+	// Same bytes with S=1: rejected rather than read as a 16-byte header
+	payload[0] |= 0x01
+	binary.BigEndian.PutUint16(payload[2:4], 12)
+	payload = append(payload, make([]byte, 8)...)
+	if _, err := parseHeader(payload); err == nil {
+		t.Error("Heartbeat Response with S=1 accepted")
+	}
+}
@@ -0,0 +1,252 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// IE types of Packet Detection Rules (TS 29.244 7.5.2.2, 7.5.4.2)
+const (
+	IETypeCreatedPDR = 8  // Created PDR (Session Establishment/Modification Response)
+	IETypeUpdatePDR  = 9  // Update PDR
+	IETypeRemovePDR  = 15 // Remove PDR
+	IETypePDRID      = 56 // PDR ID
+)
+
+// PDR is a Packet Detection Rule of a session. The UPF matches a packet
+// against the PDRs of its session in precedence order, lowest value first,
+// so a PDR shadowed by one with a lower precedence never sees traffic.
+type PDR struct {
+	ID         uint16
+	Precedence uint32
+
+	// From the PDI: the interface the packets come from (Access, Core,
+	// ...) and the local F-TEID they arrive on. TEID is 0 if the PDI has
+	// no F-TEID, or while the UPF has not reported the one it chose.
+	SourceInterface string
+	TEID            uint32
+	TEIDAddr        net.IP
+
+	FARID uint32 // FAR applied to matching packets (0 if not given)
+}
+
+// sourceInterfaceNames are the Source Interface values (TS 29.244 8.2.2)
+var sourceInterfaceNames = map[uint8]string{
+	0: "Access",
+	1: "Core",
+	2: "SGi-LAN/N6-LAN",
+	3: "CP-function",
+	4: "5G VN Internal",
+}
+
+// extractPDRs applies the Create, Update and Remove PDR IEs of an
+// Establishment or Modification Request to the session's PDRs, which stay
+// sorted by precedence (see sortPDRs)
+func (s *Sniffer) extractPDRs(vlog msgLog, ieData []byte, session *Session) {
+	// Index the PDRs by ID once per message, sessions may have many
+	var index map[uint16]int
+	find := func(id uint16) (int, bool) {
+		if index == nil {
+			index = make(map[uint16]int, len(session.PDRs))
+			for i, pdr := range session.PDRs {
+				index[pdr.ID] = i
+			}
+		}
+		i, ok := index[id]
+		return i, ok
+	}
+
+	changed := false
+	removed := make(map[int]bool)
+	walkIEs(ieData, func(ieType uint16, ieValue []byte) {
+		switch ieType {
+		case IETypeCreatePDR, IETypeUpdatePDR:
+			// An Update of an unknown PDR (session established before the
+			// sniffer started) is recorded with the IEs it carries
+			id, ok := findPDRID(ieValue)
+			if !ok {
+				return
+			}
+			i, ok := find(id)
+			if !ok {
+				i = len(session.PDRs)
+				session.PDRs = append(session.PDRs, PDR{ID: id})
+				index[id] = i
+			} else if ieType == IETypeCreatePDR {
+				session.PDRs[i] = PDR{ID: id}
+			}
+			parsePDR(ieValue, &session.PDRs[i])
+			changed = true
+		case IETypeRemovePDR:
+			id, ok := findPDRID(ieValue)
+			if !ok {
+				return
+			}
+			// Dropped below, so that the index stays valid
+			if i, ok := find(id); ok {
+				removed[i] = true
+				delete(index, id)
+				changed = true
+			}
+		}
+	})
+	if !changed {
+		return
+	}
+
+	if len(removed) > 0 {
+		kept := session.PDRs[:0]
+		for i, pdr := range session.PDRs {
+			if !removed[i] {
+				kept = append(kept, pdr)
+			}
+		}
+		session.PDRs = kept
+	}
+	sortPDRs(session.PDRs)
+	session.PDRCount = len(session.PDRs)
+	vlog.Printf("   └─ PDRs by precedence: %s", formatPDROrder(session.PDRs))
+}
+
+// parsePDR applies the IEs of a Create or Update PDR grouped IE to pdr. A
+// PDI replaces the previous one entirely.
+func parsePDR(pdrData []byte, pdr *PDR) {
+	walkIEs(pdrData, func(ieType uint16, ieValue []byte) {
+		switch ieType {
+		case IETypePrecedence:
+			if len(ieValue) >= 4 {
+				pdr.Precedence = binary.BigEndian.Uint32(ieValue[0:4])
+			}
+		case IETypeFARID:
+			if len(ieValue) >= 4 {
+				pdr.FARID = binary.BigEndian.Uint32(ieValue[0:4])
+			}
+		case IETypePDI:
+			pdr.SourceInterface = ""
+			pdr.TEID = 0
+			pdr.TEIDAddr = nil
+			walkIEs(ieValue, func(ieType uint16, ieValue []byte) {
+				switch ieType {
+				case IETypeSourceInterface:
+					if len(ieValue) >= 1 {
+						pdr.SourceInterface = formatSourceInterface(ieValue[0] & 0x0F)
+					}
+				case IETypeFTEID:
+					// CHOOSE F-TEIDs are filled in from the Created PDR
+					if teid, ok := parseFTEIDTEID(ieValue); ok {
+						pdr.TEID = teid
+						pdr.TEIDAddr = parseFTEIDAddr(ieValue)
+					}
+				}
+			})
+		}
+	})
+}
+
+// findPDRID returns the PDR ID of a grouped IE
+func findPDRID(data []byte) (uint16, bool) {
+	var id uint16
+	var found bool
+	walkIEs(data, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypePDRID && len(ieValue) >= 2 {
+			id = binary.BigEndian.Uint16(ieValue[0:2])
+			found = true
+		}
+	})
+	return id, found
+}
+
+func formatSourceInterface(value uint8) string {
+	if name, ok := sourceInterfaceNames[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type-%d", value)
+}
+
+// sortPDRs orders PDRs the way the UPF evaluates them: by precedence, and
+// by ID among equal precedences so the order is the same on every call
+func sortPDRs(pdrs []PDR) {
+	slices.SortFunc(pdrs, func(a, b PDR) int {
+		if a.Precedence != b.Precedence {
+			if a.Precedence < b.Precedence {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ID) - int(b.ID)
+	})
+}
+
+// formatPDROrder lists PDRs as id(precedence), for the message log
+func formatPDROrder(pdrs []PDR) string {
+	order := make([]byte, 0, 12*len(pdrs))
+	for i, pdr := range pdrs {
+		if i > 0 {
+			order = append(order, ' ')
+		}
+		order = fmt.Appendf(order, "%d(%d)", pdr.ID, pdr.Precedence)
+	}
+	return string(order)
+}
+
+// createdPDRTEIDs returns the F-TEIDs the UPF chose, by PDR ID, from the
+// Created PDR IEs of a response
+func createdPDRTEIDs(ieData []byte) map[uint16]PDR {
+	var created map[uint16]PDR
+	walkIEs(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType != IETypeCreatedPDR {
+			return
+		}
+		id, ok := findPDRID(ieValue)
+		if !ok {
+			return
+		}
+		walkIEs(ieValue, func(ieType uint16, fteid []byte) {
+			if ieType != IETypeFTEID {
+				return
+			}
+			if teid, ok := parseFTEIDTEID(fteid); ok {
+				if created == nil {
+					created = make(map[uint16]PDR)
+				}
+				created[id] = PDR{ID: id, TEID: teid, TEIDAddr: parseFTEIDAddr(fteid)}
+			}
+		})
+	})
+	return created
+}
+
+// SetCreatedPDRTEIDs records the F-TEIDs the UPF chose for the PDRs of the
+// session whose SMF-side SEID is cpSEID, from the Created PDR IEs of the
+// Establishment Response
+func (c *Correlation) SetCreatedPDRTEIDs(cpSEID uint64, ieData []byte) {
+	created := createdPDRTEIDs(ieData)
+	if len(created) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.cpSEIDMap[cpSEID]
+	if !ok {
+		return
+	}
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return
+	}
+	changed := false
+	for i := range session.PDRs {
+		if pdr, ok := created[session.PDRs[i].ID]; ok {
+			session.PDRs[i].TEID = pdr.TEID
+			session.PDRs[i].TEIDAddr = pdr.TEIDAddr
+			changed = true
+		}
+	}
+	if changed {
+		c.saveLocked(session)
+	}
+}
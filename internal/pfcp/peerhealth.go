@@ -0,0 +1,142 @@
+package pfcp
+
+import (
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PeerHealth is the N4 health of one PFCP peer, from the capture times of
+// the messages it sent
+type PeerHealth struct {
+	Peer      string    `json:"peer"`
+	Messages  uint64    `json:"messages"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	// Mean and standard deviation (jitter) of the time between two
+	// messages, in milliseconds
+	MeanInterArrivalMs float64 `json:"mean_inter_arrival_ms"`
+	JitterMs           float64 `json:"jitter_ms"`
+
+	// Heartbeat Requests sent, and the heartbeats missing between them
+	// (counted with a heartbeat interval only, see SetPeerHealth)
+	Heartbeats      uint64    `json:"heartbeats"`
+	LastHeartbeat   time.Time `json:"last_heartbeat"`
+	HeartbeatMisses uint64    `json:"heartbeat_misses"`
+}
+
+// peerHealth tracks PeerHealth per peer IP
+type peerHealth struct {
+	mu                sync.Mutex
+	heartbeatInterval time.Duration
+	peers             map[string]*peerArrivals
+}
+
+// peerArrivals is the running state of one peer. The inter-arrival mean
+// and variance are kept with Welford's algorithm, in seconds.
+type peerArrivals struct {
+	stat      PeerHealth
+	intervals uint64
+	mean      float64
+	m2        float64
+}
+
+// SetPeerHealth enables tracking the inter-arrival time of the PFCP
+// messages of each peer, by capture time so replayed captures give the
+// same numbers (see PeerHealth). With a heartbeatInterval, the time
+// between a peer's Heartbeat Requests is checked against it: a gap of n
+// intervals (rounded) counts n-1 missed heartbeats. PFCP does not carry
+// the interval, it is the nodes' configuration. Must be called before
+// Start.
+func (s *Sniffer) SetPeerHealth(enabled bool, heartbeatInterval time.Duration) {
+	if enabled {
+		s.peerHealth = &peerHealth{
+			heartbeatInterval: heartbeatInterval,
+			peers:             make(map[string]*peerArrivals),
+		}
+	} else {
+		s.peerHealth = nil
+	}
+}
+
+// trackPeer records a message of msgType sent by peer at the capture time
+// ts, if enabled. Messages processed out of capture order (by different
+// workers, see SetWorkers) only count towards Messages.
+func (s *Sniffer) trackPeer(msgType uint8, peer net.IP, ts time.Time) {
+	if s.peerHealth == nil || peer == nil {
+		return
+	}
+	h := s.peerHealth
+	key := peer.String()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.peers[key]
+	if !ok {
+		p = &peerArrivals{stat: PeerHealth{Peer: key, FirstSeen: ts, LastSeen: ts}}
+		h.peers[key] = p
+	}
+	p.stat.Messages++
+
+	if ok && !ts.Before(p.stat.LastSeen) {
+		gap := ts.Sub(p.stat.LastSeen).Seconds()
+		p.intervals++
+		delta := gap - p.mean
+		p.mean += delta / float64(p.intervals)
+		p.m2 += delta * (gap - p.mean)
+		p.stat.LastSeen = ts
+	}
+
+	if msgType != MsgTypeHeartbeatRequest {
+		return
+	}
+	p.stat.Heartbeats++
+	last := p.stat.LastHeartbeat
+	if !last.IsZero() && ts.Before(last) {
+		return
+	}
+	if !last.IsZero() && h.heartbeatInterval > 0 {
+		gap := ts.Sub(last)
+		if missed := (gap+h.heartbeatInterval/2)/h.heartbeatInterval - 1; missed > 0 {
+			p.stat.HeartbeatMisses += uint64(missed)
+		}
+	}
+	p.stat.LastHeartbeat = ts
+}
+
+// PeerHealth returns the health of the peers seen so far, ordered by peer,
+// and whether tracking is enabled at all
+func (s *Sniffer) PeerHealth() ([]PeerHealth, bool) {
+	if s.peerHealth == nil {
+		return nil, false
+	}
+	h := s.peerHealth
+	h.mu.Lock()
+	peers := make([]PeerHealth, 0, len(h.peers))
+	for _, p := range h.peers {
+		stat := p.stat
+		stat.MeanInterArrivalMs = p.mean * 1000
+		if p.intervals > 1 {
+			stat.JitterMs = math.Sqrt(p.m2/float64(p.intervals-1)) * 1000
+		}
+		peers = append(peers, stat)
+	}
+	h.mu.Unlock()
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Peer < peers[j].Peer
+	})
+	return peers, true
+}
+
+// HeartbeatInterval returns the heartbeat interval given to SetPeerHealth
+func (s *Sniffer) HeartbeatInterval() time.Duration {
+	if s.peerHealth == nil {
+		return 0
+	}
+	return s.peerHealth.heartbeatInterval
+}
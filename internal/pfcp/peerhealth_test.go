@@ -0,0 +1,79 @@
+package pfcp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPeerHealth(t *testing.T) {
+	quietLog(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	// Seconds after start of the SMF's messages, in processing order. With
+	// a 10s interval, the 24s gap rounds to 2 intervals and the 15s one
+	// up to 2, each one missed heartbeat; the 14s gap rounds down to 1.
+	arrivals := []struct {
+		at        float64
+		heartbeat bool
+	}{
+		{0, true}, {10, true}, {20, true}, {25, false}, {44, true}, {58, true}, {73, true},
+		{30, true}, // Out of capture order, only counted
+	}
+	const wantMisses = 2
+
+	for _, interval := range []time.Duration{0, 10 * time.Second} {
+		s := newTestSniffer()
+		s.SetPeerHealth(true, interval)
+		for i, a := range arrivals {
+			ts := start.Add(time.Duration(a.at * float64(time.Second)))
+			payload := heartbeatRequest(uint32(i))
+			if !a.heartbeat {
+				payload = establishmentRequest(uint32(i), 0x1001, testSMF, "10.60.0.1", 0x10001)
+			}
+			s.processPacket(packetAt(t, testSMF, testUPF, payload, ts))
+		}
+
+		peers, enabled := s.PeerHealth()
+		if !enabled || len(peers) != 1 {
+			t.Fatalf("peer health %v, enabled %v, want the SMF", peers, enabled)
+		}
+		got := peers[0]
+		if got.Peer != testSMF || got.Messages != 8 || got.Heartbeats != 7 {
+			t.Errorf("%s: %d messages, %d heartbeats, want %s, 8 and 7", got.Peer, got.Messages, got.Heartbeats, testSMF)
+		}
+		if !got.LastSeen.Equal(start.Add(73*time.Second)) || !got.LastHeartbeat.Equal(got.LastSeen) {
+			t.Errorf("last seen %v, last heartbeat %v, want start+73s", got.LastSeen, got.LastHeartbeat)
+		}
+
+		// Two-pass mean and sample standard deviation of the in-order gaps
+		gaps := []float64{10, 10, 5, 19, 14, 15}
+		var mean, variance float64
+		for _, gap := range gaps {
+			mean += gap
+		}
+		mean /= float64(len(gaps))
+		for _, gap := range gaps {
+			variance += (gap - mean) * (gap - mean)
+		}
+		jitter := math.Sqrt(variance/float64(len(gaps)-1)) * 1000
+		if math.Abs(got.MeanInterArrivalMs-mean*1000) > 1e-6 || math.Abs(got.JitterMs-jitter) > 1e-6 {
+			t.Errorf("mean %.3fms, jitter %.3fms, want %.3fms and %.3fms", got.MeanInterArrivalMs, got.JitterMs, mean*1000, jitter)
+		}
+
+		misses := uint64(0)
+		if interval > 0 {
+			misses = wantMisses
+		}
+		if got.HeartbeatMisses != misses {
+			t.Errorf("interval %s: %d heartbeat misses, want %d", interval, got.HeartbeatMisses, misses)
+		}
+	}
+}
+
+func TestPeerHealthDisabled(t *testing.T) {
+	s := newTestSniffer()
+	s.processPacket(packet(t, testSMF, testUPF, heartbeatRequest(1)))
+	if peers, enabled := s.PeerHealth(); enabled || peers != nil {
+		t.Errorf("peer health %v, enabled %v without SetPeerHealth", peers, enabled)
+	}
+}
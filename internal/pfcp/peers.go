@@ -0,0 +1,90 @@
+package pfcp
+
+import (
+	"net"
+	"slices"
+)
+
+// sessionPeers returns the SMF and UPF IPs of a session that are known
+func sessionPeers(session *Session) []net.IP {
+	peers := make([]net.IP, 0, 2)
+	if session.SMFIP != nil {
+		peers = append(peers, session.SMFIP)
+	}
+	if session.UPFIP != nil && !session.UPFIP.Equal(session.SMFIP) {
+		peers = append(peers, session.UPFIP)
+	}
+	return peers
+}
+
+// indexPeersLocked records session under its SMF and UPF IPs in peerMap,
+// caller must hold c.mu
+func (c *Correlation) indexPeersLocked(session *Session) {
+	for _, ip := range sessionPeers(session) {
+		key := ip.String()
+		seids, ok := c.peerMap[key]
+		if !ok {
+			seids = make(map[uint64]struct{})
+			c.peerMap[key] = seids
+		}
+		seids[session.SEID] = struct{}{}
+	}
+}
+
+// unindexPeersLocked removes session from peerMap, caller must hold c.mu
+func (c *Correlation) unindexPeersLocked(session *Session) {
+	for _, ip := range sessionPeers(session) {
+		key := ip.String()
+		delete(c.peerMap[key], session.SEID)
+		if len(c.peerMap[key]) == 0 {
+			delete(c.peerMap, key)
+		}
+	}
+}
+
+// peerSEIDsLocked returns the SEIDs of the sessions of the peer at ip in
+// ascending order, caller must hold c.mu
+func (c *Correlation) peerSEIDsLocked(ip net.IP) []uint64 {
+	return sortedSEIDs(c.peerMap[ip.String()])
+}
+
+// sortedSEIDs returns the SEIDs of set in ascending order
+func sortedSEIDs(set map[uint64]struct{}) []uint64 {
+	seids := make([]uint64, 0, len(set))
+	for seid := range set {
+		seids = append(seids, seid)
+	}
+	slices.Sort(seids)
+	return seids
+}
+
+// GetSessionsByPeer returns copies of the sessions controlled by the SMF
+// at ip or handled by the UPF at ip
+func (c *Correlation) GetSessionsByPeer(ip net.IP) []*Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seids := c.peerSEIDsLocked(ip)
+	sessions := make([]*Session, 0, len(seids))
+	for _, seid := range seids {
+		if session, ok := c.store.GetBySEID(seid); ok {
+			sessions = append(sessions, session.Clone())
+		}
+	}
+	return sessions
+}
+
+// RemoveSessionsByPeer removes every session controlled or handled by the
+// node at ip and publishes a SessionEventPeerRestart event for each.
+// Returns the number of sessions removed.
+func (c *Correlation) RemoveSessionsByPeer(ip net.IP) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// removeSessionLocked edits peerMap, so work on a copy
+	seids := c.peerSEIDsLocked(ip)
+	for _, seid := range seids {
+		c.removeSessionLocked(seid, SessionEventPeerRestart)
+	}
+	return len(seids)
+}
@@ -0,0 +1,55 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"log"
+	"net"
+)
+
+// extractRecoveryTimeStamp returns the Recovery Time Stamp IE in ieData
+// (seconds since 1900, NTP format), or 0 if there is none
+func (s *Sniffer) extractRecoveryTimeStamp(ieData []byte) uint32 {
+	var ts uint32
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType == IETypeRecoveryTimeStamp && ts == 0 && len(ieValue) >= 4 {
+			ts = binary.BigEndian.Uint32(ieValue[0:4])
+		}
+	})
+	return ts
+}
+
+// checkPeerRecovery tracks the Recovery Time Stamp of the node that sent a
+// heartbeat or association message. A later timestamp means the peer
+// restarted and lost all its sessions without deleting them, so the
+// sessions associated with it are removed. An earlier one comes from a
+// reordered or delayed message and is ignored, keeping the latest seen.
+// Timestamps are compared in serial number arithmetic so that the NTP
+// era rollover in 2036 does not look like a step back.
+func (s *Sniffer) checkPeerRecovery(msgType uint8, peerIP net.IP, ieData []byte) {
+	if peerIP == nil {
+		return
+	}
+	ts := s.extractRecoveryTimeStamp(ieData)
+	if ts == 0 {
+		return
+	}
+
+	peer := peerIP.String()
+	s.recoveryMu.Lock()
+	prev, known := s.recoveryTimes[peer]
+	restarted := known && int32(ts-prev) > 0
+	if !known || restarted {
+		s.recoveryTimes[peer] = ts
+	}
+	s.recoveryMu.Unlock()
+
+	if !restarted {
+		return
+	}
+
+	log.Printf("[PFCP] Peer %s restarted (%s: recovery time stamp %d -> %d)",
+		peer, MessageTypeName(msgType), prev, ts)
+	if n := s.correlation.RemoveSessionsByPeer(peerIP); n > 0 {
+		log.Printf("   └─ Removed %d stale sessions of %s", n, peer)
+	}
+}
@@ -0,0 +1,37 @@
+package pfcp
+
+import "testing"
+
+func TestCheckPeerRecovery(t *testing.T) {
+	quietLog(t)
+	heartbeat := func(seq, ts uint32) []byte {
+		return message(MsgTypeHeartbeatRequest, 0, seq, ie(IETypeRecoveryTimeStamp, u32(ts)))
+	}
+	tests := []struct {
+		name     string
+		ts       []uint32
+		restarts bool
+	}{
+		{"same", []uint32{0xe0000100, 0xe0000100}, false},
+		{"later", []uint32{0xe0000100, 0xe0000200}, true},
+		{"earlier", []uint32{0xe0000100, 0xe0000080}, false},
+		// The max is kept, the earlier one does not become the reference
+		{"earlier then same", []uint32{0xe0000100, 0xe0000080, 0xe0000100}, false},
+		{"NTP era rollover", []uint32{0xffffff00, 0x00000010}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSniffer()
+			s.processPacket(packet(t, testSMF, testUPF, heartbeat(1, tt.ts[0])))
+			establish(t, s, 2, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+			for i, ts := range tt.ts[1:] {
+				s.processPacket(packet(t, testSMF, testUPF, heartbeat(uint32(3+i), ts)))
+			}
+
+			_, kept := s.correlation.GetSessionByTEID(0x10001)
+			if kept == tt.restarts {
+				t.Errorf("session kept %v after recovery time stamps %x, want %v", kept, tt.ts, !tt.restarts)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+//go:build redis
+
+package pfcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// RedisStore is a SessionStore that keeps the sessions in memory, like the
+// default store, and also saves them in a Redis hash so they survive an
+// agent restart. Lookups never go to Redis.
+//
+// Writes happen in the background: a changed session is encoded when it is
+// added and written with the other changes of the last redisFlushInterval
+// in one pipeline, so PFCP processing never waits for Redis and a session
+// changing every second costs one write per interval. Failed writes are
+// retried on the next flush unless the session changed again.
+type RedisStore struct {
+	*memoryStore
+	client  *redis.Client
+	key     string
+	onError func(n int, err error)
+
+	mu      sync.Mutex
+	pending map[uint64][]byte // SEID -> encoded session, nil to delete
+
+	lastLog  time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// storedSession is how a session is saved in Redis: its exported fields,
+// plus the unexported ones that still matter after a restart
+type storedSession struct {
+	*Session
+	BufferingFARs []uint32 `json:",omitempty"`
+	Establishment uint64   `json:",omitempty"`
+}
+
+// NewRedisStore connects to Redis and loads the sessions saved under
+// cfg.Key, to be passed to NewCorrelationWithStore. Close flushes the
+// pending writes.
+func NewRedisStore(cfg RedisStoreConfig) (*RedisStore, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("no redis address configured")
+	}
+	if cfg.Key == "" {
+		return nil, errors.New("no redis key configured")
+	}
+
+	s := &RedisStore{
+		memoryStore: newMemoryStore(),
+		client: redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			DialTimeout:  redisTimeout,
+			ReadTimeout:  redisTimeout,
+			WriteTimeout: redisTimeout,
+		}),
+		key:      cfg.Key,
+		onError:  cfg.OnError,
+		pending:  make(map[uint64][]byte),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		s.client.Close()
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// load reads the saved sessions. Undecodable ones are skipped.
+func (s *RedisStore) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	saved, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return err
+	}
+	for field, data := range saved {
+		stored := storedSession{Session: &Session{}}
+		if err := json.Unmarshal([]byte(data), &stored); err != nil || stored.SEID == 0 {
+			log.Printf("[WARN] Redis store: skipping undecodable session %s in %s", field, s.key)
+			continue
+		}
+		stored.bufferingFARs = stored.BufferingFARs
+		stored.establishment = stored.Establishment
+		s.memoryStore.Add(stored.Session)
+	}
+	return nil
+}
+
+// Add stores the session and queues it for writing
+func (s *RedisStore) Add(session *Session) {
+	s.memoryStore.Add(session)
+	data, err := json.Marshal(storedSession{
+		Session:       session,
+		BufferingFARs: session.bufferingFARs,
+		Establishment: session.establishment,
+	})
+	if err != nil {
+		log.Printf("[WARN] Redis store: failed to encode session SEID=0x%x: %v", session.SEID, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.pending[session.SEID] = data
+	s.mu.Unlock()
+}
+
+// Remove removes the session and queues its deletion
+func (s *RedisStore) Remove(seid uint64) {
+	s.memoryStore.Remove(seid)
+
+	s.mu.Lock()
+	s.pending[seid] = nil
+	s.mu.Unlock()
+}
+
+func (s *RedisStore) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(redisFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush writes the pending changes in one pipeline. On failure they are
+// queued again, unless the session changed in the meantime.
+func (s *RedisStore) flush() {
+	s.mu.Lock()
+	batch := s.pending
+	if len(batch) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.pending = make(map[uint64][]byte)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+	for seid, data := range batch {
+		field := model.FormatSEID(seid)
+		if data == nil {
+			pipe.HDel(ctx, s.key, field)
+		} else {
+			pipe.HSet(ctx, s.key, field, data)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.mu.Lock()
+		for seid, data := range batch {
+			if _, changed := s.pending[seid]; !changed {
+				s.pending[seid] = data
+			}
+		}
+		s.mu.Unlock()
+		s.fail(len(batch), err)
+	}
+}
+
+func (s *RedisStore) fail(n int, err error) {
+	if s.onError != nil {
+		s.onError(n, err)
+	}
+	if time.Since(s.lastLog) >= redisFailureLogInterval {
+		s.lastLog = time.Now()
+		log.Printf("[WARN] Redis store: failed to save %d session change(s), retrying: %v", n, err)
+	}
+}
+
+// Close writes the pending changes and disconnects
+func (s *RedisStore) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	<-s.done
+	if err := s.client.Close(); err != nil {
+		log.Printf("[WARN] Redis store: close failed: %v", err)
+	}
+}
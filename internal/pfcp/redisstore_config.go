@@ -0,0 +1,33 @@
+package pfcp
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRedisDisabled is returned by NewRedisStore when the binary was built
+// without the "redis" build tag
+var ErrRedisDisabled = errors.New("redis session store not compiled in (build with -tags redis)")
+
+// Redis store timing: changed sessions are written in batches every
+// redisFlushInterval, failures logged at most every redisFailureLogInterval
+const (
+	redisFlushInterval      = time.Second
+	redisFailureLogInterval = 30 * time.Second
+	redisTimeout            = 5 * time.Second
+)
+
+// RedisStoreConfig configures the Redis session store
+type RedisStoreConfig struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+
+	// Hash holding the sessions, one field per internal SEID. Agents
+	// sharing a Redis need one each.
+	Key string
+
+	// OnError is called with the number of session writes that failed and
+	// will be retried, e.g. to feed a failure counter. May be nil.
+	OnError func(n int, err error)
+}
@@ -0,0 +1,16 @@
+//go:build !redis
+
+package pfcp
+
+// RedisStore is a placeholder used when the Redis store is not compiled in
+type RedisStore struct {
+	*memoryStore
+}
+
+// NewRedisStore always fails with ErrRedisDisabled in this build
+func NewRedisStore(cfg RedisStoreConfig) (*RedisStore, error) {
+	return nil, ErrRedisDisabled
+}
+
+// Close is a no-op in this build
+func (s *RedisStore) Close() {}
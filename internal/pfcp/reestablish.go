@@ -0,0 +1,62 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+)
+
+// establishmentDigest fingerprints an Establishment Request by its
+// sequence number and IEs. A retransmission repeats both byte for byte,
+// while a new establishment reusing the SEID has a new sequence number.
+func establishmentDigest(seq uint32, ieData []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(binary.BigEndian.AppendUint32(nil, seq))
+	h.Write(ieData)
+	return h.Sum64()
+}
+
+// checkReestablishmentLocked handles an Establishment for a CP SEID that
+// already has a session, caller must hold c.mu. A retransmission of the
+// request that created the session is ignored (returns false). Anything
+// else means the SMF reused the SEID, most likely after a Deletion we
+// missed: the old session is removed, dropping its TEID and UE IP
+// mappings, so the new one starts clean instead of being merged into it.
+func (c *Correlation) checkReestablishmentLocked(session *Session) bool {
+	if session.RemoteSEID == 0 {
+		return true
+	}
+	seid, ok := c.cpSEIDMap[session.RemoteSEID]
+	if !ok {
+		return true
+	}
+	existing, ok := c.store.GetBySEID(seid)
+	if !ok || existing == session {
+		return true
+	}
+
+	if session.establishment != 0 && session.establishment == existing.establishment {
+		c.establishmentRetransmissions.Add(1)
+		log.Printf("[DEBUG] AddSession: Retransmitted Establishment for CP SEID 0x%x ignored (SEID=0x%x)",
+			session.RemoteSEID, seid)
+		return false
+	}
+
+	c.reestablishments.Add(1)
+	log.Printf("[WARN] CP SEID 0x%x re-established, replacing session SEID=0x%x (UE IP %s)",
+		session.RemoteSEID, seid, existing.UEIP)
+	c.removeSessionLocked(seid, SessionEventDeleted)
+	return true
+}
+
+// EstablishmentRetransmissions returns the number of retransmitted
+// Establishment Requests that were ignored
+func (c *Correlation) EstablishmentRetransmissions() uint64 {
+	return c.establishmentRetransmissions.Load()
+}
+
+// Reestablishments returns the number of sessions replaced by a new
+// Establishment with the same CP SEID
+func (c *Correlation) Reestablishments() uint64 {
+	return c.reestablishments.Load()
+}
@@ -0,0 +1,106 @@
+package pfcp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstablishmentRetransmission(t *testing.T) {
+	quietLog(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	request := establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001)
+
+	s := newTestSniffer()
+	s.processPacket(packetAt(t, testSMF, testUPF, request, start))
+	s.processPacket(packetAt(t, testUPF, testSMF, establishmentResponse(1, 0x1001, 0x2001), start.Add(time.Millisecond)))
+	before, ok := s.correlation.GetSessionByTEID(0x10001)
+	if !ok {
+		t.Fatal("session not established")
+	}
+
+	// The SMF missed the response and sends the same request again
+	s.processPacket(packetAt(t, testSMF, testUPF, request, start.Add(3*time.Second)))
+
+	c := s.correlation
+	if got := c.EstablishmentRetransmissions(); got != 1 {
+		t.Errorf("%d retransmissions, want 1", got)
+	}
+	if got := c.Reestablishments(); got != 0 {
+		t.Errorf("%d re-establishments, want 0", got)
+	}
+	after, ok := c.GetSessionByTEID(0x10001)
+	if !ok || after.SEID != before.SEID {
+		t.Fatal("retransmission replaced the session")
+	}
+	if !after.CreatedAt.Equal(start) {
+		t.Errorf("CreatedAt %v, want %v", after.CreatedAt, start)
+	}
+	if after.LocalSEID != 0x2001 {
+		t.Errorf("UP SEID 0x%x lost, want 0x2001", after.LocalSEID)
+	}
+	if n := c.SessionCount(); n != 1 {
+		t.Errorf("%d sessions, want 1", n)
+	}
+}
+
+func TestEstablishmentSEIDReuse(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+	old := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	// The Deletion was missed, the SMF reuses CP SEID 0x1001 for a new UE
+	establish(t, s, 7, 0x1001, 0x2002, "10.60.0.2", 0x10002)
+
+	if got := c.Reestablishments(); got != 1 {
+		t.Errorf("%d re-establishments, want 1", got)
+	}
+	if got := c.EstablishmentRetransmissions(); got != 0 {
+		t.Errorf("%d retransmissions, want 0", got)
+	}
+	if _, ok := c.GetSessionBySEID(old); ok {
+		t.Error("old session kept")
+	}
+	if _, ok := c.GetSessionByTEID(0x10001); ok {
+		t.Error("TEID of the old session still mapped")
+	}
+	if _, ok := c.GetSessionByUEIP("10.60.0.1"); ok {
+		t.Error("UE IP of the old session still mapped")
+	}
+	session, ok := c.GetSessionByTEID(0x10002)
+	if !ok {
+		t.Fatal("new session not established")
+	}
+	if session.UEIP.String() != "10.60.0.2" || len(session.TEIDs) != 1 {
+		t.Errorf("new session UE IP %s, TEIDs %v: merged with the old one", session.UEIP, session.TEIDs)
+	}
+	if n := c.SessionCount(); n != 1 {
+		t.Errorf("%d sessions, want 1", n)
+	}
+}
+
+func TestEstablishmentDuplicateUEIPCaptureTime(t *testing.T) {
+	quietLog(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		gap    time.Duration
+		merged bool
+	}{
+		{"10ms apart", 10 * time.Millisecond, false},
+		{"5min apart", 5 * time.Minute, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Both arrive at once, as when reading a capture file
+			s := newTestSniffer()
+			s.processPacket(packetAt(t, testSMF, testUPF,
+				establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001), start))
+			s.processPacket(packetAt(t, testSMF, testUPF,
+				establishmentRequest(2, 0x1002, testSMF, "10.60.0.1", 0x10002), start.Add(tc.gap)))
+
+			if _, ok := s.correlation.GetSessionByTEID(0x10002); ok != tc.merged {
+				t.Errorf("second TEID mapped %v, want %v", ok, tc.merged)
+			}
+		})
+	}
+}
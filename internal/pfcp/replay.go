@@ -0,0 +1,144 @@
+package pfcp
+
+import (
+	"context"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+// maxReplayRecords bounds the events and the errors a replay returns
+const maxReplayRecords = 10000
+
+// ReplayEvent is a session lifecycle event seen during a replay, stamped
+// with the capture time and 1-based index of the packet that caused it
+type ReplayEvent struct {
+	Time   string   `json:"time"`
+	Packet int      `json:"packet"`
+	Type   string   `json:"type"`
+	SEID   string   `json:"seid"`
+	UEIP   string   `json:"ue_ip,omitempty"`
+	TEIDs  []string `json:"teids,omitempty"`
+}
+
+// ReplayError is a PFCP message of a replay that could not be parsed
+type ReplayError struct {
+	Time   string `json:"time"`
+	Packet int    `json:"packet"`
+	Source string `json:"source,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ReplayResult is what a replayed capture produced
+type ReplayResult struct {
+	Packets  int                 `json:"packets"`
+	Sessions []model.SessionInfo `json:"sessions"` // Sessions left at the end, by SEID
+	Events   []ReplayEvent       `json:"events"`
+	Errors   []ReplayError       `json:"errors"`
+
+	// Events or errors past maxReplayRecords were left out
+	Truncated bool `json:"truncated,omitempty"`
+
+	// The replay was stopped before the end of the capture
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// replayRecorder collects a ReplayResult. It is called from the capture
+// loop of a single-worker sniffer, so it needs no locking.
+type replayRecorder struct {
+	result     ReplayResult
+	packetTime time.Time
+}
+
+func (r *replayRecorder) packet(ts time.Time) {
+	r.result.Packets++
+	r.packetTime = ts
+}
+
+func (r *replayRecorder) event(eventType string, session *Session) {
+	if len(r.result.Events) >= maxReplayRecords {
+		r.result.Truncated = true
+		return
+	}
+	teids := make([]string, 0, len(session.TEIDs))
+	for _, teid := range session.TEIDs {
+		teids = append(teids, model.FormatTEID(teid))
+	}
+	r.result.Events = append(r.result.Events, ReplayEvent{
+		Time:   r.packetTime.Format(time.RFC3339Nano),
+		Packet: r.result.Packets,
+		Type:   eventType,
+		SEID:   model.FormatSEID(session.SEID),
+		UEIP:   ipString(session.UEIP),
+		TEIDs:  teids,
+	})
+}
+
+func (r *replayRecorder) error(src net.IP, msg string) {
+	if len(r.result.Errors) >= maxReplayRecords {
+		r.result.Truncated = true
+		return
+	}
+	r.result.Errors = append(r.result.Errors, ReplayError{
+		Time:   r.packetTime.Format(time.RFC3339Nano),
+		Packet: r.result.Packets,
+		Source: ipString(src),
+		Error:  msg,
+	})
+}
+
+// Replay runs the PFCP messages of a pcap file through a new Correlation,
+// leaving all others alone, and returns the sessions it ends up with, the
+// lifecycle events on the way and the messages that could not be parsed.
+// Replayed sessions are not traced. When ctx is done the replay stops and
+// returns what it has so far.
+func Replay(ctx context.Context, path string, port uint16) (*ReplayResult, error) {
+	recorder := &replayRecorder{}
+	correlation := NewCorrelation()
+	correlation.replay = recorder
+
+	sniffer := NewSnifferFromFile(path, port, correlation)
+	sniffer.replay = recorder
+	if err := sniffer.Start(); err != nil {
+		return nil, err
+	}
+	timedOut := false
+	select {
+	case <-sniffer.Done():
+	case <-ctx.Done():
+		timedOut = true
+	}
+	sniffer.Stop()
+
+	result := &recorder.result
+	result.TimedOut = timedOut
+	if result.Events == nil {
+		result.Events = []ReplayEvent{}
+	}
+	if result.Errors == nil {
+		result.Errors = []ReplayError{}
+	}
+
+	// Durations are relative to the end of the capture
+	end := recorder.packetTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	sessions := correlation.GetAllSessions()
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		if a.SEID < b.SEID {
+			return -1
+		}
+		if a.SEID > b.SEID {
+			return 1
+		}
+		return 0
+	})
+	result.Sessions = make([]model.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		result.Sessions = append(result.Sessions, session.Model(end))
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package pfcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// capturedSession is an establishment and a later modification of one
+// session as captured at start, with their capture times
+func capturedSession(tb testing.TB, start time.Time) (packets []gopacket.Packet, established, modified time.Time) {
+	established = start
+	modified = start.Add(90 * time.Second)
+	packets = []gopacket.Packet{
+		packetAt(tb, testSMF, testUPF, establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001), established),
+		packetAt(tb, testUPF, testSMF, establishmentResponse(1, 0x1001, 0x2001), established.Add(3*time.Millisecond)),
+		packetAt(tb, testSMF, testUPF, modificationRequest(2, 0x2001, 2, 0x10002), modified),
+	}
+	return packets, established, modified
+}
+
+func TestSessionTimestampsFromCapture(t *testing.T) {
+	quietLog(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	packets, established, modified := capturedSession(t, start)
+
+	s := newTestSniffer()
+	for _, p := range packets {
+		s.processPacket(p)
+	}
+
+	session, ok := s.correlation.GetSessionByTEID(0x10001)
+	if !ok {
+		t.Fatal("session not established")
+	}
+	if !session.CreatedAt.Equal(established) {
+		t.Errorf("CreatedAt %v, want the capture time %v", session.CreatedAt, established)
+	}
+	if !session.ModifiedAt.Equal(modified) {
+		t.Errorf("ModifiedAt %v, want the capture time %v", session.ModifiedAt, modified)
+	}
+	if !session.LastActive.Equal(modified) {
+		t.Errorf("LastActive %v, want the capture time %v", session.LastActive, modified)
+	}
+}
+
+func TestReplayTimestamps(t *testing.T) {
+	quietLog(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	packets, established, modified := capturedSession(t, start)
+
+	path := writePcap(t, layers.LinkTypeEthernet, packets)
+	result, err := Replay(context.Background(), path, 8805)
+	if err != nil {
+		// Only opening the capture fails, which needs libpcap
+		t.Skipf("cannot replay captures: %v", err)
+	}
+
+	if result.Packets != len(packets) {
+		t.Errorf("%d packets replayed, want %d", result.Packets, len(packets))
+	}
+	if len(result.Sessions) != 1 {
+		t.Fatalf("%d sessions, want 1", len(result.Sessions))
+	}
+	if got, want := result.Sessions[0].CreatedAt, established.Format(time.RFC3339); got != want {
+		t.Errorf("created_at %s, want the capture time %s", got, want)
+	}
+	if got, want := result.Sessions[0].LastActive, modified.Format(time.RFC3339); got != want {
+		t.Errorf("last_active %s, want the capture time %s", got, want)
+	}
+	for _, event := range result.Events {
+		want := packets[event.Packet-1].Metadata().Timestamp.Format(time.RFC3339Nano)
+		if event.Time != want {
+			t.Errorf("%s event of packet %d at %s, want %s", event.Type, event.Packet, event.Time, want)
+		}
+	}
+}
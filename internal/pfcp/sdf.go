@@ -0,0 +1,97 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SDF Filter IE flags (TS 29.244 section 8.2.5)
+const (
+	sdfFlagFD  = 0x01 // Flow Description present
+	sdfFlagTTC = 0x02 // ToS Traffic Class present
+	sdfFlagSPI = 0x04 // Security Parameter Index present
+	sdfFlagFL  = 0x08 // Flow Label present
+	sdfFlagBID = 0x10 // SDF Filter ID present
+)
+
+// parseSDFFilter renders an SDF Filter IE as a string: the flow
+// description (an IPFilterRule such as "permit out ip from any to
+// 10.60.0.1") if present, otherwise the other fields it carries. Returns
+// false for a truncated or empty filter.
+func parseSDFFilter(ieValue []byte) (string, bool) {
+	if len(ieValue) < 2 {
+		return "", false
+	}
+	flags := ieValue[0]
+	offset := 2 // flags + spare octet
+
+	var parts []string
+	if flags&sdfFlagFD != 0 {
+		if offset+2 > len(ieValue) {
+			return "", false
+		}
+		fdLen := int(binary.BigEndian.Uint16(ieValue[offset : offset+2]))
+		offset += 2
+		if offset+fdLen > len(ieValue) {
+			return "", false
+		}
+		if fdLen > 0 {
+			parts = append(parts, string(ieValue[offset:offset+fdLen]))
+		}
+		offset += fdLen
+	}
+	if flags&sdfFlagTTC != 0 {
+		if offset+2 > len(ieValue) {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("tos=0x%02x/0x%02x", ieValue[offset], ieValue[offset+1]))
+		offset += 2
+	}
+	if flags&sdfFlagSPI != 0 {
+		if offset+4 > len(ieValue) {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("spi=0x%x", binary.BigEndian.Uint32(ieValue[offset:offset+4])))
+		offset += 4
+	}
+	if flags&sdfFlagFL != 0 {
+		if offset+3 > len(ieValue) {
+			return "", false
+		}
+		label := uint32(ieValue[offset])<<16 | uint32(ieValue[offset+1])<<8 | uint32(ieValue[offset+2])
+		parts = append(parts, fmt.Sprintf("flow_label=0x%x", label&0xFFFFF))
+		offset += 3
+	}
+	if flags&sdfFlagBID != 0 {
+		if offset+4 > len(ieValue) {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("sdf_id=%d", binary.BigEndian.Uint32(ieValue[offset:offset+4])))
+	}
+
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, " "), true
+}
+
+// parseApplicationID returns the Application ID IE value, an opaque
+// identifier of a PFD set configured on the UPF
+func parseApplicationID(ieValue []byte) (string, bool) {
+	if len(ieValue) == 0 {
+		return "", false
+	}
+	return string(ieValue), true
+}
+
+// appendUnique appends the values of add that are not in list yet
+func appendUnique(list []string, add ...string) []string {
+	for _, v := range add {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,150 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+)
+
+// IETypeFQCSID is the FQ-CSID IE, which groups the sessions a node
+// handles into Connection Sets so they can be deleted together by a
+// Session Set Deletion Request
+const IETypeFQCSID = 65
+
+// SessionEventSetDeletion is published for each session removed by a
+// Session Set Deletion Request (see RemoveSessionSet)
+const SessionEventSetDeletion = "set_deletion"
+
+// FQ-CSID node ID types (TS 29.244 8.2.46)
+const (
+	fqcsidNodeIPv4   = 0
+	fqcsidNodeIPv6   = 1
+	fqcsidNodeMCCMNC = 2
+)
+
+// parseFQCSID returns the CSIDs of an FQ-CSID IE as "<node>/<csid>" keys,
+// which identify a Connection Set across nodes. FQ-CSID layout: node ID
+// type (4 bits) | number of CSIDs (4 bits) | node address | 2-byte CSIDs
+func parseFQCSID(ieValue []byte) []string {
+	if len(ieValue) < 1 {
+		return nil
+	}
+	nodeType, count := ieValue[0]>>4, int(ieValue[0]&0x0f)
+
+	var node string
+	rest := ieValue[1:]
+	switch nodeType {
+	case fqcsidNodeIPv4:
+		if len(rest) < net.IPv4len {
+			return nil
+		}
+		node, rest = net.IP(rest[:net.IPv4len]).String(), rest[net.IPv4len:]
+	case fqcsidNodeIPv6:
+		if len(rest) < net.IPv6len {
+			return nil
+		}
+		node, rest = net.IP(rest[:net.IPv6len]).String(), rest[net.IPv6len:]
+	case fqcsidNodeMCCMNC:
+		if len(rest) < 4 {
+			return nil
+		}
+		node, rest = fmt.Sprintf("%08x", binary.BigEndian.Uint32(rest[:4])), rest[4:]
+	default:
+		return nil
+	}
+
+	csids := make([]string, 0, count)
+	for i := 0; i < count && len(rest) >= 2; i++ {
+		csids = append(csids, fmt.Sprintf("%s/%d", node, binary.BigEndian.Uint16(rest[:2])))
+		rest = rest[2:]
+	}
+	return csids
+}
+
+// extractCSIDs returns the CSIDs of all FQ-CSID IEs in ieData (SGW-C,
+// MME, SMF, UPF, ... FQ-CSIDs share the IE type), without duplicates
+func (s *Sniffer) extractCSIDs(ieData []byte) []string {
+	var csids []string
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType != IETypeFQCSID {
+			return
+		}
+		for _, csid := range parseFQCSID(ieValue) {
+			if !slices.Contains(csids, csid) {
+				csids = append(csids, csid)
+			}
+		}
+	})
+	return csids
+}
+
+// AddSessionCSIDs adds csids, from the UPF's FQ-CSID in the Establishment
+// Response, to the session whose SMF-side SEID is cpSEID
+func (c *Correlation) AddSessionCSIDs(cpSEID uint64, csids []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.cpSEIDMap[cpSEID]
+	if !ok {
+		return false
+	}
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return false
+	}
+	for _, csid := range csids {
+		if !slices.Contains(session.CSIDs, csid) {
+			session.CSIDs = append(session.CSIDs, csid)
+		}
+	}
+	c.saveLocked(session)
+	return true
+}
+
+// RemoveSessionSet removes the sessions of a Session Set Deletion Request
+// sent by the node at peer: those in one of the Connection Sets csids, or,
+// if the request carried no FQ-CSID, every session of the node. A
+// SessionEventSetDeletion event is published for each, carrying the number
+// of sessions removed. Returns that number.
+func (c *Correlation) RemoveSessionSet(peer net.IP, csids []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// removeSessionLocked edits peerMap, so work on a copy
+	var seids []uint64
+	if len(csids) == 0 {
+		if peer == nil {
+			return 0
+		}
+		seids = c.peerSEIDsLocked(peer)
+	} else {
+		for _, session := range c.store.All() {
+			if slices.ContainsFunc(session.CSIDs, func(csid string) bool {
+				return slices.Contains(csids, csid)
+			}) {
+				seids = append(seids, session.SEID)
+			}
+		}
+	}
+
+	c.setDeletionSize = len(seids)
+	for _, seid := range seids {
+		c.removeSessionLocked(seid, SessionEventSetDeletion)
+	}
+	c.setDeletionSize = 0
+	return len(seids)
+}
+
+// handleSessionSetDeletion removes the sessions of a Session Set Deletion
+// Request, sent after a partial failure of the node at srcIP
+func (s *Sniffer) handleSessionSetDeletion(srcIP net.IP, ieData []byte) {
+	csids := s.extractCSIDs(ieData)
+	n := s.correlation.RemoveSessionSet(srcIP, csids)
+	if len(csids) > 0 {
+		log.Printf("[PFCP] Session Set Deletion from %s: removed %d sessions of CSIDs %v", srcIP, n, csids)
+	} else {
+		log.Printf("[PFCP] Session Set Deletion from %s without FQ-CSID: removed all %d sessions of the node", srcIP, n)
+	}
+}
@@ -0,0 +1,114 @@
+package pfcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+)
+
+// fqcsidIE is an FQ-CSID of an IPv4 node
+func fqcsidIE(node string, csids ...uint16) []byte {
+	value := append([]byte{byte(fqcsidNodeIPv4<<4 | len(csids))}, ipBytes(node)...)
+	for _, csid := range csids {
+		value = append(value, u16(csid)...)
+	}
+	return ie(IETypeFQCSID, value)
+}
+
+func nodeIDIE(ip string) []byte {
+	return ie(60, u8(0), ipBytes(ip))
+}
+
+func TestParseFQCSID(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []byte
+		want  []string
+	}{
+		{"ipv4", []byte{0x02, 10, 0, 0, 1, 0, 1, 0, 2}, []string{"10.0.0.1/1", "10.0.0.1/2"}},
+		{"ipv6", append(append([]byte{0x11}, ipBytes("2001:db8::1")...), 0, 7), []string{"2001:db8::1/7"}},
+		{"mcc mnc", []byte{0x21, 0x12, 0x34, 0x56, 0x78, 0, 9}, []string{"12345678/9"}},
+		{"short count", []byte{0x03, 10, 0, 0, 1, 0, 1}, []string{"10.0.0.1/1"}},
+		{"short node", []byte{0x01, 10, 0}, nil},
+		{"unknown node type", []byte{0x31, 1, 2, 3, 4, 0, 1}, nil},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseFQCSID(tt.value)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseFQCSID = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionSetDeletion(t *testing.T) {
+	s := newTestSniffer()
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.TopicSessions, 64)
+	defer sub.Close()
+	s.correlation.SetEventBus(bus)
+
+	// Sessions 1-3 are in Connection Set 7 of the SMF, 4-5 in set 8
+	for i := 1; i <= 5; i++ {
+		csid := uint16(7)
+		if i > 3 {
+			csid = 8
+		}
+		establish(t, s, uint32(i), uint64(0x100+i), uint64(0x200+i),
+			fmt.Sprintf("10.60.0.%d", i), uint32(0x1000+i), fqcsidIE(testSMF, csid))
+	}
+	if n := s.correlation.SessionCount(); n != 5 {
+		t.Fatalf("%d sessions established, want 5", n)
+	}
+
+	s.processPacket(packet(t, testSMF, testUPF,
+		message(MsgTypeSessionSetDeletionRequest, 0, 10, nodeIDIE(testSMF), fqcsidIE(testSMF, 7))))
+	if n := s.correlation.SessionCount(); n != 2 {
+		t.Fatalf("%d sessions left after deleting set 7, want 2", n)
+	}
+	for i := 1; i <= 5; i++ {
+		_, ok := s.correlation.GetSessionByTEID(uint32(0x1000 + i))
+		if want := i > 3; ok != want {
+			t.Errorf("session %d present = %v, want %v", i, ok, want)
+		}
+	}
+
+	var deleted []SessionEvent
+	for len(sub.C) > 0 {
+		if event := (<-sub.C).Payload.(SessionEvent); event.Type == SessionEventSetDeletion {
+			deleted = append(deleted, event)
+		}
+	}
+	if len(deleted) != 3 {
+		t.Fatalf("%d set_deletion events, want 3", len(deleted))
+	}
+	for _, event := range deleted {
+		if event.SetSize != 3 {
+			t.Errorf("set_deletion event of SEID 0x%x has set_size %d, want 3", event.SEID, event.SetSize)
+		}
+	}
+
+	// Without FQ-CSID, all the sessions of the sending node go
+	s.processPacket(packet(t, testSMF, testUPF,
+		message(MsgTypeSessionSetDeletionRequest, 0, 11, nodeIDIE(testSMF))))
+	if n := s.correlation.SessionCount(); n != 0 {
+		t.Fatalf("%d sessions left after deleting the node's sessions, want 0", n)
+	}
+}
+
+func TestSessionSetDeletionWithoutIEs(t *testing.T) {
+	s := newTestSniffer()
+	establish(t, s, 1, 0x101, 0x201, "10.60.0.1", 0x1001)
+
+	// A length under 4 puts the end of the IEs before their start
+	msg := message(MsgTypeSessionSetDeletionRequest, 0, 2)
+	msg[2], msg[3] = 0, 2
+	s.processPacket(packet(t, testSMF, testUPF, msg))
+
+	if n := s.correlation.SessionCount(); n != 1 {
+		t.Errorf("%d sessions after an empty Session Set Deletion, want 1", n)
+	}
+}
@@ -0,0 +1,112 @@
+package pfcp
+
+import (
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Clone returns a deep copy of the session that shares no memory with the
+// original, so it can be read without holding the correlation lock. The
+// tracing span is not copied.
+func (s *Session) Clone() *Session {
+	c := *s
+	c.UEIP = cloneIP(s.UEIP)
+	if s.UEIPs != nil {
+		c.UEIPs = make([]net.IP, len(s.UEIPs))
+		for i, ip := range s.UEIPs {
+			c.UEIPs[i] = cloneIP(ip)
+		}
+	}
+	c.UPFIP = cloneIP(s.UPFIP)
+	c.SMFIP = cloneIP(s.SMFIP)
+	c.GNBIP = cloneIP(s.GNBIP)
+	c.UplinkPeerIP = cloneIP(s.UplinkPeerIP)
+	c.N9PeerIP = cloneIP(s.N9PeerIP)
+	c.TEIDs = slices.Clone(s.TEIDs)
+	c.SDFFilters = slices.Clone(s.SDFFilters)
+	c.AppIDs = slices.Clone(s.AppIDs)
+	c.CSIDs = slices.Clone(s.CSIDs)
+	if s.PDRs != nil {
+		c.PDRs = make([]PDR, len(s.PDRs))
+		for i, pdr := range s.PDRs {
+			c.PDRs[i] = pdr
+			c.PDRs[i].TEIDAddr = cloneIP(pdr.TEIDAddr)
+		}
+	}
+	if s.BAR != nil {
+		bar := *s.BAR
+		c.BAR = &bar
+	}
+	c.bufferingFARs = slices.Clone(s.bufferingFARs)
+	c.Tags = maps.Clone(s.Tags)
+	if s.ReportedUsage != nil {
+		usage := *s.ReportedUsage
+		c.ReportedUsage = &usage
+	}
+	c.span = nil
+	return &c
+}
+
+func cloneIP(ip net.IP) net.IP {
+	if ip == nil {
+		return nil
+	}
+	return slices.Clone(ip)
+}
+
+// cloned wraps a live session lookup so that it returns a copy
+func cloned(session *Session, ok bool) (*Session, bool) {
+	if !ok || session == nil {
+		return nil, false
+	}
+	return session.Clone(), true
+}
+
+// UpdateUplinkStats sets the uplink counters of the session owning teid
+// and marks it active if they grew
+func (c *Correlation) UpdateUplinkStats(teid uint32, packets, bytes uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.store.GetByTEID(teid)
+	if !ok {
+		return
+	}
+	if packets == session.PacketsUL && bytes == session.BytesUL {
+		return
+	}
+	if packets > session.PacketsUL || bytes > session.BytesUL {
+		session.LastActive = now
+	}
+	session.PacketsUL = packets
+	session.BytesUL = bytes
+	c.saveLocked(session)
+}
+
+// UpdateDownlinkStats sets the downlink counters of the session owning
+// ueIP and marks it active if they grew
+func (c *Correlation) UpdateDownlinkStats(ueIP netip.Addr, packets, bytes uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.ueIPMap[ueIP.Unmap()]
+	if !ok {
+		return
+	}
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return
+	}
+	if packets == session.PacketsDL && bytes == session.BytesDL {
+		return
+	}
+	if packets > session.PacketsDL || bytes > session.BytesDL {
+		session.LastActive = now
+	}
+	session.PacketsDL = packets
+	session.BytesDL = bytes
+	c.saveLocked(session)
+}
@@ -0,0 +1,100 @@
+package pfcp
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloneSharesNoMemory(t *testing.T) {
+	s := newTestSniffer()
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+	live, _ := s.correlation.store.GetBySEID(seid)
+
+	clone := live.Clone()
+	clone.TEIDs[0] = 0
+	clone.UEIP[3] = 99
+	clone.UEIPs[0][3] = 99
+	clone.PDRs[0].TEID = 0
+	clone.SMFIP[3] = 99
+
+	if live.TEIDs[0] != 0x10001 || live.PDRs[0].TEID != 0x10001 {
+		t.Error("clone shares the TEIDs or PDRs of the session")
+	}
+	if live.UEIP.String() != "10.60.0.1" || live.UEIPs[0].String() != "10.60.0.1" {
+		t.Error("clone shares the UE IPs of the session")
+	}
+	if live.SMFIP.String() != testSMF {
+		t.Error("clone shares the SMF IP of the session")
+	}
+}
+
+// TestConcurrentReadsDuringModifications reads the sessions through the
+// API getters while the sniffer modifies them; run with -race
+func TestConcurrentReadsDuringModifications(t *testing.T) {
+	quietLog(t)
+	const modifications = 200
+	s := newTestSniffer()
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	read := func(get func() (*Session, bool)) {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			session, ok := get()
+			if !ok {
+				t.Error("session not found during modifications")
+				return
+			}
+			// Readers own the copies they get, writing them is fine too
+			for i := range session.TEIDs {
+				session.TEIDs[i]++
+			}
+			for i := range session.PDRs {
+				session.PDRs[i].Precedence++
+			}
+			_ = session.ModifiedAt
+			_ = session.BytesUL
+		}
+	}
+	readers.Add(4)
+	go read(func() (*Session, bool) { return s.correlation.GetSessionBySEID(seid) })
+	go read(func() (*Session, bool) { return s.correlation.GetSessionByTEID(0x10001) })
+	go read(func() (*Session, bool) { return s.correlation.GetSessionByUEIP("10.60.0.1") })
+	go read(func() (*Session, bool) {
+		sessions := s.correlation.GetAllSessions()
+		if len(sessions) != 1 {
+			return nil, false
+		}
+		return sessions[0], true
+	})
+
+	for i := 0; i < modifications; i++ {
+		teid := uint32(0x20000 + i)
+		s.processPacket(packet(t, testSMF, testUPF, modificationRequest(uint32(2+i), 0x2001, uint16(2+i), teid)))
+		s.correlation.UpdateUplinkStats(0x10001, uint64(i), uint64(100*i), time.Now())
+	}
+	close(done)
+	readers.Wait()
+
+	session, _ := s.correlation.GetSessionBySEID(seid)
+	if len(session.TEIDs) != 1+modifications {
+		t.Errorf("%d TEIDs after %d modifications, want %d", len(session.TEIDs), modifications, 1+modifications)
+	}
+	for i := 0; i < modifications; i++ {
+		if !slices.Contains(session.TEIDs, uint32(0x20000+i)) {
+			t.Fatalf("TEID 0x%x missing, readers changed the live session", 0x20000+i)
+		}
+	}
+	if !session.UEIP.Equal(net.ParseIP("10.60.0.1")) {
+		t.Errorf("UE IP %s", session.UEIP)
+	}
+}
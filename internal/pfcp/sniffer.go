@@ -5,24 +5,44 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
+	"github.com/solar224/5G-DPOP/internal/events"
+	"github.com/solar224/5G-DPOP/internal/model"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // PFCP Message Types (3GPP TS 29.244)
 const (
 	MsgTypeHeartbeatRequest             = 1
 	MsgTypeHeartbeatResponse            = 2
+	MsgTypePFDManagementRequest         = 3
+	MsgTypePFDManagementResponse        = 4
+	MsgTypeAssociationSetupRequest      = 5
+	MsgTypeAssociationSetupResponse     = 6
+	MsgTypeAssociationUpdateRequest     = 7
+	MsgTypeAssociationUpdateResponse    = 8
+	MsgTypeAssociationReleaseRequest    = 9
+	MsgTypeAssociationReleaseResponse   = 10
+	MsgTypeVersionNotSupportedResponse  = 11
+	MsgTypeNodeReportRequest            = 12
+	MsgTypeNodeReportResponse           = 13
+	MsgTypeSessionSetDeletionRequest    = 14
+	MsgTypeSessionSetDeletionResponse   = 15
 	MsgTypeSessionEstablishmentRequest  = 50
 	MsgTypeSessionEstablishmentResponse = 51
 	MsgTypeSessionModificationRequest   = 52
 	MsgTypeSessionModificationResponse  = 53
 	MsgTypeSessionDeletionRequest       = 54
 	MsgTypeSessionDeletionResponse      = 55
+	MsgTypeSessionReportRequest         = 56
+	MsgTypeSessionReportResponse        = 57
 )
 
 // PFCP IE Types (3GPP TS 29.244)
@@ -43,13 +63,15 @@ const (
 	IETypeGBR                  = 27  // GBR (Guaranteed Bit Rate)
 	IETypeQERCorrelationID     = 28  // QER Correlation ID
 	IETypePrecedence           = 29  // Precedence
-	IETypePDUSessionType       = 85  // PDU Session Type
+	IETypePDUSessionType       = 113 // PDU Session Type
 	IETypeOuterHeaderRemoval   = 95  // Outer Header Removal
 	IETypeOuterHeaderCreation  = 84  // Outer Header Creation
 	IETypeUEIPAddr             = 93  // UE IP Address
 	IETypeQFI                  = 124 // QFI (QoS Flow Identifier)
 	IEType5QI                  = 45  // 5QI (5G QoS Identifier)
 	IETypeARP                  = 46  // ARP (Allocation and Retention Priority)
+	IETypeFSEID                = 57  // F-SEID
+	IETypeRecoveryTimeStamp    = 96  // Recovery Time Stamp
 	IETypeSNSSAI               = 148 // S-NSSAI (Network Slice Selection Assistance Information)
 	IEType3GPPInterfaceType    = 160 // 3GPP Interface Type
 )
@@ -59,8 +81,10 @@ type Session struct {
 	SEID         uint64
 	LocalSEID    uint64
 	RemoteSEID   uint64
-	UEIP         net.IP
+	UEIP         net.IP   // Primary UE IP (IPv4 for dual-stack sessions)
+	UEIPs        []net.IP // All UE IPs, including UEIP (dual-stack: IPv4 and IPv6)
 	UPFIP        net.IP
+	SMFIP        net.IP   // Controlling SMF (sender of the session requests)
 	GNBIP        net.IP   // Downlink Peer IP (gNB for N3)
 	UplinkPeerIP net.IP   // Uplink Peer IP (gNB or prev UPF)
 	N9PeerIP     net.IP   // N9 Peer UPF IP (for ULCL: i-upf <-> psa-upf)
@@ -78,12 +102,26 @@ type Session struct {
 	SessionType string // IPv4, IPv6, IPv4v6
 	SessionID   uint8  // PDU Session ID
 
+	// Application context from the PDIs of the session's PDRs
+	SDFFilters []string // SDF Filter flow descriptions
+	AppIDs     []string // Application IDs
+
+	// Connection Sets of the session, from the FQ-CSIDs of its
+	// establishment, as "<node>/<csid>" (see RemoveSessionSet)
+	CSIDs []string
+
+	// Packet Detection Rules, in the order the UPF evaluates them
+	PDRs []PDR
+
 	// Traffic statistics
 	BytesUL   uint64
 	BytesDL   uint64
 	PacketsUL uint64
 	PacketsDL uint64
 
+	// Volumes the UPF reported in Usage Reports (nil until the first one)
+	ReportedUsage *ReportedUsage
+
 	// QoS parameters
 	QoS5QI      uint8  // 5G QoS Identifier
 	ARPPL       uint8  // ARP Priority Level
@@ -92,38 +130,213 @@ type Session struct {
 	MBRUplink   uint64 // Maximum Bit Rate UL (kbps)
 	MBRDownlink uint64 // Maximum Bit Rate DL (kbps)
 
+	// Downlink buffering: the BAR (nil if none) and the IDs of the FARs
+	// whose Apply Action is BUFF (see Buffering)
+	BAR           *BAR
+	bufferingFARs []uint32
+
 	// Status
 	Status     string // Active, Idle, Releasing
 	LastActive time.Time
+
+	// Operator annotations, set through the API and never from PFCP (see
+	// SetSessionTags)
+	Tags map[string]string
+
+	// IncompleteEstablishment is set for sessions first seen in a
+	// Modification Request, i.e. established before the sniffer started.
+	// Their Establishment-only fields (SUPI, QoS, ...) may be missing.
+	IncompleteEstablishment bool
+
+	// Tracing span covering the session lifetime (nil until created)
+	span trace.Span
+
+	// Set anew, unique across sessions, each time the session is stored
+	// (see putLocked), to tell whether a copy is still current (see
+	// ApplyModification)
+	revision uint64
+
+	// Digest of the Establishment Request that created the session, to
+	// recognize its retransmissions (see establishmentDigest)
+	establishment uint64
+}
+
+// Session lifecycle event types
+const (
+	SessionEventCreated = "created"
+	SessionEventUpdated = "updated"
+	SessionEventDeleted = "deleted"
+
+	// Removed because the peer node restarted (see RemoveSessionsByPeer)
+	SessionEventPeerRestart = "peer_restart"
+
+	// Removed to make room for a new session (see SetSessionLimit)
+	SessionEventEvicted = "evicted"
+)
+
+// SessionEvent is published on events.TopicSessions when a session changes
+type SessionEvent struct {
+	Type  string   `json:"type"`
+	SEID  uint64   `json:"seid"`
+	UEIP  net.IP   `json:"ue_ip"`
+	TEIDs []uint32 `json:"teids"`
+
+	// Number of sessions removed by the same Session Set Deletion
+	// (SessionEventSetDeletion only)
+	SetSize int `json:"set_size,omitempty"`
+}
+
+// PartitionKey keys session events by UE IP so that all events for one UE
+// land in the same Kafka partition
+func (e SessionEvent) PartitionKey() string {
+	if e.UEIP == nil {
+		return model.FormatSEID(e.SEID)
+	}
+	return e.UEIP.String()
 }
 
 // Correlation manages the mapping between sessions and TEIDs
 type Correlation struct {
 	mu          sync.RWMutex
-	sessions    map[uint64]*Session // SEID -> Session
-	teidMap     map[uint32]uint64   // TEID -> SEID
-	ueIPMap     map[string]uint64   // UE IP string -> primary SEID (for deduplication)
-	seidCounter uint64              // Counter for generating unique SEIDs
+	store       SessionStore          // SEID -> Session, TEID -> SEID
+	ueIPMap     map[netip.Addr]uint64 // UE IP -> primary SEID (for deduplication)
+	seidCounter uint64                // Counter for generating unique SEIDs
+	revision    uint64                // Last Session.revision given out
 	// Track session creation timestamps to handle race conditions
-	sessionCreationTime map[string]time.Time // UE IP -> creation time
+	sessionCreationTime map[netip.Addr]time.Time // UE IP -> capture time it was indexed at
+
+	// PFCP SEIDs as seen on the wire -> internal SEID. The CP SEID (SMF)
+	// comes from the Establishment Request, the UP SEID (UPF) from the
+	// Establishment Response; Modification/Deletion requests carry the
+	// UP SEID in their header.
+	cpSEIDMap map[uint64]uint64
+	upSEIDMap map[uint64]uint64
+
+	// Peer (SMF or UPF) IP -> SEIDs of the sessions it handles
+	peerMap map[string]map[uint64]struct{}
+
+	// Creation/deletion times within the last minute (see Summary)
+	recentCreated []time.Time
+	recentDeleted []time.Time
+
+	// Optional bus for session lifecycle events (nil disables publishing)
+	bus *events.Bus
+
+	// Set for the throwaway correlation of a Replay, which records the
+	// lifecycle events instead of publishing and tracing them
+	replay *replayRecorder
+
+	// TEIDs moved from one session to another (see mapTEIDLocked)
+	teidCollisions atomic.Uint64
+
+	// Uplink and downlink TEIDs, in total and per SEID (see putLocked)
+	activeTEIDs  teidCounts
+	sessionTEIDs map[uint64]teidCounts
+
+	// Establishments for a CP SEID that already has a session (see
+	// checkReestablishmentLocked)
+	establishmentRetransmissions atomic.Uint64
+	reestablishments             atomic.Uint64
+
+	// GTP-U Error Indications that matched a session
+	bearerErrors atomic.Uint64
+
+	// Bumped whenever session data changes (see Version, Subscribe)
+	version     atomic.Uint64
+	subscribers []chan uint64
+
+	// Session limit, 0 means unlimited (see SetSessionLimit)
+	maxSessions    int
+	overflowPolicy OverflowPolicy
+	overflows      atomic.Uint64
+	overflowLogged bool
+	evictionLogged time.Time
+
+	// Stored sessions by creation time, to evict the oldest
+	creation *creationOrder
+
+	// Size of the Session Set Deletion in progress (see RemoveSessionSet)
+	setDeletionSize int
 }
 
-// NewCorrelation creates a new correlation store
+// NewCorrelation creates a new correlation store, keeping the sessions in
+// memory
 func NewCorrelation() *Correlation {
-	return &Correlation{
-		sessions:            make(map[uint64]*Session),
-		teidMap:             make(map[uint32]uint64),
-		ueIPMap:             make(map[string]uint64),
+	return NewCorrelationWithStore(newMemoryStore())
+}
+
+// NewCorrelationWithStore creates a correlation store keeping its sessions
+// in store. Sessions already in store are indexed and their TEIDs mapped
+// again; getNextSEID skips their SEIDs.
+func NewCorrelationWithStore(store SessionStore) *Correlation {
+	c := &Correlation{
+		store:               store,
+		ueIPMap:             make(map[netip.Addr]uint64),
 		seidCounter:         0,
-		sessionCreationTime: make(map[string]time.Time),
+		sessionCreationTime: make(map[netip.Addr]time.Time),
+		cpSEIDMap:           make(map[uint64]uint64),
+		upSEIDMap:           make(map[uint64]uint64),
+		peerMap:             make(map[string]map[uint64]struct{}),
+		sessionTEIDs:        make(map[uint64]teidCounts),
+		creation:            newCreationOrder(),
+	}
+
+	for _, session := range store.All() {
+		c.indexUEAddrsLocked(session, captureTime(session.CreatedAt))
+		if session.RemoteSEID != 0 {
+			c.cpSEIDMap[session.RemoteSEID] = session.SEID
+		}
+		if session.LocalSEID != 0 {
+			c.upSEIDMap[session.LocalSEID] = session.SEID
+		}
+		for _, teid := range session.TEIDs {
+			c.mapTEIDLocked(teid, session.SEID)
+		}
+		c.indexPeersLocked(session)
+		c.countTEIDsLocked(session.SEID, teidDirections(session))
+		c.creation.put(session)
+	}
+	return c
+}
+
+// SetEventBus sets the bus that session lifecycle events are published to
+func (c *Correlation) SetEventBus(bus *events.Bus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bus = bus
+}
+
+// publish sends a session lifecycle event, caller must hold c.mu
+func (c *Correlation) publish(eventType string, session *Session) {
+	c.changedLocked()
+	c.recordLifecycle(eventType, time.Now())
+	if c.replay != nil {
+		c.replay.event(eventType, session)
+		return
+	}
+	c.traceSession(eventType, session)
+	if c.bus == nil {
+		return
 	}
+	c.bus.Publish(events.TopicSessions, SessionEvent{
+		Type:    eventType,
+		SEID:    session.SEID,
+		UEIP:    session.UEIP,
+		TEIDs:   append([]uint32(nil), session.TEIDs...),
+		SetSize: c.setDeletionSize,
+	})
 }
 
 // getNextSEID generates a sequential SEID for new sessions
 // Uses atomic-like pattern with mutex already held by caller
+// Skips SEIDs already taken by partial sessions kept under their PFCP SEID
 func (c *Correlation) getNextSEID() uint64 {
-	c.seidCounter++
-	return c.seidCounter
+	for {
+		c.seidCounter++
+		if _, used := c.store.GetBySEID(c.seidCounter); !used {
+			return c.seidCounter
+		}
+	}
 }
 
 // AddSession adds or updates a session
@@ -133,33 +346,67 @@ func (c *Correlation) AddSession(session *Session) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// If session has no UE IP, we cannot properly deduplicate - skip it
+	// If session has no UE IP, we cannot properly deduplicate - skip it,
+	// unless it is a partial session recovered from a Modification, which
+	// is kept under its PFCP SEID so its TEIDs are still correlated
 	if session.UEIP == nil {
-		log.Printf("[WARN] AddSession: session without UE IP, skipping (SEID=0x%x)", session.SEID)
+		if !session.IncompleteEstablishment || session.SEID == 0 {
+			log.Printf("[WARN] AddSession: session without UE IP, skipping (SEID=0x%x)", session.SEID)
+			return
+		}
+		if existing, exists := c.store.GetBySEID(session.SEID); !exists {
+			if !c.makeRoomLocked() {
+				return
+			}
+			c.putLocked(session)
+			c.indexPeersLocked(session)
+			log.Printf("[DEBUG] AddSession: Partial session SEID=0x%x without UE IP (total sessions: %d)",
+				session.SEID, c.store.Count())
+			c.publish(SessionEventCreated, session)
+		} else if existing == session {
+			c.putLocked(session)
+			c.indexPeersLocked(session)
+			c.publish(SessionEventUpdated, session)
+		} else {
+			log.Printf("[WARN] AddSession: SEID 0x%x already in use, skipping partial session", session.SEID)
+			return
+		}
+		for _, teid := range session.TEIDs {
+			c.mapTEIDLocked(teid, session.SEID)
+		}
 		return
 	}
 
-	ueIPStr := session.UEIP.String()
+	// An Establishment for a known CP SEID is either a retransmission or
+	// replaces the old session
+	if !c.checkReestablishmentLocked(session) {
+		return
+	}
 
-	// Check if we already have a session for this UE IP
-	if existingSEID, exists := c.ueIPMap[ueIPStr]; exists {
-		if existingSession, ok := c.sessions[existingSEID]; ok {
+	// Check if we already have a session for any of the UE IPs
+	if ueAddr, existingSEID, exists := c.lookupUEAddrsLocked(ueAddrs(session)); exists {
+		if existingSession, ok := c.store.GetBySEID(existingSEID); ok {
 			// Only merge if this is clearly an update (same session being modified)
 			// Don't merge if the existing session was just created (within 100ms)
 			// This prevents race conditions during rapid session establishment
-			creationTime, hasTime := c.sessionCreationTime[ueIPStr]
-			timeSinceCreation := time.Since(creationTime)
+			// Both times are capture times, so this holds when a capture
+			// file is processed in a fraction of the time it covers
+			creationTime, hasTime := c.sessionCreationTime[ueAddr]
+			timeSinceCreation := captureTime(session.CreatedAt).Sub(creationTime)
+			if timeSinceCreation < 0 {
+				timeSinceCreation = -timeSinceCreation
+			}
 
 			if hasTime && timeSinceCreation < 100*time.Millisecond {
 				// Recent session - likely a race condition, skip this update
 				log.Printf("[DEBUG] AddSession: Skipping duplicate for UE IP %s (created %v ago)",
-					ueIPStr, timeSinceCreation)
+					ueAddr, timeSinceCreation)
 				return
 			}
 
 			// Merge with existing session
 			log.Printf("[DEBUG] AddSession: Merging session for UE IP %s (existing SEID=0x%x)",
-				ueIPStr, existingSEID)
+				ueAddr, existingSEID)
 
 			// Merge TEIDs (avoid duplicates)
 			teidSet := make(map[uint32]bool)
@@ -169,7 +416,7 @@ func (c *Correlation) AddSession(session *Session) {
 			for _, t := range session.TEIDs {
 				if !teidSet[t] && t != 0 {
 					existingSession.TEIDs = append(existingSession.TEIDs, t)
-					c.teidMap[t] = existingSEID
+					c.mapTEIDLocked(t, existingSEID)
 				}
 			}
 			// Update other fields if they have better data
@@ -182,40 +429,64 @@ func (c *Correlation) AddSession(session *Session) {
 			if session.UPFIP != nil && existingSession.UPFIP == nil {
 				existingSession.UPFIP = session.UPFIP
 			}
+			if session.SMFIP != nil && existingSession.SMFIP == nil {
+				existingSession.SMFIP = session.SMFIP
+			}
 			if session.GNBIP != nil && existingSession.GNBIP == nil {
 				existingSession.GNBIP = session.GNBIP
 			}
+			existingSession.SDFFilters = appendUnique(existingSession.SDFFilters, session.SDFFilters...)
+			existingSession.AppIDs = appendUnique(existingSession.AppIDs, session.AppIDs...)
 			if session.MBRUplink > 0 {
 				existingSession.MBRUplink = session.MBRUplink
 			}
 			if session.MBRDownlink > 0 {
 				existingSession.MBRDownlink = session.MBRDownlink
 			}
-			existingSession.LastActive = time.Now()
+			if session.RemoteSEID != 0 && session.RemoteSEID != existingSession.RemoteSEID {
+				existingSession.RemoteSEID = session.RemoteSEID
+				c.cpSEIDMap[session.RemoteSEID] = existingSEID
+			}
+			mergeUEIPs(existingSession, append([]net.IP{session.UEIP}, session.UEIPs...))
+			c.indexUEAddrsLocked(existingSession, captureTime(session.CreatedAt))
+			if session.LastActive.After(existingSession.LastActive) {
+				existingSession.LastActive = session.LastActive
+			} else if session.LastActive.IsZero() {
+				existingSession.LastActive = time.Now()
+			}
+			c.putLocked(existingSession)
+			c.indexPeersLocked(existingSession)
+			c.publish(SessionEventUpdated, existingSession)
 			return
 		}
 	}
 
 	// New session with this UE IP
+	if _, exists := c.store.GetBySEID(session.SEID); !exists && !c.makeRoomLocked() {
+		return
+	}
+
 	// Assign a new sequential SEID if not already set
 	if session.SEID == 0 {
 		session.SEID = c.getNextSEID()
 	}
 
-	// Register this UE IP -> SEID mapping
-	c.ueIPMap[ueIPStr] = session.SEID
-	c.sessionCreationTime[ueIPStr] = time.Now()
+	// Register the UE IP -> SEID mappings
+	c.indexUEAddrsLocked(session, captureTime(session.CreatedAt))
 
 	// Store session
-	c.sessions[session.SEID] = session
+	c.putLocked(session)
+	if session.RemoteSEID != 0 {
+		c.cpSEIDMap[session.RemoteSEID] = session.SEID
+	}
 	for _, teid := range session.TEIDs {
-		if teid != 0 {
-			c.teidMap[teid] = session.SEID
-		}
+		c.mapTEIDLocked(teid, session.SEID)
 	}
+	c.indexPeersLocked(session)
 
 	log.Printf("[DEBUG] AddSession: New session SEID=0x%x for UE IP %s (total sessions: %d)",
-		session.SEID, ueIPStr, len(c.sessions))
+		session.SEID, session.UEIP, c.store.Count())
+	c.publish(SessionEventCreated, session)
 }
 
 // RemoveSession removes a session
@@ -223,62 +494,106 @@ func (c *Correlation) RemoveSession(seid uint64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if session, ok := c.sessions[seid]; ok {
-		for _, teid := range session.TEIDs {
-			delete(c.teidMap, teid)
-		}
+	c.removeSessionLocked(seid, SessionEventDeleted)
+}
+
+// removeSessionLocked removes a session and publishes eventType, caller
+// must hold c.mu
+func (c *Correlation) removeSessionLocked(seid uint64, eventType string) {
+	if session, ok := c.store.GetBySEID(seid); ok {
+		c.unmapTEIDsLocked(session)
 		// Remove from UE IP map and creation time tracking
-		if session.UEIP != nil {
-			ueIPStr := session.UEIP.String()
-			delete(c.ueIPMap, ueIPStr)
-			delete(c.sessionCreationTime, ueIPStr)
+		c.unindexUEAddrsLocked(session)
+		if c.cpSEIDMap[session.RemoteSEID] == seid {
+			delete(c.cpSEIDMap, session.RemoteSEID)
 		}
-		delete(c.sessions, seid)
-		log.Printf("[DEBUG] RemoveSession: Removed SEID=0x%x (total sessions: %d)", seid, len(c.sessions))
+		if c.upSEIDMap[session.LocalSEID] == seid {
+			delete(c.upSEIDMap, session.LocalSEID)
+		}
+		c.unindexPeersLocked(session)
+		c.deleteLocked(seid)
+		log.Printf("[DEBUG] RemoveSession: Removed SEID=0x%x (total sessions: %d)", seid, c.store.Count())
+		c.publish(eventType, session)
 	}
 }
 
-// GetSessionByTEID looks up session by TEID
+// GetSessionByTEID looks up session by TEID. The returned session is a
+// copy (see Session.Clone).
 func (c *Correlation) GetSessionByTEID(teid uint32) (*Session, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if seid, ok := c.teidMap[teid]; ok {
-		return c.sessions[seid], true
-	}
-	return nil, false
+	session, ok := c.store.GetByTEID(teid)
+	return cloned(session, ok)
 }
 
-// GetSessionBySEID looks up session by SEID
+// GetSessionBySEID looks up session by SEID. The returned session is a
+// copy (see Session.Clone).
 func (c *Correlation) GetSessionBySEID(seid uint64) (*Session, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	session, ok := c.sessions[seid]
-	return session, ok
+	session, ok := c.store.GetBySEID(seid)
+	return cloned(session, ok)
 }
 
-// GetSessionByUEIP looks up session by UE IP address
+// GetSessionByUEIP looks up session by UE IP address. The returned session
+// is a copy (see Session.Clone).
 func (c *Correlation) GetSessionByUEIP(ueIP string) (*Session, bool) {
+	addr, err := netip.ParseAddr(ueIP)
+	if err != nil {
+		return nil, false
+	}
+	return c.GetSessionByUEAddr(addr)
+}
+
+// GetSessionByUEAddr looks up session by UE IP address without the string
+// round trip, for hot paths fed by eBPF events. The returned session is a
+// copy (see Session.Clone).
+func (c *Correlation) GetSessionByUEAddr(ueIP netip.Addr) (*Session, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	for _, session := range c.sessions {
-		if session.UEIP != nil && session.UEIP.String() == ueIP {
-			return session, true
-		}
+	seid, ok := c.ueIPMap[ueIP.Unmap()]
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	session, ok := c.store.GetBySEID(seid)
+	return cloned(session, ok)
 }
 
-// GetAllSessions returns all sessions
+// HasUEAddr reports whether ueIP belongs to a session, like
+// GetSessionByUEAddr without copying the session, for checks on every
+// eBPF event
+func (c *Correlation) HasUEAddr(ueIP netip.Addr) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seid, ok := c.ueIPMap[ueIP.Unmap()]
+	if !ok {
+		return false
+	}
+	_, ok = c.store.GetBySEID(seid)
+	return ok
+}
+
+// ipToAddr converts a net.IP to a map-key friendly netip.Addr, with IPv4
+// addresses always in their 4-byte form
+func ipToAddr(ip net.IP) netip.Addr {
+	addr, _ := netip.AddrFromSlice(ip)
+	return addr.Unmap()
+}
+
+// GetAllSessions returns a snapshot of all sessions. The sessions are
+// copies, so callers can read them while the sniffer keeps updating the
+// correlation.
 func (c *Correlation) GetAllSessions() []*Session {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	sessions := make([]*Session, 0, len(c.sessions))
-	for _, s := range c.sessions {
-		sessions = append(sessions, s)
+	sessions := make([]*Session, 0, c.store.Count())
+	for _, s := range c.store.All() {
+		sessions = append(sessions, s.Clone())
 	}
 	return sessions
 }
@@ -287,7 +602,7 @@ func (c *Correlation) GetAllSessions() []*Session {
 func (c *Correlation) SessionCount() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return len(c.sessions)
+	return c.store.Count()
 }
 
 // Sniffer captures and parses PFCP packets
@@ -295,8 +610,78 @@ type Sniffer struct {
 	handle      *pcap.Handle
 	correlation *Correlation
 	stopChan    chan struct{}
+	stopOnce    sync.Once
+	done        chan struct{}
 	iface       string
 	port        uint16
+
+	// Offline capture file (set by NewSnifferFromFile, empty for live capture)
+	pcapFile string
+
+	// Number of packet processing workers (<= 1 processes inline)
+	workers int
+
+	// libpcap timestamp source for live capture (empty for the default)
+	tstampSource string
+
+	// How long Start waits for the capture interface to appear
+	ifaceWait time.Duration
+
+	// Optional pcap tap (nil when disabled)
+	tapPath    string
+	tapMaxSize int64
+	tap        *pcapTap
+
+	// Per-message-type counters, indexed by PFCP message type
+	msgCounts [256]atomic.Uint64
+
+	// Message types logged verbosely (see SetLogTypes)
+	logTypes [256]bool
+
+	// Messages dropped because their header could not be parsed or their
+	// grouped IEs nest deeper than maxIEDepth (see SetMaxIEDepth)
+	parseErrors atomic.Uint64
+	maxIEDepth  int
+
+	// Messages whose length runs past the captured payload (snaplen or a
+	// bogus length), skipped without parsing their IEs
+	truncatedMessages atomic.Uint64
+
+	// Live capture handle for libpcap statistics, nil before Start, for
+	// offline capture and once stopped; captureDrops keeps the last count
+	captureMu     sync.Mutex
+	captureHandle *pcap.Handle
+	captureDrops  uint64
+
+	// Modifications seen for sessions whose Establishment was never seen
+	incompleteEstablishments atomic.Uint64
+
+	// Volumes of all Usage Reports seen
+	usage usageCounters
+
+	// Session Establishment Requests and outcomes
+	establishments establishmentCounters
+
+	// IE type counts (nil unless enabled by SetIEStats)
+	ieStats *ieCounter
+
+	// Inter-arrival times per peer (nil unless enabled by SetPeerHealth)
+	peerHealth *peerHealth
+
+	// Set by Replay to record packet times and parse errors
+	replay *replayRecorder
+
+	// Also capture GTP-U Error Indications (see SetGTPUErrorIndications)
+	gtpuErrors       bool
+	errorIndications atomic.Uint64
+
+	// Node-related message counters, keyed by message type and peer
+	nodeStatsMu sync.Mutex
+	nodeStats   map[nodeStatKey]*NodeMessageStat
+
+	// Last Recovery Time Stamp seen per peer IP, to detect restarts
+	recoveryMu    sync.Mutex
+	recoveryTimes map[string]uint32
 }
 
 // NewSniffer creates a new PFCP sniffer
@@ -306,60 +691,193 @@ func NewSniffer(iface string, port uint16, correlation *Correlation) *Sniffer {
 		port:        port,
 		correlation: correlation,
 		stopChan:    make(chan struct{}),
+		done:        make(chan struct{}),
+		nodeStats:   make(map[nodeStatKey]*NodeMessageStat),
+
+		recoveryTimes: make(map[string]uint32),
 	}
 }
 
+// NewSnifferFromFile creates a sniffer that reads PFCP packets from a pcap
+// file instead of a live interface. Start processes the file in the
+// background; Done is closed once the whole file has been read.
+func NewSnifferFromFile(path string, port uint16, correlation *Correlation) *Sniffer {
+	s := NewSniffer(path, port, correlation)
+	s.pcapFile = path
+	return s
+}
+
+// SetTap copies every captured packet into a pcap file at path, rotating it
+// after maxSize bytes (0 disables rotation). Must be called before Start.
+func (s *Sniffer) SetTap(path string, maxSize int64) {
+	s.tapPath = path
+	s.tapMaxSize = maxSize
+}
+
+// SetWorkers sets the number of goroutines that process packets. With
+// n <= 1 (the default) packets are processed on the capture goroutine.
+// Messages between the same two peers stay in order on one worker (see
+// workerPool), so the traffic of a single SMF and UPF is not spread out.
+// Must be called before Start.
+func (s *Sniffer) SetWorkers(n int) {
+	s.workers = n
+}
+
 // Start begins capturing PFCP packets
 func (s *Sniffer) Start() error {
 	var err error
 
-	// Open the device for capturing
-	s.handle, err = pcap.OpenLive(s.iface, 65535, true, pcap.BlockForever)
-	if err != nil {
-		return fmt.Errorf("failed to open device %s: %w", s.iface, err)
+	// Open the device (or file) for capturing
+	if s.pcapFile != "" {
+		s.handle, err = pcap.OpenOffline(s.pcapFile)
+		if err != nil {
+			return fmt.Errorf("failed to open capture file %s: %w", s.pcapFile, err)
+		}
+	} else {
+		if err := s.waitForInterface(); err != nil {
+			return err
+		}
+		s.handle, err = s.openLive()
+		if err != nil {
+			return fmt.Errorf("failed to open device %s: %w", s.iface, err)
+		}
 	}
 
 	// Set BPF filter for PFCP (UDP port 8805)
-	filter := fmt.Sprintf("udp port %d", s.port)
+	filter := s.captureFilter()
 	if err := s.handle.SetBPFFilter(filter); err != nil {
+		s.handle.Close()
+		s.handle = nil
 		return fmt.Errorf("failed to set BPF filter: %w", err)
 	}
 
-	log.Printf("PFCP Sniffer started on %s, filter: %s", s.iface, filter)
+	linkType := s.handle.LinkType()
+	if _, ok := linkTypeDecoder(linkType); !ok {
+		s.handle.Close()
+		s.handle = nil
+		return fmt.Errorf("unsupported link type %d on %s, capture on an Ethernet, Linux cooked (e.g. \"any\") or raw IP interface", linkType, s.iface)
+	}
+
+	if s.tapPath != "" {
+		s.tap, err = newPcapTap(s.tapPath, s.tapMaxSize, linkType)
+		if err != nil {
+			s.handle.Close()
+			s.handle = nil
+			return err
+		}
+		log.Printf("PFCP tap writing to %s", s.tapPath)
+	}
+
+	log.Printf("PFCP Sniffer started on %s (%s), filter: %s", s.iface, linkTypeName(linkType), filter)
+
+	if s.pcapFile == "" {
+		s.captureMu.Lock()
+		s.captureHandle = s.handle
+		s.captureMu.Unlock()
+	}
 
 	go s.captureLoop()
 
 	return nil
 }
 
-// Stop stops the sniffer
+// Done is closed when the capture loop exits (end of file or Stop)
+func (s *Sniffer) Done() <-chan struct{} {
+	return s.done
+}
+
+// Stop stops the sniffer and waits for the capture loop to exit. The loop
+// stops processing first, then the handle is closed so the packet source
+// sees EOF and closes its channel, which the loop drains before it returns.
+// Calling Stop more than once, or without a successful Start, is a no-op.
 func (s *Sniffer) Stop() {
-	close(s.stopChan)
-	if s.handle != nil {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+		if s.handle == nil {
+			return
+		}
+		s.CaptureDrops()
+		s.captureMu.Lock()
+		s.captureHandle = nil
+		s.captureMu.Unlock()
 		s.handle.Close()
-	}
+		<-s.done
+	})
 }
 
 func (s *Sniffer) captureLoop() {
-	packetSource := gopacket.NewPacketSource(s.handle, s.handle.LinkType())
+	defer close(s.done)
+
+	decoder, _ := linkTypeDecoder(s.handle.LinkType())
+	packetSource := gopacket.NewPacketSource(s.handle, decoder)
+	if s.tap != nil {
+		defer s.tap.close()
+	}
 
+	process := s.processPacket
+	if s.workers > 1 {
+		pool := newWorkerPool(s.workers, s.processPacket)
+		defer pool.stop()
+		process = pool.dispatch
+	}
+
+	packets := packetSource.Packets()
 	for {
+		// Check for Stop first, select picks randomly when a packet is
+		// ready as well
 		select {
 		case <-s.stopChan:
+			drainPackets(packets)
 			return
-		case packet := <-packetSource.Packets():
-			s.processPacket(packet)
+		default:
+		}
+
+		select {
+		case <-s.stopChan:
+			drainPackets(packets)
+			return
+		case packet, ok := <-packets:
+			if !ok {
+				return
+			}
+			if s.tap != nil {
+				s.tap.write(packet)
+			}
+			process(packet)
 		}
 	}
 }
 
+// drainPackets discards packets until the packet source closes the
+// channel, so its goroutine is not left blocked on a full channel
+func drainPackets(packets <-chan gopacket.Packet) {
+	for range packets {
+	}
+}
+
 func (s *Sniffer) processPacket(packet gopacket.Packet) {
-	// Get IP layer to extract source and destination IPs
+	// Stamp sessions with the capture time rather than the processing
+	// time, so replayed captures keep their recorded timeline
+	ts := packet.Metadata().Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if s.replay != nil {
+		s.replay.packet(ts)
+	}
+
+	// Get IP layer to extract source and destination IPs. Requests flow
+	// SMF -> UPF and responses UPF -> SMF, so the handlers use these to
+	// attribute sessions to their peers.
 	var srcIP, dstIP net.IP
 	if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
 		ip, _ := ipLayer.(*layers.IPv4)
 		srcIP = ip.SrcIP
 		dstIP = ip.DstIP
+	} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+		ip, _ := ipLayer.(*layers.IPv6)
+		srcIP = ip.SrcIP
+		dstIP = ip.DstIP
 	}
 
 	// Get UDP layer
@@ -371,42 +889,76 @@ func (s *Sniffer) processPacket(packet gopacket.Packet) {
 	udp, _ := udpLayer.(*layers.UDP)
 	payload := udp.Payload
 
-	if len(payload) < 8 {
+	if s.gtpuErrors && (udp.SrcPort == GTPUPort || udp.DstPort == GTPUPort) {
+		s.handleGTPU(payload, srcIP)
 		return
 	}
 
 	// Parse PFCP header (3GPP TS 29.244)
-	// Byte 0: Version (3 bits) + Spare (3 bits) + MP (1 bit) + S (1 bit)
-	// Byte 1: Message Type
-	// Bytes 2-3: Message Length (excludes first 4 bytes of header)
-	// If S=1: Bytes 4-11: SEID, then Bytes 12-15: Sequence Number + Spare
-	// If S=0: Bytes 4-7: Sequence Number + Spare
-	msgType := payload[1]
-	msgLen := binary.BigEndian.Uint16(payload[2:4])
-
-	// Check if it's a session message (has SEID) - S bit is bit 0
-	hasSessionID := (payload[0] & 0x01) != 0
-
-	var seid uint64
-	var ieOffset int
-
-	if hasSessionID {
-		if len(payload) < 16 {
+	h, err := parseHeader(payload)
+	if err != nil {
+		s.parseErrors.Add(1)
+		log.Printf("[PFCP-WARN] Dropping message from %s: %v", srcIP, err)
+		if s.replay != nil {
+			s.replay.error(srcIP, err.Error())
+		}
+		return
+	}
+	msgType, msgLen, hasSessionID, seid := h.msgType, h.msgLen, h.hasSEID, h.seid
+	ieOffset, ieDataEnd := h.ieOffset, h.ieEnd
+	if h.truncated {
+		// The IEs that made it into the payload would give a partial view
+		// of the session (e.g. a Create PDR without its F-TEID), so the
+		// message is left out altogether
+		s.truncatedMessages.Add(1)
+		log.Printf("[PFCP-WARN] Message length (%d) exceeds payload (%d), skipping message type %d from %s", 4+int(msgLen), len(payload), msgType, srcIP)
+		if s.replay != nil {
+			s.replay.error(srcIP, fmt.Sprintf("message length (%d) exceeds payload (%d), skipped", 4+int(msgLen), len(payload)))
+		}
+		return
+	}
+
+	if ieOffset < ieDataEnd {
+		// Nesting is bounded before any of the parsers below recurse
+		if err := checkIEDepth(payload[ieOffset:ieDataEnd], s.ieDepthLimit()); err != nil {
+			s.parseErrors.Add(1)
+			log.Printf("[PFCP-WARN] Dropping message type %d from %s: %v", msgType, srcIP, err)
+			if s.replay != nil {
+				s.replay.error(srcIP, err.Error())
+			}
 			return
 		}
-		seid = binary.BigEndian.Uint64(payload[4:12])
-		ieOffset = 16 // Header (4) + SEID (8) + SeqNum (4) = 16
-	} else {
-		ieOffset = 8 // Header (4) + SeqNum (4) = 8
 	}
 
-	// Calculate IE data end position
-	// msgLen is the length of everything after the first 4 bytes
-	// So total packet should be: 4 + msgLen
-	ieDataEnd := 4 + int(msgLen)
-	if ieDataEnd > len(payload) {
-		log.Printf("[PFCP-WARN] Message length (%d) exceeds payload (%d), truncating", ieDataEnd, len(payload))
-		ieDataEnd = len(payload)
+	if _, known := messageTypeNames[msgType]; known {
+		s.msgCounts[msgType].Add(1)
+	}
+	vlog := s.msgLog(msgType)
+	s.trackPeer(msgType, srcIP, ts)
+
+	if ieOffset < ieDataEnd {
+		s.countIEs(payload[ieOffset:ieDataEnd])
+	}
+
+	// Node-related messages carry no SEID; some (e.g. Version Not
+	// Supported) have no IEs at all, so dispatch them before the IE check
+	if isNodeMessage(msgType) {
+		s.handleNodeMessage(vlog, msgType, srcIP, dstIP, ts)
+		if ieOffset < ieDataEnd {
+			s.checkPeerRecovery(msgType, srcIP, payload[ieOffset:ieDataEnd])
+			if msgType == MsgTypeSessionSetDeletionRequest {
+				s.handleSessionSetDeletion(srcIP, payload[ieOffset:ieDataEnd])
+			}
+		}
+		return
+	}
+
+	// Deletion Requests have no mandatory IEs, the header SEID is all
+	// that identifies the session
+	if msgType == MsgTypeSessionDeletionRequest {
+		vlog.Printf("[PFCP-DEBUG] Session Deletion Request: SEID=0x%x, SMF=%s, UPF=%s", seid, srcIP, dstIP)
+		s.handleSessionDeletion(vlog, seid, srcIP, dstIP)
+		return
 	}
 
 	// Ensure we have IE data to process
@@ -423,46 +975,61 @@ func (s *Sniffer) processPacket(packet gopacket.Packet) {
 	// For Session Establishment Request: srcIP=SMF, dstIP=UPF
 	switch msgType {
 	case MsgTypeSessionEstablishmentRequest:
-		log.Printf("[PFCP-DEBUG] Session Establishment Request: SEID=0x%x, SMF=%s, UPF=%s, msgLen=%d", seid, srcIP, dstIP, msgLen)
-		s.handleSessionEstablishmentRequest(ieData, dstIP) // dstIP is the UPF receiving this request
+		vlog.Printf("[PFCP-DEBUG] Session Establishment Request: SEID=0x%x, SMF=%s, UPF=%s, msgLen=%d", seid, srcIP, dstIP, msgLen)
+		s.establishments.requested.Add(1)
+		s.handleSessionEstablishmentRequest(vlog, ieData, h.seq, srcIP, dstIP, ts)
 	case MsgTypeSessionEstablishmentResponse:
 		// Response contains the UPF-assigned SEID, but limited data
-		// We'll update existing session if we can match by F-TEID
-		log.Printf("[PFCP-DEBUG] Session Establishment Response: SEID=0x%x (ignored - use Request data)", seid)
+		// Only used to learn the UP SEID used by later Modification/Deletion
+		// For responses: srcIP=UPF, dstIP=SMF
+		vlog.Printf("[PFCP-DEBUG] Session Establishment Response: SEID=0x%x, UPF=%s, SMF=%s", seid, srcIP, dstIP)
+		s.handleEstablishmentOutcome(vlog, ieData, ts)
+		s.handleSessionEstablishmentResponse(vlog, seid, ieData, dstIP, srcIP)
 	case MsgTypeSessionModificationRequest:
-		log.Printf("[PFCP-DEBUG] Session Modification Request: SEID=0x%x, UPF=%s", seid, dstIP)
-		s.handleSessionModification(seid, ieData, dstIP)
+		vlog.Printf("[PFCP-DEBUG] Session Modification Request: SEID=0x%x, UPF=%s", seid, dstIP)
+		s.handleSessionModification(vlog, seid, ieData, srcIP, dstIP, ts)
 	case MsgTypeSessionModificationResponse:
-		log.Printf("[PFCP-DEBUG] Session Modification Response: SEID=0x%x (ignored)", seid)
-	case MsgTypeSessionDeletionRequest:
-		log.Printf("[PFCP-DEBUG] Session Deletion Request: SEID=0x%x", seid)
-		s.handleSessionDeletion(seid)
+		// Only Usage Reports are used, e.g. of URRs the request removed
+		vlog.Printf("[PFCP-DEBUG] Session Modification Response: SEID=0x%x", seid)
+		s.handleUsageReports(vlog, seid, ieData, IETypeUsageReportModification, ts)
+	case MsgTypeSessionDeletionResponse:
+		// Carries the final Usage Reports; the session is already removed
+		// by the Deletion Request, so they only count towards the totals
+		vlog.Printf("[PFCP-DEBUG] Session Deletion Response: SEID=0x%x", seid)
+		s.handleUsageReports(vlog, seid, ieData, IETypeUsageReportDeletion, ts)
+	case MsgTypeSessionReportRequest:
+		// Sent by the UPF, the header carries the SMF's SEID
+		vlog.Printf("[PFCP-DEBUG] Session Report Request: SEID=0x%x, UPF=%s", seid, srcIP)
+		s.handleUsageReports(vlog, seid, ieData, IETypeUsageReportSessionReport, ts)
 	default:
 		// Log unknown message types for debugging
 		if hasSessionID {
-			log.Printf("[PFCP-DEBUG] Unknown msg type 0x%x with SEID=0x%x", msgType, seid)
+			vlog.Printf("[PFCP-DEBUG] Unknown msg type 0x%x with SEID=0x%x", msgType, seid)
 		}
 	}
 }
 
 // handleSessionEstablishmentRequest handles Session Establishment Request
 // This is the only place where new sessions are created (Request has all the data)
-// upfIP is the destination IP of the PFCP message (the UPF receiving this request)
-func (s *Sniffer) handleSessionEstablishmentRequest(ieData []byte, upfIP net.IP) {
-	// First, extract UE IP - this is our primary key for session identification
-	ueIP := s.extractUEIP(ieData)
-	if ueIP == nil {
-		log.Printf("[PFCP] Session Establishment: No UE IP found in IEs, skipping")
+// smfIP and upfIP are the source and destination IPs of the PFCP message
+// (the SMF sending and the UPF receiving this request), ts its capture time
+// and seq its sequence number
+func (s *Sniffer) handleSessionEstablishmentRequest(vlog msgLog, ieData []byte, seq uint32, smfIP, upfIP net.IP, ts time.Time) {
+	// First, extract UE IPs - these are our primary key for session identification
+	ueIPs := s.extractUEIPs(vlog, ieData)
+	if len(ueIPs) == 0 {
+		vlog.Printf("[PFCP] Session Establishment: No UE IP found in IEs, skipping")
 		return
 	}
+	ueIP := ueIPs[0]
 
 	ueIPStr := ueIP.String()
-	log.Printf("[PFCP] Session Establishment Request: UE_IP=%s, UPF=%s", ueIPStr, upfIP)
+	vlog.Printf("[PFCP] Session Establishment Request: UE_IP=%s, UPF=%s", ueIPStr, upfIP)
 
 	// Extract TEIDs first - we need these to properly identify the session
-	teids := s.extractUniqueTEIDs(ieData, nil)
+	teids := s.extractUniqueTEIDs(vlog, ieData, nil)
 	if len(teids) == 0 {
-		log.Printf("   └─ Warning: No TEIDs found for UE IP %s", ueIPStr)
+		vlog.Printf("   └─ Warning: No TEIDs found for UE IP %s", ueIPStr)
 	}
 
 	// Create new session - always create a new entry for each unique UE IP
@@ -470,112 +1037,127 @@ func (s *Sniffer) handleSessionEstablishmentRequest(ieData []byte, upfIP net.IP)
 	session := &Session{
 		SEID:       0, // Will be assigned by AddSession
 		UEIP:       ueIP,
+		UEIPs:      ueIPs,
 		UPFIP:      upfIP, // Set UPF IP from PFCP message destination
-		CreatedAt:  time.Now(),
-		LastActive: time.Now(),
+		SMFIP:      smfIP, // Set SMF IP from PFCP message source
+		CreatedAt:  ts,
+		LastActive: ts,
 		TEIDs:      teids,
 		Status:     "Active",
 	}
 
+	// The SMF's F-SEID lets us match the Establishment Response
+	session.RemoteSEID = s.extractFSEID(ieData)
+	session.establishment = establishmentDigest(seq, ieData)
+	session.CSIDs = s.extractCSIDs(ieData)
+
 	// Parse IEs to extract all available info
-	s.extractSessionInfo(ieData, session)
+	s.extractSessionInfo(vlog, ieData, session)
+	s.extractBuffering(vlog, ieData, session)
+	s.extractPDRs(vlog, ieData, session)
 
 	// Extract F-TEID details (gNB/peer UPF IPs from Outer Header Creation)
-	s.extractFTEIDDetails(ieData, session)
+	s.extractFTEIDDetails(vlog, ieData, session)
 
 	// Add session (will handle deduplication and SEID assignment)
 	s.correlation.AddSession(session)
 
-	log.Printf("   └─ Session created: TEIDs: %v, UE_IP: %v, UPF_IP: %v, DNN: %s, QFI: %d, MBR: UL=%d/DL=%d kbps",
+	vlog.Printf("   └─ Session created: TEIDs: %v, UE_IP: %v, UPF_IP: %v, DNN: %s, QFI: %d, MBR: UL=%d/DL=%d kbps",
 		session.TEIDs, ueIP, upfIP, session.DNN, session.QFI, session.MBRUplink, session.MBRDownlink)
 }
 
-func (s *Sniffer) handleSessionModification(seid uint64, ieData []byte, upfIP net.IP) {
-	log.Printf("[PFCP] Session Modification: SEID=0x%x, UPF=%s", seid, upfIP)
+func (s *Sniffer) handleSessionModification(vlog msgLog, seid uint64, ieData []byte, smfIP, upfIP net.IP, ts time.Time) {
+	vlog.Printf("[PFCP] Session Modification: SEID=0x%x, UPF=%s", seid, upfIP)
 
-	// First try to find session by UE IP (our primary key)
-	ueIP := s.extractUEIP(ieData)
-	var session *Session
-	var ok bool
-
-	if ueIP != nil {
-		session, ok = s.correlation.GetSessionByUEIP(ueIP.String())
-		if ok {
-			log.Printf("   └─ Found session by UE IP %s (SEID=0x%x)", ueIP.String(), session.SEID)
-		}
+	ueIPs := s.extractUEIPs(vlog, ieData)
+	var ueIP net.IP
+	if len(ueIPs) > 0 {
+		ueIP = ueIPs[0]
 	}
 
-	// If not found by UE IP, try by SEID (fallback)
-	if !ok {
-		session, ok = s.correlation.GetSessionBySEID(seid)
-		if ok {
-			log.Printf("   └─ Found session by SEID 0x%x", seid)
+	// modify applies the message to a session; for a known session it runs
+	// on a private copy inside Correlation.ApplyModification
+	modify := func(session *Session) {
+		// Update UPF/SMF IPs if not already set
+		if session.UPFIP == nil && upfIP != nil {
+			session.UPFIP = upfIP
 		}
-	}
-
-	if !ok {
-		// Session not found - only create if we have UE IP
-		if ueIP == nil {
-			log.Printf("   └─ Session not found and no UE IP, skipping modification")
-			return
+		if session.SMFIP == nil && smfIP != nil {
+			session.SMFIP = smfIP
 		}
 
-		log.Printf("   └─ Session not found, creating from modification data with UE IP %s", ueIP.String())
-
-		// Create new session - SEID will be assigned by AddSession
-		session = &Session{
-			SEID:       0, // Will be assigned by AddSession
-			UEIP:       ueIP,
-			UPFIP:      upfIP, // Set UPF IP from PFCP message destination
-			CreatedAt:  time.Now(),
-			LastActive: time.Now(),
-			TEIDs:      make([]uint32, 0),
-			Status:     "Active",
-		}
-	}
+		// Extract session info from modification IEs
+		s.extractSessionInfo(vlog, ieData, session)
+		s.extractBuffering(vlog, ieData, session)
+		s.extractPDRs(vlog, ieData, session)
 
-	// Update UPF IP if not already set
-	if session.UPFIP == nil && upfIP != nil {
-		session.UPFIP = upfIP
-	}
+		// Extract TEIDs and merge with existing (removes duplicates)
+		session.TEIDs = s.extractUniqueTEIDs(vlog, ieData, session.TEIDs)
 
-	// Extract session info from modification IEs
-	s.extractSessionInfo(ieData, session)
+		// Add UE IPs not known yet (sets UEIP if missing)
+		mergeUEIPs(session, ueIPs)
 
-	// Extract TEIDs and merge with existing (removes duplicates)
-	session.TEIDs = s.extractUniqueTEIDs(ieData, session.TEIDs)
+		// Extract gNB IP from Modification (this is where gNB endpoint info appears)
+		s.extractGNBIPFromModification(vlog, ieData, session)
 
-	// Extract UE IP if present and not already set
-	if session.UEIP == nil && ueIP != nil {
-		session.UEIP = ueIP
+		session.ModifiedAt = ts
+		session.LastActive = ts
 	}
 
-	// Extract gNB IP from Modification (this is where gNB endpoint info appears)
-	s.extractGNBIPFromModification(ieData, session)
+	session, ok := s.correlation.ApplyModification(seid, ueIPs, modify)
+	if !ok {
+		// Session not found - most likely established before the sniffer
+		// started. Keep a partial record so its TEIDs are still tracked.
+		s.incompleteEstablishments.Add(1)
+
+		// With a UE IP, AddSession assigns a sequential SEID as usual;
+		// without one the session can only be found again by PFCP SEID
+		var partialSEID uint64
+		if ueIP != nil {
+			vlog.Printf("   └─ Session not found, creating partial session with UE IP %s", ueIP.String())
+		} else {
+			partialSEID = seid
+			vlog.Printf("   └─ Session not found and no UE IP, creating partial session under SEID 0x%x", seid)
+		}
 
-	session.ModifiedAt = time.Now()
-	session.LastActive = time.Now()
-	s.correlation.AddSession(session)
+		session = &Session{
+			SEID:                    partialSEID,
+			UPFIP:                   upfIP, // Set UPF IP from PFCP message destination
+			SMFIP:                   smfIP, // Set SMF IP from PFCP message source
+			CreatedAt:               ts,
+			LastActive:              ts,
+			TEIDs:                   make([]uint32, 0),
+			Status:                  "Active",
+			IncompleteEstablishment: true,
+		}
+		modify(session)
+
+		// The partial session is not shared yet, so it can be added as is
+		s.correlation.AddSession(session)
+	}
 
-	log.Printf("   └─ Updated: TEIDs: %v, UE_IP: %v, UPF_IP: %v, MBR: UL=%d/DL=%d kbps",
+	vlog.Printf("   └─ Updated: TEIDs: %v, UE_IP: %v, UPF_IP: %v, MBR: UL=%d/DL=%d kbps",
 		session.TEIDs, session.UEIP, session.UPFIP, session.MBRUplink, session.MBRDownlink)
 }
 
-func (s *Sniffer) handleSessionDeletion(seid uint64) {
-	log.Printf("PFCP Session Deletion: SEID=0x%x", seid)
-	// Try to find session by the incoming SEID first
-	if _, ok := s.correlation.GetSessionBySEID(seid); ok {
+func (s *Sniffer) handleSessionDeletion(vlog msgLog, seid uint64, smfIP, upfIP net.IP) {
+	vlog.Printf("PFCP Session Deletion: SEID=0x%x, SMF=%s, UPF=%s", seid, smfIP, upfIP)
+	// The header carries the UPF's SEID, learnt from the Establishment Response
+	if session, ok := s.correlation.GetSessionByPFCPSEID(seid); ok {
+		s.correlation.RemoveSession(session.SEID)
+		vlog.Printf("   └─ Removed session SEID=0x%x by PFCP SEID 0x%x", session.SEID, seid)
+	} else if session, ok := s.correlation.GetSessionBySEID(seid); ok && session.IncompleteEstablishment {
+		// Partial sessions without UE IP are stored under the PFCP SEID
 		s.correlation.RemoveSession(seid)
-		log.Printf("   └─ Removed session by SEID 0x%x", seid)
+		vlog.Printf("   └─ Removed session by SEID 0x%x", seid)
 	} else {
-		// Session may have been stored with a different SEID (our sequential one)
-		// This is expected since free5gc's SEID != our internal SEID
-		log.Printf("   └─ Session SEID 0x%x not found in our store (this is normal)", seid)
+		// Establishment Response not seen (e.g. sniffer started mid-session)
+		vlog.Printf("   └─ Session SEID 0x%x not found in our store (this is normal)", seid)
 	}
 }
 
 // extractSessionInfo extracts DNN, QFI, and other session info from PFCP IEs
-func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
+func (s *Sniffer) extractSessionInfo(vlog msgLog, ieData []byte, session *Session) {
 	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
 		switch ieType {
 		case IETypeNetworkInstance: // Network Instance (DNN)
@@ -591,20 +1173,30 @@ func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
 				}
 				if len(dnn) > 0 {
 					session.DNN = dnn
-					log.Printf("   └─ Found DNN: %s", dnn)
+					vlog.Printf("   └─ Found DNN: %s", dnn)
 				}
 			}
+		case IETypeSDFFilter: // SDF Filter (within a PDI)
+			if filter, ok := parseSDFFilter(ieValue); ok {
+				session.SDFFilters = appendUnique(session.SDFFilters, filter)
+				vlog.Printf("   └─ Found SDF Filter: %s", filter)
+			}
+		case IETypeApplicationID: // Application ID (within a PDI)
+			if appID, ok := parseApplicationID(ieValue); ok {
+				session.AppIDs = appendUnique(session.AppIDs, appID)
+				vlog.Printf("   └─ Found Application ID: %s", appID)
+			}
 		case IETypeQFI: // QFI
 			if len(ieValue) >= 1 {
 				session.QFI = ieValue[0] & 0x3F // QFI is 6 bits
-				log.Printf("   └─ Found QFI: %d", session.QFI)
+				vlog.Printf("   └─ Found QFI: %d", session.QFI)
 			}
 		case IETypeMBR: // Maximum Bit Rate (Type 26)
 			// According to 3GPP TS 29.244, MBR IE format:
 			// - UL MBR: 5 bytes (40 bits) in kbps
 			// - DL MBR: 5 bytes (40 bits) in kbps
 			// Total: 10 bytes
-			log.Printf("   └─ MBR IE length: %d bytes, content: %x", len(ieValue), ieValue)
+			vlog.Printf("   └─ MBR IE length: %d bytes, content: %x", len(ieValue), ieValue)
 			if len(ieValue) >= 10 {
 				// 5 bytes each: use 40-bit encoding
 				ulMBR := uint64(0)
@@ -615,28 +1207,28 @@ func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
 				}
 				session.MBRUplink = ulMBR
 				session.MBRDownlink = dlMBR
-				log.Printf("   └─ Found MBR (10-byte): UL=%d kbps, DL=%d kbps", session.MBRUplink, session.MBRDownlink)
+				vlog.Printf("   └─ Found MBR (10-byte): UL=%d kbps, DL=%d kbps", session.MBRUplink, session.MBRDownlink)
 			} else if len(ieValue) >= 8 {
 				// Fallback: 4 bytes each (32-bit)
 				session.MBRUplink = uint64(binary.BigEndian.Uint32(ieValue[0:4]))
 				session.MBRDownlink = uint64(binary.BigEndian.Uint32(ieValue[4:8]))
-				log.Printf("   └─ Found MBR (8-byte): UL=%d kbps, DL=%d kbps", session.MBRUplink, session.MBRDownlink)
+				vlog.Printf("   └─ Found MBR (8-byte): UL=%d kbps, DL=%d kbps", session.MBRUplink, session.MBRDownlink)
 			} else if len(ieValue) >= 4 {
 				// Single direction (uplink only or downlink only)
 				// This seems to be the case in current SMF implementation
 				session.MBRUplink = uint64(binary.BigEndian.Uint32(ieValue[0:4]))
-				log.Printf("   └─ Found MBR (4-byte, UL only): UL=%d kbps", session.MBRUplink)
+				vlog.Printf("   └─ Found MBR (4-byte, UL only): UL=%d kbps", session.MBRUplink)
 			}
 		case IETypeGBR: // Guaranteed Bit Rate
 			if len(ieValue) >= 8 {
 				session.GBRUplink = uint64(binary.BigEndian.Uint32(ieValue[0:4]))
 				session.GBRDownlink = uint64(binary.BigEndian.Uint32(ieValue[4:8]))
-				log.Printf("   └─ Found GBR: UL=%d kbps, DL=%d kbps", session.GBRUplink, session.GBRDownlink)
+				vlog.Printf("   └─ Found GBR: UL=%d kbps, DL=%d kbps", session.GBRUplink, session.GBRDownlink)
 			}
 		case IETypePrecedence: // Precedence (can indicate QoS priority)
 			if len(ieValue) >= 4 {
 				precedence := binary.BigEndian.Uint32(ieValue[0:4])
-				log.Printf("   └─ Found Precedence: %d", precedence)
+				vlog.Printf("   └─ Found Precedence: %d", precedence)
 			}
 		case IETypePDUSessionType: // PDU Session Type
 			if len(ieValue) >= 1 {
@@ -655,18 +1247,18 @@ func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
 				default:
 					session.SessionType = fmt.Sprintf("Type-%d", pduType)
 				}
-				log.Printf("   └─ Found PDU Session Type: %s", session.SessionType)
+				vlog.Printf("   └─ Found PDU Session Type: %s", session.SessionType)
 			}
 		case IEType5QI: // 5QI (5G QoS Identifier)
 			if len(ieValue) >= 1 {
 				session.QoS5QI = ieValue[0]
-				log.Printf("   └─ Found 5QI: %d", session.QoS5QI)
+				vlog.Printf("   └─ Found 5QI: %d", session.QoS5QI)
 			}
 		case IETypeARP: // ARP (Allocation and Retention Priority)
 			if len(ieValue) >= 1 {
 				// ARP IE format: Priority Level (4 bits) + PCI (1 bit) + PVI (1 bit) + spare (2 bits)
 				session.ARPPL = (ieValue[0] >> 4) & 0x0F // Upper 4 bits are priority level
-				log.Printf("   └─ Found ARP Priority Level: %d", session.ARPPL)
+				vlog.Printf("   └─ Found ARP Priority Level: %d", session.ARPPL)
 			}
 		case IETypeSNSSAI: // S-NSSAI
 			if len(ieValue) >= 1 {
@@ -683,7 +1275,7 @@ func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
 				if sd != "" {
 					session.SNssai += fmt.Sprintf(",SD:%s", sd)
 				}
-				log.Printf("   └─ Found S-NSSAI: %s", session.SNssai)
+				vlog.Printf("   └─ Found S-NSSAI: %s", session.SNssai)
 			}
 		}
 	})
@@ -692,7 +1284,7 @@ func (s *Sniffer) extractSessionInfo(ieData []byte, session *Session) {
 // extractFTEIDDetails extracts F-TEID and Outer Header Creation details
 // For ULCL: Outer Header Creation in i-upf's FAR points to psa-upf (N9 interface)
 // For single UPF: Outer Header Creation points to gNB (N3)
-func (s *Sniffer) extractFTEIDDetails(ieData []byte, session *Session) {
+func (s *Sniffer) extractFTEIDDetails(vlog msgLog, ieData []byte, session *Session) {
 	// Known UPF IPs in ULCL configuration (could be made configurable)
 	// For now, we detect UPF IPs by checking common UPF IP patterns
 	isUPFIP := func(ip net.IP) bool {
@@ -709,10 +1301,11 @@ func (s *Sniffer) extractFTEIDDetails(ieData []byte, session *Session) {
 
 	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
 		// Outer Header Creation contains the destination for forwarded packets
-		if ieType == IETypeOuterHeaderCreation && len(ieValue) >= 10 {
-			// Flags (2) + TEID (4) + IPv4 (4)
-			ip := net.IP(make([]byte, 4))
-			copy(ip, ieValue[6:10])
+		if ieType == IETypeOuterHeaderCreation {
+			_, ip, ok := parseOuterHeaderCreation(ieValue)
+			if !ok {
+				return
+			}
 
 			// Skip if it's the same as this UPF's IP
 			if session.UPFIP != nil && ip.Equal(session.UPFIP) {
@@ -724,13 +1317,13 @@ func (s *Sniffer) extractFTEIDDetails(ieData []byte, session *Session) {
 				// This is likely another UPF (N9 peer)
 				if session.N9PeerIP == nil {
 					session.N9PeerIP = ip
-					log.Printf("   └─ Outer Header Creation N9 peer UPF: %s", ip)
+					vlog.Printf("   └─ Outer Header Creation N9 peer UPF: %s", ip)
 				}
 			} else {
 				// This is likely gNB (N3)
 				if session.GNBIP == nil {
 					session.GNBIP = ip
-					log.Printf("   └─ Outer Header Creation gNB (N3): %s", ip)
+					vlog.Printf("   └─ Outer Header Creation gNB (N3): %s", ip)
 				}
 			}
 		}
@@ -739,32 +1332,24 @@ func (s *Sniffer) extractFTEIDDetails(ieData []byte, session *Session) {
 
 // extractGNBIPFromModification extracts gNB IP from Session Modification
 // This is where gNB's F-TEID info is provided after gNB responds to AMF
-func (s *Sniffer) extractGNBIPFromModification(ieData []byte, session *Session) {
+func (s *Sniffer) extractGNBIPFromModification(vlog msgLog, ieData []byte, session *Session) {
 	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
 		// Outer Header Creation in Session Modification contains gNB endpoint
 		// This is in FAR (Forwarding Action Rules) for downlink
-		if ieType == IETypeOuterHeaderCreation && len(ieValue) >= 10 {
-			// Flags (2) + TEID (4) + IPv4 (4)
-			ip := net.IP(ieValue[6:10])
+		if ieType == IETypeOuterHeaderCreation {
+			_, ip, ok := parseOuterHeaderCreation(ieValue)
 			// Only update gNB IP if it's different from UPF IP
-			if session.UPFIP == nil || !ip.Equal(session.UPFIP) {
+			if ok && (session.UPFIP == nil || !ip.Equal(session.UPFIP)) {
 				session.GNBIP = ip
-				log.Printf("   └─ Outer Header gNB IP: %s", ip)
+				vlog.Printf("   └─ Outer Header gNB IP: %s", ip)
 			}
 		}
 		// Also check F-TEID in Update FAR which may contain gNB info
-		if ieType == IETypeFTEID && len(ieValue) >= 5 {
-			flags := ieValue[0]
-			offset := 5 // Skip flags (1) + TEID (4)
-
-			// Check for IPv4 address (bit 0)
-			if flags&0x01 != 0 && len(ieValue) >= offset+4 {
-				ip := net.IP(ieValue[offset : offset+4])
-				// If this IP is different from UPF IP, it's likely gNB IP
-				if session.UPFIP != nil && !ip.Equal(session.UPFIP) {
-					session.GNBIP = ip
-					log.Printf("   └─ F-TEID gNB IP from Modification: %s", ip)
-				}
+		if ieType == IETypeFTEID {
+			// If this IP is different from UPF IP, it's likely gNB IP
+			if ip := parseFTEIDAddr(ieValue); ip != nil && session.UPFIP != nil && !ip.Equal(session.UPFIP) {
+				session.GNBIP = ip
+				vlog.Printf("   └─ F-TEID gNB IP from Modification: %s", ip)
 			}
 		}
 	})
@@ -775,26 +1360,28 @@ func (s *Sniffer) extractGNBIPFromModification(ieData []byte, session *Session)
 // destination TEIDs (gNB or peer UPF), not the UPF's own TEIDs. The Outer Header
 // Creation TEID belongs to the remote endpoint (gNB) and may be shared across
 // multiple PDU sessions, which would cause incorrect TEID association.
-func (s *Sniffer) extractTEIDs(ieData []byte) []uint32 {
+func (s *Sniffer) extractTEIDs(vlog msgLog, ieData []byte) []uint32 {
 	teids := make([]uint32, 0)
 	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
 		// F-TEID IE (Type 21) - This is the UPF's own TEID for receiving packets
-		if ieType == IETypeFTEID && len(ieValue) >= 5 {
-			// First byte is flags, next 4 bytes is TEID
-			teid := binary.BigEndian.Uint32(ieValue[1:5])
-			if teid > 0 {
+		if ieType == IETypeFTEID {
+			// CHOOSE F-TEIDs carry no TEID yet, the UPF assigns it
+			teid, ok := parseFTEIDTEID(ieValue)
+			switch {
+			case ok && teid > 0:
 				teids = append(teids, teid)
-				log.Printf("   └─ Found F-TEID (UPF): 0x%x", teid)
+				vlog.Printf("   └─ Found F-TEID (UPF): 0x%x", teid)
+			case !ok && len(ieValue) >= 1 && ieValue[0]&fteidFlagCH != 0:
+				vlog.Printf("   └─ Found F-TEID (UPF): CHOOSE, assigned by the UPF")
 			}
 		}
 		// NOTE: Outer Header Creation IE (Type 84) contains the DESTINATION TEID
 		// (where UPF should send packets to, e.g., gNB's TEID). This TEID belongs
 		// to the gNB, not to this session, so we don't extract it here.
 		// It's only logged for debugging purposes.
-		if ieType == 84 && len(ieValue) >= 6 {
-			teid := binary.BigEndian.Uint32(ieValue[2:6])
-			if teid > 0 {
-				log.Printf("   └─ Outer Header Creation TEID (gNB dest): 0x%x (not added to session)", teid)
+		if ieType == IETypeOuterHeaderCreation {
+			if teid, _, _ := parseOuterHeaderCreation(ieValue); teid > 0 {
+				vlog.Printf("   └─ Outer Header Creation TEID (gNB dest): 0x%x (not added to session)", teid)
 			}
 		}
 	})
@@ -802,7 +1389,7 @@ func (s *Sniffer) extractTEIDs(ieData []byte) []uint32 {
 }
 
 // extractUniqueTEIDs extracts TEIDs and merges with existing ones, removing duplicates
-func (s *Sniffer) extractUniqueTEIDs(ieData []byte, existingTEIDs []uint32) []uint32 {
+func (s *Sniffer) extractUniqueTEIDs(vlog msgLog, ieData []byte, existingTEIDs []uint32) []uint32 {
 	// Use a map to track unique TEIDs
 	teidSet := make(map[uint32]bool)
 
@@ -814,7 +1401,7 @@ func (s *Sniffer) extractUniqueTEIDs(ieData []byte, existingTEIDs []uint32) []ui
 	}
 
 	// Extract new TEIDs from IE data
-	newTEIDs := s.extractTEIDs(ieData)
+	newTEIDs := s.extractTEIDs(vlog, ieData)
 	for _, t := range newTEIDs {
 		if t != 0 {
 			teidSet[t] = true
@@ -830,89 +1417,11 @@ func (s *Sniffer) extractUniqueTEIDs(ieData []byte, existingTEIDs []uint32) []ui
 	return result
 }
 
-// extractUEIP extracts UE IP Address from PFCP IEs (including nested IEs)
-// According to 3GPP TS 29.244, UE IP Address IE (Type 93) format:
-// - Flags (1 byte): bit 0=S/D, bit 1=V4, bit 2=V6, bit 3=IPv6D, bit 4=CHV4, bit 5=CHV6
-// - IPv4 address (4 bytes) if V4 bit is set and CHV4 is not set
-// - IPv6 address (16 bytes) if V6 bit is set and CHV6 is not set
-func (s *Sniffer) extractUEIP(ieData []byte) net.IP {
-	var ueIP net.IP
-	var foundCount int
-
-	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
-		// UE IP Address IE (Type 93)
-		if ieType == IETypeUEIPAddr && len(ieValue) >= 1 {
-			flags := ieValue[0]
-			offset := 1
-
-			// Check V4 bit (bit 1) and ensure CHV4 (bit 4) is not set
-			// CHV4 means "Choose IPv4 Address" - the IP hasn't been assigned yet
-			hasV4 := (flags & 0x02) != 0
-			isChooseV4 := (flags & 0x10) != 0
-
-			if hasV4 && !isChooseV4 && len(ieValue) >= offset+4 {
-				extractedIP := net.IP(make([]byte, 4))
-				copy(extractedIP, ieValue[offset:offset+4])
-
-				// Validate that it's a proper UE IP (not 0.0.0.0)
-				if !extractedIP.Equal(net.IPv4zero) {
-					// Only use the first valid UE IP found (avoid overwriting)
-					if ueIP == nil {
-						ueIP = extractedIP
-						foundCount++
-						log.Printf("   └─ Found UE IP: %s (flags=0x%02x)", ueIP, flags)
-					} else if !ueIP.Equal(extractedIP) {
-						// Log if we find a different UE IP (shouldn't happen in same session)
-						log.Printf("   └─ Additional UE IP found (ignored): %s", extractedIP)
-					}
-				}
-			} else if isChooseV4 {
-				log.Printf("   └─ UE IP Address IE with CHV4 flag (IP not yet assigned)")
-			}
-		}
-	})
-
-	if ueIP == nil {
-		log.Printf("   └─ No valid UE IP found in PFCP message")
-	}
-
-	return ueIP
-}
-
-// parseIEsRecursive recursively parses PFCP IEs and calls callback for each IE
+// parseIEsRecursive recursively parses PFCP IEs and calls callback for each
+// IE. Grouped IEs nested deeper than the sniffer's limit are not descended
+// into; processPacket drops such messages before their IEs are parsed.
 func (s *Sniffer) parseIEsRecursive(ieData []byte, callback func(ieType uint16, ieValue []byte)) {
-	offset := 0
-
-	for offset < len(ieData)-4 {
-		if offset+4 > len(ieData) {
-			break
-		}
-
-		ieType := binary.BigEndian.Uint16(ieData[offset : offset+2])
-		ieLen := binary.BigEndian.Uint16(ieData[offset+2 : offset+4])
-
-		if ieLen == 0 || offset+4+int(ieLen) > len(ieData) {
-			break
-		}
-
-		ieValue := ieData[offset+4 : offset+4+int(ieLen)]
-
-		// Call callback for this IE
-		callback(ieType, ieValue)
-
-		// Recursively parse grouped IEs
-		// These IE types contain nested IEs:
-		// - Create PDR (1), Create FAR (3), Create URR (6), Create QER (7)
-		// - PDI (2), Forwarding Parameters (4), Duplicating Parameters (5)
-		// - Update PDR (9), Update FAR (10), etc.
-		switch ieType {
-		case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16:
-			// These are grouped IEs, parse recursively
-			s.parseIEsRecursive(ieValue, callback)
-		}
-
-		offset += 4 + int(ieLen)
-	}
+	walkIETree(ieData, 1, s.ieDepthLimit(), callback)
 }
 
 // GetCorrelation returns the correlation store
@@ -925,12 +1434,11 @@ func (c *Correlation) UpdateUplinkPeer(teid uint32, peerIP net.IP) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if seid, ok := c.teidMap[teid]; ok {
-		if session, ok := c.sessions[seid]; ok {
-			if session.UplinkPeerIP == nil || !session.UplinkPeerIP.Equal(peerIP) {
-				session.UplinkPeerIP = peerIP
-				log.Printf("[PFCP] Updated Uplink Peer IP for SEID 0x%x: %s", session.SEID, peerIP)
-			}
+	if session, ok := c.store.GetByTEID(teid); ok {
+		if session.UplinkPeerIP == nil || !session.UplinkPeerIP.Equal(peerIP) {
+			session.UplinkPeerIP = peerIP
+			c.saveLocked(session)
+			log.Printf("[PFCP] Updated Uplink Peer IP for SEID 0x%x: %s", session.SEID, peerIP)
 		}
 	}
 }
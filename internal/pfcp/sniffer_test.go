@@ -0,0 +1,232 @@
+package pfcp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"go.uber.org/goleak"
+)
+
+// ieTypeDestinationInterface is the Destination Interface IE of a FAR's
+// Forwarding Parameters, which the sniffer does not decode
+const ieTypeDestinationInterface = 42
+
+// fullEstablishmentRequest is an Establishment Request as a 5GC SMF sends
+// them, with the given number of PDRs, FARs and QERs: odd PDRs uplink on
+// consecutive TEIDs after teid, even PDRs downlink to ueIP
+func fullEstablishmentRequest(seq uint32, cpSEID uint64, ueIP string, teid uint32, rules int) []byte {
+	ies := [][]byte{fseidIE(cpSEID, testSMF)}
+	for i := 1; i <= rules; i++ {
+		id := uint32(i)
+		pdi := [][]byte{ie(IETypeSourceInterface, u8(0))}
+		if i%2 == 1 {
+			pdi = append(pdi, fteidIE(teid+id, testUPF), ie(IETypeNetworkInstance, []byte("internet")))
+		} else {
+			pdi[0] = ie(IETypeSourceInterface, u8(1))
+			pdi = append(pdi, ueIPIE(ueIP))
+		}
+		ies = append(ies, ie(IETypeCreatePDR,
+			ie(IETypePDRID, u16(uint16(i))),
+			ie(IETypePrecedence, u32(id*10)),
+			ie(IETypePDI, pdi...),
+			ie(IETypeFARID, u32(id)),
+			ie(IETypeQERID, u32(id)),
+		))
+		ies = append(ies, ie(IETypeCreateFAR,
+			ie(IETypeFARID, u32(id)),
+			ie(IETypeApplyAction, u8(0x02)), // FORW
+			ie(IETypeForwardingParameters,
+				ie(ieTypeDestinationInterface, u8(0)),
+				// GTP-U/UDP/IPv4 towards the gNB
+				ie(IETypeOuterHeaderCreation, u16(0x0100), u32(0x9000+id), ipBytes("10.100.201.1")),
+			),
+		))
+		ies = append(ies, ie(IETypeCreateQER,
+			ie(IETypeQERID, u32(id)),
+			ie(IETypeGateStatus, u8(0)),
+			ie(IETypeMBR, []byte{0, 0, 0, 0x27, 0x10, 0, 0, 0, 0x27, 0x10}),
+			ie(IETypeQFI, u8(9)),
+		))
+	}
+	return message(MsgTypeSessionEstablishmentRequest, 0, seq, ies...)
+}
+
+// heartbeatRequest is a Heartbeat Request with a Recovery Time Stamp
+func heartbeatRequest(seq uint32) []byte {
+	return message(MsgTypeHeartbeatRequest, 0, seq, ie(IETypeRecoveryTimeStamp, u32(0xe0000000)))
+}
+
+func TestProcessPacketFullEstablishment(t *testing.T) {
+	s := newTestSniffer()
+	s.processPacket(packet(t, testSMF, testUPF, fullEstablishmentRequest(1, 0x1001, "10.60.0.1", 0x10000, 5)))
+	s.processPacket(packet(t, testUPF, testSMF, establishmentResponse(1, 0x1001, 0x2001)))
+
+	session, ok := s.correlation.GetSessionByTEID(0x10001)
+	if !ok {
+		t.Fatal("no session for the first uplink TEID")
+	}
+	if session.PDRCount != 5 || len(session.PDRs) != 5 {
+		t.Errorf("PDR count %d, %d PDRs, want 5", session.PDRCount, len(session.PDRs))
+	}
+	for _, teid := range []uint32{0x10001, 0x10003, 0x10005} {
+		if _, ok := s.correlation.GetSessionByTEID(teid); !ok {
+			t.Errorf("no session for uplink TEID 0x%x", teid)
+		}
+	}
+	if session.UEIP.String() != "10.60.0.1" || session.QFI != 9 {
+		t.Errorf("UE IP %s, QFI %d, want 10.60.0.1 and 9", session.UEIP, session.QFI)
+	}
+}
+
+func TestHasUEAddr(t *testing.T) {
+	quietLog(t)
+	c := NewCorrelation()
+	c.AddSession(&Session{
+		SEID:  0x1001,
+		UEIP:  net.ParseIP("10.60.0.1"),
+		UEIPs: []net.IP{net.ParseIP("10.60.0.1"), net.ParseIP("2001:db8::1")},
+	})
+
+	for addr, want := range map[string]bool{
+		"10.60.0.1":        true,
+		"::ffff:10.60.0.1": true,
+		"2001:db8::1":      true,
+		"10.60.0.2":        false,
+	} {
+		if got := c.HasUEAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("HasUEAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	c.RemoveSession(0x1001)
+	if c.HasUEAddr(netip.MustParseAddr("10.60.0.1")) {
+		t.Error("UE address of a removed session known")
+	}
+}
+
+func BenchmarkProcessPacketEstablishment(b *testing.B) {
+	quietLog(b)
+	// Distinct sessions, so that each request creates one rather than
+	// being taken for a retransmission
+	packets := make([]gopacket.Packet, 1024)
+	for i := range packets {
+		ueIP := fmt.Sprintf("10.60.%d.%d", i/250, i%250+1)
+		packets[i] = packet(b, testSMF, testUPF, fullEstablishmentRequest(uint32(i), uint64(0x1000+i), ueIP, uint32(0x10000+16*i), 5))
+	}
+
+	var s *Sniffer
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%len(packets) == 0 {
+			b.StopTimer()
+			s = newTestSniffer()
+			b.StartTimer()
+		}
+		s.processPacket(packets[i%len(packets)])
+	}
+}
+
+func BenchmarkProcessPacketModification(b *testing.B) {
+	quietLog(b)
+	s := newTestSniffer()
+	establish(b, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+	p := packet(b, testSMF, testUPF, modificationRequest(2, 0x2001, 2, 0x10002,
+		ie(IETypeCreateFAR,
+			ie(IETypeFARID, u32(2)),
+			ie(IETypeApplyAction, u8(0x02)),
+			ie(IETypeForwardingParameters,
+				ie(ieTypeDestinationInterface, u8(0)),
+				ie(IETypeOuterHeaderCreation, u16(0x0100), u32(0x9001), ipBytes("10.100.201.1")),
+			),
+		),
+	))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.processPacket(p)
+	}
+}
+
+func BenchmarkProcessPacketHeartbeat(b *testing.B) {
+	quietLog(b)
+	s := newTestSniffer()
+	p := packet(b, testSMF, testUPF, heartbeatRequest(1))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.processPacket(p)
+	}
+}
+
+// BenchmarkCorrelationContention adds sessions and looks them up by TEID
+// from parallel goroutines, 7 lookups per add as for the eBPF stats path
+func BenchmarkCorrelationContention(b *testing.B) {
+	quietLog(b)
+	const sessions = 4096
+	c := NewCorrelation()
+	now := time.Now()
+	var next atomic.Uint32
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			n := next.Add(1) % sessions
+			teid := 0x10000 + n
+			if i%8 == 0 {
+				ueIP := net.IPv4(10, 60, byte(n>>8), byte(n)).To4()
+				c.AddSession(&Session{
+					UEIP:       ueIP,
+					UEIPs:      []net.IP{ueIP},
+					TEIDs:      []uint32{teid},
+					CreatedAt:  now,
+					LastActive: now,
+				})
+				continue
+			}
+			c.GetSessionByTEID(teid)
+		}
+	})
+}
+
+func TestSnifferStopWithoutStart(t *testing.T) {
+	s := newTestSniffer()
+	s.Stop()
+	s.Stop()
+	goleak.VerifyNone(t)
+}
+
+// TestSnifferStartStopCycles starts and stops sniffers reading a capture
+// as fast as possible, stopping while packets are in flight
+func TestSnifferStartStopCycles(t *testing.T) {
+	quietLog(t)
+	path := writePcap(t, layers.LinkTypeEthernet, sessionBurst(t, 500, 4))
+
+	for i := 0; i < 50; i++ {
+		s := NewSnifferFromFile(path, 8805, NewCorrelation())
+		s.SetWorkers(1 + i%4)
+		if err := s.Start(); err != nil {
+			// Only opening the capture fails, which needs libpcap
+			t.Skipf("cannot read captures: %v", err)
+		}
+		if i%2 == 1 {
+			time.Sleep(time.Millisecond)
+		}
+		s.Stop()
+		select {
+		case <-s.Done():
+		default:
+			t.Fatal("capture loop still running after Stop")
+		}
+		s.Stop()
+	}
+	goleak.VerifyNone(t)
+}
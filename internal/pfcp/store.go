@@ -0,0 +1,117 @@
+package pfcp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SessionStore holds the sessions of a Correlation by internal SEID and the
+// TEID index that attributes GTP-U traffic to them. The in-memory store is
+// the default; the Redis store also saves the sessions so they survive an
+// agent restart (see NewRedisStore).
+//
+// Correlation calls the store under its lock, lookups under the read lock,
+// and keeps its other indexes (UE IPs, PFCP SEIDs, peers) itself, rebuilding
+// them from All when created with a store that already holds sessions.
+// Sessions are live values: Correlation changes them in place and adds them
+// again to have the change saved.
+type SessionStore interface {
+	// Add stores the session under its SEID, replacing any previous one
+	Add(session *Session)
+	Remove(seid uint64)
+	GetBySEID(seid uint64) (*Session, bool)
+	GetByTEID(teid uint32) (*Session, bool)
+	All() []*Session
+	Count() int
+
+	// MapTEID points teid at the session seid, UnmapTEID removes teid if it
+	// still points at seid. A TEID maps to one session at a time and is
+	// not derived from Session.TEIDs, the TEID limit may evict it.
+	MapTEID(teid uint32, seid uint64)
+	UnmapTEID(teid uint32, seid uint64)
+	TEIDCount() int
+
+	// TEIDOwner returns the SEID teid is mapped to. Unlike GetByTEID it
+	// does not count as a use of the mapping for the TEID limit, for
+	// lookups that do not attribute traffic.
+	TEIDOwner(teid uint32) (uint64, bool)
+
+	// SetTEIDLimit bounds the TEID index (see Correlation.SetTEIDLimit),
+	// TEIDEvictions returns the number of mappings it evicted
+	SetTEIDLimit(max int)
+	TEIDEvictions() uint64
+}
+
+// memoryStore is the in-memory SessionStore
+type memoryStore struct {
+	sessions map[uint64]*Session // SEID -> Session
+	teids    *teidIndex          // TEID -> SEID
+
+	// TEID mappings evicted by the TEID limit (see SetTEIDLimit)
+	teidEvictions      atomic.Uint64
+	teidEvictionLogged time.Time
+}
+
+// NewMemoryStore returns an empty in-memory session store
+func NewMemoryStore() SessionStore {
+	return newMemoryStore()
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		sessions: make(map[uint64]*Session),
+		teids:    newTEIDIndex(),
+	}
+}
+
+func (m *memoryStore) Add(session *Session) {
+	m.sessions[session.SEID] = session
+}
+
+func (m *memoryStore) Remove(seid uint64) {
+	delete(m.sessions, seid)
+}
+
+func (m *memoryStore) GetBySEID(seid uint64) (*Session, bool) {
+	session, ok := m.sessions[seid]
+	return session, ok
+}
+
+func (m *memoryStore) GetByTEID(teid uint32) (*Session, bool) {
+	seid, ok := m.teids.get(teid)
+	if !ok {
+		return nil, false
+	}
+	return m.GetBySEID(seid)
+}
+
+func (m *memoryStore) All() []*Session {
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func (m *memoryStore) Count() int {
+	return len(m.sessions)
+}
+
+func (m *memoryStore) TEIDOwner(teid uint32) (uint64, bool) {
+	return m.teids.peek(teid)
+}
+
+func (m *memoryStore) MapTEID(teid uint32, seid uint64) {
+	m.teids.set(teid, seid)
+	m.enforceTEIDLimit()
+}
+
+func (m *memoryStore) UnmapTEID(teid uint32, seid uint64) {
+	if current, ok := m.teids.peek(teid); ok && current == seid {
+		m.teids.remove(teid)
+	}
+}
+
+func (m *memoryStore) TEIDCount() int {
+	return m.teids.len()
+}
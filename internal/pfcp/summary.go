@@ -0,0 +1,68 @@
+package pfcp
+
+import "time"
+
+// summaryWindow is the window for the created/deleted session counts
+const summaryWindow = time.Minute
+
+// Summary holds aggregate statistics over all sessions
+type Summary struct {
+	Sessions          int `json:"total_sessions"`
+	TEIDs             int `json:"total_teids"`
+	UEIPs             int `json:"unique_ue_ips"`
+	CreatedLastMinute int `json:"sessions_created_last_minute"`
+	DeletedLastMinute int `json:"sessions_deleted_last_minute"`
+	Buffering         int `json:"buffering_sessions"`
+
+	// Session limit (0 = unlimited) and how often it was hit
+	MaxSessions      int    `json:"max_sessions"`
+	OverflowPolicy   string `json:"overflow_policy"`
+	SessionOverflows uint64 `json:"session_overflows"`
+}
+
+// recordLifecycle remembers when sessions were created or deleted for the
+// summary counts, caller must hold c.mu
+func (c *Correlation) recordLifecycle(eventType string, now time.Time) {
+	cutoff := now.Add(-summaryWindow)
+	switch eventType {
+	case SessionEventCreated:
+		c.recentCreated = append(pruneBefore(c.recentCreated, cutoff), now)
+	case SessionEventDeleted, SessionEventPeerRestart, SessionEventEvicted, SessionEventSetDeletion:
+		c.recentDeleted = append(pruneBefore(c.recentDeleted, cutoff), now)
+	}
+}
+
+// pruneBefore drops the timestamps older than cutoff from the sorted ts
+func pruneBefore(ts []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(ts) && ts[i].Before(cutoff) {
+		i++
+	}
+	return ts[i:]
+}
+
+// Summary computes aggregate session statistics
+func (c *Correlation) Summary() Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	buffering := 0
+	for _, session := range c.store.All() {
+		if session.Buffering() {
+			buffering++
+		}
+	}
+
+	cutoff := time.Now().Add(-summaryWindow)
+	return Summary{
+		Sessions:          c.store.Count(),
+		TEIDs:             c.store.TEIDCount(),
+		UEIPs:             len(c.ueIPMap),
+		CreatedLastMinute: len(pruneBefore(c.recentCreated, cutoff)),
+		DeletedLastMinute: len(pruneBefore(c.recentDeleted, cutoff)),
+		Buffering:         buffering,
+		MaxSessions:       c.maxSessions,
+		OverflowPolicy:    c.overflowPolicy.String(),
+		SessionOverflows:  c.overflows.Load(),
+	}
+}
@@ -0,0 +1,60 @@
+package pfcp
+
+import (
+	"fmt"
+	"maps"
+	"unicode/utf8"
+)
+
+// Bounds of the operator tags of a session (see SetSessionTags)
+const (
+	MaxSessionTags = 16
+	MaxTagKeyLen   = 64  // bytes
+	MaxTagValueLen = 256 // bytes
+)
+
+// ValidateTags checks tags against the bounds of SetSessionTags
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxSessionTags {
+		return fmt.Errorf("%d tags, at most %d allowed", len(tags), MaxSessionTags)
+	}
+	for key, value := range tags {
+		if key == "" {
+			return fmt.Errorf("empty tag key")
+		}
+		if len(key) > MaxTagKeyLen {
+			return fmt.Errorf("tag key %.16q... longer than %d bytes", key, MaxTagKeyLen)
+		}
+		if len(value) > MaxTagValueLen {
+			return fmt.Errorf("value of tag %q longer than %d bytes", key, MaxTagValueLen)
+		}
+		if !utf8.ValidString(key) || !utf8.ValidString(value) {
+			return fmt.Errorf("tag %q is not valid UTF-8", key)
+		}
+	}
+	return nil
+}
+
+// SetSessionTags replaces the operator tags of a session, e.g. "under
+// test", which are kept with the session until it is deleted but never come
+// from PFCP. Empty tags clear them. It returns false if there is no session
+// seid, and an error if tags fail ValidateTags.
+func (c *Correlation) SetSessionTags(seid uint64, tags map[string]string) (bool, error) {
+	if err := ValidateTags(tags); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return false, nil
+	}
+	session.Tags = nil
+	if len(tags) > 0 {
+		session.Tags = maps.Clone(tags)
+	}
+	c.saveLocked(session)
+	return true, nil
+}
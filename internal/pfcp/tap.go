@@ -0,0 +1,123 @@
+package pfcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// tapSnaplen matches the snapshot length used for live capture
+const tapSnaplen = 65535
+
+// pcapTap copies captured packets into a pcap file with their original
+// capture timestamps, so a mis-parsed message can be replayed offline with
+// NewSnifferFromFile. The file is rotated to "<path>.1" once it would grow
+// past maxSize bytes.
+type pcapTap struct {
+	path     string
+	maxSize  int64
+	linkType layers.LinkType
+
+	file *os.File
+	buf  *bufio.Writer
+	w    *pcapgo.Writer
+	size int64
+}
+
+func newPcapTap(path string, maxSize int64, linkType layers.LinkType) (*pcapTap, error) {
+	t := &pcapTap{
+		path:     path,
+		maxSize:  maxSize,
+		linkType: linkType,
+	}
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// open truncates the tap file and writes a fresh pcap header
+func (t *pcapTap) open() error {
+	file, err := os.Create(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to create tap file %s: %w", t.path, err)
+	}
+
+	t.file = file
+	t.buf = bufio.NewWriter(file)
+	t.w = pcapgo.NewWriter(t.buf)
+	if err := writePcapHeader(t.buf, t.linkType); err != nil {
+		file.Close()
+		t.file = nil
+		return fmt.Errorf("failed to write pcap header to %s: %w", t.path, err)
+	}
+	t.size = 24 // pcap global header
+	return nil
+}
+
+// writePcapHeader writes the pcap global header. pcapgo's WriteFileHeader
+// takes the 8-bit link type of the handle, which cannot hold SLL2.
+func writePcapHeader(w io.Writer, linkType layers.LinkType) error {
+	var header [24]byte
+	binary.LittleEndian.PutUint32(header[0:4], 0xa1b2c3d4) // microsecond timestamps
+	binary.LittleEndian.PutUint16(header[4:6], 2)          // version 2.4
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], tapSnaplen)
+	binary.LittleEndian.PutUint32(header[20:24], pcapFileLinkType(linkType))
+	_, err := w.Write(header[:])
+	return err
+}
+
+func (t *pcapTap) write(packet gopacket.Packet) {
+	if t.file == nil {
+		return
+	}
+
+	ci := packet.Metadata().CaptureInfo
+	recordSize := int64(16 + len(packet.Data())) // record header + data
+	if t.maxSize > 0 && t.size+recordSize > t.maxSize {
+		t.rotate()
+		if t.file == nil {
+			return
+		}
+	}
+
+	if err := t.w.WritePacket(ci, packet.Data()); err != nil {
+		log.Printf("[WARN] PFCP tap: write to %s failed: %v", t.path, err)
+		return
+	}
+	t.size += recordSize
+
+	// Flush per packet: PFCP rates are low and a crash should not lose
+	// the message that caused it
+	if err := t.buf.Flush(); err != nil {
+		log.Printf("[WARN] PFCP tap: flush %s failed: %v", t.path, err)
+	}
+}
+
+// rotate moves the current file to "<path>.1" and starts a new one
+func (t *pcapTap) rotate() {
+	t.close()
+	if err := os.Rename(t.path, t.path+".1"); err != nil {
+		log.Printf("[WARN] PFCP tap: rotate %s failed: %v", t.path, err)
+	}
+	if err := t.open(); err != nil {
+		log.Printf("[WARN] PFCP tap: %v", err)
+	}
+}
+
+func (t *pcapTap) close() {
+	if t.file == nil {
+		return
+	}
+	t.buf.Flush()
+	t.file.Close()
+	t.file = nil
+}
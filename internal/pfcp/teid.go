@@ -0,0 +1,41 @@
+package pfcp
+
+import (
+	"log"
+	"slices"
+)
+
+// mapTEIDLocked maps teid to seid, caller must hold c.mu.
+//
+// A TEID is only meaningful for one session at a time. If it is already
+// mapped to another live session (the UPF reused it, or a message was
+// missed), the newest session wins: the TEID is moved over and dropped from
+// the older session, and the collision is counted. Keeping the older
+// mapping instead would attribute the new session's traffic to a session
+// the UPF no longer uses the TEID for.
+func (c *Correlation) mapTEIDLocked(teid uint32, seid uint64) {
+	if teid == 0 {
+		return
+	}
+	if old, ok := c.store.GetByTEID(teid); ok && old.SEID != seid {
+		c.teidCollisions.Add(1)
+		log.Printf("[WARN] TEID 0x%x of SEID=0x%x reassigned to SEID=0x%x", teid, old.SEID, seid)
+		old.TEIDs = slices.DeleteFunc(old.TEIDs, func(t uint32) bool { return t == teid })
+		c.putLocked(old)
+	}
+	c.store.MapTEID(teid, seid)
+}
+
+// unmapTEIDsLocked removes the TEID mappings still owned by session,
+// caller must hold c.mu
+func (c *Correlation) unmapTEIDsLocked(session *Session) {
+	for _, teid := range session.TEIDs {
+		c.store.UnmapTEID(teid, session.SEID)
+	}
+}
+
+// TEIDCollisions returns the number of TEIDs that were moved from one
+// session to another (see mapTEIDLocked)
+func (c *Correlation) TEIDCollisions() uint64 {
+	return c.teidCollisions.Load()
+}
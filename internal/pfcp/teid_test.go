@@ -0,0 +1,56 @@
+package pfcp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTEIDCollision(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+
+	// The UPF hands TEID 0x10001 to a second UE while the first session
+	// still holds it (e.g. its deletion was not captured)
+	first := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+	second := establish(t, s, 2, 0x1002, 0x2002, "10.60.0.2", 0x10001)
+	if first == second {
+		t.Fatal("both UEs got the same session")
+	}
+
+	if got := c.TEIDCollisions(); got != 1 {
+		t.Errorf("%d TEID collisions, want 1", got)
+	}
+	owner, ok := c.GetSessionByTEID(0x10001)
+	if !ok || owner.SEID != second {
+		t.Fatalf("TEID 0x10001 maps to SEID 0x%x, want the newest session 0x%x", owner.SEID, second)
+	}
+	old, ok := c.GetSessionBySEID(first)
+	if !ok {
+		t.Fatal("older session removed on collision")
+	}
+	if slices.Contains(old.TEIDs, 0x10001) {
+		t.Error("older session still lists the reassigned TEID")
+	}
+
+	// Removing the older session leaves the TEID with the newer one
+	c.RemoveSession(first)
+	if owner, ok := c.GetSessionByTEID(0x10001); !ok || owner.SEID != second {
+		t.Error("removing the older session unmapped the TEID of the newer one")
+	}
+}
+
+func TestTEIDRemapSameSession(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	seid := establish(t, s, 1, 0x1001, 0x2001, "10.60.0.1", 0x10001)
+
+	// A Modification repeating the session's own TEID is no collision
+	s.processPacket(packet(t, testSMF, testUPF, modificationRequest(2, 0x2001, 1, 0x10001)))
+	if got := s.correlation.TEIDCollisions(); got != 0 {
+		t.Errorf("%d TEID collisions, want 0", got)
+	}
+	if owner, ok := s.correlation.GetSessionByTEID(0x10001); !ok || owner.SEID != seid {
+		t.Error("TEID no longer maps to its session")
+	}
+}
@@ -0,0 +1,74 @@
+package pfcp
+
+import "slices"
+
+// teidCounts is a number of TEIDs per direction
+type teidCounts struct {
+	uplink, downlink int
+}
+
+// teidDirections counts the TEIDs of a session by the direction of the
+// traffic they receive, taken from the Source Interface of the PDR matching
+// on them: Access (N3, from the gNB) is uplink, Core (N9, from the next
+// UPF) downlink. TEIDs without such a PDR, e.g. of sessions first seen in
+// a Modification, are not counted.
+func teidDirections(session *Session) teidCounts {
+	var counts teidCounts
+	seen := make(map[uint32]bool, len(session.PDRs))
+	for _, pdr := range session.PDRs {
+		// A TEID may be shared by several PDRs (one per QoS flow)
+		if pdr.TEID == 0 || seen[pdr.TEID] || !slices.Contains(session.TEIDs, pdr.TEID) {
+			continue
+		}
+		switch pdr.SourceInterface {
+		case sourceInterfaceNames[0]: // Access
+			counts.uplink++
+		case sourceInterfaceNames[1]: // Core
+			counts.downlink++
+		default:
+			continue
+		}
+		seen[pdr.TEID] = true
+	}
+	return counts
+}
+
+// putLocked stores a new or changed session and updates the TEID counts
+// and the creation order, caller must hold c.mu for writing. Correlation
+// adds sessions to its store only through putLocked, and removes them
+// through deleteLocked.
+func (c *Correlation) putLocked(session *Session) {
+	c.revision++
+	session.revision = c.revision
+	c.store.Add(session)
+	c.countTEIDsLocked(session.SEID, teidDirections(session))
+	c.creation.put(session)
+}
+
+// deleteLocked removes a session from the store, the TEID counts and the
+// creation order, caller must hold c.mu for writing
+func (c *Correlation) deleteLocked(seid uint64) {
+	c.store.Remove(seid)
+	c.countTEIDsLocked(seid, teidCounts{})
+	c.creation.remove(seid)
+}
+
+// countTEIDsLocked replaces the TEID counts of a session
+func (c *Correlation) countTEIDsLocked(seid uint64, counts teidCounts) {
+	old := c.sessionTEIDs[seid]
+	c.activeTEIDs.uplink += counts.uplink - old.uplink
+	c.activeTEIDs.downlink += counts.downlink - old.downlink
+	if counts == (teidCounts{}) {
+		delete(c.sessionTEIDs, seid)
+	} else {
+		c.sessionTEIDs[seid] = counts
+	}
+}
+
+// ActiveTEIDs returns the number of TEIDs of the current sessions receiving
+// uplink and downlink traffic (see teidDirections)
+func (c *Correlation) ActiveTEIDs() (uplink, downlink int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.activeTEIDs.uplink, c.activeTEIDs.downlink
+}
@@ -0,0 +1,171 @@
+package pfcp
+
+import (
+	"container/list"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// teidEvictionLogInterval rate limits the warning about TEIDs of live
+// sessions being evicted, a too low bound would otherwise log every mapping
+const teidEvictionLogInterval = time.Minute
+
+// teidIndex maps TEIDs to SEIDs, optionally bounded to max entries with
+// least recently used eviction. It is the TEID index of the in-memory
+// session store, guarded by Correlation.mu.
+//
+// Lookups happen under the read lock, so they cannot reorder the list.
+// Instead they mark the entry as used, and eviction gives marked entries a
+// second chance by moving them to the front (the CLOCK approximation of
+// LRU).
+type teidIndex struct {
+	max     int // 0 means unbounded
+	entries map[uint32]*list.Element
+	order   *list.List // of *teidEntry, most recently mapped first
+}
+
+type teidEntry struct {
+	teid uint32
+	seid uint64
+	used atomic.Bool // looked up since the entry was last moved to the front
+}
+
+func newTEIDIndex() *teidIndex {
+	return &teidIndex{
+		entries: make(map[uint32]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the SEID teid is mapped to. Safe under the read lock.
+func (t *teidIndex) get(teid uint32) (uint64, bool) {
+	elem, ok := t.entries[teid]
+	if !ok {
+		return 0, false
+	}
+	entry := elem.Value.(*teidEntry)
+	entry.used.Store(true)
+	return entry.seid, true
+}
+
+// peek returns the SEID teid is mapped to without marking the entry as
+// used. Safe under the read lock.
+func (t *teidIndex) peek(teid uint32) (uint64, bool) {
+	elem, ok := t.entries[teid]
+	if !ok {
+		return 0, false
+	}
+	return elem.Value.(*teidEntry).seid, true
+}
+
+// set maps teid to seid and makes it the most recently used entry
+func (t *teidIndex) set(teid uint32, seid uint64) {
+	if elem, ok := t.entries[teid]; ok {
+		entry := elem.Value.(*teidEntry)
+		entry.seid = seid
+		entry.used.Store(false)
+		t.order.MoveToFront(elem)
+		return
+	}
+	t.entries[teid] = t.order.PushFront(&teidEntry{teid: teid, seid: seid})
+}
+
+func (t *teidIndex) remove(teid uint32) {
+	if elem, ok := t.entries[teid]; ok {
+		t.order.Remove(elem)
+		delete(t.entries, teid)
+	}
+}
+
+func (t *teidIndex) len() int {
+	return len(t.entries)
+}
+
+// evict removes the least recently used entry, skipping entries looked up
+// since they were last moved. Returns false if the index is empty.
+func (t *teidIndex) evict() (*teidEntry, bool) {
+	for {
+		elem := t.order.Back()
+		if elem == nil {
+			return nil, false
+		}
+		entry := elem.Value.(*teidEntry)
+		if entry.used.Swap(false) {
+			t.order.MoveToFront(elem)
+			continue
+		}
+		t.order.Remove(elem)
+		delete(t.entries, entry.teid)
+		return entry, true
+	}
+}
+
+// SetTEIDLimit bounds the number of TEID mappings kept, evicting the least
+// recently used ones, so bearer churn cannot grow the TEID map without
+// limit even when sessions are not removed. max <= 0 disables the limit.
+func (c *Correlation) SetTEIDLimit(max int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store.SetTEIDLimit(max)
+}
+
+func (m *memoryStore) SetTEIDLimit(max int) {
+	m.teids.max = max
+	m.enforceTEIDLimit()
+}
+
+// enforceTEIDLimit evicts TEID mappings over the limit. Evicting the TEID
+// of a live session loses attribution of its traffic, which means the
+// limit is too low for the signaling load.
+func (m *memoryStore) enforceTEIDLimit() {
+	if m.teids.max <= 0 {
+		return
+	}
+	for m.teids.len() > m.teids.max {
+		entry, ok := m.teids.evict()
+		if !ok {
+			return
+		}
+		m.teidEvictions.Add(1)
+		if _, live := m.sessions[entry.seid]; !live {
+			continue
+		}
+		if now := time.Now(); now.Sub(m.teidEvictionLogged) >= teidEvictionLogInterval {
+			log.Printf("[WARN] TEID limit (%d) reached, evicted TEID 0x%x of live session SEID=0x%x",
+				m.teids.max, entry.teid, entry.seid)
+			m.teidEvictionLogged = now
+		}
+	}
+}
+
+func (m *memoryStore) TEIDEvictions() uint64 {
+	return m.teidEvictions.Load()
+}
+
+// TEIDMapSize returns the number of TEIDs currently mapped to sessions
+func (c *Correlation) TEIDMapSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.store.TEIDCount()
+}
+
+// UEIPByTEID returns the UE IP of the session teid is mapped to, nil if the
+// session has none. ok is false if teid is not mapped to a session; a TEID
+// evicted by the TEID limit is no longer known.
+func (c *Correlation) UEIPByTEID(teid uint32) (ueIP net.IP, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	session, ok := c.store.GetByTEID(teid)
+	if !ok {
+		return nil, false
+	}
+	return session.UEIP, true
+}
+
+// TEIDEvictions returns the number of TEID mappings evicted by the TEID
+// limit (see SetTEIDLimit)
+func (c *Correlation) TEIDEvictions() uint64 {
+	return c.store.TEIDEvictions()
+}
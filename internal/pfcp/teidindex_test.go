@@ -0,0 +1,121 @@
+package pfcp
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTEIDIndexEvict(t *testing.T) {
+	idx := newTEIDIndex()
+	for teid := uint32(1); teid <= 4; teid++ {
+		idx.set(teid, uint64(teid))
+	}
+	// 1 gets a second chance, peeking at 2 does not give it one
+	idx.get(1)
+	idx.peek(2)
+
+	var order []uint32
+	for {
+		entry, ok := idx.evict()
+		if !ok {
+			break
+		}
+		order = append(order, entry.teid)
+	}
+	if got := fmt.Sprint(order); got != "[2 3 4 1]" {
+		t.Errorf("evicted %s, want [2 3 4 1]", got)
+	}
+	if idx.len() != 0 {
+		t.Errorf("%d entries left", idx.len())
+	}
+}
+
+func TestTEIDIndexSetResetsUse(t *testing.T) {
+	idx := newTEIDIndex()
+	idx.set(1, 1)
+	idx.set(2, 2)
+	idx.get(1)
+	idx.set(1, 3) // remapped, the old use does not count
+
+	entry, _ := idx.evict()
+	if entry.teid != 2 {
+		t.Fatalf("evicted TEID %d, want 2", entry.teid)
+	}
+	if seid, ok := idx.peek(1); !ok || seid != 3 {
+		t.Errorf("TEID 1 -> %d, %v, want 3", seid, ok)
+	}
+}
+
+func TestTEIDLimit(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	s := newTestSniffer()
+	c := s.correlation
+	c.SetTEIDLimit(2)
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		establishAt(t, s, i, start.Add(time.Duration(i)*time.Second))
+		// Traffic keeps the first TEID mapped
+		c.GetSessionByTEID(0x10001)
+	}
+
+	if got := fmt.Sprint(liveTEIDs(c, 5)); got != "[1 5]" {
+		t.Errorf("TEIDs of sessions %s mapped, want [1 5]", got)
+	}
+	if n := c.TEIDEvictions(); n != 3 {
+		t.Errorf("TEIDEvictions() = %d, want 3", n)
+	}
+	if n := c.TEIDMapSize(); n != 2 {
+		t.Errorf("TEIDMapSize() = %d, want 2", n)
+	}
+	if n := strings.Count(buf.String(), "TEID limit (2) reached"); n != 1 {
+		t.Errorf("eviction of live sessions' TEIDs logged %d times, want once per %s", n, teidEvictionLogInterval)
+	}
+}
+
+func TestTEIDLimitStaleMappingNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	m := newMemoryStore()
+	m.SetTEIDLimit(1)
+	m.MapTEID(1, 0xa) // no session 0xa
+	m.MapTEID(2, 0xb)
+
+	if n := m.TEIDEvictions(); n != 1 {
+		t.Errorf("TEIDEvictions() = %d, want 1", n)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("eviction of a TEID without a session logged: %s", buf.String())
+	}
+}
+
+func TestTEIDLookupsWithoutUse(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	c := s.correlation
+	c.SetTEIDLimit(2)
+	start := time.Now()
+	establishAt(t, s, 1, start)
+	establishAt(t, s, 2, start.Add(time.Second))
+
+	// Neither unmapping on behalf of another session nor dumping or
+	// counting the index is traffic for TEID 1
+	c.store.UnmapTEID(0x10001, 0xdead)
+	c.Dump(0)
+	c.TEIDStats(0)
+
+	establishAt(t, s, 3, start.Add(2*time.Second))
+	if got := fmt.Sprint(liveTEIDs(c, 3)); got != "[2 3]" {
+		t.Errorf("TEIDs of sessions %s mapped, want [2 3]", got)
+	}
+}
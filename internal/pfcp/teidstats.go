@@ -0,0 +1,83 @@
+package pfcp
+
+import "github.com/solar224/5G-DPOP/internal/model"
+
+// Histogram buckets of TEIDStats
+const (
+	DefaultTEIDStatsBuckets = 16
+	MaxTEIDStatsBuckets     = 256
+)
+
+// TEIDStats describes the values of the mapped TEIDs, to spot allocation
+// problems of a UPF's TEID pool: TEIDs crowding one end of the range, or
+// gaps where a pool should be
+type TEIDStats struct {
+	Count   int          `json:"count"`
+	Min     string       `json:"min,omitempty"`
+	Max     string       `json:"max,omitempty"`
+	Buckets []TEIDBucket `json:"buckets"` // Equal parts of Min..Max
+}
+
+// TEIDBucket is the number of mapped TEIDs from From to To (inclusive)
+type TEIDBucket struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// TEIDStats returns the statistics of the mapped TEIDs with their range
+// split into at most buckets buckets, DefaultTEIDStatsBuckets if buckets
+// <= 0 and MaxTEIDStatsBuckets at most. A range narrower than buckets
+// values gets one bucket per value.
+func (c *Correlation) TEIDStats(buckets int) TEIDStats {
+	if buckets <= 0 {
+		buckets = DefaultTEIDStatsBuckets
+	}
+	if buckets > MaxTEIDStatsBuckets {
+		buckets = MaxTEIDStatsBuckets
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Only the TEIDs the index maps to the session: one a later session
+	// took over, or evicted by the TEID limit, is not counted
+	teids := make([]uint32, 0, c.store.TEIDCount())
+	for _, session := range c.store.All() {
+		for _, teid := range session.TEIDs {
+			if owner, ok := c.store.TEIDOwner(teid); ok && owner == session.SEID {
+				teids = append(teids, teid)
+			}
+		}
+	}
+
+	stats := TEIDStats{Count: len(teids), Buckets: []TEIDBucket{}}
+	if len(teids) == 0 {
+		return stats
+	}
+	lo, hi := teids[0], teids[0]
+	for _, teid := range teids {
+		lo = min(lo, teid)
+		hi = max(hi, teid)
+	}
+	stats.Min = model.FormatTEID(lo)
+	stats.Max = model.FormatTEID(hi)
+
+	// Computed in 64 bits, the full range has 2^32 values
+	span := uint64(hi) - uint64(lo) + 1
+	width := (span + uint64(buckets) - 1) / uint64(buckets)
+	counts := make([]int, (span+width-1)/width)
+	for _, teid := range teids {
+		counts[(uint64(teid)-uint64(lo))/width]++
+	}
+	for i, count := range counts {
+		from := uint64(lo) + uint64(i)*width
+		to := min(from+width-1, uint64(hi))
+		stats.Buckets = append(stats.Buckets, TEIDBucket{
+			From:  model.FormatTEID(uint32(from)),
+			To:    model.FormatTEID(uint32(to)),
+			Count: count,
+		})
+	}
+	return stats
+}
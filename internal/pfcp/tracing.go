@@ -0,0 +1,75 @@
+package pfcp
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/solar224/5G-DPOP/internal/model"
+)
+
+var tracer = otel.Tracer("github.com/solar224/5G-DPOP/internal/pfcp")
+
+// traceSession records a session lifecycle event on the session's span.
+// A span is started when the session is created and ended when it is
+// deleted, so a trace covers the whole lifetime of the PDU session.
+// Caller must hold c.mu.
+func (c *Correlation) traceSession(eventType string, session *Session) {
+	switch eventType {
+	case SessionEventCreated:
+		// The attributes are only built for spans that are recorded, this
+		// runs under the lock for every session
+		_, session.span = tracer.Start(context.Background(), "pfcp.session",
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithTimestamp(session.CreatedAt),
+		)
+		if session.span.IsRecording() {
+			session.span.SetAttributes(sessionAttributes(session)...)
+		}
+	case SessionEventUpdated:
+		if session.span != nil && session.span.IsRecording() {
+			session.span.AddEvent("pfcp.session.modified", trace.WithAttributes(sessionAttributes(session)...))
+		}
+	case SessionEventDeleted, SessionEventPeerRestart, SessionEventEvicted, SessionEventSetDeletion:
+		if session.span != nil {
+			session.span.AddEvent("pfcp.session." + eventType)
+			session.span.End()
+			session.span = nil
+		}
+	}
+}
+
+func sessionAttributes(session *Session) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("pfcp.seid", model.FormatSEID(session.SEID)),
+	}
+	if session.UEIP != nil {
+		attrs = append(attrs, attribute.String("pfcp.ue_ip", session.UEIP.String()))
+	}
+	if session.DNN != "" {
+		attrs = append(attrs, attribute.String("pfcp.dnn", session.DNN))
+	}
+	teids := make([]string, 0, len(session.TEIDs))
+	for _, teid := range session.TEIDs {
+		teids = append(teids, fmt.Sprintf("0x%x", teid))
+	}
+	attrs = append(attrs, attribute.StringSlice("pfcp.teids", teids))
+	return attrs
+}
+
+// SpanContextByTEID returns the trace context of the session owning teid,
+// so events seen on the data plane can be attached to the session's trace
+func (c *Correlation) SpanContextByTEID(teid uint32) (trace.SpanContext, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	session, ok := c.store.GetByTEID(teid)
+	if !ok || session.span == nil {
+		return trace.SpanContext{}, false
+	}
+	sc := session.span.SpanContext()
+	return sc, sc.IsValid()
+}
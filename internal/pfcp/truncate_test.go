@@ -0,0 +1,67 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestProcessPacketTruncated(t *testing.T) {
+	quietLog(t)
+	full := establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001)
+
+	// Snaplen cut the message after its header and part of the F-SEID
+	cut := full[:20]
+	// The length field claims far more than any capture holds
+	lie := append([]byte(nil), full...)
+	binary.BigEndian.PutUint16(lie[2:4], 0xffff)
+
+	for name, payload := range map[string][]byte{"cut": cut, "lie": lie} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestSniffer()
+			s.processPacket(packet(t, testSMF, testUPF, payload))
+
+			if n := s.TruncatedMessages(); n != 1 {
+				t.Errorf("TruncatedMessages() = %d, want 1", n)
+			}
+			if n := s.correlation.SessionCount(); n != 0 {
+				t.Errorf("%d sessions from a truncated message, want 0", n)
+			}
+			if n := s.parseErrors.Load(); n != 0 {
+				t.Errorf("%d parse errors, truncation is counted on its own", n)
+			}
+		})
+	}
+}
+
+func TestProcessPacketAfterTruncated(t *testing.T) {
+	quietLog(t)
+	s := newTestSniffer()
+	full := establishmentRequest(1, 0x1001, testSMF, "10.60.0.1", 0x10001)
+
+	s.processPacket(packet(t, testSMF, testUPF, full[:len(full)-1]))
+	// The retransmission arrives whole
+	s.processPacket(packet(t, testSMF, testUPF, full))
+
+	if n := s.TruncatedMessages(); n != 1 {
+		t.Errorf("TruncatedMessages() = %d, want 1", n)
+	}
+	if _, ok := s.correlation.GetSessionByTEID(0x10001); !ok {
+		t.Error("session of the whole message not created")
+	}
+}
+
+func TestParseHeaderTruncated(t *testing.T) {
+	full := heartbeatRequest(1)
+	h, err := parseHeader(full)
+	if err != nil || h.truncated || h.ieEnd != len(full) {
+		t.Fatalf("whole message: truncated %v, ieEnd %d, err %v", h.truncated, h.ieEnd, err)
+	}
+
+	h, err = parseHeader(full[:len(full)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.truncated || h.ieEnd != len(full)-2 {
+		t.Errorf("cut message: truncated %v, ieEnd %d, want true and %d", h.truncated, h.ieEnd, len(full)-2)
+	}
+}
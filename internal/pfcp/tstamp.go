@@ -0,0 +1,74 @@
+package pfcp
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// SetTimestampSource selects the libpcap timestamp type for live capture,
+// e.g. "host", "host_hiprec" or "adapter" (see pcap-tstamp(7)). If the
+// interface does not support it the default source is used. Empty keeps
+// the default. Must be called before Start.
+func (s *Sniffer) SetTimestampSource(source string) {
+	s.tstampSource = source
+}
+
+// openLive opens the capture interface. Timestamps are requested with
+// nanosecond precision; libpcap falls back to microseconds where the
+// platform cannot provide them.
+func (s *Sniffer) openLive() (*pcap.Handle, error) {
+	inactive, err := pcap.NewInactiveHandle(s.iface)
+	if err != nil {
+		return nil, err
+	}
+	defer inactive.CleanUp()
+
+	if err := inactive.SetSnapLen(65535); err != nil {
+		return nil, err
+	}
+	if err := inactive.SetPromisc(true); err != nil {
+		return nil, err
+	}
+	if err := inactive.SetTimeout(pcap.BlockForever); err != nil {
+		return nil, err
+	}
+
+	if s.tstampSource != "" {
+		if err := s.setTimestampSource(inactive); err != nil {
+			log.Printf("[WARN] PFCP: %v, using the default timestamp source", err)
+		}
+	}
+
+	handle, err := inactive.Activate()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("PFCP capture timestamp resolution: %v", handle.Resolution())
+	return handle, nil
+}
+
+func (s *Sniffer) setTimestampSource(inactive *pcap.InactiveHandle) error {
+	source, err := pcap.TimestampSourceFromString(s.tstampSource)
+	if err != nil {
+		return fmt.Errorf("unknown timestamp source %q: %w", s.tstampSource, err)
+	}
+
+	supported := false
+	for _, t := range inactive.SupportedTimestamps() {
+		if t == source {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("timestamp source %s not supported by %s", source, s.iface)
+	}
+
+	if err := inactive.SetTimestampSource(source); err != nil {
+		return fmt.Errorf("failed to set timestamp source %s: %w", source, err)
+	}
+	log.Printf("PFCP capture timestamp source: %s", source)
+	return nil
+}
@@ -0,0 +1,151 @@
+package pfcp
+
+import (
+	"net"
+	"net/netip"
+	"time"
+)
+
+// UE IP Address IE flags (TS 29.244 8.2.62)
+const (
+	ueIPFlagV6   = 0x01
+	ueIPFlagV4   = 0x02
+	ueIPFlagCHV4 = 0x10 // Choose IPv4: not assigned yet
+	ueIPFlagCHV6 = 0x20 // Choose IPv6: not assigned yet
+)
+
+// extractUEIPs extracts all UE IP addresses from PFCP IEs (including nested
+// IEs in PDIs). A dual-stack session has both an IPv4 and an IPv6 address,
+// either in one IE or spread over several PDRs. IPv4 addresses come first.
+func (s *Sniffer) extractUEIPs(vlog msgLog, ieData []byte) []net.IP {
+	var v4, v6 []net.IP
+	seen := make(map[netip.Addr]bool)
+
+	add := func(list *[]net.IP, raw []byte, flags uint8) {
+		ip := net.IP(make([]byte, len(raw)))
+		copy(ip, raw)
+		if ip.IsUnspecified() || seen[ipToAddr(ip)] {
+			return
+		}
+		seen[ipToAddr(ip)] = true
+		*list = append(*list, ip)
+		vlog.Printf("   └─ Found UE IP: %s (flags=0x%02x)", ip, flags)
+	}
+
+	s.parseIEsRecursive(ieData, func(ieType uint16, ieValue []byte) {
+		// UE IP Address IE (Type 93)
+		if ieType != IETypeUEIPAddr || len(ieValue) < 1 {
+			return
+		}
+		flags := ieValue[0]
+		offset := 1
+
+		// IPv4 address, if present, precedes the IPv6 address
+		if flags&ueIPFlagV4 != 0 {
+			if flags&ueIPFlagCHV4 != 0 {
+				vlog.Printf("   └─ UE IP Address IE with CHV4 flag (IP not yet assigned)")
+			} else if len(ieValue) >= offset+4 {
+				add(&v4, ieValue[offset:offset+4], flags)
+			}
+			offset += 4
+		}
+		if flags&ueIPFlagV6 != 0 {
+			if flags&ueIPFlagCHV6 != 0 {
+				vlog.Printf("   └─ UE IP Address IE with CHV6 flag (IP not yet assigned)")
+			} else if len(ieValue) >= offset+16 {
+				add(&v6, ieValue[offset:offset+16], flags)
+			}
+		}
+	})
+
+	if len(v4) == 0 && len(v6) == 0 {
+		vlog.Printf("   └─ No valid UE IP found in PFCP message")
+	}
+
+	return append(v4, v6...)
+}
+
+// ueAddrs returns all UE addresses of a session: UEIP followed by any
+// other address in UEIPs
+func ueAddrs(session *Session) []netip.Addr {
+	addrs := make([]netip.Addr, 0, 1+len(session.UEIPs))
+	if session.UEIP != nil {
+		addrs = append(addrs, ipToAddr(session.UEIP))
+	}
+	for _, ip := range session.UEIPs {
+		addr := ipToAddr(ip)
+		if !containsAddr(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func containsAddr(addrs []netip.Addr, addr netip.Addr) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUEIPs adds the addresses in ips that session does not have yet
+func mergeUEIPs(session *Session, ips []net.IP) {
+	if session.UEIP == nil && len(ips) > 0 {
+		session.UEIP = ips[0]
+	}
+	known := ueAddrs(session)
+	if len(session.UEIPs) == 0 && session.UEIP != nil {
+		session.UEIPs = []net.IP{session.UEIP}
+	}
+	for _, ip := range ips {
+		if addr := ipToAddr(ip); !containsAddr(known, addr) {
+			session.UEIPs = append(session.UEIPs, ip)
+			known = append(known, addr)
+		}
+	}
+}
+
+// lookupUEAddrsLocked returns the SEID registered for any of addrs,
+// caller must hold c.mu
+func (c *Correlation) lookupUEAddrsLocked(addrs []netip.Addr) (netip.Addr, uint64, bool) {
+	for _, addr := range addrs {
+		if seid, ok := c.ueIPMap[addr]; ok {
+			return addr, seid, true
+		}
+	}
+	return netip.Addr{}, 0, false
+}
+
+// indexUEAddrsLocked registers the UE addresses of session that are not
+// yet owned by another session, recording ts, the capture time of the
+// message that brought them. Caller must hold c.mu.
+func (c *Correlation) indexUEAddrsLocked(session *Session, ts time.Time) {
+	for _, addr := range ueAddrs(session) {
+		if _, taken := c.ueIPMap[addr]; !taken {
+			c.ueIPMap[addr] = session.SEID
+			c.sessionCreationTime[addr] = ts
+		}
+	}
+}
+
+// captureTime returns ts, the capture time of a session's message, or the
+// current time for sessions not from a capture (e.g. injected ones)
+func captureTime(ts time.Time) time.Time {
+	if ts.IsZero() {
+		return time.Now()
+	}
+	return ts
+}
+
+// unindexUEAddrsLocked removes the UE addresses owned by session, caller
+// must hold c.mu
+func (c *Correlation) unindexUEAddrsLocked(session *Session) {
+	for _, addr := range ueAddrs(session) {
+		if c.ueIPMap[addr] == session.SEID {
+			delete(c.ueIPMap, addr)
+			delete(c.sessionCreationTime, addr)
+		}
+	}
+}
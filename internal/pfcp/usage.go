@@ -0,0 +1,186 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// IE types of Usage Reports (TS 29.244 8.1.2)
+const (
+	IETypeVolumeMeasurement        = 66 // Volume Measurement (within a Usage Report)
+	IETypeUsageReportModification  = 78 // Usage Report (Session Modification Response)
+	IETypeUsageReportDeletion      = 79 // Usage Report (Session Deletion Response)
+	IETypeUsageReportSessionReport = 80 // Usage Report (Session Report Request)
+	IETypeURRID                    = 81 // URR ID
+)
+
+// Volume Measurement flags telling which volumes follow, in this order
+// (TS 29.244 8.2.40)
+const (
+	volumeFlagTotal    = 0x01 // TOVOL
+	volumeFlagUplink   = 0x02 // ULVOL
+	volumeFlagDownlink = 0x04 // DLVOL
+)
+
+// VolumeMeasurement is the Volume Measurement IE of one Usage Report. The
+// Has fields tell which volumes the UPF included.
+type VolumeMeasurement struct {
+	TotalBytes    uint64
+	UplinkBytes   uint64
+	DownlinkBytes uint64
+
+	HasTotal    bool
+	HasUplink   bool
+	HasDownlink bool
+}
+
+// ReportedUsage is the traffic volume the UPF reported for a session in its
+// Usage Reports, summed over all reports. It is the UPF's own accounting,
+// to cross-check against the volumes measured by eBPF.
+type ReportedUsage struct {
+	TotalBytes    uint64
+	UplinkBytes   uint64
+	DownlinkBytes uint64
+	Reports       uint64
+	LastReport    time.Time
+}
+
+// add sums a report into the usage
+func (u *ReportedUsage) add(vol VolumeMeasurement, ts time.Time) {
+	u.TotalBytes += vol.TotalBytes
+	u.UplinkBytes += vol.UplinkBytes
+	u.DownlinkBytes += vol.DownlinkBytes
+	u.Reports++
+	u.LastReport = ts
+}
+
+// usageCounters are the volumes of all Usage Reports seen by the sniffer,
+// whether or not their session is known
+type usageCounters struct {
+	reports       atomic.Uint64
+	totalBytes    atomic.Uint64
+	uplinkBytes   atomic.Uint64
+	downlinkBytes atomic.Uint64
+}
+
+// parseVolumeMeasurement reads a Volume Measurement IE: a flags octet,
+// then an 8 octet counter for each of total, uplink and downlink volume
+// whose flag is set. ok is false if a flagged counter is missing.
+func parseVolumeMeasurement(data []byte) (vol VolumeMeasurement, ok bool) {
+	if len(data) < 1 {
+		return vol, false
+	}
+	flags := data[0]
+	offset := 1
+	next := func() (uint64, bool) {
+		if offset+8 > len(data) {
+			return 0, false
+		}
+		v := binary.BigEndian.Uint64(data[offset : offset+8])
+		offset += 8
+		return v, true
+	}
+
+	if flags&volumeFlagTotal != 0 {
+		if vol.TotalBytes, ok = next(); !ok {
+			return vol, false
+		}
+		vol.HasTotal = true
+	}
+	if flags&volumeFlagUplink != 0 {
+		if vol.UplinkBytes, ok = next(); !ok {
+			return vol, false
+		}
+		vol.HasUplink = true
+	}
+	if flags&volumeFlagDownlink != 0 {
+		if vol.DownlinkBytes, ok = next(); !ok {
+			return vol, false
+		}
+		vol.HasDownlink = true
+	}
+	return vol, true
+}
+
+// extractUsageReports returns the Volume Measurements of the Usage Report
+// IEs of type reportType in a message. Reports without a (valid) Volume
+// Measurement, e.g. time-only reports, are skipped. A report with uplink
+// and downlink but no total volume gets their sum as total.
+func extractUsageReports(ieData []byte, reportType uint16) []VolumeMeasurement {
+	var volumes []VolumeMeasurement
+	walkIEs(ieData, func(ieType uint16, ieValue []byte) {
+		if ieType != reportType {
+			return
+		}
+		walkIEs(ieValue, func(ieType uint16, ieValue []byte) {
+			if ieType != IETypeVolumeMeasurement {
+				return
+			}
+			vol, ok := parseVolumeMeasurement(ieValue)
+			if !ok {
+				log.Printf("[PFCP-WARN] Truncated Volume Measurement (%d bytes)", len(ieValue))
+				return
+			}
+			if !vol.HasTotal && vol.HasUplink && vol.HasDownlink {
+				vol.TotalBytes = vol.UplinkBytes + vol.DownlinkBytes
+			}
+			volumes = append(volumes, vol)
+		})
+	})
+	return volumes
+}
+
+// handleUsageReports counts the Usage Reports of type reportType in a
+// message sent by the UPF and adds them to the session whose SMF-side SEID
+// is cpSEID, the SEID in the header of messages towards the SMF
+func (s *Sniffer) handleUsageReports(vlog msgLog, cpSEID uint64, ieData []byte, reportType uint16, ts time.Time) {
+	volumes := extractUsageReports(ieData, reportType)
+	if len(volumes) == 0 {
+		return
+	}
+	for _, vol := range volumes {
+		s.usage.reports.Add(1)
+		s.usage.totalBytes.Add(vol.TotalBytes)
+		s.usage.uplinkBytes.Add(vol.UplinkBytes)
+		s.usage.downlinkBytes.Add(vol.DownlinkBytes)
+	}
+
+	if seid, ok := s.correlation.AddUsageReports(cpSEID, volumes, ts); ok {
+		vlog.Printf("   └─ %d usage report(s) for SEID=0x%x", len(volumes), seid)
+	} else {
+		vlog.Printf("   └─ %d usage report(s) for unknown CP SEID 0x%x", len(volumes), cpSEID)
+	}
+}
+
+// UsageReports returns the number of Usage Reports with a Volume
+// Measurement seen, and the total, uplink and downlink bytes they reported
+func (s *Sniffer) UsageReports() (reports, totalBytes, uplinkBytes, downlinkBytes uint64) {
+	return s.usage.reports.Load(), s.usage.totalBytes.Load(),
+		s.usage.uplinkBytes.Load(), s.usage.downlinkBytes.Load()
+}
+
+// AddUsageReports adds UPF-reported volumes to the session whose SMF-side
+// SEID is cpSEID. Returns the internal SEID of that session.
+func (c *Correlation) AddUsageReports(cpSEID uint64, volumes []VolumeMeasurement, ts time.Time) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seid, ok := c.cpSEIDMap[cpSEID]
+	if !ok {
+		return 0, false
+	}
+	session, ok := c.store.GetBySEID(seid)
+	if !ok {
+		return 0, false
+	}
+	if session.ReportedUsage == nil {
+		session.ReportedUsage = &ReportedUsage{}
+	}
+	for _, vol := range volumes {
+		session.ReportedUsage.add(vol, ts)
+	}
+	c.saveLocked(session)
+	return seid, true
+}
@@ -0,0 +1,65 @@
+package pfcp
+
+import "slices"
+
+// Version returns a counter that is incremented by every change to the
+// session data: sessions added, modified or removed, SEID bindings, peers
+// and traffic counters. It only increases while the process runs and starts
+// over when the agent restarts.
+func (c *Correlation) Version() uint64 {
+	return c.version.Load()
+}
+
+// Subscribe returns a channel that receives the new version after changes.
+// Notifications are coalesced: the channel holds at most one value, and a
+// change while it is full replaces the pending value with the newer
+// version, so a slow reader skips intermediate versions but always sees the
+// latest one. Sends never block the writer. Call Unsubscribe when done.
+func (c *Correlation) Subscribe() <-chan uint64 {
+	ch := make(chan uint64, 1)
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops notifications on a channel returned by Subscribe and
+// closes it
+func (c *Correlation) Unsubscribe(sub <-chan uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, ch := range c.subscribers {
+		if ch == sub {
+			c.subscribers = slices.Delete(c.subscribers, i, i+1)
+			close(ch)
+			return
+		}
+	}
+}
+
+// saveLocked has the store save a session changed in place and notifies
+// the subscribers, caller must hold c.mu for writing
+func (c *Correlation) saveLocked(session *Session) {
+	c.putLocked(session)
+	c.changedLocked()
+}
+
+// changedLocked bumps the version and notifies the subscribers. Caller
+// must hold c.mu for writing, which also serializes the notifications.
+func (c *Correlation) changedLocked() {
+	v := c.version.Add(1)
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- v:
+			continue
+		default:
+		}
+		// Full: replace the pending notification with the newer version
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- v
+	}
+}
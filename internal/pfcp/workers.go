@@ -0,0 +1,84 @@
+package pfcp
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// workerQueueSize bounds each worker's backlog. When a queue is full the
+// capture goroutine blocks, pushing back on the pcap buffer as before.
+const workerQueueSize = 1024
+
+// workerPool fans packets out to a fixed set of workers. Packets are
+// assigned by the pair of IP addresses they travel between, so that
+// messages for the same session are processed in order by the same worker.
+//
+// The header SEID would not do: the Establishment Request carries SEID 0
+// (the UPF has not assigned one yet), its Response the CP SEID and the
+// Modifications the UP SEID, so the Response could be processed before its
+// Request and a Modification before the Establishment. All of these travel
+// between the session's SMF and UPF, in either direction, as do the node
+// messages concerning it. The pool thus spreads the load across N4 peer
+// pairs; the messages of a single SMF and UPF all go to one worker.
+type workerPool struct {
+	queues []chan gopacket.Packet
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(n int, process func(gopacket.Packet)) *workerPool {
+	p := &workerPool{
+		queues: make([]chan gopacket.Packet, n),
+	}
+	for i := range p.queues {
+		q := make(chan gopacket.Packet, workerQueueSize)
+		p.queues[i] = q
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for packet := range q {
+				process(packet)
+			}
+		}()
+	}
+	return p
+}
+
+// dispatch queues packet on the worker owning its peer pair
+func (p *workerPool) dispatch(packet gopacket.Packet) {
+	p.queues[peerPairKey(packet)%uint64(len(p.queues))] <- packet
+}
+
+// stop drains the queues and waits for the workers to finish
+func (p *workerPool) stop() {
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.wg.Wait()
+}
+
+// peerPairKey hashes the source and destination IP addresses of packet,
+// independently of their order so that requests and responses get the same
+// key. Packets without an IP layer get 0.
+func peerPairKey(packet gopacket.Packet) uint64 {
+	var src, dst []byte
+	if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+		ip := ipLayer.(*layers.IPv4)
+		src, dst = ip.SrcIP, ip.DstIP
+	} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+		ip := ipLayer.(*layers.IPv6)
+		src, dst = ip.SrcIP, ip.DstIP
+	} else {
+		return 0
+	}
+	return addrHash(src) + addrHash(dst)
+}
+
+// addrHash is the 64-bit FNV-1a hash of an address
+func addrHash(addr []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(addr)
+	return h.Sum64()
+}
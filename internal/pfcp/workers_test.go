@@ -0,0 +1,95 @@
+package pfcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/gopacket"
+)
+
+// sessionBurst returns the Establishment Request, Response and a
+// Modification for each of n sessions spread over smfs SMFs, in the order
+// they would be captured
+func sessionBurst(tb testing.TB, n, smfs int) []gopacket.Packet {
+	tb.Helper()
+	packets := make([]gopacket.Packet, 0, 3*n)
+	for i := 0; i < n; i++ {
+		smf := fmt.Sprintf("10.100.%d.1", i%smfs)
+		seq := uint32(i)
+		cpSEID, upSEID := uint64(0x10000+i), uint64(0x20000+i)
+		ueIP := fmt.Sprintf("10.60.%d.%d", i/250, i%250+1)
+		packets = append(packets,
+			packet(tb, smf, testUPF, establishmentRequest(seq, cpSEID, smf, ueIP, uint32(0x100000+i))),
+			packet(tb, testUPF, smf, establishmentResponse(seq, cpSEID, upSEID)),
+			packet(tb, smf, testUPF, modificationRequest(seq+1<<16, upSEID, 2, uint32(0x200000+i))),
+		)
+	}
+	return packets
+}
+
+func TestWorkerPoolKeepsSessionOrder(t *testing.T) {
+	quietLog(t)
+	const sessions = 200
+	s := newTestSniffer()
+	pool := newWorkerPool(4, s.processPacket)
+	for _, p := range sessionBurst(t, sessions, 8) {
+		pool.dispatch(p)
+	}
+	pool.stop()
+
+	if n := s.correlation.SessionCount(); n != sessions {
+		t.Fatalf("%d sessions, want %d", n, sessions)
+	}
+	for i := 0; i < sessions; i++ {
+		byEstablishment, ok := s.correlation.GetSessionByTEID(uint32(0x100000 + i))
+		if !ok {
+			t.Fatalf("session %d: no session for the established TEID", i)
+		}
+		byModification, ok := s.correlation.GetSessionByTEID(uint32(0x200000 + i))
+		if !ok || byModification.SEID != byEstablishment.SEID {
+			t.Fatalf("session %d: TEID added by the Modification not on the established session", i)
+		}
+		if _, ok := s.correlation.GetSessionByPFCPSEID(uint64(0x20000 + i)); !ok {
+			t.Errorf("session %d: UP SEID not bound", i)
+		}
+	}
+}
+
+func TestPeerPairKey(t *testing.T) {
+	request := packet(t, testSMF, testUPF, message(MsgTypeHeartbeatRequest, 0, 1))
+	response := packet(t, testUPF, testSMF, message(MsgTypeHeartbeatResponse, 0, 1))
+	if peerPairKey(request) != peerPairKey(response) {
+		t.Error("request and response of a peer pair get different keys")
+	}
+	other := packet(t, "10.100.200.2", testUPF, message(MsgTypeHeartbeatRequest, 0, 1))
+	if peerPairKey(request) == peerPairKey(other) {
+		t.Error("two peer pairs get the same key")
+	}
+	v6 := packet(t, "2001:db8::1", "2001:db8::3", message(MsgTypeHeartbeatRequest, 0, 1))
+	if peerPairKey(v6) == 0 {
+		t.Error("IPv6 packet gets no key")
+	}
+}
+
+// BenchmarkWorkerPoolBurst processes a burst of session setups with
+// different numbers of workers, from a single SMF (one N4 peer pair, all on
+// one worker) and from 16
+func BenchmarkWorkerPoolBurst(b *testing.B) {
+	quietLog(b)
+	for _, smfs := range []int{1, 16} {
+		packets := sessionBurst(b, 2000, smfs)
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("smfs=%d/workers=%d", smfs, workers), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					s := newTestSniffer()
+					pool := newWorkerPool(workers, s.processPacket)
+					for _, p := range packets {
+						pool.dispatch(p)
+					}
+					pool.stop()
+				}
+				b.ReportMetric(float64(b.N*len(packets))/b.Elapsed().Seconds(), "packets/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,140 @@
+//go:build kafka
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+)
+
+// Kafka publishes events from one or more subscriptions to a Kafka topic.
+//
+// The writer runs in async mode: messages are batched in the background and
+// delivery errors are reported through KafkaConfig.OnError instead of
+// blocking the consumer, so an unreachable broker only costs dropped
+// messages, never a stalled agent.
+type Kafka struct {
+	writer  *kafka.Writer
+	onError func(n int, err error)
+
+	lastLogMu sync.Mutex
+	lastLog   time.Time
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewKafka creates a Kafka sink. No connection is made until the first
+// batch is flushed.
+func NewKafka(cfg KafkaConfig) (*Kafka, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("no kafka brokers configured")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("no kafka topic configured")
+	}
+
+	k := &Kafka{
+		onError:  cfg.OnError,
+		stopChan: make(chan struct{}),
+	}
+	k.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Topic:                  cfg.Topic,
+		Balancer:               &kafka.Hash{},
+		BatchTimeout:           100 * time.Millisecond,
+		WriteTimeout:           5 * time.Second,
+		RequiredAcks:           kafka.RequireOne,
+		Async:                  true,
+		AllowAutoTopicCreation: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				k.fail(len(messages), err)
+			}
+		},
+	}
+	return k, nil
+}
+
+// Start consumes every subscription on its own goroutine until Close
+func (k *Kafka) Start(subs ...*events.Subscription) {
+	for _, sub := range subs {
+		k.wg.Add(1)
+		go k.run(sub)
+	}
+}
+
+func (k *Kafka) run(sub *events.Subscription) {
+	defer k.wg.Done()
+	defer sub.Close()
+
+	for {
+		select {
+		case <-k.stopChan:
+			// Publish what was queued before Close, the writer flushes it
+			sub.Drain(k.publish)
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			k.publish(event)
+		}
+	}
+}
+
+func (k *Kafka) publish(event events.Event) {
+	value, err := json.Marshal(kafkaMessage{
+		Type:      event.Topic,
+		Timestamp: event.Timestamp,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		log.Printf("[WARN] Kafka sink: failed to encode event: %v", err)
+		return
+	}
+
+	msg := kafka.Message{Value: value}
+	if keyer, ok := event.Payload.(Keyer); ok {
+		msg.Key = []byte(keyer.PartitionKey())
+	}
+
+	// In async mode this only enqueues; delivery errors go to Completion
+	if err := k.writer.WriteMessages(context.Background(), msg); err != nil {
+		k.fail(1, err)
+	}
+}
+
+func (k *Kafka) fail(n int, err error) {
+	if k.onError != nil {
+		k.onError(n, err)
+	}
+
+	k.lastLogMu.Lock()
+	defer k.lastLogMu.Unlock()
+	if time.Since(k.lastLog) >= failureLogInterval {
+		k.lastLog = time.Now()
+		log.Printf("[WARN] Kafka sink: failed to publish %d message(s): %v", n, err)
+	}
+}
+
+// Close stops the consumers, publishes the events they had queued and
+// flushes pending messages
+func (k *Kafka) Close() {
+	k.stopOnce.Do(func() {
+		close(k.stopChan)
+	})
+	k.wg.Wait()
+	if err := k.writer.Close(); err != nil {
+		log.Printf("[WARN] Kafka sink: close failed: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"errors"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+)
+
+// failureLogInterval rate-limits delivery error logs while a sink's
+// endpoint is down
+const failureLogInterval = 30 * time.Second
+
+// ErrKafkaDisabled is returned by NewKafka when the binary was built
+// without the "kafka" build tag
+var ErrKafkaDisabled = errors.New("kafka sink not compiled in (build with -tags kafka)")
+
+// Keyer is implemented by event payloads that want a specific Kafka
+// message key, e.g. a TEID or UE IP for partition locality. Payloads that
+// don't implement it are published without a key.
+type Keyer interface {
+	PartitionKey() string
+}
+
+// KafkaConfig configures the Kafka producer sink
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+
+	// OnError is called with the number of messages that could not be
+	// delivered, e.g. to feed a failure counter. May be nil.
+	OnError func(n int, err error)
+}
+
+// kafkaMessage is the envelope written as the Kafka message value, so
+// consumers can tell drop and session events apart on a shared topic
+type kafkaMessage struct {
+	Type      events.Topic `json:"type"`
+	Timestamp time.Time    `json:"timestamp"`
+	Data      interface{}  `json:"data"`
+}
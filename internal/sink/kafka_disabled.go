@@ -0,0 +1,19 @@
+//go:build !kafka
+
+package sink
+
+import "github.com/solar224/5G-DPOP/internal/events"
+
+// Kafka is a placeholder used when the Kafka sink is not compiled in
+type Kafka struct{}
+
+// NewKafka always fails with ErrKafkaDisabled in this build
+func NewKafka(cfg KafkaConfig) (*Kafka, error) {
+	return nil, ErrKafkaDisabled
+}
+
+// Start is a no-op in this build
+func (k *Kafka) Start(subs ...*events.Subscription) {}
+
+// Close is a no-op in this build
+func (k *Kafka) Close() {}
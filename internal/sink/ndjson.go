@@ -0,0 +1,181 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/solar224/5G-DPOP/internal/events"
+)
+
+// flushInterval bounds how long an event may sit in the write buffer
+const flushInterval = 1 * time.Second
+
+// NDJSONFile appends every event payload received from a subscription to a
+// file as one JSON object per line. Writes happen on a dedicated goroutine
+// so disk I/O never blocks the producer; the subscription's buffer absorbs
+// bursts and drops on overflow.
+//
+// When the file grows past maxSize bytes it is renamed to "<path>.1"
+// (replacing any previous one) and a new file is started. Reopen closes and
+// reopens the file at the same path, for use with external logrotate.
+type NDJSONFile struct {
+	path    string
+	maxSize int64
+
+	file *os.File
+	w    *bufio.Writer
+	size int64
+
+	reopenChan chan struct{}
+	stopChan   chan struct{}
+	done       chan struct{}
+	stopOnce   sync.Once
+	started    bool
+}
+
+// NewNDJSONFile opens (or creates) path for appending. A maxSize <= 0
+// disables size-based rotation.
+func NewNDJSONFile(path string, maxSize int64) (*NDJSONFile, error) {
+	f := &NDJSONFile{
+		path:       path,
+		maxSize:    maxSize,
+		reopenChan: make(chan struct{}, 1),
+		stopChan:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *NDJSONFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", f.path, err)
+	}
+
+	f.file = file
+	f.w = bufio.NewWriter(file)
+	f.size = info.Size()
+	return nil
+}
+
+func (f *NDJSONFile) closeFile() {
+	if f.file == nil {
+		return
+	}
+	if err := f.w.Flush(); err != nil {
+		log.Printf("[WARN] NDJSON sink: flush %s failed: %v", f.path, err)
+	}
+	f.file.Close()
+	f.file = nil
+}
+
+// Start consumes sub on a background goroutine until Close is called
+func (f *NDJSONFile) Start(sub *events.Subscription) {
+	f.started = true
+	go f.run(sub)
+}
+
+func (f *NDJSONFile) run(sub *events.Subscription) {
+	defer close(f.done)
+	defer sub.Close()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stopChan:
+			sub.Drain(func(event events.Event) { f.write(event.Payload) })
+			f.closeFile()
+			return
+		case <-f.reopenChan:
+			f.closeFile()
+			if err := f.open(); err != nil {
+				log.Printf("[WARN] NDJSON sink: reopen failed: %v", err)
+			} else {
+				log.Printf("[INFO] NDJSON sink: reopened %s", f.path)
+			}
+		case <-ticker.C:
+			if f.file != nil {
+				f.w.Flush()
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				f.closeFile()
+				return
+			}
+			f.write(event.Payload)
+		}
+	}
+}
+
+func (f *NDJSONFile) write(payload interface{}) {
+	if f.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[WARN] NDJSON sink: failed to encode event: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	if f.maxSize > 0 && f.size+int64(len(line)) > f.maxSize && f.size > 0 {
+		f.rotate()
+		if f.file == nil {
+			return
+		}
+	}
+
+	n, err := f.w.Write(line)
+	f.size += int64(n)
+	if err != nil {
+		log.Printf("[WARN] NDJSON sink: write to %s failed: %v", f.path, err)
+	}
+}
+
+// rotate moves the current file to "<path>.1" and starts a new one
+func (f *NDJSONFile) rotate() {
+	f.closeFile()
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		log.Printf("[WARN] NDJSON sink: rotate %s failed: %v", f.path, err)
+	}
+	if err := f.open(); err != nil {
+		log.Printf("[WARN] NDJSON sink: %v", err)
+	}
+}
+
+// Reopen asks the writer goroutine to close and reopen the file (e.g. on SIGHUP)
+func (f *NDJSONFile) Reopen() {
+	select {
+	case f.reopenChan <- struct{}{}:
+	default: // a reopen is already pending
+	}
+}
+
+// Close writes the events still queued, flushes pending writes and closes
+// the file
+func (f *NDJSONFile) Close() {
+	f.stopOnce.Do(func() {
+		close(f.stopChan)
+	})
+	if !f.started {
+		f.closeFile()
+		return
+	}
+	<-f.done
+}
@@ -0,0 +1,354 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Remote-write defaults, used for zero RemoteWriteConfig fields
+const (
+	DefaultRemoteWriteInterval  = 15 * time.Second
+	DefaultRemoteWriteBatchSize = 2000
+	DefaultRemoteWriteTimeout   = 10 * time.Second
+
+	remoteWriteMinBackoff = 500 * time.Millisecond
+	remoteWriteMaxBackoff = 30 * time.Second
+)
+
+// RemoteWriteConfig configures the Prometheus remote-write sink
+type RemoteWriteConfig struct {
+	URL string
+
+	// Username and Password enable HTTP basic auth if Username is set
+	Username string
+	Password string
+
+	// Interval between metric snapshots
+	Interval time.Duration
+
+	// BatchSize is the maximum number of series per request
+	BatchSize int
+
+	// Timeout bounds each HTTP request
+	Timeout time.Duration
+
+	// Labels are added to every series, e.g. job and instance, which a
+	// scrape would otherwise attach
+	Labels map[string]string
+
+	// OnError is called with the number of samples that could not be
+	// delivered, e.g. to feed a failure counter. May be nil.
+	OnError func(n int, err error)
+}
+
+// RemoteWrite periodically snapshots a Prometheus gatherer and pushes the
+// samples to a remote-write endpoint, so the agent can be monitored where
+// nothing can scrape :9100.
+//
+// Failed batches are retried with exponential backoff on network errors,
+// 5xx and 429 responses until the next snapshot is due; other 4xx
+// responses are dropped since resending the same payload cannot succeed.
+// A snapshot never queues behind a previous one, so a long outage costs
+// gaps in the data, not memory.
+type RemoteWrite struct {
+	cfg      RemoteWriteConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+
+	lastLogMu sync.Mutex
+	lastLog   time.Time
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// remoteWriteSeries is one series of a snapshot, labels sorted by name
+type remoteWriteSeries struct {
+	labels []remoteWriteLabel
+	value  float64
+}
+
+type remoteWriteLabel struct {
+	name, value string
+}
+
+// errRemoteWriteRejected marks a response that must not be retried
+var errRemoteWriteRejected = errors.New("rejected by remote-write endpoint")
+
+// NewRemoteWrite creates a remote-write sink for the metrics of gatherer.
+// Nothing is sent until Start.
+func NewRemoteWrite(cfg RemoteWriteConfig, gatherer prometheus.Gatherer) (*RemoteWrite, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote-write URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid remote-write URL %q: scheme must be http or https", cfg.URL)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultRemoteWriteInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultRemoteWriteBatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRemoteWriteTimeout
+	}
+
+	return &RemoteWrite{
+		cfg:      cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// Start pushes a snapshot every interval until Close
+func (r *RemoteWrite) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+func (r *RemoteWrite) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			// Send the last values once; with stopChan closed there are
+			// no retries, each request is bounded by the timeout
+			r.push()
+			return
+		case <-ticker.C:
+			r.push()
+		}
+	}
+}
+
+// push gathers one snapshot and sends it in batches. Retries stop at the
+// next interval so the following snapshot supersedes a stale one.
+func (r *RemoteWrite) push() {
+	now := time.Now()
+	families, err := r.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		r.fail(0, fmt.Errorf("gather: %w", err))
+		return
+	}
+
+	series := r.snapshot(families)
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(r.cfg.Interval))
+	defer cancel()
+
+	timestamp := now.UnixMilli()
+	for start := 0; start < len(series); start += r.cfg.BatchSize {
+		end := start + r.cfg.BatchSize
+		if end > len(series) {
+			end = len(series)
+		}
+		batch := series[start:end]
+		body := snappy.Encode(nil, encodeWriteRequest(batch, timestamp))
+		if err := r.sendWithRetry(ctx, body); err != nil {
+			r.fail(len(batch), err)
+			if !errors.Is(err, errRemoteWriteRejected) {
+				// The endpoint is unreachable, later batches would fail too
+				r.fail(len(series)-end, err)
+				return
+			}
+		}
+	}
+}
+
+// sendWithRetry posts body, backing off exponentially on retryable errors
+// until ctx expires or Close is called
+func (r *RemoteWrite) sendWithRetry(ctx context.Context, body []byte) error {
+	backoff := remoteWriteMinBackoff
+	for {
+		err := r.send(ctx, body)
+		if err == nil || errors.Is(err, errRemoteWriteRejected) {
+			return err
+		}
+
+		select {
+		case <-r.stopChan:
+			return err
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > remoteWriteMaxBackoff {
+			backoff = remoteWriteMaxBackoff
+		}
+	}
+}
+
+func (r *RemoteWrite) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRemoteWriteRejected, err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "upf-agent")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if r.cfg.Username != "" {
+		req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return fmt.Errorf("remote-write endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	default:
+		return fmt.Errorf("%w: %s: %s", errRemoteWriteRejected, resp.Status, bytes.TrimSpace(msg))
+	}
+}
+
+// snapshot flattens metric families into series the way a scrape would:
+// histograms and summaries expand into _bucket/quantile, _sum and _count
+func (r *RemoteWrite) snapshot(families []*dto.MetricFamily) []remoteWriteSeries {
+	var series []remoteWriteSeries
+	add := func(name string, m *dto.Metric, value float64, extra ...remoteWriteLabel) {
+		labels := make([]remoteWriteLabel, 0, len(m.GetLabel())+len(r.cfg.Labels)+len(extra)+1)
+		labels = append(labels, remoteWriteLabel{"__name__", name})
+		seen := make(map[string]bool, len(m.GetLabel()))
+		for _, lp := range m.GetLabel() {
+			labels = append(labels, remoteWriteLabel{lp.GetName(), lp.GetValue()})
+			seen[lp.GetName()] = true
+		}
+		for name, value := range r.cfg.Labels {
+			if !seen[name] {
+				labels = append(labels, remoteWriteLabel{name, value})
+			}
+		}
+		labels = append(labels, extra...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+		series = append(series, remoteWriteSeries{labels: labels, value: value})
+	}
+
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				hasInf := false
+				for _, b := range h.GetBucket() {
+					hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", m, float64(b.GetCumulativeCount()),
+						remoteWriteLabel{"le", formatFloat(b.GetUpperBound())})
+				}
+				if !hasInf {
+					add(name+"_bucket", m, float64(h.GetSampleCount()), remoteWriteLabel{"le", "+Inf"})
+				}
+				add(name+"_sum", m, h.GetSampleSum())
+				add(name+"_count", m, float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, m, q.GetValue(),
+						remoteWriteLabel{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", m, s.GetSampleSum())
+				add(name+"_count", m, float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest protobuf:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteWriteSeries, timestamp int64) []byte {
+	var buf, ts, tmp []byte
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			tmp = tmp[:0]
+			tmp = protowire.AppendTag(tmp, 1, protowire.BytesType)
+			tmp = protowire.AppendString(tmp, l.name)
+			tmp = protowire.AppendTag(tmp, 2, protowire.BytesType)
+			tmp = protowire.AppendString(tmp, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, tmp)
+		}
+		tmp = tmp[:0]
+		tmp = protowire.AppendTag(tmp, 1, protowire.Fixed64Type)
+		tmp = protowire.AppendFixed64(tmp, math.Float64bits(s.value))
+		tmp = protowire.AppendTag(tmp, 2, protowire.VarintType)
+		tmp = protowire.AppendVarint(tmp, uint64(timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, tmp)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+	return buf
+}
+
+func (r *RemoteWrite) fail(n int, err error) {
+	if r.cfg.OnError != nil && n > 0 {
+		r.cfg.OnError(n, err)
+	}
+
+	r.lastLogMu.Lock()
+	defer r.lastLogMu.Unlock()
+	if time.Since(r.lastLog) >= failureLogInterval {
+		r.lastLog = time.Now()
+		log.Printf("[WARN] Remote-write sink: failed to push %d sample(s): %v", n, err)
+	}
+}
+
+// Close abandons any retry in progress and pushes a final snapshot, so the
+// values since the last interval are not lost on shutdown
+func (r *RemoteWrite) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+	r.wg.Wait()
+}
@@ -0,0 +1,1274 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: dpop/v1/dpop.proto
+
+package dpopv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamDropsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StreamDropsRequest) Reset() {
+	*x = StreamDropsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamDropsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamDropsRequest) ProtoMessage() {}
+
+func (x *StreamDropsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamDropsRequest.ProtoReflect.Descriptor instead.
+func (*StreamDropsRequest) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{0}
+}
+
+type StreamMetricsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StreamMetricsRequest) Reset() {
+	*x = StreamMetricsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMetricsRequest) ProtoMessage() {}
+
+func (x *StreamMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMetricsRequest.ProtoReflect.Descriptor instead.
+func (*StreamMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{1}
+}
+
+type StreamSessionsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StreamSessionsRequest) Reset() {
+	*x = StreamSessionsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamSessionsRequest) ProtoMessage() {}
+
+func (x *StreamSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamSessionsRequest.ProtoReflect.Descriptor instead.
+func (*StreamSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{2}
+}
+
+type GetSessionsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetSessionsRequest) Reset() {
+	*x = GetSessionsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionsRequest) ProtoMessage() {}
+
+func (x *GetSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionsRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{3}
+}
+
+type GetSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seid string `protobuf:"bytes,1,opt,name=seid,proto3" json:"seid,omitempty"`
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetSessionRequest) GetSeid() string {
+	if x != nil {
+		return x.Seid
+	}
+	return ""
+}
+
+// DirectionStats holds counters for a single traffic direction
+type DirectionStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Packets                uint64  `protobuf:"varint,1,opt,name=packets,proto3" json:"packets,omitempty"`
+	Bytes                  uint64  `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	ThroughputMbps         float64 `protobuf:"fixed64,3,opt,name=throughput_mbps,json=throughputMbps,proto3" json:"throughput_mbps,omitempty"`
+	LastUpdated            string  `protobuf:"bytes,4,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	ThroughputMbpsSmoothed float64 `protobuf:"fixed64,5,opt,name=throughput_mbps_smoothed,json=throughputMbpsSmoothed,proto3" json:"throughput_mbps_smoothed,omitempty"` // EWMA of throughput_mbps
+}
+
+func (x *DirectionStats) Reset() {
+	*x = DirectionStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DirectionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DirectionStats) ProtoMessage() {}
+
+func (x *DirectionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DirectionStats.ProtoReflect.Descriptor instead.
+func (*DirectionStats) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DirectionStats) GetPackets() uint64 {
+	if x != nil {
+		return x.Packets
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetBytes() uint64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetThroughputMbps() float64 {
+	if x != nil {
+		return x.ThroughputMbps
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetLastUpdated() string {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return ""
+}
+
+func (x *DirectionStats) GetThroughputMbpsSmoothed() float64 {
+	if x != nil {
+		return x.ThroughputMbpsSmoothed
+	}
+	return 0
+}
+
+// TrafficStats holds uplink and downlink traffic statistics
+type TrafficStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Uplink   *DirectionStats `protobuf:"bytes,1,opt,name=uplink,proto3" json:"uplink,omitempty"`
+	Downlink *DirectionStats `protobuf:"bytes,2,opt,name=downlink,proto3" json:"downlink,omitempty"`
+}
+
+func (x *TrafficStats) Reset() {
+	*x = TrafficStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TrafficStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrafficStats) ProtoMessage() {}
+
+func (x *TrafficStats) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrafficStats.ProtoReflect.Descriptor instead.
+func (*TrafficStats) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TrafficStats) GetUplink() *DirectionStats {
+	if x != nil {
+		return x.Uplink
+	}
+	return nil
+}
+
+func (x *TrafficStats) GetDownlink() *DirectionStats {
+	if x != nil {
+		return x.Downlink
+	}
+	return nil
+}
+
+// DropEvent is a single packet drop reported by the eBPF agent
+type DropEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp string `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Teid      string `protobuf:"bytes,2,opt,name=teid,proto3" json:"teid,omitempty"`
+	SrcIp     string `protobuf:"bytes,3,opt,name=src_ip,json=srcIp,proto3" json:"src_ip,omitempty"`
+	DstIp     string `protobuf:"bytes,4,opt,name=dst_ip,json=dstIp,proto3" json:"dst_ip,omitempty"`
+	SrcPort   uint32 `protobuf:"varint,5,opt,name=src_port,json=srcPort,proto3" json:"src_port,omitempty"`
+	DstPort   uint32 `protobuf:"varint,6,opt,name=dst_port,json=dstPort,proto3" json:"dst_port,omitempty"`
+	Reason    string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	Direction string `protobuf:"bytes,8,opt,name=direction,proto3" json:"direction,omitempty"`
+	PktLen    uint32 `protobuf:"varint,9,opt,name=pkt_len,json=pktLen,proto3" json:"pkt_len,omitempty"`
+}
+
+func (x *DropEvent) Reset() {
+	*x = DropEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DropEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropEvent) ProtoMessage() {}
+
+func (x *DropEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropEvent.ProtoReflect.Descriptor instead.
+func (*DropEvent) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DropEvent) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *DropEvent) GetTeid() string {
+	if x != nil {
+		return x.Teid
+	}
+	return ""
+}
+
+func (x *DropEvent) GetSrcIp() string {
+	if x != nil {
+		return x.SrcIp
+	}
+	return ""
+}
+
+func (x *DropEvent) GetDstIp() string {
+	if x != nil {
+		return x.DstIp
+	}
+	return ""
+}
+
+func (x *DropEvent) GetSrcPort() uint32 {
+	if x != nil {
+		return x.SrcPort
+	}
+	return 0
+}
+
+func (x *DropEvent) GetDstPort() uint32 {
+	if x != nil {
+		return x.DstPort
+	}
+	return 0
+}
+
+func (x *DropEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *DropEvent) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *DropEvent) GetPktLen() uint32 {
+	if x != nil {
+		return x.PktLen
+	}
+	return 0
+}
+
+// FlowTraffic is per-destination traffic for ULCL path differentiation
+type FlowTraffic struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DestIp     string `protobuf:"bytes,1,opt,name=dest_ip,json=destIp,proto3" json:"dest_ip,omitempty"`
+	Packets    uint64 `protobuf:"varint,2,opt,name=packets,proto3" json:"packets,omitempty"`
+	Bytes      uint64 `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	LastActive string `protobuf:"bytes,4,opt,name=last_active,json=lastActive,proto3" json:"last_active,omitempty"`
+	OuterDst   string `protobuf:"bytes,5,opt,name=outer_dst,json=outerDst,proto3" json:"outer_dst,omitempty"`
+}
+
+func (x *FlowTraffic) Reset() {
+	*x = FlowTraffic{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FlowTraffic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowTraffic) ProtoMessage() {}
+
+func (x *FlowTraffic) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowTraffic.ProtoReflect.Descriptor instead.
+func (*FlowTraffic) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FlowTraffic) GetDestIp() string {
+	if x != nil {
+		return x.DestIp
+	}
+	return ""
+}
+
+func (x *FlowTraffic) GetPackets() uint64 {
+	if x != nil {
+		return x.Packets
+	}
+	return 0
+}
+
+func (x *FlowTraffic) GetBytes() uint64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *FlowTraffic) GetLastActive() string {
+	if x != nil {
+		return x.LastActive
+	}
+	return ""
+}
+
+func (x *FlowTraffic) GetOuterDst() string {
+	if x != nil {
+		return x.OuterDst
+	}
+	return ""
+}
+
+// SessionInfo describes a PDU session correlated from PFCP signaling
+type SessionInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seid         string         `protobuf:"bytes,1,opt,name=seid,proto3" json:"seid,omitempty"`
+	UeIp         string         `protobuf:"bytes,2,opt,name=ue_ip,json=ueIp,proto3" json:"ue_ip,omitempty"`
+	Teids        []string       `protobuf:"bytes,3,rep,name=teids,proto3" json:"teids,omitempty"`
+	CreatedAt    string         `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	PacketsUl    uint64         `protobuf:"varint,5,opt,name=packets_ul,json=packetsUl,proto3" json:"packets_ul,omitempty"`
+	PacketsDl    uint64         `protobuf:"varint,6,opt,name=packets_dl,json=packetsDl,proto3" json:"packets_dl,omitempty"`
+	UpfIp        string         `protobuf:"bytes,7,opt,name=upf_ip,json=upfIp,proto3" json:"upf_ip,omitempty"`
+	GnbIp        string         `protobuf:"bytes,8,opt,name=gnb_ip,json=gnbIp,proto3" json:"gnb_ip,omitempty"`
+	UplinkPeerIp string         `protobuf:"bytes,9,opt,name=uplink_peer_ip,json=uplinkPeerIp,proto3" json:"uplink_peer_ip,omitempty"`
+	N9PeerIp     string         `protobuf:"bytes,10,opt,name=n9_peer_ip,json=n9PeerIp,proto3" json:"n9_peer_ip,omitempty"`
+	Supi         string         `protobuf:"bytes,11,opt,name=supi,proto3" json:"supi,omitempty"`
+	Dnn          string         `protobuf:"bytes,12,opt,name=dnn,proto3" json:"dnn,omitempty"`
+	SNssai       string         `protobuf:"bytes,13,opt,name=s_nssai,json=sNssai,proto3" json:"s_nssai,omitempty"`
+	Qfi          uint32         `protobuf:"varint,14,opt,name=qfi,proto3" json:"qfi,omitempty"`
+	SessionType  string         `protobuf:"bytes,15,opt,name=session_type,json=sessionType,proto3" json:"session_type,omitempty"`
+	PduSessionId uint32         `protobuf:"varint,16,opt,name=pdu_session_id,json=pduSessionId,proto3" json:"pdu_session_id,omitempty"`
+	BytesUl      uint64         `protobuf:"varint,17,opt,name=bytes_ul,json=bytesUl,proto3" json:"bytes_ul,omitempty"`
+	BytesDl      uint64         `protobuf:"varint,18,opt,name=bytes_dl,json=bytesDl,proto3" json:"bytes_dl,omitempty"`
+	FlowTraffic  []*FlowTraffic `protobuf:"bytes,19,rep,name=flow_traffic,json=flowTraffic,proto3" json:"flow_traffic,omitempty"`
+	Qos_5Qi      uint32         `protobuf:"varint,20,opt,name=qos_5qi,json=qos5qi,proto3" json:"qos_5qi,omitempty"`
+	ArpPriority  uint32         `protobuf:"varint,21,opt,name=arp_priority,json=arpPriority,proto3" json:"arp_priority,omitempty"`
+	GbrUlKbps    uint64         `protobuf:"varint,22,opt,name=gbr_ul_kbps,json=gbrUlKbps,proto3" json:"gbr_ul_kbps,omitempty"`
+	GbrDlKbps    uint64         `protobuf:"varint,23,opt,name=gbr_dl_kbps,json=gbrDlKbps,proto3" json:"gbr_dl_kbps,omitempty"`
+	MbrUlKbps    uint64         `protobuf:"varint,24,opt,name=mbr_ul_kbps,json=mbrUlKbps,proto3" json:"mbr_ul_kbps,omitempty"`
+	MbrDlKbps    uint64         `protobuf:"varint,25,opt,name=mbr_dl_kbps,json=mbrDlKbps,proto3" json:"mbr_dl_kbps,omitempty"`
+	Status       string         `protobuf:"bytes,26,opt,name=status,proto3" json:"status,omitempty"`
+	Duration     string         `protobuf:"bytes,27,opt,name=duration,proto3" json:"duration,omitempty"`
+	LastActive   string         `protobuf:"bytes,28,opt,name=last_active,json=lastActive,proto3" json:"last_active,omitempty"`
+	// Set for sessions first seen in a Modification Request (established
+	// before the agent started); establishment-only fields may be empty
+	IncompleteEstablishment bool `protobuf:"varint,29,opt,name=incomplete_establishment,json=incompleteEstablishment,proto3" json:"incomplete_establishment,omitempty"`
+	// Controlling SMF (sender of the session requests)
+	SmfIp string `protobuf:"bytes,30,opt,name=smf_ip,json=smfIp,proto3" json:"smf_ip,omitempty"`
+	// All UE IPs of the session (IPv4 and IPv6 for dual-stack sessions)
+	UeIps []string `protobuf:"bytes,31,rep,name=ue_ips,json=ueIps,proto3" json:"ue_ips,omitempty"`
+	// SDF filter flow descriptions and Application IDs from the PDRs
+	SdfFilters []string `protobuf:"bytes,32,rep,name=sdf_filters,json=sdfFilters,proto3" json:"sdf_filters,omitempty"`
+	AppIds     []string `protobuf:"bytes,33,rep,name=app_ids,json=appIds,proto3" json:"app_ids,omitempty"`
+}
+
+func (x *SessionInfo) Reset() {
+	*x = SessionInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionInfo) ProtoMessage() {}
+
+func (x *SessionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionInfo.ProtoReflect.Descriptor instead.
+func (*SessionInfo) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SessionInfo) GetSeid() string {
+	if x != nil {
+		return x.Seid
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetUeIp() string {
+	if x != nil {
+		return x.UeIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetTeids() []string {
+	if x != nil {
+		return x.Teids
+	}
+	return nil
+}
+
+func (x *SessionInfo) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetPacketsUl() uint64 {
+	if x != nil {
+		return x.PacketsUl
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetPacketsDl() uint64 {
+	if x != nil {
+		return x.PacketsDl
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetUpfIp() string {
+	if x != nil {
+		return x.UpfIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetGnbIp() string {
+	if x != nil {
+		return x.GnbIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetUplinkPeerIp() string {
+	if x != nil {
+		return x.UplinkPeerIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetN9PeerIp() string {
+	if x != nil {
+		return x.N9PeerIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetSupi() string {
+	if x != nil {
+		return x.Supi
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetDnn() string {
+	if x != nil {
+		return x.Dnn
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetSNssai() string {
+	if x != nil {
+		return x.SNssai
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetQfi() uint32 {
+	if x != nil {
+		return x.Qfi
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetSessionType() string {
+	if x != nil {
+		return x.SessionType
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetPduSessionId() uint32 {
+	if x != nil {
+		return x.PduSessionId
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetBytesUl() uint64 {
+	if x != nil {
+		return x.BytesUl
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetBytesDl() uint64 {
+	if x != nil {
+		return x.BytesDl
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetFlowTraffic() []*FlowTraffic {
+	if x != nil {
+		return x.FlowTraffic
+	}
+	return nil
+}
+
+func (x *SessionInfo) GetQos_5Qi() uint32 {
+	if x != nil {
+		return x.Qos_5Qi
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetArpPriority() uint32 {
+	if x != nil {
+		return x.ArpPriority
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetGbrUlKbps() uint64 {
+	if x != nil {
+		return x.GbrUlKbps
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetGbrDlKbps() uint64 {
+	if x != nil {
+		return x.GbrDlKbps
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetMbrUlKbps() uint64 {
+	if x != nil {
+		return x.MbrUlKbps
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetMbrDlKbps() uint64 {
+	if x != nil {
+		return x.MbrDlKbps
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetDuration() string {
+	if x != nil {
+		return x.Duration
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetLastActive() string {
+	if x != nil {
+		return x.LastActive
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetIncompleteEstablishment() bool {
+	if x != nil {
+		return x.IncompleteEstablishment
+	}
+	return false
+}
+
+func (x *SessionInfo) GetSmfIp() string {
+	if x != nil {
+		return x.SmfIp
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetUeIps() []string {
+	if x != nil {
+		return x.UeIps
+	}
+	return nil
+}
+
+func (x *SessionInfo) GetSdfFilters() []string {
+	if x != nil {
+		return x.SdfFilters
+	}
+	return nil
+}
+
+func (x *SessionInfo) GetAppIds() []string {
+	if x != nil {
+		return x.AppIds
+	}
+	return nil
+}
+
+// SessionList is a snapshot of all known sessions
+type SessionList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Total    uint32         `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Sessions []*SessionInfo `protobuf:"bytes,2,rep,name=sessions,proto3" json:"sessions,omitempty"`
+}
+
+func (x *SessionList) Reset() {
+	*x = SessionList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dpop_v1_dpop_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionList) ProtoMessage() {}
+
+func (x *SessionList) ProtoReflect() protoreflect.Message {
+	mi := &file_dpop_v1_dpop_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionList.ProtoReflect.Descriptor instead.
+func (*SessionList) Descriptor() ([]byte, []int) {
+	return file_dpop_v1_dpop_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SessionList) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *SessionList) GetSessions() []*SessionInfo {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+var File_dpop_v1_dpop_proto protoreflect.FileDescriptor
+
+var file_dpop_v1_dpop_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x64, 0x70, 0x6f, 0x70, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x22, 0x14, 0x0a,
+	0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x44, 0x72, 0x6f, 0x70, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x17, 0x0a, 0x15, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x14, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x27, 0x0a, 0x11, 0x47, 0x65,
+	0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x65, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73,
+	0x65, 0x69, 0x64, 0x22, 0xc6, 0x01, 0x0a, 0x0e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x68, 0x72, 0x6f, 0x75, 0x67,
+	0x68, 0x70, 0x75, 0x74, 0x5f, 0x6d, 0x62, 0x70, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0e, 0x74, 0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x70, 0x75, 0x74, 0x4d, 0x62, 0x70, 0x73, 0x12,
+	0x21, 0x0a, 0x0c, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6c, 0x61, 0x73, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x64, 0x12, 0x38, 0x0a, 0x18, 0x74, 0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x70, 0x75, 0x74,
+	0x5f, 0x6d, 0x62, 0x70, 0x73, 0x5f, 0x73, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x65, 0x64, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x16, 0x74, 0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x70, 0x75, 0x74,
+	0x4d, 0x62, 0x70, 0x73, 0x53, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x65, 0x64, 0x22, 0x74, 0x0a, 0x0c,
+	0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x2f, 0x0a, 0x06,
+	0x75, 0x70, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x64,
+	0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x06, 0x75, 0x70, 0x6c, 0x69, 0x6e, 0x6b, 0x12, 0x33, 0x0a,
+	0x08, 0x64, 0x6f, 0x77, 0x6e, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x08, 0x64, 0x6f, 0x77, 0x6e, 0x6c, 0x69,
+	0x6e, 0x6b, 0x22, 0xf0, 0x01, 0x0a, 0x09, 0x44, 0x72, 0x6f, 0x70, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x12,
+	0x0a, 0x04, 0x74, 0x65, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65,
+	0x69, 0x64, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x72, 0x63, 0x5f, 0x69, 0x70, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x73, 0x72, 0x63, 0x49, 0x70, 0x12, 0x15, 0x0a, 0x06, 0x64, 0x73, 0x74,
+	0x5f, 0x69, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x64, 0x73, 0x74, 0x49, 0x70,
+	0x12, 0x19, 0x0a, 0x08, 0x73, 0x72, 0x63, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x07, 0x73, 0x72, 0x63, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x64,
+	0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x64,
+	0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1c,
+	0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x17, 0x0a, 0x07,
+	0x70, 0x6b, 0x74, 0x5f, 0x6c, 0x65, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x70,
+	0x6b, 0x74, 0x4c, 0x65, 0x6e, 0x22, 0x94, 0x01, 0x0a, 0x0b, 0x46, 0x6c, 0x6f, 0x77, 0x54, 0x72,
+	0x61, 0x66, 0x66, 0x69, 0x63, 0x12, 0x17, 0x0a, 0x07, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x69, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x65, 0x73, 0x74, 0x49, 0x70, 0x12, 0x18,
+	0x0a, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x07, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1f,
+	0x0a, 0x0b, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x12,
+	0x1b, 0x0a, 0x09, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x5f, 0x64, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x44, 0x73, 0x74, 0x22, 0xd8, 0x07, 0x0a,
+	0x0b, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x65, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x65, 0x69, 0x64,
+	0x12, 0x13, 0x0a, 0x05, 0x75, 0x65, 0x5f, 0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x75, 0x65, 0x49, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x65, 0x69, 0x64, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x74, 0x65, 0x69, 0x64, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x63,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x73, 0x5f, 0x75, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09,
+	0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x55, 0x6c, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x73, 0x5f, 0x64, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x44, 0x6c, 0x12, 0x15, 0x0a, 0x06, 0x75, 0x70, 0x66, 0x5f,
+	0x69, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x75, 0x70, 0x66, 0x49, 0x70, 0x12,
+	0x15, 0x0a, 0x06, 0x67, 0x6e, 0x62, 0x5f, 0x69, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x67, 0x6e, 0x62, 0x49, 0x70, 0x12, 0x24, 0x0a, 0x0e, 0x75, 0x70, 0x6c, 0x69, 0x6e, 0x6b,
+	0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x69, 0x70, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
+	0x75, 0x70, 0x6c, 0x69, 0x6e, 0x6b, 0x50, 0x65, 0x65, 0x72, 0x49, 0x70, 0x12, 0x1c, 0x0a, 0x0a,
+	0x6e, 0x39, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x69, 0x70, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x6e, 0x39, 0x50, 0x65, 0x65, 0x72, 0x49, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x75,
+	0x70, 0x69, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x75, 0x70, 0x69, 0x12, 0x10,
+	0x0a, 0x03, 0x64, 0x6e, 0x6e, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6e, 0x6e,
+	0x12, 0x17, 0x0a, 0x07, 0x73, 0x5f, 0x6e, 0x73, 0x73, 0x61, 0x69, 0x18, 0x0d, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x4e, 0x73, 0x73, 0x61, 0x69, 0x12, 0x10, 0x0a, 0x03, 0x71, 0x66, 0x69,
+	0x18, 0x0e, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x71, 0x66, 0x69, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x24,
+	0x0a, 0x0e, 0x70, 0x64, 0x75, 0x5f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64,
+	0x18, 0x10, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x70, 0x64, 0x75, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x49, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x75, 0x6c,
+	0x18, 0x11, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x62, 0x79, 0x74, 0x65, 0x73, 0x55, 0x6c, 0x12,
+	0x19, 0x0a, 0x08, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x64, 0x6c, 0x18, 0x12, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x07, 0x62, 0x79, 0x74, 0x65, 0x73, 0x44, 0x6c, 0x12, 0x37, 0x0a, 0x0c, 0x66, 0x6c,
+	0x6f, 0x77, 0x5f, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x18, 0x13, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x77, 0x54,
+	0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x77, 0x54, 0x72, 0x61, 0x66,
+	0x66, 0x69, 0x63, 0x12, 0x17, 0x0a, 0x07, 0x71, 0x6f, 0x73, 0x5f, 0x35, 0x71, 0x69, 0x18, 0x14,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x71, 0x6f, 0x73, 0x35, 0x71, 0x69, 0x12, 0x21, 0x0a, 0x0c,
+	0x61, 0x72, 0x70, 0x5f, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x15, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0b, 0x61, 0x72, 0x70, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12,
+	0x1e, 0x0a, 0x0b, 0x67, 0x62, 0x72, 0x5f, 0x75, 0x6c, 0x5f, 0x6b, 0x62, 0x70, 0x73, 0x18, 0x16,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x67, 0x62, 0x72, 0x55, 0x6c, 0x4b, 0x62, 0x70, 0x73, 0x12,
+	0x1e, 0x0a, 0x0b, 0x67, 0x62, 0x72, 0x5f, 0x64, 0x6c, 0x5f, 0x6b, 0x62, 0x70, 0x73, 0x18, 0x17,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x67, 0x62, 0x72, 0x44, 0x6c, 0x4b, 0x62, 0x70, 0x73, 0x12,
+	0x1e, 0x0a, 0x0b, 0x6d, 0x62, 0x72, 0x5f, 0x75, 0x6c, 0x5f, 0x6b, 0x62, 0x70, 0x73, 0x18, 0x18,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x62, 0x72, 0x55, 0x6c, 0x4b, 0x62, 0x70, 0x73, 0x12,
+	0x1e, 0x0a, 0x0b, 0x6d, 0x62, 0x72, 0x5f, 0x64, 0x6c, 0x5f, 0x6b, 0x62, 0x70, 0x73, 0x18, 0x19,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x62, 0x72, 0x44, 0x6c, 0x4b, 0x62, 0x70, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x61, 0x63, 0x74, 0x69,
+	0x76, 0x65, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x41, 0x63,
+	0x74, 0x69, 0x76, 0x65, 0x12, 0x39, 0x0a, 0x18, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65,
+	0x74, 0x65, 0x5f, 0x65, 0x73, 0x74, 0x61, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x6d, 0x65, 0x6e, 0x74,
+	0x18, 0x1d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x17, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65,
+	0x74, 0x65, 0x45, 0x73, 0x74, 0x61, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x6d, 0x65, 0x6e, 0x74, 0x12,
+	0x15, 0x0a, 0x06, 0x73, 0x6d, 0x66, 0x5f, 0x69, 0x70, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x73, 0x6d, 0x66, 0x49, 0x70, 0x12, 0x15, 0x0a, 0x06, 0x75, 0x65, 0x5f, 0x69, 0x70, 0x73,
+	0x18, 0x1f, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x75, 0x65, 0x49, 0x70, 0x73, 0x12, 0x1f, 0x0a,
+	0x0b, 0x73, 0x64, 0x66, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x18, 0x20, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0a, 0x73, 0x64, 0x66, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x12, 0x17,
+	0x0a, 0x07, 0x61, 0x70, 0x70, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x21, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x06, 0x61, 0x70, 0x70, 0x49, 0x64, 0x73, 0x22, 0x55, 0x0a, 0x0b, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x30, 0x0a, 0x08,
+	0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x32, 0xed,
+	0x02, 0x0a, 0x14, 0x4f, 0x62, 0x73, 0x65, 0x72, 0x76, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x40, 0x0a, 0x0b, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x44, 0x72, 0x6f, 0x70, 0x73, 0x12, 0x1b, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x44, 0x72, 0x6f, 0x70, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72,
+	0x6f, 0x70, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x47, 0x0a, 0x0d, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1d, 0x2e, 0x64, 0x70, 0x6f,
+	0x70, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x64, 0x70, 0x6f, 0x70,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x30, 0x01, 0x12, 0x48, 0x0a, 0x0e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1e, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x30, 0x01, 0x12, 0x40, 0x0a, 0x0b,
+	0x47, 0x65, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1b, 0x2e, 0x64, 0x70,
+	0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e,
+	0x76, 0x31, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x3e,
+	0x0a, 0x0a, 0x47, 0x65, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x2e, 0x64,
+	0x70, 0x6f, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x64, 0x70, 0x6f, 0x70, 0x2e,
+	0x76, 0x31, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x32,
+	0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x6f, 0x6c,
+	0x61, 0x72, 0x32, 0x32, 0x34, 0x2f, 0x35, 0x47, 0x2d, 0x44, 0x50, 0x4f, 0x50, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x64, 0x70, 0x6f, 0x70, 0x2f, 0x76, 0x31, 0x3b, 0x64, 0x70, 0x6f, 0x70,
+	0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_dpop_v1_dpop_proto_rawDescOnce sync.Once
+	file_dpop_v1_dpop_proto_rawDescData = file_dpop_v1_dpop_proto_rawDesc
+)
+
+func file_dpop_v1_dpop_proto_rawDescGZIP() []byte {
+	file_dpop_v1_dpop_proto_rawDescOnce.Do(func() {
+		file_dpop_v1_dpop_proto_rawDescData = protoimpl.X.CompressGZIP(file_dpop_v1_dpop_proto_rawDescData)
+	})
+	return file_dpop_v1_dpop_proto_rawDescData
+}
+
+var file_dpop_v1_dpop_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_dpop_v1_dpop_proto_goTypes = []interface{}{
+	(*StreamDropsRequest)(nil),    // 0: dpop.v1.StreamDropsRequest
+	(*StreamMetricsRequest)(nil),  // 1: dpop.v1.StreamMetricsRequest
+	(*StreamSessionsRequest)(nil), // 2: dpop.v1.StreamSessionsRequest
+	(*GetSessionsRequest)(nil),    // 3: dpop.v1.GetSessionsRequest
+	(*GetSessionRequest)(nil),     // 4: dpop.v1.GetSessionRequest
+	(*DirectionStats)(nil),        // 5: dpop.v1.DirectionStats
+	(*TrafficStats)(nil),          // 6: dpop.v1.TrafficStats
+	(*DropEvent)(nil),             // 7: dpop.v1.DropEvent
+	(*FlowTraffic)(nil),           // 8: dpop.v1.FlowTraffic
+	(*SessionInfo)(nil),           // 9: dpop.v1.SessionInfo
+	(*SessionList)(nil),           // 10: dpop.v1.SessionList
+}
+var file_dpop_v1_dpop_proto_depIdxs = []int32{
+	5,  // 0: dpop.v1.TrafficStats.uplink:type_name -> dpop.v1.DirectionStats
+	5,  // 1: dpop.v1.TrafficStats.downlink:type_name -> dpop.v1.DirectionStats
+	8,  // 2: dpop.v1.SessionInfo.flow_traffic:type_name -> dpop.v1.FlowTraffic
+	9,  // 3: dpop.v1.SessionList.sessions:type_name -> dpop.v1.SessionInfo
+	0,  // 4: dpop.v1.ObservabilityService.StreamDrops:input_type -> dpop.v1.StreamDropsRequest
+	1,  // 5: dpop.v1.ObservabilityService.StreamMetrics:input_type -> dpop.v1.StreamMetricsRequest
+	2,  // 6: dpop.v1.ObservabilityService.StreamSessions:input_type -> dpop.v1.StreamSessionsRequest
+	3,  // 7: dpop.v1.ObservabilityService.GetSessions:input_type -> dpop.v1.GetSessionsRequest
+	4,  // 8: dpop.v1.ObservabilityService.GetSession:input_type -> dpop.v1.GetSessionRequest
+	7,  // 9: dpop.v1.ObservabilityService.StreamDrops:output_type -> dpop.v1.DropEvent
+	6,  // 10: dpop.v1.ObservabilityService.StreamMetrics:output_type -> dpop.v1.TrafficStats
+	10, // 11: dpop.v1.ObservabilityService.StreamSessions:output_type -> dpop.v1.SessionList
+	10, // 12: dpop.v1.ObservabilityService.GetSessions:output_type -> dpop.v1.SessionList
+	9,  // 13: dpop.v1.ObservabilityService.GetSession:output_type -> dpop.v1.SessionInfo
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_dpop_v1_dpop_proto_init() }
+func file_dpop_v1_dpop_proto_init() {
+	if File_dpop_v1_dpop_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_dpop_v1_dpop_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamDropsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamMetricsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamSessionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSessionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DirectionStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrafficStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DropEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FlowTraffic); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dpop_v1_dpop_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_dpop_v1_dpop_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dpop_v1_dpop_proto_goTypes,
+		DependencyIndexes: file_dpop_v1_dpop_proto_depIdxs,
+		MessageInfos:      file_dpop_v1_dpop_proto_msgTypes,
+	}.Build()
+	File_dpop_v1_dpop_proto = out.File
+	file_dpop_v1_dpop_proto_rawDesc = nil
+	file_dpop_v1_dpop_proto_goTypes = nil
+	file_dpop_v1_dpop_proto_depIdxs = nil
+}
@@ -0,0 +1,351 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: dpop/v1/dpop.proto
+
+package dpopv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ObservabilityService_StreamDrops_FullMethodName    = "/dpop.v1.ObservabilityService/StreamDrops"
+	ObservabilityService_StreamMetrics_FullMethodName  = "/dpop.v1.ObservabilityService/StreamMetrics"
+	ObservabilityService_StreamSessions_FullMethodName = "/dpop.v1.ObservabilityService/StreamSessions"
+	ObservabilityService_GetSessions_FullMethodName    = "/dpop.v1.ObservabilityService/GetSessions"
+	ObservabilityService_GetSession_FullMethodName     = "/dpop.v1.ObservabilityService/GetSession"
+)
+
+// ObservabilityServiceClient is the client API for ObservabilityService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ObservabilityServiceClient interface {
+	// StreamDrops sends every new packet drop event as it is collected
+	StreamDrops(ctx context.Context, in *StreamDropsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamDropsClient, error)
+	// StreamMetrics sends a traffic statistics snapshot once per collection
+	// interval
+	StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamMetricsClient, error)
+	// StreamSessions sends the full session list once per collection interval
+	StreamSessions(ctx context.Context, in *StreamSessionsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamSessionsClient, error)
+	// GetSessions returns all known PDU sessions
+	GetSessions(ctx context.Context, in *GetSessionsRequest, opts ...grpc.CallOption) (*SessionList, error)
+	// GetSession returns a single session by SEID (e.g. "0x1")
+	GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*SessionInfo, error)
+}
+
+type observabilityServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewObservabilityServiceClient(cc grpc.ClientConnInterface) ObservabilityServiceClient {
+	return &observabilityServiceClient{cc}
+}
+
+func (c *observabilityServiceClient) StreamDrops(ctx context.Context, in *StreamDropsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamDropsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObservabilityService_ServiceDesc.Streams[0], ObservabilityService_StreamDrops_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &observabilityServiceStreamDropsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObservabilityService_StreamDropsClient interface {
+	Recv() (*DropEvent, error)
+	grpc.ClientStream
+}
+
+type observabilityServiceStreamDropsClient struct {
+	grpc.ClientStream
+}
+
+func (x *observabilityServiceStreamDropsClient) Recv() (*DropEvent, error) {
+	m := new(DropEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *observabilityServiceClient) StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamMetricsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObservabilityService_ServiceDesc.Streams[1], ObservabilityService_StreamMetrics_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &observabilityServiceStreamMetricsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObservabilityService_StreamMetricsClient interface {
+	Recv() (*TrafficStats, error)
+	grpc.ClientStream
+}
+
+type observabilityServiceStreamMetricsClient struct {
+	grpc.ClientStream
+}
+
+func (x *observabilityServiceStreamMetricsClient) Recv() (*TrafficStats, error) {
+	m := new(TrafficStats)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *observabilityServiceClient) StreamSessions(ctx context.Context, in *StreamSessionsRequest, opts ...grpc.CallOption) (ObservabilityService_StreamSessionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObservabilityService_ServiceDesc.Streams[2], ObservabilityService_StreamSessions_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &observabilityServiceStreamSessionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObservabilityService_StreamSessionsClient interface {
+	Recv() (*SessionList, error)
+	grpc.ClientStream
+}
+
+type observabilityServiceStreamSessionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *observabilityServiceStreamSessionsClient) Recv() (*SessionList, error) {
+	m := new(SessionList)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *observabilityServiceClient) GetSessions(ctx context.Context, in *GetSessionsRequest, opts ...grpc.CallOption) (*SessionList, error) {
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, ObservabilityService_GetSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *observabilityServiceClient) GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*SessionInfo, error) {
+	out := new(SessionInfo)
+	err := c.cc.Invoke(ctx, ObservabilityService_GetSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ObservabilityServiceServer is the server API for ObservabilityService service.
+// All implementations must embed UnimplementedObservabilityServiceServer
+// for forward compatibility
+type ObservabilityServiceServer interface {
+	// StreamDrops sends every new packet drop event as it is collected
+	StreamDrops(*StreamDropsRequest, ObservabilityService_StreamDropsServer) error
+	// StreamMetrics sends a traffic statistics snapshot once per collection
+	// interval
+	StreamMetrics(*StreamMetricsRequest, ObservabilityService_StreamMetricsServer) error
+	// StreamSessions sends the full session list once per collection interval
+	StreamSessions(*StreamSessionsRequest, ObservabilityService_StreamSessionsServer) error
+	// GetSessions returns all known PDU sessions
+	GetSessions(context.Context, *GetSessionsRequest) (*SessionList, error)
+	// GetSession returns a single session by SEID (e.g. "0x1")
+	GetSession(context.Context, *GetSessionRequest) (*SessionInfo, error)
+	mustEmbedUnimplementedObservabilityServiceServer()
+}
+
+// UnimplementedObservabilityServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedObservabilityServiceServer struct {
+}
+
+func (UnimplementedObservabilityServiceServer) StreamDrops(*StreamDropsRequest, ObservabilityService_StreamDropsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamDrops not implemented")
+}
+func (UnimplementedObservabilityServiceServer) StreamMetrics(*StreamMetricsRequest, ObservabilityService_StreamMetricsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMetrics not implemented")
+}
+func (UnimplementedObservabilityServiceServer) StreamSessions(*StreamSessionsRequest, ObservabilityService_StreamSessionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSessions not implemented")
+}
+func (UnimplementedObservabilityServiceServer) GetSessions(context.Context, *GetSessionsRequest) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSessions not implemented")
+}
+func (UnimplementedObservabilityServiceServer) GetSession(context.Context, *GetSessionRequest) (*SessionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSession not implemented")
+}
+func (UnimplementedObservabilityServiceServer) mustEmbedUnimplementedObservabilityServiceServer() {}
+
+// UnsafeObservabilityServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ObservabilityServiceServer will
+// result in compilation errors.
+type UnsafeObservabilityServiceServer interface {
+	mustEmbedUnimplementedObservabilityServiceServer()
+}
+
+func RegisterObservabilityServiceServer(s grpc.ServiceRegistrar, srv ObservabilityServiceServer) {
+	s.RegisterService(&ObservabilityService_ServiceDesc, srv)
+}
+
+func _ObservabilityService_StreamDrops_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamDropsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObservabilityServiceServer).StreamDrops(m, &observabilityServiceStreamDropsServer{stream})
+}
+
+type ObservabilityService_StreamDropsServer interface {
+	Send(*DropEvent) error
+	grpc.ServerStream
+}
+
+type observabilityServiceStreamDropsServer struct {
+	grpc.ServerStream
+}
+
+func (x *observabilityServiceStreamDropsServer) Send(m *DropEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ObservabilityService_StreamMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObservabilityServiceServer).StreamMetrics(m, &observabilityServiceStreamMetricsServer{stream})
+}
+
+type ObservabilityService_StreamMetricsServer interface {
+	Send(*TrafficStats) error
+	grpc.ServerStream
+}
+
+type observabilityServiceStreamMetricsServer struct {
+	grpc.ServerStream
+}
+
+func (x *observabilityServiceStreamMetricsServer) Send(m *TrafficStats) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ObservabilityService_StreamSessions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamSessionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObservabilityServiceServer).StreamSessions(m, &observabilityServiceStreamSessionsServer{stream})
+}
+
+type ObservabilityService_StreamSessionsServer interface {
+	Send(*SessionList) error
+	grpc.ServerStream
+}
+
+type observabilityServiceStreamSessionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *observabilityServiceStreamSessionsServer) Send(m *SessionList) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ObservabilityService_GetSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObservabilityServiceServer).GetSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ObservabilityService_GetSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObservabilityServiceServer).GetSessions(ctx, req.(*GetSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ObservabilityService_GetSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObservabilityServiceServer).GetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ObservabilityService_GetSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObservabilityServiceServer).GetSession(ctx, req.(*GetSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ObservabilityService_ServiceDesc is the grpc.ServiceDesc for ObservabilityService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ObservabilityService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dpop.v1.ObservabilityService",
+	HandlerType: (*ObservabilityServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSessions",
+			Handler:    _ObservabilityService_GetSessions_Handler,
+		},
+		{
+			MethodName: "GetSession",
+			Handler:    _ObservabilityService_GetSession_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamDrops",
+			Handler:       _ObservabilityService_StreamDrops_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamMetrics",
+			Handler:       _ObservabilityService_StreamMetrics_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamSessions",
+			Handler:       _ObservabilityService_StreamSessions_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dpop/v1/dpop.proto",
+}
@@ -53,9 +53,9 @@ export default function TrafficChart({ metrics, theme = 'dark' }: TrafficChartPr
             second: '2-digit'
         })
 
-        // Get raw Mbps values
-        const rawUplink = metrics.uplink.throughput_mbps
-        const rawDownlink = metrics.downlink.throughput_mbps
+        // Get Mbps values, smoothed by the API server when available
+        const rawUplink = metrics.uplink.throughput_mbps_smoothed ?? metrics.uplink.throughput_mbps
+        const rawDownlink = metrics.downlink.throughput_mbps_smoothed ?? metrics.downlink.throughput_mbps
 
         // Calculate packets per second (delta)
         const uplinkPktsDelta = metrics.uplink.packets - prevPacketsRef.current.uplink
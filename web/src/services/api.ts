@@ -8,6 +8,7 @@ export interface DirectionStats {
     packets: number
     bytes: number
     throughput_mbps: number
+    throughput_mbps_smoothed?: number
     last_updated: string
 }
 